@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Abhishek2095
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+const defaultAnalyzeTop = 10
+
+// runAnalyze implements `kvstash analyze`, the offline counterpart of MEMORY
+// ANALYZE. It reads a RESP command file, such as an append-only file, and
+// prints the composition of the keyspace it builds.
+func runAnalyze(args []string) error {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	var (
+		db        = flags.Int("db", 0, "Database to analyze")
+		delimiter = flags.String("prefix-delimiter", store.DefaultAnalyzeDelimiter, "Separator between a key's prefix and the rest of its name")
+		top       = flags.Int("top", defaultAnalyzeTop, "Number of largest keys and prefixes to list")
+	)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: kvstash analyze [flags] <file>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected one file to analyze, got %d", flags.NArg())
+	}
+	if *top < 1 {
+		return errors.New("-top must be a positive integer")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	// The report goes to stdout, so the replay's store logs nothing
+	quiet := &obs.Logger{Logger: slog.New(slog.DiscardHandler)}
+	result, err := server.AnalyzeFile(file, *db, *delimiter, *top, quiet)
+	if err != nil {
+		return fmt.Errorf("failed to analyze %s: %w", flags.Arg(0), err)
+	}
+
+	return printAnalysis(os.Stdout, result)
+}
+
+// printAnalysis writes the analysis as aligned tables
+func printAnalysis(w io.Writer, result server.FileAnalysis) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	analysis := result.Analysis
+
+	fmt.Fprintf(tw, "commands\t%d\t\n", result.Commands)
+	fmt.Fprintf(tw, "skipped\t%d\t\n", result.Skipped)
+	fmt.Fprintf(tw, "keys\t%d\t\n", analysis.Keys)
+	fmt.Fprintf(tw, "bytes\t%d\t\n", analysis.Bytes)
+
+	fmt.Fprintf(tw, "\nlargest keys\tbytes\t\n")
+	for _, key := range analysis.Largest {
+		fmt.Fprintf(tw, "%s\t%d\t\n", key.Key, key.Bytes)
+	}
+
+	fmt.Fprintf(tw, "\nsize\tkeys\tbytes\t\n")
+	for i, bucket := range analysis.SizeHistogram {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t\n", analysis.SizeLabel(i), bucket.Keys, bucket.Bytes)
+	}
+
+	fmt.Fprintf(tw, "\nttl\tkeys\tbytes\t\n")
+	fmt.Fprintf(tw, "none\t%d\t%d\t\n", analysis.Persistent.Keys, analysis.Persistent.Bytes)
+	for i, bucket := range analysis.TTLHistogram {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t\n", analysis.TTLLabel(i), bucket.Keys, bucket.Bytes)
+	}
+
+	fmt.Fprintf(tw, "\nprefix\tkeys\tbytes\t\n")
+	for _, prefix := range analysis.Prefixes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t\n", prefix.Prefix, prefix.Keys, prefix.Bytes)
+	}
+
+	return tw.Flush()
+}
@@ -6,7 +6,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -23,6 +25,17 @@ const (
 )
 
 func main() {
+	// kvstash analyze <file> analyzes a command file offline
+	if len(os.Args) > 1 && os.Args[1] == "analyze" {
+		if err := runAnalyze(os.Args[2:]); err != nil {
+			if !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, "kvstash analyze:", err)
+			}
+			os.Exit(2)
+		}
+		return
+	}
+
 	var (
 		configPath = flag.String("config", defaultConfigPath, "Path to configuration file")
 		addr       = flag.String("addr", defaultAddr, "Server listen address")
@@ -69,18 +82,31 @@ func main() {
 		errCh <- srv.ListenAndServe()
 	}()
 
-	// Wait for shutdown signal
+	// Wait for shutdown signal. SIGHUP reloads the TLS certificates.
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	select {
-	case err := <-errCh:
-		if err != nil {
-			logger.Error("Server error", "error", err)
-			os.Exit(1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+wait:
+	for {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				logger.Error("Server error", "error", err)
+				os.Exit(1)
+			}
+			break wait
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP {
+				if cfg.Server.TLS.ListenAddr != "" {
+					if err := srv.ReloadTLS(); err != nil {
+						logger.Error("Failed to reload TLS certificates", "error", err)
+					}
+				}
+				continue
+			}
+			logger.Info("Received shutdown signal", "signal", sig)
+			break wait
 		}
-	case sig := <-sigCh:
-		logger.Info("Received shutdown signal", "signal", sig)
 	}
 
 	// Graceful shutdown
@@ -0,0 +1,596 @@
+// Package acl implements Redis-style access control lists: users with
+// passwords, an on/off state, permitted commands and command categories, and
+// key and channel patterns.
+package acl
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Abhishek2095/kv-stash/internal/glob"
+)
+
+// DefaultUser is the user new connections are authenticated as when it needs
+// no password, and the user AUTH with only a password refers to
+const DefaultUser = "default"
+
+// Category is a set of command categories
+type Category uint32
+
+// Command categories, as used in +@category and -@category rules
+const (
+	Read Category = 1 << iota
+	Write
+	Keyspace
+	String
+	PubSub
+	Connection
+	Admin
+	Dangerous
+	Fast
+	Slow
+)
+
+// All holds every category
+const All = Read | Write | Keyspace | String | PubSub | Connection | Admin | Dangerous | Fast | Slow
+
+// categoryNames lists the categories in the order ACL CAT reports them
+var categoryNames = []struct {
+	category Category
+	name     string
+}{
+	{Keyspace, "keyspace"},
+	{Read, "read"},
+	{Write, "write"},
+	{String, "string"},
+	{PubSub, "pubsub"},
+	{Admin, "admin"},
+	{Fast, "fast"},
+	{Slow, "slow"},
+	{Dangerous, "dangerous"},
+	{Connection, "connection"},
+}
+
+// Categories returns the names of every category
+func Categories() []string {
+	names := make([]string, len(categoryNames))
+	for i, c := range categoryNames {
+		names[i] = c.name
+	}
+	return names
+}
+
+// ParseCategory returns the category with the given name, without the @
+func ParseCategory(name string) (Category, bool) {
+	name = strings.ToLower(name)
+	if name == "all" {
+		return All, true
+	}
+	for _, c := range categoryNames {
+		if c.name == name {
+			return c.category, true
+		}
+	}
+	return 0, false
+}
+
+// reason explains why a rule or change was rejected. The text matches
+// Redis, so it is capitalized unlike most errors.
+type reason string
+
+func (r reason) Error() string {
+	return string(r)
+}
+
+// Reasons a rule is rejected
+const (
+	errSyntax         reason = "Syntax error"
+	errUnknownCommand reason = "Unknown command or category name in ACL"
+	errNoSuchPassword reason = "The password you are trying to remove from the user does not exist"
+	errInvalidHash    reason = "The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters"
+)
+
+// ErrDefaultUser is returned when deleting the default user
+var ErrDefaultUser error = reason("The 'default' user cannot be removed")
+
+// RuleError reports a rule that could not be applied to a user
+type RuleError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *RuleError) Error() string {
+	return "Error in ACL SETUSER modifier '" + e.Rule + "': " + e.Reason
+}
+
+// User is a snapshot of one user's rules. Changing a user replaces its
+// snapshot, so a User is never modified once published and may be read
+// without locking.
+type User struct {
+	name    string
+	enabled bool
+	nopass  bool
+	// passwords are SHA-256 hashes in hex, in the order they were added
+	passwords []string
+
+	allKeys     bool
+	keys        []string
+	allChannels bool
+	channels    []string
+
+	// commandRules are the command rules applied since the last +@all or
+	// -@all, which starts them. allowed is the resulting set of commands.
+	commandRules []string
+	allowed      map[string]bool
+}
+
+// Name returns the user's name
+func (u *User) Name() string {
+	return u.name
+}
+
+// Enabled reports whether the user may authenticate
+func (u *User) Enabled() bool {
+	return u.enabled
+}
+
+// NoPass reports whether the user accepts any password
+func (u *User) NoPass() bool {
+	return u.nopass
+}
+
+// Passwords returns the SHA-256 hashes of the user's passwords
+func (u *User) Passwords() []string {
+	return append([]string(nil), u.passwords...)
+}
+
+// CheckPassword reports whether password is one of the user's passwords,
+// comparing hashes in constant time
+func (u *User) CheckPassword(password string) bool {
+	if u.nopass {
+		return true
+	}
+	hash := HashPassword(password)
+	match := false
+	for _, candidate := range u.passwords {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(candidate)) == 1 {
+			match = true
+		}
+	}
+	return match
+}
+
+// CanRun reports whether the user may run the named command, given in upper
+// case
+func (u *User) CanRun(command string) bool {
+	return u.allowed[command]
+}
+
+// CanAccessKey reports whether key matches one of the user's key patterns
+func (u *User) CanAccessKey(key string) bool {
+	if u.allKeys {
+		return true
+	}
+	for _, pattern := range u.keys {
+		if glob.Match(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessChannel reports whether the user may use channel. A pattern, as
+// subscribed to with PSUBSCRIBE, must be one of the user's patterns verbatim.
+func (u *User) CanAccessChannel(channel string, pattern bool) bool {
+	if u.allChannels {
+		return true
+	}
+	for _, allowed := range u.channels {
+		if pattern && allowed == channel || !pattern && glob.Match(allowed, channel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Flags returns the user's flags as reported by ACL GETUSER
+func (u *User) Flags() []string {
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+// CommandRules returns the user's command rules, such as "+@all -flushall"
+func (u *User) CommandRules() string {
+	return strings.Join(u.commandRules, " ")
+}
+
+// KeyRules returns the user's key patterns, such as "~job:* ~tmp:*"
+func (u *User) KeyRules() string {
+	if u.allKeys {
+		return "~*"
+	}
+	return strings.Join(prefixAll("~", u.keys), " ")
+}
+
+// ChannelRules returns the user's channel patterns, such as "&news.*"
+func (u *User) ChannelRules() string {
+	if u.allChannels {
+		return "&*"
+	}
+	return strings.Join(prefixAll("&", u.channels), " ")
+}
+
+// String describes the user as a line of an ACL file, as listed by ACL LIST
+func (u *User) String() string {
+	parts := []string{"user", u.name, u.Flags()[0]}
+	if u.nopass {
+		parts = append(parts, "nopass")
+	}
+	parts = append(parts, prefixAll("#", u.passwords)...)
+	if u.allKeys {
+		parts = append(parts, "~*")
+	} else {
+		parts = append(parts, prefixAll("~", u.keys)...)
+	}
+	if u.allChannels {
+		parts = append(parts, "&*")
+	} else {
+		parts = append(parts, "resetchannels")
+		parts = append(parts, prefixAll("&", u.channels)...)
+	}
+	parts = append(parts, u.commandRules...)
+	return strings.Join(parts, " ")
+}
+
+// prefixAll returns each of values with prefix prepended
+func prefixAll(prefix string, values []string) []string {
+	prefixed := make([]string, len(values))
+	for i, value := range values {
+		prefixed[i] = prefix + value
+	}
+	return prefixed
+}
+
+// HashPassword returns the SHA-256 hash of password in hex, as users store it
+func HashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// ACL holds the users and the log of denied access. It is safe for
+// concurrent use: lookups read an immutable snapshot of the users and never
+// block.
+type ACL struct {
+	// commands maps every command name, in upper case, to its categories
+	commands map[string]Category
+	// defaultPassword is the default user's password when nothing else
+	// defines the user; empty for none
+	defaultPassword string
+
+	// mu serializes changes to users, which are copied on write
+	mu    sync.Mutex
+	users atomic.Pointer[map[string]*User]
+
+	log denialLog
+}
+
+// New creates an ACL knowing commands, given in upper case, and holding only
+// the default user. The default user requires defaultPassword unless it is
+// empty.
+func New(commands map[string]Category, defaultPassword string) *ACL {
+	a := &ACL{
+		commands:        commands,
+		defaultPassword: defaultPassword,
+		log:             denialLog{maxLen: DefaultLogMaxLen},
+	}
+	a.users.Store(&map[string]*User{DefaultUser: a.newDefaultUser()})
+	return a
+}
+
+// newDefaultUser creates the default user, enabled and with access to every
+// command, key and channel
+func (a *ACL) newDefaultUser() *User {
+	user := newUser(DefaultUser)
+	user.enabled = true
+	user.allKeys, user.allChannels = true, true
+	_ = a.applyCommandRule(user, "+@all")
+	if a.defaultPassword == "" {
+		user.nopass = true
+	} else {
+		addPassword(user, HashPassword(a.defaultPassword))
+	}
+	return user
+}
+
+// User returns the named user, or nil if there is none
+func (a *ACL) User(name string) *User {
+	return (*a.users.Load())[name]
+}
+
+// Users returns every user sorted by name
+func (a *ACL) Users() []*User {
+	users := *a.users.Load()
+	sorted := make([]*User, 0, len(users))
+	for _, user := range users {
+		sorted = append(sorted, user)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	return sorted
+}
+
+// Authenticate returns the named user if it is enabled and password is one
+// of its passwords
+func (a *ACL) Authenticate(name, password string) (*User, bool) {
+	user := a.User(name)
+	if user == nil || !user.enabled || !user.CheckPassword(password) {
+		return nil, false
+	}
+	return user, true
+}
+
+// SetUser applies rules to the named user, creating it if needed. A new user
+// starts disabled, without passwords and without access to anything. Either
+// every rule is applied or, on error, none is.
+func (a *ACL) SetUser(name string, rules ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current := *a.users.Load()
+	user := current[name].clone()
+	if user == nil {
+		user = newUser(name)
+	}
+	if err := a.apply(user, rules); err != nil {
+		return err
+	}
+
+	users := make(map[string]*User, len(current)+1)
+	for n, u := range current {
+		users[n] = u
+	}
+	users[name] = user
+	a.users.Store(&users)
+	return nil
+}
+
+// DeleteUser deletes the named users, returning how many existed. The default
+// user cannot be deleted.
+func (a *ACL) DeleteUser(names ...string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current := *a.users.Load()
+	users := make(map[string]*User, len(current))
+	for n, u := range current {
+		users[n] = u
+	}
+
+	deleted := 0
+	for _, name := range names {
+		if name == DefaultUser {
+			return 0, ErrDefaultUser
+		}
+		if _, ok := users[name]; ok {
+			delete(users, name)
+			deleted++
+		}
+	}
+	a.users.Store(&users)
+	return deleted, nil
+}
+
+// Commands returns the sorted names of the commands in any of categories
+func (a *ACL) Commands(categories Category) []string {
+	var names []string
+	for name, c := range a.commands {
+		if c&categories != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newUser creates a user without any access, as ACL SETUSER does
+func newUser(name string) *User {
+	return &User{
+		name:         name,
+		commandRules: []string{"-@all"},
+		allowed:      map[string]bool{},
+	}
+}
+
+// clone returns a copy of u that can be changed without affecting u
+func (u *User) clone() *User {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	c.passwords = append([]string(nil), u.passwords...)
+	c.keys = append([]string(nil), u.keys...)
+	c.channels = append([]string(nil), u.channels...)
+	c.commandRules = append([]string(nil), u.commandRules...)
+	c.allowed = make(map[string]bool, len(u.allowed))
+	for name := range u.allowed {
+		c.allowed[name] = true
+	}
+	return &c
+}
+
+// apply applies rules to user in order
+func (a *ACL) apply(user *User, rules []string) error {
+	for _, rule := range rules {
+		if err := a.applyRule(user, rule); err != nil {
+			return &RuleError{Rule: rule, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// applyRule applies a single rule to user
+func (a *ACL) applyRule(user *User, rule string) error {
+	switch strings.ToLower(rule) {
+	case "on":
+		user.enabled = true
+	case "off":
+		user.enabled = false
+	case "nopass":
+		user.nopass = true
+		user.passwords = nil
+	case "resetpass":
+		user.nopass = false
+		user.passwords = nil
+	case "allkeys":
+		user.allKeys, user.keys = true, nil
+	case "resetkeys":
+		user.allKeys, user.keys = false, nil
+	case "allchannels":
+		user.allChannels, user.channels = true, nil
+	case "resetchannels":
+		user.allChannels, user.channels = false, nil
+	case "allcommands":
+		return a.applyCommandRule(user, "+@all")
+	case "nocommands":
+		return a.applyCommandRule(user, "-@all")
+	case "reset":
+		*user = *newUser(user.name)
+	default:
+		return a.applyPrefixedRule(user, rule)
+	}
+	return nil
+}
+
+// applyPrefixedRule applies a rule made of a prefix and an operand, such as
+// a password, pattern or command
+func (a *ACL) applyPrefixedRule(user *User, rule string) error {
+	if rule == "" {
+		return errSyntax
+	}
+
+	operand := rule[1:]
+	switch rule[0] {
+	case '>':
+		addPassword(user, HashPassword(operand))
+	case '<':
+		return removePassword(user, HashPassword(operand))
+	case '#':
+		if !validHash(operand) {
+			return errInvalidHash
+		}
+		addPassword(user, operand)
+	case '!':
+		if !validHash(operand) {
+			return errInvalidHash
+		}
+		return removePassword(user, operand)
+	case '~':
+		user.keys, user.allKeys = addPattern(user.keys, user.allKeys, operand)
+	case '&':
+		user.channels, user.allChannels = addPattern(user.channels, user.allChannels, operand)
+	case '+', '-':
+		return a.applyCommandRule(user, rule)
+	default:
+		return errSyntax
+	}
+	return nil
+}
+
+// addPassword adds a password hash to user, which then needs a password
+func addPassword(user *User, hash string) {
+	user.nopass = false
+	for _, existing := range user.passwords {
+		if existing == hash {
+			return
+		}
+	}
+	user.passwords = append(user.passwords, hash)
+}
+
+// removePassword removes a password hash from user
+func removePassword(user *User, hash string) error {
+	for i, existing := range user.passwords {
+		if existing == hash {
+			user.passwords = append(user.passwords[:i], user.passwords[i+1:]...)
+			return nil
+		}
+	}
+	return errNoSuchPassword
+}
+
+// validHash reports whether hash is a SHA-256 hash in lower case hex
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for i := range len(hash) {
+		if !('0' <= hash[i] && hash[i] <= '9' || 'a' <= hash[i] && hash[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// addPattern adds pattern to patterns. The * pattern replaces the others with
+// the all flag, which makes further patterns redundant.
+func addPattern(patterns []string, all bool, pattern string) ([]string, bool) {
+	if all {
+		return nil, true
+	}
+	if pattern == "*" {
+		return nil, true
+	}
+	for _, existing := range patterns {
+		if existing == pattern {
+			return patterns, false
+		}
+	}
+	return append(patterns, pattern), false
+}
+
+// applyCommandRule applies +command, -command, +@category or -@category
+func (a *ACL) applyCommandRule(user *User, rule string) error {
+	allow := rule[0] == '+'
+	name := rule[1:]
+
+	var commands []string
+	if category, ok := strings.CutPrefix(name, "@"); ok {
+		c, ok := ParseCategory(category)
+		if !ok {
+			return errUnknownCommand
+		}
+		commands = a.Commands(c)
+		rule = rule[:1] + "@" + strings.ToLower(category)
+		if c == All {
+			// +@all and -@all override every earlier command rule
+			user.commandRules = nil
+		}
+	} else {
+		name = strings.ToUpper(name)
+		if _, ok := a.commands[name]; !ok {
+			return errUnknownCommand
+		}
+		commands = []string{name}
+		rule = strings.ToLower(rule)
+	}
+
+	for _, command := range commands {
+		if allow {
+			user.allowed[command] = true
+		} else {
+			delete(user.allowed, command)
+		}
+	}
+	user.commandRules = append(user.commandRules, rule)
+	return nil
+}
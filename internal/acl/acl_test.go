@@ -0,0 +1,275 @@
+package acl_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/acl"
+)
+
+// testCommands is a small command table for the tests
+var testCommands = map[string]acl.Category{
+	"GET":      acl.Read | acl.String | acl.Fast,
+	"SET":      acl.Write | acl.String | acl.Slow,
+	"DEL":      acl.Write | acl.Keyspace | acl.Slow,
+	"KEYS":     acl.Read | acl.Keyspace | acl.Slow | acl.Dangerous,
+	"FLUSHALL": acl.Write | acl.Keyspace | acl.Slow | acl.Dangerous,
+	"PUBLISH":  acl.PubSub | acl.Fast,
+	"PING":     acl.Connection | acl.Fast,
+}
+
+func TestACL_DefaultUser(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	user := a.User(acl.DefaultUser)
+	if user == nil {
+		t.Fatal("Expected the default user to exist")
+	}
+	if got := user.String(); got != "user default on nopass ~* &* +@all" {
+		t.Errorf("Unexpected default user %q", got)
+	}
+	if !user.CanRun("FLUSHALL") || !user.CanAccessKey("any") || !user.CanAccessChannel("any", false) {
+		t.Error("Expected the default user to have full access")
+	}
+	if _, ok := a.Authenticate(acl.DefaultUser, "anything"); !ok {
+		t.Error("Expected the nopass default user to accept any password")
+	}
+
+	a = acl.New(testCommands, "secret")
+	user = a.User(acl.DefaultUser)
+	if got := user.String(); got != "user default on #"+acl.HashPassword("secret")+" ~* &* +@all" {
+		t.Errorf("Unexpected default user with a password %q", got)
+	}
+	if _, ok := a.Authenticate(acl.DefaultUser, "wrong"); ok {
+		t.Error("Expected a wrong password to be rejected")
+	}
+	if _, ok := a.Authenticate(acl.DefaultUser, "secret"); !ok {
+		t.Error("Expected the password to be accepted")
+	}
+}
+
+func TestACL_SetUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		rules    []string
+		expected string
+	}{
+		{name: "new user has no access", rules: nil, expected: "user alice off resetchannels -@all"},
+		{name: "enabled with password", rules: []string{"on", ">pw"}, expected: "user alice on #" + acl.HashPassword("pw") + " resetchannels -@all"},
+		{name: "password hash", rules: []string{"#" + acl.HashPassword("pw")}, expected: "user alice off #" + acl.HashPassword("pw") + " resetchannels -@all"},
+		{name: "removed password", rules: []string{">a", ">b", "<a"}, expected: "user alice off #" + acl.HashPassword("b") + " resetchannels -@all"},
+		{name: "nopass clears passwords", rules: []string{">a", "nopass"}, expected: "user alice off nopass resetchannels -@all"},
+		{name: "key and channel patterns", rules: []string{"~job:*", "~tmp:*", "&jobs.*"}, expected: "user alice off ~job:* ~tmp:* resetchannels &jobs.* -@all"},
+		{name: "star pattern", rules: []string{"~job:*", "~*", "allchannels"}, expected: "user alice off ~* &* -@all"},
+		{name: "resetkeys", rules: []string{"allkeys", "resetkeys", "~a"}, expected: "user alice off ~a resetchannels -@all"},
+		{name: "command rules", rules: []string{"+@all", "-@dangerous", "+keys", "-SET"}, expected: "user alice off resetchannels +@all -@dangerous +keys -set"},
+		{name: "+@all restarts command rules", rules: []string{"+get", "-@all", "+@READ", "allcommands"}, expected: "user alice off resetchannels +@all"},
+		{name: "reset", rules: []string{"on", "nopass", "~*", "+@all", "reset"}, expected: "user alice off resetchannels -@all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := acl.New(testCommands, "")
+			if err := a.SetUser("alice", tt.rules...); err != nil {
+				t.Fatalf("SetUser failed: %v", err)
+			}
+			if got := a.User("alice").String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestACL_SetUser_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		rule     string
+		expected string
+	}{
+		{name: "unknown rule", rule: "bogus", expected: "Error in ACL SETUSER modifier 'bogus': Syntax error"},
+		{name: "empty rule", rule: "", expected: "Error in ACL SETUSER modifier '': Syntax error"},
+		{name: "unknown command", rule: "+nosuch", expected: "Error in ACL SETUSER modifier '+nosuch': Unknown command or category name in ACL"},
+		{name: "unknown category", rule: "-@nosuch", expected: "Error in ACL SETUSER modifier '-@nosuch': Unknown command or category name in ACL"},
+		{name: "missing password", rule: "<nope", expected: "Error in ACL SETUSER modifier '<nope': The password you are trying to remove from the user does not exist"},
+		{
+			name:     "invalid hash",
+			rule:     "#ABC",
+			expected: "Error in ACL SETUSER modifier '#ABC': The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := acl.New(testCommands, "")
+			if err := a.SetUser("alice", "on", tt.rule); err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+			var ruleErr *acl.RuleError
+			if err := a.SetUser("alice", tt.rule); !errors.As(err, &ruleErr) || ruleErr.Rule != tt.rule {
+				t.Errorf("Expected a RuleError for %q, got %v", tt.rule, err)
+			}
+			// A failed SETUSER changes nothing, not even creating the user
+			if a.User("alice") != nil {
+				t.Error("Expected the user not to be created")
+			}
+		})
+	}
+}
+
+func TestUser_Permissions(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	if err := a.SetUser("jobs", "on", ">pw", "+@all", "-@dangerous", "~job:*", "&jobs.*"); err != nil {
+		t.Fatalf("SetUser failed: %v", err)
+	}
+	user := a.User("jobs")
+
+	commands := map[string]bool{"GET": true, "SET": true, "DEL": true, "PING": true, "FLUSHALL": false, "KEYS": false}
+	for command, expected := range commands {
+		if got := user.CanRun(command); got != expected {
+			t.Errorf("Expected CanRun(%s) to be %v", command, expected)
+		}
+	}
+
+	keys := map[string]bool{"job:1": true, "job:": true, "jobs": false, "other": false}
+	for key, expected := range keys {
+		if got := user.CanAccessKey(key); got != expected {
+			t.Errorf("Expected CanAccessKey(%s) to be %v", key, expected)
+		}
+	}
+
+	channels := []struct {
+		channel  string
+		pattern  bool
+		expected bool
+	}{
+		{channel: "jobs.done", expected: true},
+		{channel: "news", expected: false},
+		{channel: "jobs.*", pattern: true, expected: true},
+		{channel: "jobs.d*", pattern: true, expected: false},
+	}
+	for _, tt := range channels {
+		if got := user.CanAccessChannel(tt.channel, tt.pattern); got != tt.expected {
+			t.Errorf("Expected CanAccessChannel(%s, %v) to be %v", tt.channel, tt.pattern, tt.expected)
+		}
+	}
+}
+
+func TestACL_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	if err := a.SetUser("alice", "on", ">one", ">two"); err != nil {
+		t.Fatalf("SetUser failed: %v", err)
+	}
+	if err := a.SetUser("bob", "off", ">pw"); err != nil {
+		t.Fatalf("SetUser failed: %v", err)
+	}
+	if err := a.SetUser("carol", "on"); err != nil {
+		t.Fatalf("SetUser failed: %v", err)
+	}
+
+	tests := []struct {
+		user     string
+		password string
+		expected bool
+	}{
+		{user: "alice", password: "one", expected: true},
+		{user: "alice", password: "two", expected: true},
+		{user: "alice", password: "three", expected: false},
+		{user: "bob", password: "pw", expected: false},
+		{user: "carol", password: "", expected: false},
+		{user: "nobody", password: "pw", expected: false},
+	}
+	for _, tt := range tests {
+		if _, ok := a.Authenticate(tt.user, tt.password); ok != tt.expected {
+			t.Errorf("Expected Authenticate(%s, %s) to be %v", tt.user, tt.password, tt.expected)
+		}
+	}
+}
+
+func TestACL_SetUserReplacesSnapshot(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	if err := a.SetUser("alice", "on", "+get"); err != nil {
+		t.Fatalf("SetUser failed: %v", err)
+	}
+	before := a.User("alice")
+	if err := a.SetUser("alice", "+set"); err != nil {
+		t.Fatalf("SetUser failed: %v", err)
+	}
+
+	if before.CanRun("SET") {
+		t.Error("Expected an earlier snapshot to be unchanged")
+	}
+	if after := a.User("alice"); !after.CanRun("SET") || !after.CanRun("GET") {
+		t.Error("Expected the new snapshot to have both commands")
+	}
+}
+
+func TestACL_DeleteUser(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	for _, name := range []string{"alice", "bob"} {
+		if err := a.SetUser(name); err != nil {
+			t.Fatalf("SetUser failed: %v", err)
+		}
+	}
+
+	deleted, err := a.DeleteUser("alice", "nobody")
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 user deleted, got %d, %v", deleted, err)
+	}
+	if _, err := a.DeleteUser("bob", acl.DefaultUser); !errors.Is(err, acl.ErrDefaultUser) {
+		t.Errorf("Expected ErrDefaultUser, got %v", err)
+	}
+
+	var names []string
+	for _, user := range a.Users() {
+		names = append(names, user.Name())
+	}
+	if expected := []string{"bob", "default"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected users %v, got %v", expected, names)
+	}
+}
+
+func TestACL_Commands(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	if got, expected := a.Commands(acl.Dangerous), []string{"FLUSHALL", "KEYS"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := a.Commands(acl.All); len(got) != len(testCommands) {
+		t.Errorf("Expected every command in @all, got %v", got)
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range acl.Categories() {
+		if _, ok := acl.ParseCategory(name); !ok {
+			t.Errorf("Expected category %s to parse", name)
+		}
+	}
+	if category, ok := acl.ParseCategory("ALL"); !ok || category != acl.All {
+		t.Errorf("Expected ALL to be every category, got %v", category)
+	}
+	if _, ok := acl.ParseCategory("nosuch"); ok {
+		t.Error("Expected an unknown category not to parse")
+	}
+}
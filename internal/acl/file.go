@@ -0,0 +1,92 @@
+package acl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadFile replaces every user with those defined in the ACL file at path.
+// The file is either loaded whole or, on error, not at all.
+func (a *ACL) LoadFile(path string) error {
+	file, err := os.Open(path) // #nosec G304 -- Path comes from the operator's configuration
+	if err != nil {
+		return fmt.Errorf("failed to open ACL file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	return a.Load(file, path)
+}
+
+// Load replaces every user with those defined by r, one "user <name> [rules]"
+// line each. name identifies r in errors. A default user not defined by r is
+// recreated. Connections keep their user only if it is still defined.
+func (a *ACL) Load(r io.Reader, name string) error {
+	users := make(map[string]*User)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] != "user" || len(fields) < 2 {
+			return fmt.Errorf("%s:%d: should start with user keyword followed by the username", name, line)
+		}
+
+		username := fields[1]
+		if _, ok := users[username]; ok {
+			return fmt.Errorf("%s:%d: duplicate user '%s' found", name, line, username)
+		}
+		user := newUser(username)
+		if err := a.apply(user, fields[2:]); err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		users[username] = user
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	if users[DefaultUser] == nil {
+		users[DefaultUser] = a.newDefaultUser()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users.Store(&users)
+	return nil
+}
+
+// SaveFile writes every user to the ACL file at path, replacing it
+// atomically
+func (a *ACL) SaveFile(path string) error {
+	// Holding the lock keeps concurrent saves from interleaving
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create ACL file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	writer := bufio.NewWriter(tmp)
+	for _, user := range a.Users() {
+		_, _ = writer.WriteString(user.String() + "\n")
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write ACL file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write ACL file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace ACL file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package acl_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/acl"
+)
+
+func TestACL_SaveAndLoadFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "users.acl")
+	a := acl.New(testCommands, "")
+	if err := a.SetUser("jobs", "on", ">pw", "~job:*", "&jobs.*", "+@all", "-@dangerous"); err != nil {
+		t.Fatalf("SetUser failed: %v", err)
+	}
+	if err := a.SetUser(acl.DefaultUser, "resetpass", ">admin"); err != nil {
+		t.Fatalf("SetUser failed: %v", err)
+	}
+	if err := a.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the ACL file: %v", err)
+	}
+	expected := "user default on #" + acl.HashPassword("admin") + " ~* &* +@all\n" +
+		"user jobs on #" + acl.HashPassword("pw") + " ~job:* resetchannels &jobs.* +@all -@dangerous\n"
+	if string(data) != expected {
+		t.Errorf("Expected file\n%s\ngot\n%s", expected, data)
+	}
+
+	loaded := acl.New(testCommands, "")
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	for _, user := range a.Users() {
+		if got := loaded.User(user.Name()); got == nil || got.String() != user.String() {
+			t.Errorf("Expected %q to be loaded, got %v", user.String(), got)
+		}
+	}
+	if _, ok := loaded.Authenticate("jobs", "pw"); !ok {
+		t.Error("Expected a loaded user to authenticate")
+	}
+}
+
+func TestACL_Load(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		content  string
+		users    []string
+		errorMsg string
+	}{
+		{name: "default user recreated", content: "user alice on nopass +get\n\n", users: []string{"alice", "default"}},
+		{name: "default user replaced", content: "user default off\n", users: []string{"default"}},
+		{name: "missing keyword", content: "user alice on\nalice off\n", errorMsg: "users.acl:2: should start with user keyword followed by the username"},
+		{name: "missing username", content: "user\n", errorMsg: "users.acl:1: should start with user keyword"},
+		{name: "duplicate user", content: "user alice\nuser alice\n", errorMsg: "users.acl:2: duplicate user 'alice' found"},
+		{name: "invalid rule", content: "user alice +nosuch\n", errorMsg: "users.acl:1: Error in ACL SETUSER modifier '+nosuch'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := acl.New(testCommands, "")
+			if err := a.SetUser("bob", "on"); err != nil {
+				t.Fatalf("SetUser failed: %v", err)
+			}
+
+			err := a.Load(strings.NewReader(tt.content), "users.acl")
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				// A failed load leaves the users as they were
+				if a.User("bob") == nil {
+					t.Error("Expected the users to be unchanged")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			var names []string
+			for _, user := range a.Users() {
+				names = append(names, user.Name())
+			}
+			if strings.Join(names, ",") != strings.Join(tt.users, ",") {
+				t.Errorf("Expected users %v, got %v", tt.users, names)
+			}
+		})
+	}
+}
+
+func TestACL_LoadFile_Missing(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	if err := a.LoadFile(filepath.Join(t.TempDir(), "missing.acl")); err == nil {
+		t.Error("Expected an error for a missing ACL file")
+	}
+}
@@ -0,0 +1,108 @@
+package acl
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLogMaxLen is the number of entries the log keeps, the newest
+	// replacing the oldest
+	DefaultLogMaxLen = 128
+	// logGroupWindow is how many of the newest entries a repeated denial is
+	// looked for in, to count it there instead of adding an entry
+	logGroupWindow = 10
+)
+
+// Reasons access is logged as denied
+const (
+	ReasonCommand = "command"
+	ReasonKey     = "key"
+	ReasonChannel = "channel"
+	ReasonAuth    = "auth"
+)
+
+// LogEntry is a denied command or failed authentication. Repeated denials of
+// the same object to the same user are counted in one entry.
+type LogEntry struct {
+	// ID increases with every new entry
+	ID    int64
+	Count int64
+	// Reason is one of the Reason constants
+	Reason string
+	// Object is the command, key or channel access was denied to
+	Object     string
+	Username   string
+	ClientInfo string
+	Created    time.Time
+	Updated    time.Time
+}
+
+// denialLog is the bounded list of recent denials, newest last
+type denialLog struct {
+	mu      sync.Mutex
+	entries []*LogEntry
+	nextID  int64
+	maxLen  int
+}
+
+// Log records that username was denied access to object for reason
+func (a *ACL) Log(reason, object, username, clientInfo string) {
+	a.log.add(reason, object, username, clientInfo, time.Now())
+}
+
+// LogEntries returns up to count of the newest log entries, newest first.
+// A negative count returns every entry.
+func (a *ACL) LogEntries(count int) []LogEntry {
+	a.log.mu.Lock()
+	defer a.log.mu.Unlock()
+
+	if count < 0 || count > len(a.log.entries) {
+		count = len(a.log.entries)
+	}
+	entries := make([]LogEntry, count)
+	for i := range entries {
+		entries[i] = *a.log.entries[len(a.log.entries)-1-i]
+	}
+	return entries
+}
+
+// ResetLog removes every log entry
+func (a *ACL) ResetLog() {
+	a.log.mu.Lock()
+	defer a.log.mu.Unlock()
+
+	a.log.entries = nil
+}
+
+// add records a denial at now, counting it in a recent matching entry when
+// there is one
+func (l *denialLog) add(reason, object, username, clientInfo string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := len(l.entries) - 1; i >= 0 && i >= len(l.entries)-logGroupWindow; i-- {
+		entry := l.entries[i]
+		if entry.Reason == reason && entry.Object == object && entry.Username == username {
+			entry.Count++
+			entry.ClientInfo = clientInfo
+			entry.Updated = now
+			return
+		}
+	}
+
+	if len(l.entries) >= l.maxLen {
+		l.entries = append(l.entries[:0], l.entries[len(l.entries)-l.maxLen+1:]...)
+	}
+	l.entries = append(l.entries, &LogEntry{
+		ID:         l.nextID,
+		Count:      1,
+		Reason:     reason,
+		Object:     object,
+		Username:   username,
+		ClientInfo: clientInfo,
+		Created:    now,
+		Updated:    now,
+	})
+	l.nextID++
+}
@@ -0,0 +1,73 @@
+package acl_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/acl"
+)
+
+func TestACL_Log(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	a.Log(acl.ReasonCommand, "flushall", "jobs", "id=1")
+	a.Log(acl.ReasonKey, "secret", "jobs", "id=1")
+	a.Log(acl.ReasonCommand, "flushall", "jobs", "id=2")
+	a.Log(acl.ReasonAuth, "AUTH", "admin", "id=3")
+
+	entries := a.LogEntries(-1)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %+v", entries)
+	}
+
+	// Newest first; the repeated denial is counted in its first entry
+	expected := []struct {
+		id       int64
+		count    int64
+		reason   string
+		object   string
+		username string
+		client   string
+	}{
+		{id: 2, count: 1, reason: acl.ReasonAuth, object: "AUTH", username: "admin", client: "id=3"},
+		{id: 1, count: 1, reason: acl.ReasonKey, object: "secret", username: "jobs", client: "id=1"},
+		{id: 0, count: 2, reason: acl.ReasonCommand, object: "flushall", username: "jobs", client: "id=2"},
+	}
+	for i, want := range expected {
+		got := entries[i]
+		if got.ID != want.id || got.Count != want.count || got.Reason != want.reason ||
+			got.Object != want.object || got.Username != want.username || got.ClientInfo != want.client {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	if entries[2].Updated.Before(entries[2].Created) {
+		t.Error("Expected a counted entry to be updated after it was created")
+	}
+
+	if got := a.LogEntries(1); len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("Expected only the newest entry, got %+v", got)
+	}
+
+	a.ResetLog()
+	if got := a.LogEntries(-1); len(got) != 0 {
+		t.Errorf("Expected an empty log after a reset, got %+v", got)
+	}
+}
+
+func TestACL_LogBounded(t *testing.T) {
+	t.Parallel()
+
+	a := acl.New(testCommands, "")
+	for i := range acl.DefaultLogMaxLen + 10 {
+		a.Log(acl.ReasonKey, fmt.Sprintf("key%d", i), "jobs", "")
+	}
+
+	entries := a.LogEntries(-1)
+	if len(entries) != acl.DefaultLogMaxLen {
+		t.Fatalf("Expected %d entries, got %d", acl.DefaultLogMaxLen, len(entries))
+	}
+	if newest, oldest := entries[0].ID, entries[len(entries)-1].ID; newest != acl.DefaultLogMaxLen+9 || oldest != 10 {
+		t.Errorf("Expected entries 10 to %d, got %d to %d", acl.DefaultLogMaxLen+9, oldest, newest)
+	}
+}
@@ -0,0 +1,115 @@
+// Package glob implements Redis-style glob pattern matching used by KEYS, SCAN
+// and pattern subscriptions.
+package glob
+
+// Match reports whether s matches pattern. The supported syntax follows Redis:
+// '*' matches any sequence of characters including none, '?' matches exactly
+// one character, "[abc]" matches one of the listed characters, "[^abc]" any
+// character not listed, "[a-z]" a character range, and a backslash makes the
+// following character match literally.
+func Match(pattern, s string) bool {
+	// Position to resume from when a later mismatch lets the last '*'
+	// absorb one more character
+	starPattern, starString := -1, 0
+
+	p, i := 0, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				// Collapse consecutive stars
+				for p < len(pattern) && pattern[p] == '*' {
+					p++
+				}
+				if p == len(pattern) {
+					return true
+				}
+				starPattern, starString = p, i
+				continue
+			case '?':
+				p++
+				i++
+				continue
+			case '[':
+				if next, ok := matchClass(pattern, p, s[i]); ok {
+					p = next
+					i++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) {
+					if pattern[p+1] == s[i] {
+						p += 2
+						i++
+						continue
+					}
+				} else if s[i] == '\\' {
+					p++
+					i++
+					continue
+				}
+			default:
+				if pattern[p] == s[i] {
+					p++
+					i++
+					continue
+				}
+			}
+		}
+
+		// Mismatch: backtrack to the last star if there is one
+		if starPattern < 0 {
+			return false
+		}
+		starString++
+		p, i = starPattern, starString
+	}
+
+	// Remaining pattern must be all stars
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// matchClass matches c against the bracket expression starting at
+// pattern[start] and returns the index just past the closing bracket
+func matchClass(pattern string, start int, c byte) (int, bool) {
+	p := start + 1
+	negate := false
+	if p < len(pattern) && pattern[p] == '^' {
+		negate = true
+		p++
+	}
+
+	matched := false
+	for p < len(pattern) && pattern[p] != ']' {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			if pattern[p] == c {
+				matched = true
+			}
+		case p+2 < len(pattern) && pattern[p+1] == '-' && pattern[p+2] != ']':
+			lo, hi := pattern[p], pattern[p+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			p += 2
+		case pattern[p] == c:
+			matched = true
+		}
+		p++
+	}
+
+	// Like Redis, an unterminated class runs to the end of the pattern
+	if p < len(pattern) {
+		p++
+	}
+
+	return p, matched != negate
+}
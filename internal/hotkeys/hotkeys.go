@@ -0,0 +1,239 @@
+// Package hotkeys finds the most frequently accessed keys with a sliding
+// window of Count-Min sketches and heavy-hitter candidate sets.
+package hotkeys
+
+import (
+	"errors"
+	"hash/maphash"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// sketchDepth is the number of hash rows of each Count-Min sketch
+	sketchDepth = 4
+	// sketchWidth is the number of counters per row. With depth 4 a key's
+	// count is overestimated by more than e/width (about 0.3%) of the samples
+	// in a bucket with probability below 2%.
+	sketchWidth = 1024
+	// heavyHitters is the number of candidate keys each bucket remembers
+	heavyHitters = 32
+	// bucketDuration is the time span of one bucket of the sliding window
+	bucketDuration = time.Second
+)
+
+// HotKey is a frequently accessed key with its estimated access count
+type HotKey struct {
+	Key string
+	// Count is the estimated number of accesses in the window, scaled up
+	// from the samples
+	Count int64
+	// Share is the fraction of all sampled accesses in the window that went
+	// to this key
+	Share float64
+}
+
+// Tracker estimates per-key access counts over a sliding window. Accesses
+// are sampled, so the cost of a non-sampled access is a random number draw.
+// It is safe for concurrent use.
+type Tracker struct {
+	sampleRate float64
+	window     time.Duration
+	seed       maphash.Seed
+
+	mu      sync.Mutex
+	buckets []bucket
+}
+
+// bucket holds the samples of one bucketDuration of the window
+type bucket struct {
+	start  int64 // bucket number, i.e. unix time divided by bucketDuration
+	total  int64
+	sketch [sketchDepth][sketchWidth]uint32
+	heavy  map[string]uint32 // candidate key -> sketch estimate when last seen
+
+	// minimum is the candidate with the lowest estimate when minimumKnown
+	minimum      string
+	minimumKnown bool
+}
+
+// New creates a tracker sampling accesses with probability sampleRate, which
+// must be in (0, 1], and answering queries over up to window
+func New(sampleRate float64, window time.Duration) (*Tracker, error) {
+	if sampleRate <= 0 || sampleRate > 1 {
+		return nil, errors.New("sample rate must be greater than 0 and at most 1")
+	}
+	if window < bucketDuration {
+		return nil, errors.New("window must be at least one second")
+	}
+
+	buckets := make([]bucket, window/bucketDuration)
+	for i := range buckets {
+		buckets[i].start = -1
+	}
+
+	return &Tracker{
+		sampleRate: sampleRate,
+		window:     window,
+		seed:       maphash.MakeSeed(),
+		buckets:    buckets,
+	}, nil
+}
+
+// Window returns the longest window Top can report on
+func (t *Tracker) Window() time.Duration {
+	return t.window
+}
+
+// Record counts an access to key if it is sampled
+func (t *Tracker) Record(key string) {
+	if t.sampleRate < 1 && rand.Float64() >= t.sampleRate { // #nosec G404 -- sampling does not need a secure source
+		return
+	}
+	t.add(key, time.Now())
+}
+
+// add counts one sampled access to key at now
+func (t *Tracker) add(key string, now time.Time) {
+	h1, h2 := t.hash(key)
+	number := now.UnixNano() / int64(bucketDuration)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := &t.buckets[number%int64(len(t.buckets))]
+	if b.start != number {
+		b.reset(number)
+	}
+
+	b.total++
+	estimate := uint32(0)
+	for row := range sketchDepth {
+		counter := &b.sketch[row][column(h1, h2, row)]
+		*counter++
+		if row == 0 || *counter < estimate {
+			estimate = *counter
+		}
+	}
+	b.offer(key, estimate)
+}
+
+// Top returns up to n of the most accessed keys over the last window, hottest
+// first. A window of zero or longer than the tracker's uses the whole window.
+func (t *Tracker) Top(n int, window time.Duration) []HotKey {
+	return t.top(n, window, time.Now())
+}
+
+func (t *Tracker) top(n int, window time.Duration, now time.Time) []HotKey {
+	if window <= 0 || window > t.window {
+		window = t.window
+	}
+	newest := now.UnixNano() / int64(bucketDuration)
+	oldest := newest - int64(window/bucketDuration) + 1
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var live []*bucket
+	var total int64
+	candidates := make(map[string]bool)
+	for i := range t.buckets {
+		b := &t.buckets[i]
+		if b.start < oldest || b.start > newest {
+			continue
+		}
+		live = append(live, b)
+		total += b.total
+		for key := range b.heavy {
+			candidates[key] = true
+		}
+	}
+
+	hot := make([]HotKey, 0, len(candidates))
+	for key := range candidates {
+		h1, h2 := t.hash(key)
+		var samples int64
+		for _, b := range live {
+			samples += int64(b.estimate(h1, h2))
+		}
+		hot = append(hot, HotKey{
+			Key:   key,
+			Count: int64(float64(samples) / t.sampleRate),
+			Share: float64(samples) / float64(total),
+		})
+	}
+
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Count != hot[j].Count {
+			return hot[i].Count > hot[j].Count
+		}
+		return hot[i].Key < hot[j].Key
+	})
+	if len(hot) > n {
+		hot = hot[:n]
+	}
+	return hot
+}
+
+// hash returns the two hashes from which a key's sketch columns are derived
+func (t *Tracker) hash(key string) (uint32, uint32) {
+	h := maphash.String(t.seed, key)
+	return uint32(h), uint32(h>>32) | 1 // #nosec G115 -- splitting a 64-bit hash
+}
+
+// column returns the counter of key in row using double hashing
+func column(h1, h2 uint32, row int) uint32 {
+	return (h1 + uint32(row)*h2) % sketchWidth // #nosec G115 -- row is below sketchDepth
+}
+
+// reset empties the bucket for reuse as bucket number start
+func (b *bucket) reset(start int64) {
+	b.start = start
+	b.total = 0
+	b.sketch = [sketchDepth][sketchWidth]uint32{}
+	b.heavy = make(map[string]uint32, heavyHitters)
+	b.minimumKnown = false
+}
+
+// estimate returns the sketch's count for the key hashing to h1 and h2
+func (b *bucket) estimate(h1, h2 uint32) uint32 {
+	estimate := b.sketch[0][column(h1, h2, 0)]
+	for row := 1; row < sketchDepth; row++ {
+		estimate = min(estimate, b.sketch[row][column(h1, h2, row)])
+	}
+	return estimate
+}
+
+// offer updates key's candidate entry, replacing the weakest candidate when
+// the set is full and key has overtaken it
+func (b *bucket) offer(key string, estimate uint32) {
+	_, exists := b.heavy[key]
+	if exists || len(b.heavy) < heavyHitters {
+		b.heavy[key] = estimate
+		// Estimates only grow, so the known minimum stays valid unless it
+		// is the key that grew or a new, possibly lower, key joined
+		if !exists || key == b.minimum {
+			b.minimumKnown = false
+		}
+		return
+	}
+
+	if !b.minimumKnown {
+		first := true
+		for candidate, count := range b.heavy {
+			if first || count < b.heavy[b.minimum] {
+				b.minimum, first = candidate, false
+			}
+		}
+		b.minimumKnown = true
+	}
+	if estimate <= b.heavy[b.minimum] {
+		return
+	}
+
+	delete(b.heavy, b.minimum)
+	b.heavy[key] = estimate
+	b.minimumKnown = false
+}
@@ -0,0 +1,168 @@
+package hotkeys_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/hotkeys"
+)
+
+func newTracker(t *testing.T, sampleRate float64, window time.Duration) *hotkeys.Tracker {
+	t.Helper()
+
+	tracker, err := hotkeys.New(sampleRate, window)
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+	return tracker
+}
+
+func TestNew_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		sampleRate float64
+		window     time.Duration
+	}{
+		{name: "zero sample rate", sampleRate: 0, window: time.Minute},
+		{name: "sample rate above one", sampleRate: 1.5, window: time.Minute},
+		{name: "window below a second", sampleRate: 1, window: time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := hotkeys.New(tt.sampleRate, tt.window); err == nil {
+				t.Errorf("Expected error for sample rate %v and window %v", tt.sampleRate, tt.window)
+			}
+		})
+	}
+}
+
+func TestTracker_Top(t *testing.T) {
+	t.Parallel()
+
+	tracker := newTracker(t, 1, time.Minute)
+	if hot := tracker.Top(10, 0); len(hot) != 0 {
+		t.Errorf("Expected no hot keys before any access, got %v", hot)
+	}
+
+	// One key takes 40% of the traffic, the rest is spread over many keys
+	for i := range 10000 {
+		switch {
+		case i%5 < 2:
+			tracker.Record("hot")
+		case i%5 == 2:
+			tracker.Record("warm")
+		default:
+			tracker.Record(fmt.Sprintf("cold:%d", i))
+		}
+	}
+
+	hot := tracker.Top(2, 0)
+	if len(hot) != 2 {
+		t.Fatalf("Expected 2 hot keys, got %v", hot)
+	}
+	if hot[0].Key != "hot" || hot[1].Key != "warm" {
+		t.Errorf("Expected hot then warm, got %v", hot)
+	}
+	// Count-Min sketches only overestimate
+	if hot[0].Count < 4000 || hot[0].Count > 4100 {
+		t.Errorf("Expected about 4000 accesses to hot, got %d", hot[0].Count)
+	}
+	if hot[0].Share < 0.4 || hot[0].Share > 0.41 {
+		t.Errorf("Expected hot to have a 40%% share, got %v", hot[0].Share)
+	}
+}
+
+func TestTracker_Sampling(t *testing.T) {
+	t.Parallel()
+
+	tracker := newTracker(t, 0.1, time.Minute)
+	for range 100000 {
+		tracker.Record("key")
+	}
+
+	hot := tracker.Top(1, 0)
+	if len(hot) != 1 {
+		t.Fatalf("Expected one hot key, got %v", hot)
+	}
+	// Sampled counts are scaled back up to estimate every access
+	if hot[0].Count < 90000 || hot[0].Count > 110000 {
+		t.Errorf("Expected about 100000 accesses, got %d", hot[0].Count)
+	}
+}
+
+func TestTracker_Window(t *testing.T) {
+	t.Parallel()
+
+	tracker := newTracker(t, 1, 10*time.Second)
+	for range 100 {
+		tracker.Record("old")
+	}
+
+	// Move into a later bucket
+	time.Sleep(1100 * time.Millisecond)
+	for range 10 {
+		tracker.Record("new")
+	}
+
+	recent := tracker.Top(10, time.Second)
+	if len(recent) != 1 || recent[0].Key != "new" {
+		t.Errorf("Expected only the new key in a one second window, got %v", recent)
+	}
+
+	all := tracker.Top(10, 0)
+	if len(all) != 2 || all[0].Key != "old" || all[0].Count != 100 {
+		t.Errorf("Expected both keys over the whole window, got %v", all)
+	}
+}
+
+func TestTracker_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	tracker := newTracker(t, 1, time.Minute)
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				tracker.Record(fmt.Sprintf("key:%d", (worker*i)%50))
+				if i%100 == 0 {
+					tracker.Top(5, 0)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if hot := tracker.Top(5, 0); len(hot) != 5 {
+		t.Errorf("Expected 5 hot keys, got %v", hot)
+	}
+}
+
+func BenchmarkTracker_Record(b *testing.B) {
+	for _, rate := range []float64{0.01, 1} {
+		b.Run(fmt.Sprintf("rate=%v", rate), func(b *testing.B) {
+			tracker, err := hotkeys.New(rate, time.Minute)
+			if err != nil {
+				b.Fatalf("Failed to create tracker: %v", err)
+			}
+			keys := make([]string, 1000)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key:%d", i)
+			}
+
+			b.ResetTimer()
+			for i := range b.N {
+				tracker.Record(keys[i%len(keys)])
+			}
+		})
+	}
+}
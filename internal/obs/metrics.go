@@ -2,6 +2,7 @@ package obs
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -23,11 +24,15 @@ type Metrics struct {
 	// Connection metrics
 	ConnectionsTotal   prometheus.Counter
 	ConnectionsCurrent prometheus.Gauge
+	AuthFailuresTotal  prometheus.Counter
 
 	// Storage metrics
 	KeysTotal        prometheus.Gauge
 	ExpiredKeysTotal prometheus.Counter
+	EvictedKeysTotal prometheus.Counter
+	ExpiryIndexSize  prometheus.Gauge
 	MemoryUsage      prometheus.Gauge
+	HotKeys          *prometheus.GaugeVec
 
 	// Server metrics
 	UptimeSeconds prometheus.Gauge
@@ -73,6 +78,12 @@ func NewMetrics() *Metrics {
 				Help: "Current number of open connections",
 			},
 		),
+		AuthFailuresTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "kvstash_auth_failures_total",
+				Help: "Total number of failed authentication attempts",
+			},
+		),
 		KeysTotal: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "kvstash_keys",
@@ -85,12 +96,31 @@ func NewMetrics() *Metrics {
 				Help: "Total number of keys that have expired",
 			},
 		),
+		EvictedKeysTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "kvstash_evicted_keys_total",
+				Help: "Total number of keys evicted to stay within maxmemory",
+			},
+		),
+		ExpiryIndexSize: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "kvstash_expiry_index_keys",
+				Help: "Number of volatile keys tracked by the expiry index",
+			},
+		),
 		MemoryUsage: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "kvstash_memory_usage_bytes",
 				Help: "Memory usage in bytes",
 			},
 		),
+		HotKeys: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "kvstash_hotkey_accesses_per_second",
+				Help: "Estimated accesses per second of the hottest keys, by rank",
+			},
+			[]string{"rank", "key"},
+		),
 		UptimeSeconds: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "kvstash_uptime_seconds",
@@ -107,9 +137,13 @@ func NewMetrics() *Metrics {
 		m.CommandsInFlight,
 		m.ConnectionsTotal,
 		m.ConnectionsCurrent,
+		m.AuthFailuresTotal,
 		m.KeysTotal,
 		m.ExpiredKeysTotal,
+		m.EvictedKeysTotal,
+		m.ExpiryIndexSize,
 		m.MemoryUsage,
+		m.HotKeys,
 		m.UptimeSeconds,
 	)
 
@@ -148,6 +182,11 @@ func (m *Metrics) DecConnections() {
 	m.ConnectionsCurrent.Dec()
 }
 
+// IncAuthFailures increments the failed authentication counter
+func (m *Metrics) IncAuthFailures() {
+	m.AuthFailuresTotal.Inc()
+}
+
 // SetKeys updates the total number of keys
 func (m *Metrics) SetKeys(count int64) {
 	m.KeysTotal.Set(float64(count))
@@ -158,11 +197,41 @@ func (m *Metrics) IncExpiredKeys() {
 	m.ExpiredKeysTotal.Inc()
 }
 
+// AddExpiredKeys adds count to the expired keys counter
+func (m *Metrics) AddExpiredKeys(count int64) {
+	m.ExpiredKeysTotal.Add(float64(count))
+}
+
+// AddEvictedKeys adds count to the evicted keys counter
+func (m *Metrics) AddEvictedKeys(count int64) {
+	m.EvictedKeysTotal.Add(float64(count))
+}
+
+// SetExpiryIndexSize updates the number of keys in the expiry index
+func (m *Metrics) SetExpiryIndexSize(count int64) {
+	m.ExpiryIndexSize.Set(float64(count))
+}
+
 // SetMemoryUsage updates memory usage metric
 func (m *Metrics) SetMemoryUsage(bytes int64) {
 	m.MemoryUsage.Set(float64(bytes))
 }
 
+// HotKeyRate is a hot key and its estimated accesses per second
+type HotKeyRate struct {
+	Key       string
+	PerSecond float64
+}
+
+// SetHotKeys replaces the hot key series with keys, hottest first. Old series
+// are dropped so the metric never has more series than the top-N size.
+func (m *Metrics) SetHotKeys(keys []HotKeyRate) {
+	m.HotKeys.Reset()
+	for i, key := range keys {
+		m.HotKeys.WithLabelValues(strconv.Itoa(i+1), key.Key).Set(key.PerSecond)
+	}
+}
+
 // SetUptime updates uptime metric
 func (m *Metrics) SetUptime(uptime time.Duration) {
 	m.UptimeSeconds.Set(uptime.Seconds())
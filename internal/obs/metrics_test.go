@@ -41,6 +41,9 @@ func TestNewMetrics(t *testing.T) {
 	if metrics.ExpiredKeysTotal == nil {
 		t.Error("ExpiredKeysTotal not initialized")
 	}
+	if metrics.EvictedKeysTotal == nil {
+		t.Error("EvictedKeysTotal not initialized")
+	}
 	if metrics.MemoryUsage == nil {
 		t.Error("MemoryUsage not initialized")
 	}
@@ -125,6 +128,9 @@ func TestMetrics_Storage(t *testing.T) {
 
 	metrics.IncExpiredKeys()
 	metrics.IncExpiredKeys()
+	metrics.AddExpiredKeys(5)
+	metrics.AddEvictedKeys(2)
+	metrics.SetExpiryIndexSize(3)
 
 	metrics.SetMemoryUsage(1024 * 1024)     // 1MB
 	metrics.SetMemoryUsage(2 * 1024 * 1024) // 2MB
@@ -302,6 +308,35 @@ func TestMetrics_RecordCommandWithDifferentStatuses(t *testing.T) {
 	}
 }
 
+func TestMetrics_SetHotKeys(t *testing.T) {
+	t.Parallel()
+
+	metrics := obs.NewMetrics()
+	metrics.SetHotKeys([]obs.HotKeyRate{{Key: "old", PerSecond: 5}})
+	metrics.SetHotKeys([]obs.HotKeyRate{
+		{Key: "hot", PerSecond: 400},
+		{Key: "warm", PerSecond: 100},
+	})
+
+	handler := metrics.Handler()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, series := range []string{
+		`kvstash_hotkey_accesses_per_second{key="hot",rank="1"} 400`,
+		`kvstash_hotkey_accesses_per_second{key="warm",rank="2"} 100`,
+	} {
+		if !strings.Contains(body, series) {
+			t.Errorf("Expected %s in metrics output", series)
+		}
+	}
+	if strings.Contains(body, `key="old"`) {
+		t.Error("Expected series of keys that are no longer hot to be dropped")
+	}
+}
+
 func TestMetrics_ConcurrentAccess(t *testing.T) {
 	t.Parallel()
 
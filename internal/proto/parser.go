@@ -1,13 +1,85 @@
-// Package proto implements the RESP2 protocol parser and response utilities for Redis-compatible communication.
+// Package proto implements the RESP2 and RESP3 protocol parser and response utilities for Redis-compatible communication.
 package proto
 
 import (
-	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
-	"strings"
+)
+
+const (
+	// defaultBufferSize is the initial size of a parser's read buffer
+	defaultBufferSize = 4096
+	// maxRetainedBufferSize bounds the read buffer kept between requests. A
+	// buffer grown beyond it for a large argument is dropped once drained.
+	maxRetainedBufferSize = 64 * 1024
+	// maxCommandNameLen is the longest command name the parser interns
+	maxCommandNameLen = 32
+	// maxInternedNames bounds the command names interned per parser, so a
+	// client sending random names cannot grow it without limit
+	maxInternedNames = 256
+)
+
+// Default protocol limits, matching Redis
+const (
+	// DefaultMaxBulkLen is the default longest bulk string, 512 MiB
+	DefaultMaxBulkLen = 512 * 1024 * 1024
+	// DefaultMaxMultiBulkLen is the default largest number of elements in a
+	// request
+	DefaultMaxMultiBulkLen = 1024 * 1024
+	// DefaultMaxInlineLen is the default longest line, whether an inline
+	// request or a length header
+	DefaultMaxInlineLen = 64 * 1024
+)
+
+// ProtocolError reports a request that is malformed or exceeds the parser's
+// limits. The connection cannot be resynchronized after one.
+type ProtocolError string
+
+func (e ProtocolError) Error() string {
+	return string(e)
+}
+
+// Protocol errors returned for requests exceeding the parser's limits
+const (
+	// ErrInvalidBulkLength is returned for a bulk string length that is not a
+	// number, is negative or exceeds Limits.MaxBulkLen
+	ErrInvalidBulkLength ProtocolError = "invalid bulk length"
+	// ErrInvalidMultiBulkLength is returned for an element count that is not
+	// a number, is negative or exceeds Limits.MaxMultiBulkLen
+	ErrInvalidMultiBulkLength ProtocolError = "invalid multibulk length"
+	// ErrInlineTooBig is returned for a line longer than Limits.MaxInlineLen
+	ErrInlineTooBig ProtocolError = "too big inline request"
+)
+
+// Limits bounds what a single request may hold, so a client cannot make the
+// parser allocate more than it has sent or than the limits allow
+type Limits struct {
+	// MaxBulkLen is the longest bulk string
+	MaxBulkLen int
+	// MaxMultiBulkLen is the largest number of elements in a request
+	MaxMultiBulkLen int
+	// MaxInlineLen is the longest line, excluding its line ending
+	MaxInlineLen int
+}
+
+// DefaultLimits returns the default protocol limits
+func DefaultLimits() Limits {
+	return Limits{
+		MaxBulkLen:      DefaultMaxBulkLen,
+		MaxMultiBulkLen: DefaultMaxMultiBulkLen,
+		MaxInlineLen:    DefaultMaxInlineLen,
+	}
+}
+
+// Names of the pseudo-commands returned for single-line RESP values
+var (
+	responseName = []byte("RESPONSE")
+	errorName    = []byte("ERROR")
+	integerName  = []byte("INTEGER")
 )
 
 // Command represents a parsed RESP command
@@ -16,20 +88,79 @@ type Command struct {
 	Args []string
 }
 
-// Parser handles RESP2 protocol parsing
+// Request is a command read by ReadRequest. Its arguments are slices of the
+// parser's buffer and are only valid until the next read.
+type Request struct {
+	// Name is the command name in upper case, interned so repeated commands
+	// do not allocate
+	Name string
+	Args [][]byte
+}
+
+// Parser handles RESP protocol parsing. It reads into a single buffer that is
+// reused from one request to the next.
 type Parser struct {
-	reader *bufio.Reader
+	reader io.Reader
+	limits Limits
+	buf    []byte
+	// start and end delimit the unread part of buf
+	start, end int
+	// err is the read error to report once buf is drained
+	err   error
+	argv  [][]byte
+	req   Request
+	names map[string]string
 }
 
-// NewParser creates a new RESP parser
+// NewParser creates a new RESP parser with the default limits
 func NewParser(r io.Reader) *Parser {
+	return NewParserWithLimits(r, DefaultLimits())
+}
+
+// NewParserWithLimits creates a new RESP parser enforcing limits
+func NewParserWithLimits(r io.Reader, limits Limits) *Parser {
 	return &Parser{
-		reader: bufio.NewReader(r),
+		reader: r,
+		limits: limits,
+		buf:    make([]byte, defaultBufferSize),
+		names:  make(map[string]string),
 	}
 }
 
-// ParseCommand parses a single RESP command
+// Buffered returns the number of bytes read from the connection but not yet
+// parsed. It is zero once every pipelined request received so far is parsed.
+func (p *Parser) Buffered() int {
+	return p.end - p.start
+}
+
+// BufferSize returns the size of the parser's read buffer. The buffer only
+// grows past its initial size as data arrives, and never beyond the longest
+// line or bulk string the limits allow.
+func (p *Parser) BufferSize() int {
+	return len(p.buf)
+}
+
+// ParseCommand parses a single RESP command, copying its arguments into
+// strings the caller may keep
 func (p *Parser) ParseCommand() (*Command, error) {
+	req, err := p.ReadRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, len(req.Args))
+	for i, arg := range req.Args {
+		args[i] = string(arg)
+	}
+	return &Command{Name: req.Name, Args: args}, nil
+}
+
+// ReadRequest reads a single RESP command without copying its arguments. The
+// returned request is reused by the next call.
+func (p *Parser) ReadRequest() (*Request, error) {
+	p.compact()
+	p.argv = p.argv[:0]
+
 	line, err := p.readLine()
 	if err != nil {
 		return nil, err
@@ -41,72 +172,65 @@ func (p *Parser) ParseCommand() (*Command, error) {
 
 	switch line[0] {
 	case '*':
-		return p.parseArray(line)
+		err = p.parseArray(line)
 	case '+', '-', ':', '$':
 		// Single line commands (inline)
-		return p.parseInline(line)
+		p.parseInline(line)
 	default:
 		// Inline command format
-		return p.parseInlineString(line)
+		p.parseInlineString(line)
 	}
-}
-
-// parseArray parses an array command (standard RESP format)
-func (p *Parser) parseArray(line string) (*Command, error) {
-	// Parse array length
-	countStr := line[1:]
-	count, err := strconv.Atoi(countStr)
 	if err != nil {
-		return nil, fmt.Errorf("invalid array length: %s", countStr)
+		return nil, err
 	}
 
-	if count < 0 {
-		return nil, fmt.Errorf("negative array length: %d", count)
+	p.req.Name, p.req.Args = "", p.argv
+	if len(p.argv) > 0 {
+		p.req.Name, p.req.Args = p.intern(p.argv[0]), p.argv[1:]
 	}
+	return &p.req, nil
+}
 
-	if count == 0 {
-		return &Command{Name: "", Args: []string{}}, nil
+// parseArray parses an array command (standard RESP format)
+func (p *Parser) parseArray(line []byte) error {
+	// Parse array length
+	count, err := parseInt(line[1:])
+	if err != nil || count < 0 || count > p.limits.MaxMultiBulkLen {
+		return ErrInvalidMultiBulkLength
 	}
 
-	// Read array elements
-	elements := make([]string, count)
+	// Read array elements. argv grows as they arrive rather than up front,
+	// so a large count alone allocates nothing.
 	for i := range count {
 		element, err := p.parseElement()
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse array element %d: %w", i, err)
+			var protoErr ProtocolError
+			if errors.As(err, &protoErr) {
+				return err
+			}
+			return fmt.Errorf("failed to parse array element %d: %w", i, err)
 		}
-		elements[i] = element
-	}
-
-	if len(elements) == 0 {
-		return &Command{Name: "", Args: []string{}}, nil
+		p.argv = append(p.argv, element)
 	}
 
-	return &Command{
-		Name: strings.ToUpper(elements[0]),
-		Args: elements[1:],
-	}, nil
+	return nil
 }
 
 // parseElement parses a single RESP element
-func (p *Parser) parseElement() (string, error) {
+func (p *Parser) parseElement() ([]byte, error) {
 	line, err := p.readLine()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(line) == 0 {
-		return "", errors.New("empty element")
+		return nil, errors.New("empty element")
 	}
 
 	switch line[0] {
 	case '$':
 		return p.parseBulkString(line)
-	case '+':
-		return line[1:], nil
-	case '-':
-		return line[1:], nil
-	case ':':
+	case '+', '-', ':':
 		return line[1:], nil
 	default:
 		return line, nil
@@ -114,83 +238,205 @@ func (p *Parser) parseElement() (string, error) {
 }
 
 // parseBulkString parses a bulk string
-func (p *Parser) parseBulkString(line string) (string, error) {
-	lengthStr := line[1:]
-	length, err := strconv.Atoi(lengthStr)
-	if err != nil {
-		return "", fmt.Errorf("invalid bulk string length: %s", lengthStr)
-	}
-
-	if length < 0 {
-		return "", nil // null bulk string
+func (p *Parser) parseBulkString(line []byte) ([]byte, error) {
+	length, err := parseInt(line[1:])
+	if err != nil || length < -1 || length > p.limits.MaxBulkLen {
+		return nil, ErrInvalidBulkLength
 	}
 
-	if length == 0 {
-		// Read empty line
-		_, err := p.readLine()
-		if err != nil {
-			return "", err
-		}
-		return "", nil
+	if length == -1 {
+		return nil, nil // null bulk string
 	}
 
 	// Read the bulk string data
-	data := make([]byte, length)
-	if _, err := io.ReadFull(p.reader, data); err != nil {
-		return "", fmt.Errorf("failed to read bulk string data: %w", err)
+	data, err := p.readFull(length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bulk string data: %w", err)
 	}
 
 	// Read trailing CRLF
 	if _, err := p.readLine(); err != nil {
-		return "", fmt.Errorf("failed to read bulk string trailing CRLF: %w", err)
+		return nil, fmt.Errorf("failed to read bulk string trailing CRLF: %w", err)
 	}
 
-	return string(data), nil
+	return data, nil
 }
 
 // parseInline parses a single-line RESP element
-func (p *Parser) parseInline(line string) (*Command, error) {
+func (p *Parser) parseInline(line []byte) {
 	switch line[0] {
 	case '+':
-		return &Command{Name: "RESPONSE", Args: []string{line[1:]}}, nil
+		p.argv = append(p.argv, responseName, line[1:])
 	case '-':
-		return &Command{Name: "ERROR", Args: []string{line[1:]}}, nil
+		p.argv = append(p.argv, errorName, line[1:])
 	case ':':
-		return &Command{Name: "INTEGER", Args: []string{line[1:]}}, nil
+		p.argv = append(p.argv, integerName, line[1:])
 	default:
-		return p.parseInlineString(line)
+		p.parseInlineString(line)
+	}
+}
+
+// parseInlineString parses an inline string command, splitting it on ASCII
+// whitespace
+func (p *Parser) parseInlineString(line []byte) {
+	for i := 0; i < len(line); {
+		if isSpace(line[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(line) && !isSpace(line[j]) {
+			j++
+		}
+		p.argv = append(p.argv, line[i:j])
+		i = j
 	}
 }
 
-// parseInlineString parses an inline string command
-func (p *Parser) parseInlineString(line string) (*Command, error) {
-	parts := strings.Fields(line)
-	if len(parts) == 0 {
-		return &Command{Name: "", Args: []string{}}, nil
+// intern returns the upper-case form of name, reusing a previously returned
+// string when there is one
+func (p *Parser) intern(name []byte) string {
+	if len(name) > maxCommandNameLen {
+		return string(bytes.ToUpper(name))
 	}
 
-	return &Command{
-		Name: strings.ToUpper(parts[0]),
-		Args: parts[1:],
-	}, nil
+	var upper [maxCommandNameLen]byte
+	n := copy(upper[:], name)
+	for i := range n {
+		if 'a' <= upper[i] && upper[i] <= 'z' {
+			upper[i] -= 'a' - 'A'
+		}
+	}
+
+	if s, ok := p.names[string(upper[:n])]; ok {
+		return s
+	}
+	s := string(upper[:n])
+	if len(p.names) < maxInternedNames {
+		p.names[s] = s
+	}
+	return s
+}
+
+// compact moves unread data to the front of the buffer before a request is
+// read. Slices handed out for the previous request are overwritten.
+func (p *Parser) compact() {
+	if p.start == 0 {
+		return
+	}
+
+	unread := p.end - p.start
+	if len(p.buf) > maxRetainedBufferSize && unread <= defaultBufferSize {
+		buf := make([]byte, defaultBufferSize)
+		copy(buf, p.buf[p.start:p.end])
+		p.buf = buf
+	} else {
+		copy(p.buf, p.buf[p.start:p.end])
+	}
+	p.start, p.end = 0, unread
 }
 
-// readLine reads a line ending with CRLF
-func (p *Parser) readLine() (string, error) {
-	line, err := p.reader.ReadString('\n')
+// fill reads more data. When the buffer is full it first doubles it, up to
+// want unread bytes, so it only grows as data arrives. Growing copies into a
+// new buffer so slices of the current request stay valid.
+func (p *Parser) fill(want int) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	if p.end == len(p.buf) {
+		buf := make([]byte, max(len(p.buf), min(2*len(p.buf), want)))
+		p.end = copy(buf, p.buf[p.start:p.end])
+		p.start = 0
+		p.buf = buf
+	}
+
+	n, err := p.reader.Read(p.buf[p.end:])
+	p.end += n
 	if err != nil {
-		return "", err
+		p.err = err
+		if n == 0 {
+			return err
+		}
 	}
+	return nil
+}
 
-	// Remove CRLF
-	if len(line) >= 2 && line[len(line)-2:] == "\r\n" {
-		return line[:len(line)-2], nil
+// readLine reads a line ending with CRLF or LF and returns it without the
+// line ending
+func (p *Parser) readLine() ([]byte, error) {
+	scanned := 0
+	for {
+		if i := bytes.IndexByte(p.buf[p.start+scanned:p.end], '\n'); i >= 0 {
+			line := bytes.TrimSuffix(p.buf[p.start:p.start+scanned+i], []byte("\r"))
+			if len(line) > p.limits.MaxInlineLen {
+				return nil, ErrInlineTooBig
+			}
+			p.start += scanned + i + 1
+			return line, nil
+		}
+		scanned = p.end - p.start
+		// Allow for the line ending after the longest line
+		if scanned > p.limits.MaxInlineLen+1 {
+			return nil, ErrInlineTooBig
+		}
+		if err := p.fill(p.limits.MaxInlineLen + 2); err != nil {
+			return nil, err
+		}
 	}
+}
 
-	// Handle LF only
-	if len(line) >= 1 && line[len(line)-1:] == "\n" {
-		return line[:len(line)-1], nil
+// readFull reads exactly n bytes
+func (p *Parser) readFull(n int) ([]byte, error) {
+	for p.end-p.start < n {
+		if err := p.fill(n); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
 	}
 
-	return line, nil
+	data := p.buf[p.start : p.start+n]
+	p.start += n
+	return data, nil
+}
+
+// parseInt parses a decimal length without converting it to a string
+func parseInt(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, strconv.ErrSyntax
+	}
+
+	neg := b[0] == '-'
+	if neg || b[0] == '+' {
+		b = b[1:]
+		if len(b) == 0 {
+			return 0, strconv.ErrSyntax
+		}
+	}
+
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, strconv.ErrSyntax
+		}
+		if n > (math.MaxInt-int(c-'0'))/10 {
+			return 0, strconv.ErrRange
+		}
+		n = n*10 + int(c-'0')
+	}
+	if neg {
+		return -n, nil
+	}
+	return n, nil
+}
+
+// isSpace reports whether c is ASCII whitespace
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
 }
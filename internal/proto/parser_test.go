@@ -1,8 +1,14 @@
 package proto_test
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"unsafe"
 
 	"github.com/Abhishek2095/kv-stash/internal/proto"
 )
@@ -239,3 +245,249 @@ func validateBulkStringResult(t *testing.T, cmd *proto.Command, expected string)
 		t.Errorf("ParseCommand() expected empty string argument, got %q", cmd.Args[0])
 	}
 }
+
+func TestParser_ReadRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected [][]string
+	}{
+		{
+			name:     "Lower case name is upper cased",
+			input:    "*2\r\n$3\r\nget\r\n$3\r\nkey\r\n",
+			expected: [][]string{{"GET", "key"}},
+		},
+		{
+			name:     "Mixed case inline command",
+			input:    "sEt  key \t value\r\n",
+			expected: [][]string{{"SET", "key", "value"}},
+		},
+		{
+			name:     "Pipelined requests",
+			input:    "*1\r\n$4\r\nPING\r\nGET a\r\n*3\r\n$3\r\nset\r\n$1\r\nb\r\n$0\r\n\r\n",
+			expected: [][]string{{"PING"}, {"GET", "a"}, {"SET", "b", ""}},
+		},
+		{
+			name:     "Bulk string larger than the buffer",
+			input:    "*2\r\n$4\r\nECHO\r\n$10000\r\n" + strings.Repeat("x", 10000) + "\r\n*1\r\n$4\r\nPING\r\n",
+			expected: [][]string{{"ECHO", strings.Repeat("x", 10000)}, {"PING"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Reading a byte at a time exercises every partial read
+			parser := proto.NewParser(iotest.OneByteReader(strings.NewReader(tt.input)))
+			for i, expected := range tt.expected {
+				req, err := parser.ReadRequest()
+				if err != nil {
+					t.Fatalf("ReadRequest() %d error = %v", i, err)
+				}
+
+				got := []string{req.Name}
+				for _, arg := range req.Args {
+					got = append(got, string(arg))
+				}
+				if !slices.Equal(got, expected) {
+					t.Errorf("ReadRequest() %d = %q, want %q", i, got, expected)
+				}
+			}
+
+			if _, err := parser.ReadRequest(); !errors.Is(err, io.EOF) {
+				t.Errorf("ReadRequest() after the last request error = %v, want EOF", err)
+			}
+		})
+	}
+}
+
+func TestParser_Buffered(t *testing.T) {
+	t.Parallel()
+
+	parser := proto.NewParser(strings.NewReader("PING\r\nGET a\r\n"))
+	if _, err := parser.ReadRequest(); err != nil {
+		t.Fatalf("ReadRequest() error = %v", err)
+	}
+	if got := parser.Buffered(); got != len("GET a\r\n") {
+		t.Errorf("Buffered() with a pipelined request = %d, want %d", got, len("GET a\r\n"))
+	}
+
+	if _, err := parser.ReadRequest(); err != nil {
+		t.Fatalf("ReadRequest() error = %v", err)
+	}
+	if got := parser.Buffered(); got != 0 {
+		t.Errorf("Buffered() once drained = %d, want 0", got)
+	}
+}
+
+func TestParser_ReadRequest_TruncatedBulkString(t *testing.T) {
+	t.Parallel()
+
+	parser := proto.NewParser(strings.NewReader("*2\r\n$3\r\nGET\r\n$10\r\nabc"))
+	if _, err := parser.ReadRequest(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadRequest() error = %v, want unexpected EOF", err)
+	}
+}
+
+func TestParser_ReadRequest_InternsNames(t *testing.T) {
+	t.Parallel()
+
+	parser := proto.NewParser(strings.NewReader("get a\r\nGeT b\r\n"))
+	first, err := parser.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest() error = %v", err)
+	}
+	name := first.Name
+
+	second, err := parser.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest() error = %v", err)
+	}
+	if second.Name != "GET" || unsafe.StringData(second.Name) != unsafe.StringData(name) {
+		t.Errorf("Expected both requests to share the interned name GET, got %q and %q", name, second.Name)
+	}
+}
+
+func TestParser_Limits(t *testing.T) {
+	t.Parallel()
+
+	limits := proto.Limits{MaxBulkLen: 8, MaxMultiBulkLen: 3, MaxInlineLen: 24}
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{name: "Bulk string at the limit", input: "*2\r\n$3\r\nGET\r\n$8\r\n12345678\r\n"},
+		{name: "Bulk string over the limit", input: "*2\r\n$3\r\nGET\r\n$9\r\n123456789\r\n", wantErr: proto.ErrInvalidBulkLength},
+		{name: "Huge bulk length", input: "*2\r\n$3\r\nGET\r\n$4000000000\r\n", wantErr: proto.ErrInvalidBulkLength},
+		{name: "Bulk length overflowing int", input: "*1\r\n$99999999999999999999\r\n", wantErr: proto.ErrInvalidBulkLength},
+		{name: "Negative bulk length", input: "*1\r\n$-2\r\n", wantErr: proto.ErrInvalidBulkLength},
+		{name: "Non-numeric bulk length", input: "*1\r\n$abc\r\n", wantErr: proto.ErrInvalidBulkLength},
+		{name: "Element count at the limit", input: "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"},
+		{name: "Element count over the limit", input: "*4\r\n", wantErr: proto.ErrInvalidMultiBulkLength},
+		{name: "Huge element count", input: "*2147483647\r\n", wantErr: proto.ErrInvalidMultiBulkLength},
+		{name: "Negative element count", input: "*-5\r\n", wantErr: proto.ErrInvalidMultiBulkLength},
+		{name: "Inline request at the limit", input: "ECHO 1234567890123456789\r\n"},
+		{name: "Inline request over the limit", input: "ECHO 12345678901234567890\r\n", wantErr: proto.ErrInlineTooBig},
+		{name: "Unterminated line over the limit", input: strings.Repeat("x", 100), wantErr: proto.ErrInlineTooBig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			parser := proto.NewParserWithLimits(iotest.OneByteReader(strings.NewReader(tt.input)), limits)
+			_, err := parser.ReadRequest()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("ReadRequest() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadRequest() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && err.Error() != tt.wantErr.Error() {
+				t.Errorf("ReadRequest() error = %q, want it unwrapped", err)
+			}
+		})
+	}
+}
+
+func TestParser_BufferGrowsWithData(t *testing.T) {
+	t.Parallel()
+
+	// A declared length alone must not size the buffer
+	parser := proto.NewParser(strings.NewReader("*1\r\n$500000000\r\nabc"))
+	if _, err := parser.ReadRequest(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadRequest() error = %v, want unexpected EOF", err)
+	}
+	if size := parser.BufferSize(); size > 4096 {
+		t.Errorf("BufferSize() after a short bulk string = %d, want at most 4096", size)
+	}
+}
+
+func FuzzParser_ParseCommand(f *testing.F) {
+	seeds := []string{
+		"*1\r\n$4\r\nPING\r\n",
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n",
+		"GET key\r\nPING\r\n",
+		"+OK\r\n-ERR x\r\n:1\r\n",
+		"*2\r\n$3\r\nGET\r\n$-1\r\n",
+		"*2147483647\r\n",
+		"*1\r\n$4000000000\r\n",
+		"*2\r\n$3\r\nGET\r\n$3\r\nab",
+		strings.Repeat("x", 300),
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	limits := proto.Limits{MaxBulkLen: 1024, MaxMultiBulkLen: 64, MaxInlineLen: 256}
+	maxBuffer := max(4096, limits.MaxBulkLen, limits.MaxInlineLen+2)
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		parser := proto.NewParserWithLimits(bytes.NewReader(input), limits)
+		for {
+			cmd, err := parser.ParseCommand()
+			if size := parser.BufferSize(); size > maxBuffer {
+				t.Fatalf("BufferSize() = %d, want at most %d", size, maxBuffer)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(cmd.Args)+1 > limits.MaxMultiBulkLen {
+				t.Fatalf("ParseCommand() returned %d arguments, limit is %d", len(cmd.Args), limits.MaxMultiBulkLen)
+			}
+			for _, arg := range cmd.Args {
+				if len(arg) > max(limits.MaxBulkLen, limits.MaxInlineLen) {
+					t.Fatalf("ParseCommand() returned a %d byte argument", len(arg))
+				}
+			}
+		}
+	})
+}
+
+// loopReader endlessly repeats data, standing in for a busy connection
+type loopReader struct {
+	data []byte
+	off  int
+}
+
+func (r *loopReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		copied := copy(p[n:], r.data[r.off:])
+		n += copied
+		r.off = (r.off + copied) % len(r.data)
+	}
+	return n, nil
+}
+
+const benchmarkCommand = "*3\r\n$3\r\nset\r\n$8\r\nuser:123\r\n$16\r\nsome-value-bytes\r\n"
+
+func BenchmarkParser_ReadRequest(b *testing.B) {
+	parser := proto.NewParser(&loopReader{data: []byte(benchmarkCommand)})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := parser.ReadRequest(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParser_ParseCommand(b *testing.B) {
+	parser := proto.NewParser(&loopReader{data: []byte(benchmarkCommand)})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := parser.ParseCommand(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -3,6 +3,18 @@ package proto
 import (
 	"fmt"
 	"io"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// Protocol versions negotiated with HELLO
+const (
+	// RESP2 is the protocol every connection starts with
+	RESP2 = 2
+	// RESP3 adds maps, sets, doubles, booleans, nulls, big numbers, verbatim
+	// strings, attributes and push messages
+	RESP3 = 3
 )
 
 // Response represents a RESP response
@@ -27,95 +39,348 @@ const (
 	Array
 	// NullBulkString represents a RESP null bulk string response type
 	NullBulkString
+	// Multi represents several responses written back to back, for commands
+	// such as SUBSCRIBE that reply once per argument
+	Multi
+	// Map represents a RESP3 map; its data alternates keys and values. RESP2
+	// clients receive it as a flat array.
+	Map
+	// Set represents a RESP3 set, sent to RESP2 clients as an array
+	Set
+	// Double represents a RESP3 double, sent to RESP2 clients as a bulk string
+	Double
+	// Boolean represents a RESP3 boolean, sent to RESP2 clients as 1 or 0
+	Boolean
+	// Null represents the RESP3 null, sent to RESP2 clients as a null bulk string
+	Null
+	// BigNumber represents a RESP3 big number given as a decimal string, sent
+	// to RESP2 clients as a bulk string
+	BigNumber
+	// VerbatimString represents a RESP3 verbatim string, sent to RESP2
+	// clients as a bulk string of its text
+	VerbatimString
+	// Attribute represents a RESP3 attribute map preceding a reply. RESP2
+	// clients only receive the reply.
+	Attribute
+	// Push represents a RESP3 out-of-band push message, sent to RESP2 clients
+	// as an array
+	Push
 )
 
-// WriteResponse writes a RESP response to the writer
+// Verbatim is the data of a VerbatimString response
+type Verbatim struct {
+	// Format is the three-letter format of the text, such as "txt" or "mkd"
+	Format string
+	Text   string
+}
+
+// Attributed is the data of an Attribute response
+type Attributed struct {
+	// Attributes alternates keys and values like a Map
+	Attributes []any
+	Reply      *Response
+}
+
+// maxPooledBufferSize bounds the reply buffers returned to bufferPool
+const maxPooledBufferSize = 64 * 1024
+
+// bufferPool holds reply buffers for WriteResponse and WriteResponseProtocol
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, defaultBufferSize)
+		return &buf
+	},
+}
+
+// WriteResponse writes a RESP2 response to the writer
 func WriteResponse(w io.Writer, resp *Response) error {
+	return WriteResponseProtocol(w, resp, RESP2)
+}
+
+// WriteResponseProtocol writes a response to the writer in the given protocol
+// version, downgrading RESP3 types for RESP2 clients. The reply is encoded
+// into a pooled buffer and written with a single call.
+func WriteResponseProtocol(w io.Writer, resp *Response, protocol int) error {
+	bufp := bufferPool.Get().(*[]byte)
+	defer func() {
+		if cap(*bufp) <= maxPooledBufferSize {
+			bufferPool.Put(bufp)
+		}
+	}()
+
+	buf, err := AppendResponse((*bufp)[:0], resp, protocol)
+	*bufp = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// Writer buffers responses for one connection so a pipeline of replies can
+// be sent with a single write. Nothing reaches the connection until Flush.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter creates a response writer for w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, buf: make([]byte, 0, defaultBufferSize)}
+}
+
+// WriteResponse buffers a response in the given protocol version. A response
+// that fails to encode leaves the buffer unchanged.
+func (w *Writer) WriteResponse(resp *Response, protocol int) error {
+	buf, err := AppendResponse(w.buf, resp, protocol)
+	if err != nil {
+		w.buf = buf[:len(w.buf)]
+		return err
+	}
+	w.buf = buf
+	return nil
+}
+
+// Buffered returns the number of bytes waiting to be flushed
+func (w *Writer) Buffered() int {
+	return len(w.buf)
+}
+
+// Flush writes the buffered responses. A buffer grown beyond
+// maxPooledBufferSize for a large reply is released afterwards.
+func (w *Writer) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := w.w.Write(w.buf)
+	if cap(w.buf) > maxPooledBufferSize {
+		w.buf = make([]byte, 0, defaultBufferSize)
+	} else {
+		w.buf = w.buf[:0]
+	}
+	return err
+}
+
+// AppendResponse appends the encoding of a response in the given protocol
+// version to dst and returns the extended buffer. RESP3 types are downgraded
+// for RESP2 clients.
+func AppendResponse(dst []byte, resp *Response, protocol int) ([]byte, error) {
+	resp3 := protocol >= RESP3
+
 	switch resp.Type {
 	case SimpleString:
-		return writeSimpleString(w, resp.Data.(string))
+		return appendLine(dst, '+', resp.Data.(string)), nil
 	case Error:
-		return writeError(w, resp.Data.(string))
+		return appendLine(dst, '-', resp.Data.(string)), nil
 	case Integer:
-		return writeInteger(w, resp.Data.(int64))
+		return appendInteger(dst, resp.Data.(int64)), nil
 	case BulkString:
-		return writeBulkString(w, resp.Data.(string))
-	case NullBulkString:
-		return writeNullBulkString(w)
+		return appendBulkString(dst, resp.Data.(string)), nil
+	case NullBulkString, Null:
+		return appendNull(dst, resp3), nil
 	case Array:
-		if resp.Data == nil {
-			return writeArray(w, nil)
+		if arr, _ := resp.Data.([]any); arr == nil && resp3 {
+			return append(dst, "_\r\n"...), nil
+		}
+		return appendAggregate(dst, '*', resp.Data, protocol)
+	case Multi:
+		var err error
+		for _, part := range resp.Data.([]*Response) {
+			if dst, err = AppendResponse(dst, part, protocol); err != nil {
+				return dst, err
+			}
+		}
+		return dst, nil
+	case Map:
+		if resp3 {
+			return appendMap(dst, '%', resp.Data.([]any), protocol)
+		}
+		return appendAggregate(dst, '*', resp.Data, protocol)
+	case Set:
+		if resp3 {
+			return appendAggregate(dst, '~', resp.Data, protocol)
+		}
+		return appendAggregate(dst, '*', resp.Data, protocol)
+	case Push:
+		if resp3 {
+			return appendAggregate(dst, '>', resp.Data, protocol)
+		}
+		return appendAggregate(dst, '*', resp.Data, protocol)
+	case Double:
+		return appendDouble(dst, resp.Data.(float64), resp3), nil
+	case Boolean:
+		return appendBoolean(dst, resp.Data.(bool), resp3), nil
+	case BigNumber:
+		if resp3 {
+			return appendLine(dst, '(', resp.Data.(string)), nil
 		}
-		return writeArray(w, resp.Data.([]any))
+		return appendBulkString(dst, resp.Data.(string)), nil
+	case VerbatimString:
+		verbatim := resp.Data.(Verbatim)
+		if resp3 {
+			return appendVerbatimString(dst, verbatim), nil
+		}
+		return appendBulkString(dst, verbatim.Text), nil
+	case Attribute:
+		attributed := resp.Data.(Attributed)
+		if resp3 {
+			var err error
+			if dst, err = appendMap(dst, '|', attributed.Attributes, protocol); err != nil {
+				return dst, err
+			}
+		}
+		return AppendResponse(dst, attributed.Reply, protocol)
 	default:
-		return fmt.Errorf("unknown response type: %d", resp.Type)
+		return dst, fmt.Errorf("unknown response type: %d", resp.Type)
 	}
 }
 
-// writeSimpleString writes a simple string response
-func writeSimpleString(w io.Writer, s string) error {
-	_, err := fmt.Fprintf(w, "+%s\r\n", s)
-	return err
+// appendLine appends a single-line value such as a simple string or error
+func appendLine(dst []byte, prefix byte, s string) []byte {
+	dst = append(dst, prefix)
+	dst = append(dst, s...)
+	return append(dst, "\r\n"...)
 }
 
-// writeError writes an error response
-func writeError(w io.Writer, s string) error {
-	_, err := fmt.Fprintf(w, "-%s\r\n", s)
-	return err
+// appendHeader appends a type prefix followed by a length or count
+func appendHeader(dst []byte, prefix byte, n int64) []byte {
+	dst = append(dst, prefix)
+	dst = strconv.AppendInt(dst, n, 10)
+	return append(dst, "\r\n"...)
 }
 
-// writeInteger writes an integer response
-func writeInteger(w io.Writer, i int64) error {
-	_, err := fmt.Fprintf(w, ":%d\r\n", i)
-	return err
+// appendInteger appends an integer response
+func appendInteger(dst []byte, i int64) []byte {
+	return appendHeader(dst, ':', i)
 }
 
-// writeBulkString writes a bulk string response
-func writeBulkString(w io.Writer, s string) error {
-	_, err := fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
-	return err
+// appendBulkString appends a bulk string response
+func appendBulkString(dst []byte, s string) []byte {
+	dst = appendHeader(dst, '$', int64(len(s)))
+	dst = append(dst, s...)
+	return append(dst, "\r\n"...)
 }
 
-// writeNullBulkString writes a null bulk string response
-func writeNullBulkString(w io.Writer) error {
-	_, err := w.Write([]byte("$-1\r\n"))
-	return err
+// appendNull appends the RESP3 null, or a null bulk string for RESP2
+func appendNull(dst []byte, resp3 bool) []byte {
+	if resp3 {
+		return append(dst, "_\r\n"...)
+	}
+	return append(dst, "$-1\r\n"...)
+}
+
+// appendDouble appends a RESP3 double, or a bulk string of the same text for
+// RESP2
+func appendDouble(dst []byte, f float64, resp3 bool) []byte {
+	if resp3 {
+		dst = append(dst, ',')
+		dst = appendDoubleText(dst, f)
+		return append(dst, "\r\n"...)
+	}
+
+	var scratch [32]byte
+	text := appendDoubleText(scratch[:0], f)
+	dst = appendHeader(dst, '$', int64(len(text)))
+	dst = append(dst, text...)
+	return append(dst, "\r\n"...)
+}
+
+// appendDoubleText appends f in the RESP3 double syntax, which RESP2 clients
+// also receive as a bulk string
+func appendDoubleText(dst []byte, f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return append(dst, "inf"...)
+	case math.IsInf(f, -1):
+		return append(dst, "-inf"...)
+	case math.IsNaN(f):
+		return append(dst, "nan"...)
+	default:
+		return strconv.AppendFloat(dst, f, 'f', -1, 64)
+	}
 }
 
-// writeArray writes an array response
-func writeArray(w io.Writer, arr []any) error {
+// appendBoolean appends a RESP3 boolean, or 1 and 0 for RESP2
+func appendBoolean(dst []byte, b, resp3 bool) []byte {
+	switch {
+	case resp3 && b:
+		return append(dst, "#t\r\n"...)
+	case resp3:
+		return append(dst, "#f\r\n"...)
+	case b:
+		return appendInteger(dst, 1)
+	default:
+		return appendInteger(dst, 0)
+	}
+}
+
+// appendVerbatimString appends a RESP3 verbatim string, whose payload is the
+// format, a colon and the text
+func appendVerbatimString(dst []byte, v Verbatim) []byte {
+	dst = appendHeader(dst, '=', int64(len(v.Format)+1+len(v.Text)))
+	dst = append(dst, v.Format...)
+	dst = append(dst, ':')
+	dst = append(dst, v.Text...)
+	return append(dst, "\r\n"...)
+}
+
+// appendAggregate appends an array-like aggregate with the given type prefix:
+// '*' for arrays, '~' for sets and '>' for pushes. A nil array is written as
+// the RESP2 null array.
+func appendAggregate(dst []byte, prefix byte, data any, protocol int) ([]byte, error) {
+	arr, _ := data.([]any)
 	if arr == nil {
-		_, err := w.Write([]byte("*-1\r\n"))
-		return err
+		return append(dst, "*-1\r\n"...), nil
 	}
 
-	// Write array length
-	if _, err := fmt.Fprintf(w, "*%d\r\n", len(arr)); err != nil {
-		return err
+	dst = appendHeader(dst, prefix, int64(len(arr)))
+	return appendElements(dst, arr, protocol)
+}
+
+// appendMap appends alternating keys and values as a map with the given type
+// prefix: '%' for maps and '|' for attributes
+func appendMap(dst []byte, prefix byte, pairs []any, protocol int) ([]byte, error) {
+	if len(pairs)%2 != 0 {
+		return dst, fmt.Errorf("map has an odd number of elements: %d", len(pairs))
 	}
 
-	// Write each element
-	for _, elem := range arr {
-		var resp *Response
+	dst = appendHeader(dst, prefix, int64(len(pairs)/2))
+	return appendElements(dst, pairs, protocol)
+}
+
+// appendElements appends each element of an aggregate
+func appendElements(dst []byte, elems []any, protocol int) ([]byte, error) {
+	resp3 := protocol >= RESP3
+
+	var err error
+	for _, elem := range elems {
 		switch v := elem.(type) {
 		case string:
-			resp = &Response{Type: BulkString, Data: v}
+			dst = appendBulkString(dst, v)
 		case int64:
-			resp = &Response{Type: Integer, Data: v}
+			dst = appendInteger(dst, v)
 		case int:
-			resp = &Response{Type: Integer, Data: int64(v)}
+			dst = appendInteger(dst, int64(v))
+		case float64:
+			dst = appendDouble(dst, v, resp3)
+		case bool:
+			dst = appendBoolean(dst, v, resp3)
 		case nil:
-			resp = &Response{Type: NullBulkString}
+			dst = appendNull(dst, resp3)
+		case []any:
+			dst, err = AppendResponse(dst, &Response{Type: Array, Data: v}, protocol)
+		case *Response:
+			dst, err = AppendResponse(dst, v, protocol)
 		default:
-			resp = &Response{Type: BulkString, Data: fmt.Sprintf("%v", v)}
+			dst = appendBulkString(dst, fmt.Sprintf("%v", v))
 		}
-
-		if err := WriteResponse(w, resp); err != nil {
-			return err
+		if err != nil {
+			return dst, err
 		}
 	}
 
-	return nil
+	return dst, nil
 }
 
 // NewSimpleString creates a simple string response
@@ -147,3 +412,55 @@ func NewNullBulkString() *Response {
 func NewArray(arr []any) *Response {
 	return &Response{Type: Array, Data: arr}
 }
+
+// NewMulti creates a response made of several responses written in order
+func NewMulti(parts ...*Response) *Response {
+	return &Response{Type: Multi, Data: parts}
+}
+
+// NewMap creates a map response from alternating keys and values
+func NewMap(pairs []any) *Response {
+	return &Response{Type: Map, Data: pairs}
+}
+
+// NewSet creates a set response
+func NewSet(members []any) *Response {
+	return &Response{Type: Set, Data: members}
+}
+
+// NewDouble creates a double response
+func NewDouble(f float64) *Response {
+	return &Response{Type: Double, Data: f}
+}
+
+// NewBoolean creates a boolean response
+func NewBoolean(b bool) *Response {
+	return &Response{Type: Boolean, Data: b}
+}
+
+// NewNull creates a null response
+func NewNull() *Response {
+	return &Response{Type: Null}
+}
+
+// NewBigNumber creates a big number response from its decimal digits
+func NewBigNumber(digits string) *Response {
+	return &Response{Type: BigNumber, Data: digits}
+}
+
+// NewVerbatimString creates a verbatim string response. format is a
+// three-letter hint such as "txt" or "mkd".
+func NewVerbatimString(format, text string) *Response {
+	return &Response{Type: VerbatimString, Data: Verbatim{Format: format, Text: text}}
+}
+
+// NewAttribute creates a reply preceded by an attribute map of alternating
+// keys and values
+func NewAttribute(attributes []any, reply *Response) *Response {
+	return &Response{Type: Attribute, Data: Attributed{Attributes: attributes, Reply: reply}}
+}
+
+// NewPush creates an out-of-band push message, such as a Pub/Sub message
+func NewPush(elems []any) *Response {
+	return &Response{Type: Push, Data: elems}
+}
@@ -3,6 +3,8 @@ package proto_test
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"strings"
 	"testing"
 
@@ -143,6 +145,127 @@ func TestWriteResponse(t *testing.T) {
 	}
 }
 
+func TestWriteResponseProtocol(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		response *proto.Response
+		resp2    string
+		resp3    string
+	}{
+		{
+			name:     "map",
+			response: proto.NewMap([]any{"a", int64(1), "b", []any{"x"}}),
+			resp2:    "*4\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n*1\r\n$1\r\nx\r\n",
+			resp3:    "%2\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n*1\r\n$1\r\nx\r\n",
+		},
+		{
+			name:     "set",
+			response: proto.NewSet([]any{"a", "b"}),
+			resp2:    "*2\r\n$1\r\na\r\n$1\r\nb\r\n",
+			resp3:    "~2\r\n$1\r\na\r\n$1\r\nb\r\n",
+		},
+		{
+			name:     "double",
+			response: proto.NewDouble(3.25),
+			resp2:    "$4\r\n3.25\r\n",
+			resp3:    ",3.25\r\n",
+		},
+		{
+			name:     "infinite double",
+			response: proto.NewDouble(math.Inf(-1)),
+			resp2:    "$4\r\n-inf\r\n",
+			resp3:    ",-inf\r\n",
+		},
+		{
+			name:     "true",
+			response: proto.NewBoolean(true),
+			resp2:    ":1\r\n",
+			resp3:    "#t\r\n",
+		},
+		{
+			name:     "false",
+			response: proto.NewBoolean(false),
+			resp2:    ":0\r\n",
+			resp3:    "#f\r\n",
+		},
+		{
+			name:     "null",
+			response: proto.NewNull(),
+			resp2:    "$-1\r\n",
+			resp3:    "_\r\n",
+		},
+		{
+			name:     "null bulk string",
+			response: proto.NewNullBulkString(),
+			resp2:    "$-1\r\n",
+			resp3:    "_\r\n",
+		},
+		{
+			name:     "null array",
+			response: proto.NewArray(nil),
+			resp2:    "*-1\r\n",
+			resp3:    "_\r\n",
+		},
+		{
+			name:     "big number",
+			response: proto.NewBigNumber("3492890328409238509324850943850943825024385"),
+			resp2:    "$43\r\n3492890328409238509324850943850943825024385\r\n",
+			resp3:    "(3492890328409238509324850943850943825024385\r\n",
+		},
+		{
+			name:     "verbatim string",
+			response: proto.NewVerbatimString("txt", "Some string"),
+			resp2:    "$11\r\nSome string\r\n",
+			resp3:    "=15\r\ntxt:Some string\r\n",
+		},
+		{
+			name:     "attribute",
+			response: proto.NewAttribute([]any{"ttl", int64(3600)}, proto.NewBulkString("v")),
+			resp2:    "$1\r\nv\r\n",
+			resp3:    "|1\r\n$3\r\nttl\r\n:3600\r\n$1\r\nv\r\n",
+		},
+		{
+			name:     "push",
+			response: proto.NewPush([]any{"message", "ch", "hi"}),
+			resp2:    "*3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n",
+			resp3:    ">3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n",
+		},
+		{
+			name:     "nested RESP3 elements",
+			response: proto.NewArray([]any{1.5, true, nil}),
+			resp2:    "*3\r\n$3\r\n1.5\r\n:1\r\n$-1\r\n",
+			resp3:    "*3\r\n,1.5\r\n#t\r\n_\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			for protocol, expected := range map[int]string{proto.RESP2: tt.resp2, proto.RESP3: tt.resp3} {
+				var buf bytes.Buffer
+				if err := proto.WriteResponseProtocol(&buf, tt.response, protocol); err != nil {
+					t.Fatalf("WriteResponseProtocol(RESP%d) error = %v", protocol, err)
+				}
+				if got := buf.String(); got != expected {
+					t.Errorf("WriteResponseProtocol(RESP%d) = %q, want %q", protocol, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteResponseProtocol_OddMap(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := proto.WriteResponseProtocol(&buf, proto.NewMap([]any{"key"}), proto.RESP3); err == nil {
+		t.Errorf("Expected an error for a map with an odd number of elements")
+	}
+}
+
 func TestResponseConstructors(t *testing.T) {
 	t.Parallel()
 
@@ -251,6 +374,45 @@ func TestComplexArrayResponse(t *testing.T) {
 	}
 }
 
+func TestNestedArrayResponse(t *testing.T) {
+	t.Parallel()
+
+	response := proto.NewArray([]any{
+		"0",
+		[]any{"key1", "key2"},
+		proto.NewSimpleString("OK"),
+	})
+
+	var buf bytes.Buffer
+	if err := proto.WriteResponse(&buf, response); err != nil {
+		t.Fatalf("WriteResponse() error = %v", err)
+	}
+
+	expected := "*3\r\n$1\r\n0\r\n*2\r\n$4\r\nkey1\r\n$4\r\nkey2\r\n+OK\r\n"
+	if result := buf.String(); result != expected {
+		t.Errorf("WriteResponse() = %q, want %q", result, expected)
+	}
+}
+
+func TestMultiResponse(t *testing.T) {
+	t.Parallel()
+
+	response := proto.NewMulti(
+		proto.NewArray([]any{"subscribe", "a", int64(1)}),
+		proto.NewArray([]any{"subscribe", "b", int64(2)}),
+	)
+
+	var buf bytes.Buffer
+	if err := proto.WriteResponse(&buf, response); err != nil {
+		t.Fatalf("WriteResponse() error = %v", err)
+	}
+
+	expected := "*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n*3\r\n$9\r\nsubscribe\r\n$1\r\nb\r\n:2\r\n"
+	if result := buf.String(); result != expected {
+		t.Errorf("WriteResponse() = %q, want %q", result, expected)
+	}
+}
+
 func TestBulkStringWithSpecialCharacters(t *testing.T) {
 	t.Parallel()
 
@@ -313,3 +475,112 @@ func validateResponseData(t *testing.T, actual, expected any) {
 		t.Errorf("Response data = %v, want %v", actual, expected)
 	}
 }
+
+func TestAppendResponse(t *testing.T) {
+	t.Parallel()
+
+	responses := []*proto.Response{
+		proto.NewSimpleString("OK"),
+		proto.NewInteger(-7),
+		proto.NewBulkString("hello"),
+		proto.NewNull(),
+		proto.NewArray(nil),
+		proto.NewArray([]any{"a", int64(1), nil, []any{"nested"}, proto.NewDouble(1.5)}),
+		proto.NewMap([]any{"k", true, "d", math.Inf(-1)}),
+		proto.NewVerbatimString("txt", "text"),
+		proto.NewAttribute([]any{"ttl", int64(3)}, proto.NewBigNumber("12345678901234567890")),
+		proto.NewMulti(proto.NewPush([]any{"message", "ch", "payload"}), proto.NewBoolean(false)),
+	}
+
+	for _, protocol := range []int{proto.RESP2, proto.RESP3} {
+		for _, resp := range responses {
+			var expected bytes.Buffer
+			if err := proto.WriteResponseProtocol(&expected, resp, protocol); err != nil {
+				t.Fatalf("WriteResponseProtocol() error = %v", err)
+			}
+
+			prefix := []byte("prefix")
+			got, err := proto.AppendResponse(prefix, resp, protocol)
+			if err != nil {
+				t.Fatalf("AppendResponse() error = %v", err)
+			}
+			if want := "prefix" + expected.String(); string(got) != want {
+				t.Errorf("AppendResponse(RESP%d) = %q, want %q", protocol, got, want)
+			}
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := proto.NewWriter(&buf)
+	if err := w.WriteResponse(proto.NewBulkString(strings.Repeat("x", 100*1024)), proto.RESP2); err != nil {
+		t.Fatalf("WriteResponse() error = %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	buf.Reset()
+
+	if err := w.WriteResponse(proto.NewMap([]any{"k", "v"}), proto.RESP3); err != nil {
+		t.Fatalf("WriteResponse() error = %v", err)
+	}
+	if err := w.WriteResponse(proto.NewMap([]any{"odd"}), proto.RESP3); err == nil {
+		t.Errorf("WriteResponse() with an odd map expected error, got nil")
+	}
+	if err := w.WriteResponse(proto.NewInteger(1), proto.RESP3); err != nil {
+		t.Fatalf("WriteResponse() error = %v", err)
+	}
+
+	expected := "%1\r\n$1\r\nk\r\n$1\r\nv\r\n:1\r\n"
+	if buf.Len() != 0 || w.Buffered() != len(expected) {
+		t.Errorf("Expected %d buffered bytes and nothing written before Flush, got %d and %q", len(expected), w.Buffered(), buf.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if buf.String() != expected || w.Buffered() != 0 {
+		t.Errorf("Writer wrote %q, want %q", buf.String(), expected)
+	}
+}
+
+func BenchmarkWriteResponse_BulkString(b *testing.B) {
+	resp := proto.NewBulkString("some-value-bytes")
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := proto.WriteResponse(io.Discard, resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriter_Array(b *testing.B) {
+	w := proto.NewWriter(io.Discard)
+	resp := proto.NewArray([]any{"user:1", int64(42), "value"})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := w.WriteResponse(resp, proto.RESP2); err != nil {
+			b.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendResponse_Map(b *testing.B) {
+	resp := proto.NewMap([]any{"keys", int64(42), "ratio", 0.5, "ok", true})
+	buf := make([]byte, 0, 256)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		var err error
+		if buf, err = proto.AppendResponse(buf[:0], resp, proto.RESP3); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package pubsub
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeyspaceEvents selects which keyspace notifications are published, using
+// the classes of Redis' notify-keyspace-events
+type KeyspaceEvents uint16
+
+// Keyspace notification classes
+const (
+	// EventsKeyspace publishes on __keyspace@<db>__:<key> with the event as payload
+	EventsKeyspace KeyspaceEvents = 1 << iota
+	// EventsKeyevent publishes on __keyevent@<db>__:<event> with the key as payload
+	EventsKeyevent
+	// EventsGeneric covers type-independent commands such as DEL, EXPIRE and RENAME
+	EventsGeneric
+	// EventsString covers string commands
+	EventsString
+	// EventsList covers list commands
+	EventsList
+	// EventsSet covers set commands
+	EventsSet
+	// EventsHash covers hash commands
+	EventsHash
+	// EventsZSet covers sorted set commands
+	EventsZSet
+	// EventsExpired covers keys removed because their TTL passed
+	EventsExpired
+	// EventsEvicted covers keys removed to stay within the memory limit
+	EventsEvicted
+	// EventsStream covers stream commands
+	EventsStream
+	// EventsKeyMiss covers reads of missing keys
+	EventsKeyMiss
+	// EventsNew covers keys added to the keyspace
+	EventsNew
+)
+
+// eventsAll is the set of classes the 'A' flag stands for
+const eventsAll = EventsGeneric | EventsString | EventsList | EventsSet | EventsHash |
+	EventsZSet | EventsExpired | EventsEvicted | EventsStream
+
+// eventFlags maps each notify-keyspace-events character to its class, in the
+// order String writes them
+var eventFlags = []struct {
+	flag   byte
+	events KeyspaceEvents
+}{
+	{'g', EventsGeneric},
+	{'$', EventsString},
+	{'l', EventsList},
+	{'s', EventsSet},
+	{'h', EventsHash},
+	{'z', EventsZSet},
+	{'x', EventsExpired},
+	{'e', EventsEvicted},
+	{'t', EventsStream},
+	{'m', EventsKeyMiss},
+	{'n', EventsNew},
+	{'K', EventsKeyspace},
+	{'E', EventsKeyevent},
+}
+
+// ParseKeyspaceEvents parses a notify-keyspace-events string such as "Ex" or
+// "KA". The empty string disables notifications.
+func ParseKeyspaceEvents(flags string) (KeyspaceEvents, error) {
+	var events KeyspaceEvents
+	for i := range len(flags) {
+		if flags[i] == 'A' {
+			events |= eventsAll
+			continue
+		}
+
+		found := false
+		for _, f := range eventFlags {
+			if f.flag == flags[i] {
+				events |= f.events
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid keyspace event flag %q", flags[i])
+		}
+	}
+
+	return events, nil
+}
+
+// String returns the flags of the set in canonical form, using 'A' for the
+// classes it covers
+func (e KeyspaceEvents) String() string {
+	var b strings.Builder
+	rest := e
+	if e&eventsAll == eventsAll {
+		b.WriteByte('A')
+		rest &^= eventsAll
+	}
+	for _, f := range eventFlags {
+		if rest&f.events != 0 {
+			b.WriteByte(f.flag)
+		}
+	}
+	return b.String()
+}
+
+// eventClasses maps the events the store emits to their class; any other
+// event is generic
+var eventClasses = map[string]KeyspaceEvents{
+	"set":         EventsString,
+	"append":      EventsString,
+	"setrange":    EventsString,
+	"incrby":      EventsString,
+	"incrbyfloat": EventsString,
+	"expired":     EventsExpired,
+	"evicted":     EventsEvicted,
+}
+
+// Notifier publishes keyspace notifications for the enabled event classes
+type Notifier struct {
+	broker *Broker
+	events KeyspaceEvents
+}
+
+// NewNotifier creates a notifier publishing the events selected by events
+// through broker
+func NewNotifier(broker *Broker, events KeyspaceEvents) *Notifier {
+	return &Notifier{broker: broker, events: events}
+}
+
+// Enabled reports whether any notification would be published
+func (n *Notifier) Enabled() bool {
+	return n.events&(EventsKeyspace|EventsKeyevent) != 0 && n.events&^(EventsKeyspace|EventsKeyevent) != 0
+}
+
+// Notify publishes that event happened to key in database db, on the
+// keyspace channel, the keyevent channel or both as configured
+func (n *Notifier) Notify(db int, event, key string) {
+	class, exists := eventClasses[event]
+	if !exists {
+		class = EventsGeneric
+	}
+	if n.events&class == 0 {
+		return
+	}
+
+	if n.events&EventsKeyspace != 0 {
+		n.broker.Publish("__keyspace@"+strconv.Itoa(db)+"__:"+key, event)
+	}
+	if n.events&EventsKeyevent != 0 {
+		n.broker.Publish("__keyevent@"+strconv.Itoa(db)+"__:"+event, key)
+	}
+}
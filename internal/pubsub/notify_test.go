@@ -0,0 +1,127 @@
+package pubsub_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/pubsub"
+)
+
+func TestParseKeyspaceEvents(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		flags     string
+		expected  pubsub.KeyspaceEvents
+		canonical string
+		wantErr   bool
+	}{
+		{flags: "", expected: 0, canonical: ""},
+		{flags: "Ex", expected: pubsub.EventsKeyevent | pubsub.EventsExpired, canonical: "xE"},
+		{flags: "K$g", expected: pubsub.EventsKeyspace | pubsub.EventsString | pubsub.EventsGeneric, canonical: "g$K"},
+		{flags: "KEA", canonical: "AKE"},
+		{flags: "Ag$", canonical: "A"},
+		{flags: "Kq", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flags, func(t *testing.T) {
+			t.Parallel()
+
+			events, err := pubsub.ParseKeyspaceEvents(tt.flags)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.flags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKeyspaceEvents(%q) failed: %v", tt.flags, err)
+			}
+			if tt.expected != 0 && events != tt.expected {
+				t.Errorf("ParseKeyspaceEvents(%q) = %b, want %b", tt.flags, events, tt.expected)
+			}
+			if got := events.String(); got != tt.canonical {
+				t.Errorf("String() = %q, want %q", got, tt.canonical)
+			}
+		})
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		flags    string
+		db       int
+		event    string
+		expected []pubsub.Message
+	}{
+		{
+			name:  "keyspace and keyevent",
+			flags: "KEA",
+			db:    0,
+			event: "set",
+			expected: []pubsub.Message{
+				{Pattern: "__key*__:*", Channel: "__keyspace@0__:user:1", Payload: "set"},
+				{Pattern: "__key*__:*", Channel: "__keyevent@0__:set", Payload: "user:1"},
+			},
+		},
+		{
+			name:  "keyevent only in another database",
+			flags: "Eg",
+			db:    3,
+			event: "del",
+			expected: []pubsub.Message{
+				{Pattern: "__key*__:*", Channel: "__keyevent@3__:del", Payload: "user:1"},
+			},
+		},
+		{
+			name:  "class not enabled",
+			flags: "Kx",
+			db:    0,
+			event: "del",
+		},
+		{
+			name:  "expired class",
+			flags: "Kx",
+			db:    0,
+			event: "expired",
+			expected: []pubsub.Message{
+				{Pattern: "__key*__:*", Channel: "__keyspace@0__:user:1", Payload: "expired"},
+			},
+		},
+		{
+			name:  "no channel type selected",
+			flags: "A",
+			db:    0,
+			event: "set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			events, err := pubsub.ParseKeyspaceEvents(tt.flags)
+			if err != nil {
+				t.Fatalf("ParseKeyspaceEvents failed: %v", err)
+			}
+			broker := pubsub.NewBroker()
+			sub := broker.NewSubscriber(16)
+			t.Cleanup(sub.Close)
+			sub.PSubscribe("__key*__:*")
+
+			notifier := pubsub.NewNotifier(broker, events)
+			notifier.Notify(tt.db, tt.event, "user:1")
+
+			if got := drain(sub); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if got, want := notifier.Enabled(), tt.flags != "A"; got != want {
+				t.Errorf("Enabled() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+// Package pubsub implements Redis-style publish/subscribe with channel and
+// pattern subscriptions, and the keyspace notifications published on it.
+package pubsub
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Abhishek2095/kv-stash/internal/glob"
+)
+
+// Message is a published message as delivered to one subscription
+type Message struct {
+	// Pattern is the pattern that matched the channel, or empty for a
+	// channel subscription
+	Pattern string
+	Channel string
+	Payload string
+}
+
+// Broker routes published messages to subscribers. It is safe for concurrent use.
+type Broker struct {
+	mu       sync.RWMutex
+	channels map[string]map[*Subscriber]struct{}
+	patterns map[string]map[*Subscriber]struct{}
+}
+
+// NewBroker creates a broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{
+		channels: make(map[string]map[*Subscriber]struct{}),
+		patterns: make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+// Publish delivers payload to every subscriber of channel and of a pattern
+// matching it, and returns the number of subscriptions that received it
+func (b *Broker) Publish(channel, payload string) int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var receivers int64
+	for sub := range b.channels[channel] {
+		sub.deliver(Message{Channel: channel, Payload: payload})
+		receivers++
+	}
+	for pattern, subs := range b.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		for sub := range subs {
+			sub.deliver(Message{Pattern: pattern, Channel: channel, Payload: payload})
+			receivers++
+		}
+	}
+
+	return receivers
+}
+
+// Channels returns the sorted channels with at least one subscriber, only
+// those matching pattern unless it is empty
+func (b *Broker) Channels(pattern string) []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	channels := make([]string, 0, len(b.channels))
+	for channel := range b.channels {
+		if pattern == "" || glob.Match(pattern, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// NumSub returns the number of subscribers of channel, not counting pattern
+// subscriptions
+func (b *Broker) NumSub(channel string) int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return int64(len(b.channels[channel]))
+}
+
+// NumPat returns the number of pattern subscriptions across all subscribers
+func (b *Broker) NumPat() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var count int64
+	for _, subs := range b.patterns {
+		count += int64(len(subs))
+	}
+	return count
+}
+
+// Subscriber is one client's set of subscriptions and its queue of pending
+// messages. Subscriptions are changed only by the owning client; messages are
+// read from Messages.
+type Subscriber struct {
+	broker *Broker
+
+	// channels and patterns are guarded by the broker's lock
+	channels map[string]struct{}
+	patterns map[string]struct{}
+
+	// mu guards closing messages against concurrent delivery
+	mu         sync.Mutex
+	messages   chan Message
+	closed     bool
+	overflowed bool
+}
+
+// NewSubscriber creates a subscriber queuing up to buffer undelivered
+// messages. A subscriber that falls further behind is closed, like a Redis
+// client exceeding its pubsub output buffer limit.
+func (b *Broker) NewSubscriber(buffer int) *Subscriber {
+	return &Subscriber{
+		broker:   b,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		messages: make(chan Message, buffer),
+	}
+}
+
+// Messages returns the queue of messages for the subscriber. It is closed
+// when the subscriber is closed or overflows.
+func (s *Subscriber) Messages() <-chan Message {
+	return s.messages
+}
+
+// Overflowed reports whether the subscriber was closed because it fell behind
+func (s *Subscriber) Overflowed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.overflowed
+}
+
+// Subscribe subscribes to channel and returns the subscriber's number of
+// subscriptions
+func (s *Subscriber) Subscribe(channel string) int64 {
+	return s.broker.add(s, s.channels, s.broker.channels, channel)
+}
+
+// Unsubscribe unsubscribes from channel and returns the subscriber's number
+// of subscriptions
+func (s *Subscriber) Unsubscribe(channel string) int64 {
+	return s.broker.remove(s, s.channels, s.broker.channels, channel)
+}
+
+// PSubscribe subscribes to every channel matching pattern and returns the
+// subscriber's number of subscriptions
+func (s *Subscriber) PSubscribe(pattern string) int64 {
+	return s.broker.add(s, s.patterns, s.broker.patterns, pattern)
+}
+
+// PUnsubscribe unsubscribes from pattern and returns the subscriber's number
+// of subscriptions
+func (s *Subscriber) PUnsubscribe(pattern string) int64 {
+	return s.broker.remove(s, s.patterns, s.broker.patterns, pattern)
+}
+
+// Channels returns the channels the subscriber is subscribed to, sorted
+func (s *Subscriber) Channels() []string {
+	s.broker.mu.RLock()
+	defer s.broker.mu.RUnlock()
+
+	return sortedNames(s.channels)
+}
+
+// Patterns returns the patterns the subscriber is subscribed to, sorted
+func (s *Subscriber) Patterns() []string {
+	s.broker.mu.RLock()
+	defer s.broker.mu.RUnlock()
+
+	return sortedNames(s.patterns)
+}
+
+// Count returns the subscriber's number of channel and pattern subscriptions
+func (s *Subscriber) Count() int64 {
+	s.broker.mu.RLock()
+	defer s.broker.mu.RUnlock()
+
+	return s.count()
+}
+
+// Counts returns the subscriber's number of channel and of pattern
+// subscriptions
+func (s *Subscriber) Counts() (channels, patterns int) {
+	s.broker.mu.RLock()
+	defer s.broker.mu.RUnlock()
+
+	return len(s.channels), len(s.patterns)
+}
+
+// Close removes every subscription and closes the message queue. It is safe
+// to call more than once.
+func (s *Subscriber) Close() {
+	s.broker.mu.Lock()
+	for channel := range s.channels {
+		s.broker.unlink(s.broker.channels, channel, s)
+	}
+	for pattern := range s.patterns {
+		s.broker.unlink(s.broker.patterns, pattern, s)
+	}
+	clear(s.channels)
+	clear(s.patterns)
+	s.broker.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.messages)
+	}
+}
+
+// count returns the number of subscriptions. The caller must hold the
+// broker's lock.
+func (s *Subscriber) count() int64 {
+	return int64(len(s.channels) + len(s.patterns))
+}
+
+// deliver queues msg without blocking the publisher, closing the subscriber
+// when its queue is full
+func (s *Subscriber) deliver(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.messages <- msg:
+	default:
+		s.closed, s.overflowed = true, true
+		close(s.messages)
+	}
+}
+
+// add records a subscription of sub to name in both its own set and the
+// broker's index
+func (b *Broker) add(sub *Subscriber, own map[string]struct{}, index map[string]map[*Subscriber]struct{}, name string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := own[name]; !exists {
+		own[name] = struct{}{}
+		subs, exists := index[name]
+		if !exists {
+			subs = make(map[*Subscriber]struct{})
+			index[name] = subs
+		}
+		subs[sub] = struct{}{}
+	}
+	return sub.count()
+}
+
+// remove drops a subscription of sub to name from both its own set and the
+// broker's index
+func (b *Broker) remove(sub *Subscriber, own map[string]struct{}, index map[string]map[*Subscriber]struct{}, name string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := own[name]; exists {
+		delete(own, name)
+		b.unlink(index, name, sub)
+	}
+	return sub.count()
+}
+
+// unlink removes sub from the index entry of name, dropping the entry when it
+// becomes empty. The caller must hold the broker's write lock.
+func (b *Broker) unlink(index map[string]map[*Subscriber]struct{}, name string, sub *Subscriber) {
+	subs := index[name]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(index, name)
+	}
+}
+
+// sortedNames returns the keys of set in ascending order
+func sortedNames(set map[string]struct{}) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,151 @@
+package pubsub_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/pubsub"
+)
+
+// drain returns every message queued for sub without blocking
+func drain(sub *pubsub.Subscriber) []pubsub.Message {
+	var messages []pubsub.Message
+	for {
+		select {
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return messages
+			}
+			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	t.Parallel()
+
+	broker := pubsub.NewBroker()
+	exact := broker.NewSubscriber(16)
+	pattern := broker.NewSubscriber(16)
+	t.Cleanup(exact.Close)
+	t.Cleanup(pattern.Close)
+
+	exact.Subscribe("news")
+	pattern.PSubscribe("n*")
+	pattern.PSubscribe("sport?")
+
+	if got := broker.Publish("news", "hello"); got != 2 {
+		t.Errorf("Expected 2 receivers, got %d", got)
+	}
+	if got := broker.Publish("sports", "goal"); got != 1 {
+		t.Errorf("Expected 1 receiver, got %d", got)
+	}
+	if got := broker.Publish("weather", "rain"); got != 0 {
+		t.Errorf("Expected no receivers, got %d", got)
+	}
+
+	expected := []pubsub.Message{{Channel: "news", Payload: "hello"}}
+	if got := drain(exact); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	expected = []pubsub.Message{
+		{Pattern: "n*", Channel: "news", Payload: "hello"},
+		{Pattern: "sport?", Channel: "sports", Payload: "goal"},
+	}
+	if got := drain(pattern); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestSubscriber_Subscriptions(t *testing.T) {
+	t.Parallel()
+
+	broker := pubsub.NewBroker()
+	sub := broker.NewSubscriber(16)
+	other := broker.NewSubscriber(16)
+	t.Cleanup(other.Close)
+
+	steps := []struct {
+		name     string
+		run      func() int64
+		expected int64
+	}{
+		{name: "subscribe", run: func() int64 { return sub.Subscribe("a") }, expected: 1},
+		{name: "subscribe again", run: func() int64 { return sub.Subscribe("a") }, expected: 1},
+		{name: "psubscribe", run: func() int64 { return sub.PSubscribe("a*") }, expected: 2},
+		{name: "unsubscribe unknown", run: func() int64 { return sub.Unsubscribe("b") }, expected: 2},
+		{name: "other subscribes", run: func() int64 { return other.Subscribe("a") }, expected: 1},
+		{name: "unsubscribe", run: func() int64 { return sub.Unsubscribe("a") }, expected: 1},
+		{name: "resubscribe", run: func() int64 { return sub.Subscribe("c") }, expected: 2},
+	}
+	for _, step := range steps {
+		if got := step.run(); got != step.expected {
+			t.Errorf("%s: expected %d subscriptions, got %d", step.name, step.expected, got)
+		}
+	}
+
+	if got := sub.Channels(); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("Expected channels [c], got %v", got)
+	}
+	if got := sub.Patterns(); !reflect.DeepEqual(got, []string{"a*"}) {
+		t.Errorf("Expected patterns [a*], got %v", got)
+	}
+	if channels, patterns := sub.Counts(); channels != 1 || patterns != 1 {
+		t.Errorf("Expected 1 channel and 1 pattern, got %d and %d", channels, patterns)
+	}
+	if got := broker.Channels(""); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("Expected active channels [a c], got %v", got)
+	}
+	if got := broker.Channels("c*"); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("Expected active channels [c], got %v", got)
+	}
+	if got := broker.NumSub("a"); got != 1 {
+		t.Errorf("Expected 1 subscriber of a, got %d", got)
+	}
+	if got := broker.NumPat(); got != 1 {
+		t.Errorf("Expected 1 pattern, got %d", got)
+	}
+
+	sub.Close()
+	sub.Close()
+	if got := sub.Count(); got != 0 {
+		t.Errorf("Expected no subscriptions after Close, got %d", got)
+	}
+	if got := broker.Channels(""); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("Expected active channels [a] after Close, got %v", got)
+	}
+	if got := broker.NumPat(); got != 0 {
+		t.Errorf("Expected no patterns after Close, got %d", got)
+	}
+	if _, ok := <-sub.Messages(); ok {
+		t.Errorf("Expected the message queue to be closed")
+	}
+}
+
+func TestSubscriber_Overflow(t *testing.T) {
+	t.Parallel()
+
+	broker := pubsub.NewBroker()
+	sub := broker.NewSubscriber(2)
+	t.Cleanup(sub.Close)
+	sub.Subscribe("ch")
+
+	for range 3 {
+		broker.Publish("ch", "x")
+	}
+
+	if !sub.Overflowed() {
+		t.Errorf("Expected the subscriber to overflow")
+	}
+	if got := len(drain(sub)); got != 2 {
+		t.Errorf("Expected the 2 queued messages before the close, got %d", got)
+	}
+	if _, ok := <-sub.Messages(); ok {
+		t.Errorf("Expected the message queue to be closed")
+	}
+
+	// Publishing to a closed subscriber must not panic
+	broker.Publish("ch", "y")
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// FileAnalysis is the result of analyzing a command file offline
+type FileAnalysis struct {
+	store.Analysis
+	// Commands is the number of commands read from the file
+	Commands int64
+	// Skipped is the number of commands that failed or are not supported and
+	// were left out of the keyspace
+	Skipped int64
+}
+
+// AnalyzeFile reports the composition of database db in the keyspace built by
+// a command file: a stream of RESP commands, the format of Redis' append-only
+// file and of redis-cli --pipe input. The commands are replayed into a private
+// store, without a server, and the result is analyzed like MEMORY ANALYZE.
+func AnalyzeFile(r io.Reader, db int, delimiter string, top int, logger *obs.Logger) (FileAnalysis, error) {
+	config := DefaultConfig()
+	if db < 0 || db >= config.Storage.Databases {
+		return FileAnalysis{}, fmt.Errorf("database %d is out of range", db)
+	}
+
+	s, err := store.New(&store.Config{
+		Shards:         config.Server.Shards,
+		Databases:      config.Storage.Databases,
+		EvictionPolicy: config.Storage.EvictionPolicy,
+		ExpiryIndex:    config.TTL.Index,
+	}, logger)
+	if err != nil {
+		return FileAnalysis{}, fmt.Errorf("failed to create store: %w", err)
+	}
+	defer s.Close()
+
+	handler := NewHandler(s, config, logger)
+	defer handler.Close()
+
+	var result FileAnalysis
+	parser := proto.NewParserWithLimits(r, config.Limits.protoLimits())
+	for {
+		req, err := parser.ReadRequest()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return FileAnalysis{}, fmt.Errorf("command %d: %w", result.Commands+1, err)
+		}
+
+		result.Commands++
+		if resp := handler.HandleCommand(req); resp.Type == proto.Error {
+			result.Skipped++
+		}
+	}
+
+	view, err := s.DB(db)
+	if err != nil {
+		return FileAnalysis{}, err
+	}
+	result.Analysis = view.Analyze(delimiter, top)
+	return result, nil
+}
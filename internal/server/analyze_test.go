@@ -0,0 +1,79 @@
+package server_test
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+)
+
+func TestAnalyzeFile(t *testing.T) {
+	t.Parallel()
+
+	// A file replays like an append-only file: later commands overwrite,
+	// expire and move keys written earlier
+	past := strconv.FormatInt(time.Now().Add(-time.Hour).UnixMilli(), 10)
+	var file bytes.Buffer
+	for _, args := range [][]string{
+		{"SET", "user:1", "alice"},
+		{"SET", "user:2", "bob"},
+		{"SET", "blob:1", strings.Repeat("x", 5000)},
+		{"SET", "user:2", "robert", "EX", "30"},
+		{"SET", "gone:1", "v"},
+		{"PEXPIREAT", "gone:1", past},
+		{"MULTI"},
+		{"SELECT", "1"},
+		{"SET", "other:1", "v"},
+		{"SELECT", "0"},
+		{"EXEC"},
+	} {
+		file.Write(encodeCommand(args...))
+	}
+
+	result, err := server.AnalyzeFile(&file, 0, ":", 1, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+
+	if result.Commands != 11 || result.Skipped != 2 {
+		t.Errorf("Expected 11 commands with MULTI and EXEC skipped, got %d and %d skipped", result.Commands, result.Skipped)
+	}
+	if result.Keys != 3 {
+		t.Errorf("Expected the 3 live keys of database 0, got %d", result.Keys)
+	}
+	if len(result.Largest) != 1 || result.Largest[0].Key != "blob:1" {
+		t.Errorf("Expected blob:1 as the largest key, got %v", result.Largest)
+	}
+	if result.TTLHistogram[0].Keys != 1 || result.Persistent.Keys != 2 {
+		t.Errorf("Expected one key expiring within a minute and 2 persistent keys, got %v and %v",
+			result.TTLHistogram, result.Persistent)
+	}
+}
+
+func TestAnalyzeFile_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		file string
+		db   int
+	}{
+		{name: "truncated command", file: "*2\r\n$3\r\nGET\r\n$5\r\nab"},
+		{name: "invalid length", file: "*x\r\n"},
+		{name: "database out of range", file: "", db: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := server.AnalyzeFile(strings.NewReader(tt.file), tt.db, ":", 10, obs.NewLogger(false)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/pubsub"
+)
+
+// clientSession is the state of one connection that other connections see
+// and act on with CLIENT commands. The connection's handler owns the mutable
+// fields and publishes them after every command.
+type clientSession struct {
+	id      int64
+	addr    string
+	laddr   string
+	created time.Time
+	// conn is closed to kill the client; nil for handlers without a
+	// connection
+	conn net.Conn
+
+	mu       sync.Mutex
+	name     string
+	user     string
+	db       int
+	protocol int
+	cmd      string
+	// lastActive is when the last command completed
+	lastActive time.Time
+	subscriber *pubsub.Subscriber
+	noEvict    bool
+}
+
+// begin shows cmd, an upper-case command name, as the client's current
+// command
+func (c *clientSession) begin(cmd string) {
+	c.mu.Lock()
+	c.cmd = cmd
+	c.mu.Unlock()
+}
+
+// info describes the client in the CLIENT LIST format
+func (c *clientSession) info(now time.Time) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var channels, patterns int
+	if c.subscriber != nil {
+		channels, patterns = c.subscriber.Counts()
+	}
+	flags := ""
+	if channels+patterns > 0 {
+		flags += "P"
+	}
+	if c.noEvict {
+		flags += "e"
+	}
+	if flags == "" {
+		flags = "N"
+	}
+
+	var b strings.Builder
+	b.WriteString("id=" + strconv.FormatInt(c.id, 10))
+	b.WriteString(" addr=" + c.addr)
+	b.WriteString(" laddr=" + c.laddr)
+	b.WriteString(" name=" + c.name)
+	b.WriteString(" age=" + strconv.FormatInt(int64(now.Sub(c.created).Seconds()), 10))
+	b.WriteString(" idle=" + strconv.FormatInt(int64(now.Sub(c.lastActive).Seconds()), 10))
+	b.WriteString(" flags=" + flags)
+	b.WriteString(" db=" + strconv.Itoa(c.db))
+	b.WriteString(" sub=" + strconv.Itoa(channels))
+	b.WriteString(" psub=" + strconv.Itoa(patterns))
+	b.WriteString(" cmd=" + strings.ToLower(c.cmd))
+	b.WriteString(" user=" + c.user)
+	b.WriteString(" resp=" + strconv.Itoa(c.protocol))
+	return b.String()
+}
+
+// userName returns the user the client was authenticated as after its last
+// command
+func (c *clientSession) userName() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.user
+}
+
+// pubSub reports whether the client had subscriptions after its last command
+func (c *clientSession) pubSub() bool {
+	c.mu.Lock()
+	sub := c.subscriber
+	c.mu.Unlock()
+
+	return sub != nil && sub.Count() > 0
+}
+
+// clientRegistry holds the sessions of the open connections and the pause
+// state set by CLIENT PAUSE. It is shared by every connection's handler.
+type clientRegistry struct {
+	mu      sync.RWMutex
+	clients map[int64]*clientSession
+
+	// pausedUntil is the end of the pause in Unix nanoseconds, read without
+	// the lock by every command. pauseMu guards the rest of the pause state;
+	// resumed is closed when the pause is lifted early.
+	pausedUntil atomic.Int64
+	pauseMu     sync.Mutex
+	pauseEnd    time.Time
+	pauseAll    bool
+	resumed     chan struct{}
+}
+
+// newClientRegistry creates an empty registry
+func newClientRegistry() *clientRegistry {
+	return &clientRegistry{
+		clients: make(map[int64]*clientSession),
+		resumed: make(chan struct{}),
+	}
+}
+
+// register adds the session of a new connection. conn is nil for handlers
+// that are not serving one.
+func (r *clientRegistry) register(id int64, conn net.Conn) *clientSession {
+	now := time.Now()
+	session := &clientSession{id: id, created: now, lastActive: now, conn: conn}
+	if conn != nil {
+		session.addr = remoteAddr(conn)
+		session.laddr = conn.LocalAddr().String()
+	}
+
+	r.mu.Lock()
+	r.clients[id] = session
+	r.mu.Unlock()
+	return session
+}
+
+// remove removes the session with id, if it is still registered
+func (r *clientRegistry) remove(id int64) {
+	r.mu.Lock()
+	delete(r.clients, id)
+	r.mu.Unlock()
+}
+
+// get returns the session with id, or nil
+func (r *clientRegistry) get(id int64) *clientSession {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.clients[id]
+}
+
+// list returns the sessions ordered by ID
+func (r *clientRegistry) list() []*clientSession {
+	r.mu.RLock()
+	sessions := make([]*clientSession, 0, len(r.clients))
+	for _, session := range r.clients {
+		sessions = append(sessions, session)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].id < sessions[j].id })
+	return sessions
+}
+
+// kill disconnects the client of session. Its session is removed at once, so
+// it is no longer listed while the connection winds down.
+func (r *clientRegistry) kill(session *clientSession) {
+	r.remove(session.id)
+	if session.conn != nil {
+		_ = session.conn.Close()
+	}
+}
+
+// killAll disconnects every client
+func (r *clientRegistry) killAll() {
+	for _, session := range r.list() {
+		r.kill(session)
+	}
+}
+
+// pause holds back clients' commands for d: every command when all is set,
+// otherwise only writes. A pause in progress is only ever extended and
+// widened.
+func (r *clientRegistry) pause(d time.Duration, all bool) {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+
+	now := time.Now()
+	end := now.Add(d)
+	if now.Before(r.pauseEnd) {
+		all = all || r.pauseAll
+		if end.Before(r.pauseEnd) {
+			end = r.pauseEnd
+		}
+	}
+	r.pauseEnd, r.pauseAll = end, all
+	r.pausedUntil.Store(end.UnixNano())
+}
+
+// unpause lifts the pause, resuming the clients waiting on it
+func (r *clientRegistry) unpause() {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+
+	r.pauseEnd, r.pauseAll = time.Time{}, false
+	r.pausedUntil.Store(0)
+	close(r.resumed)
+	r.resumed = make(chan struct{})
+}
+
+// waitPause blocks a command while clients are paused: writes for any pause,
+// other commands only when all commands are
+func (r *clientRegistry) waitPause(write bool) {
+	for time.Now().UnixNano() < r.pausedUntil.Load() {
+		r.pauseMu.Lock()
+		wait, all, resumed := time.Until(r.pauseEnd), r.pauseAll, r.resumed
+		r.pauseMu.Unlock()
+		if wait <= 0 || (!all && !write) {
+			return
+		}
+
+		// The pause may have been extended by the time it ends, so it is
+		// checked again
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-resumed:
+		}
+		timer.Stop()
+	}
+}
@@ -0,0 +1,210 @@
+package server_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/server"
+)
+
+// dialClients opens n connections to addr, closed when the test ends
+func dialClients(t *testing.T, addr string, n int) []net.Conn {
+	t.Helper()
+
+	conns := make([]net.Conn, n)
+	for i := range conns {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		conns[i] = conn
+	}
+	return conns
+}
+
+// readBulk reads a bulk string reply from conn
+func readBulk(t *testing.T, conn net.Conn) string {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var header []byte
+	b := make([]byte, 1)
+	for !strings.HasSuffix(string(header), "\r\n") {
+		if _, err := conn.Read(b); err != nil {
+			t.Fatalf("Failed to read a reply: %v", err)
+		}
+		header = append(header, b[0])
+	}
+	if header[0] != '$' {
+		t.Fatalf("Expected a bulk string, got %q", header)
+	}
+	n, err := strconv.Atoi(string(header[1 : len(header)-2]))
+	if err != nil {
+		t.Fatalf("Invalid bulk string length in %q", header)
+	}
+	body := make([]byte, n+2)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		t.Fatalf("Failed to read a reply: %v", err)
+	}
+	return string(body[:n])
+}
+
+// expectClosed checks that the server closed conn
+func expectClosed(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the connection to be closed, got %d bytes and %v", n, err)
+	}
+}
+
+func TestServer_ClientList(t *testing.T) {
+	t.Parallel()
+
+	addr := startServer(t, server.DefaultConfig())
+	conns := dialClients(t, addr, 2)
+	roundTrip(t, conns[0], "+OK\r\n", "CLIENT", "SETNAME", "worker")
+	roundTrip(t, conns[0], "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n", "SUBSCRIBE", "news")
+
+	if _, err := conns[1].Write(encodeCommand("CLIENT", "LIST")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(readBulk(t, conns[1]), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 clients, got %q", lines)
+	}
+	// The connections are listed by ID, in the order they were accepted
+	expected := regexp.MustCompile(`^id=\d+ addr=` + regexp.QuoteMeta(conns[0].LocalAddr().String()) +
+		` laddr=\S+ name=worker age=\d+ idle=\d+ flags=P db=0 sub=1 psub=0 cmd=subscribe user=default resp=2$` +
+		`|^id=\d+ addr=` + regexp.QuoteMeta(conns[1].LocalAddr().String()) +
+		` laddr=\S+ name= age=\d+ idle=\d+ flags=N db=0 sub=0 psub=0 cmd=client user=default resp=2$`)
+	for _, line := range lines {
+		if !expected.MatchString(line) {
+			t.Errorf("Unexpected client %q", line)
+		}
+	}
+	if strings.Contains(lines[0], " name=worker ") == strings.Contains(lines[1], " name=worker ") {
+		t.Errorf("Expected both clients to be listed, got %q", lines)
+	}
+
+	if _, err := conns[1].Write(encodeCommand("CLIENT", "LIST", "TYPE", "pubsub")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if list := readBulk(t, conns[1]); !strings.Contains(list, "name=worker") || strings.Count(list, "\n") != 1 {
+		t.Errorf("Expected only the subscriber, got %q", list)
+	}
+}
+
+func TestServer_ClientKill(t *testing.T) {
+	t.Parallel()
+
+	config := server.DefaultConfig()
+	config.Server.AuthPassword = "admin"
+	addr := startServer(t, config)
+	conns := dialClients(t, addr, 4)
+	admin, byAddr, byUser, other := conns[0], conns[1], conns[2], conns[3]
+
+	roundTrip(t, admin, "+OK\r\n", "AUTH", "admin")
+	roundTrip(t, admin, "+OK\r\n", "ACL", "SETUSER", "jobs", "on", ">pw", "+@all")
+	roundTrip(t, byUser, "+OK\r\n", "AUTH", "jobs", "pw")
+	roundTrip(t, other, "+OK\r\n", "AUTH", "admin")
+
+	if got := roundTrip(t, admin, "+OK\r\n", "CLIENT", "KILL", byAddr.LocalAddr().String()); got != "+OK\r\n" {
+		t.Errorf("Expected OK, got %q", got)
+	}
+	expectClosed(t, byAddr)
+
+	if got := roundTrip(t, admin, ":1\r\n", "CLIENT", "KILL", "USER", "jobs"); got != ":1\r\n" {
+		t.Errorf("Expected 1 client killed, got %q", got)
+	}
+	expectClosed(t, byUser)
+
+	// The caller is only killed without SKIPME, and still gets the reply
+	if got := roundTrip(t, admin, ":1\r\n", "CLIENT", "KILL", "USER", "default"); got != ":1\r\n" {
+		t.Errorf("Expected 1 client killed, got %q", got)
+	}
+	expectClosed(t, other)
+	if got := roundTrip(t, admin, ":1\r\n", "CLIENT", "KILL", "USER", "default", "SKIPME", "no"); got != ":1\r\n" {
+		t.Errorf("Expected 1 client killed, got %q", got)
+	}
+	expectClosed(t, admin)
+}
+
+func TestServer_ClientPause(t *testing.T) {
+	t.Parallel()
+
+	addr := startServer(t, server.DefaultConfig())
+	conns := dialClients(t, addr, 2)
+	admin, client := conns[0], conns[1]
+
+	roundTrip(t, admin, "+OK\r\n", "CLIENT", "PAUSE", "10000", "WRITE")
+
+	// Reads go on while writes wait for the pause to be lifted
+	roundTrip(t, client, "$-1\r\n", "GET", "k")
+	if _, err := client.Write(encodeCommand("SET", "k", "v")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := client.Read(make([]byte, 5)); err == nil {
+		t.Fatalf("Expected the write to be paused, got %d bytes", n)
+	}
+	roundTrip(t, admin, "+OK\r\n", "CLIENT", "UNPAUSE")
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	reply := make([]byte, 5)
+	if _, err := io.ReadFull(client, reply); err != nil || string(reply) != "+OK\r\n" {
+		t.Errorf("Expected the paused SET to complete, got %q, %v", reply, err)
+	}
+	if got := roundTrip(t, client, "$1\r\nv\r\n", "GET", "k"); got != "$1\r\nv\r\n" {
+		t.Errorf("Expected the value, got %q", got)
+	}
+
+	// A pause of every command ends on its own
+	roundTrip(t, admin, "+OK\r\n", "CLIENT", "PAUSE", "200", "ALL")
+	start := time.Now()
+	roundTrip(t, client, "+PONG\r\n", "PING")
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected PING to be paused, it took %v", elapsed)
+	}
+}
+
+func TestServer_ClientReply(t *testing.T) {
+	t.Parallel()
+
+	addr := startServer(t, server.DefaultConfig())
+	conn := dialClients(t, addr, 1)[0]
+
+	// Only the replies to CLIENT REPLY ON and the final GETs are sent
+	var commands []byte
+	for _, args := range [][]string{
+		{"CLIENT", "REPLY", "OFF"},
+		{"SET", "a", "1"},
+		{"CLIENT", "REPLY", "ON"},
+		{"CLIENT", "REPLY", "SKIP"},
+		{"SET", "b", "2"},
+		{"GET", "a"},
+		{"GET", "b"},
+	} {
+		commands = append(commands, encodeCommand(args...)...)
+	}
+	if _, err := conn.Write(commands); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	expected := "+OK\r\n$1\r\n1\r\n$1\r\n2\r\n"
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	reply := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("Failed to read the replies: %v (got %q)", err, reply)
+	}
+	if string(reply) != expected {
+		t.Errorf("Expected %q, got %q", expected, reply)
+	}
+}
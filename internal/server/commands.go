@@ -0,0 +1,170 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/Abhishek2095/kv-stash/internal/acl"
+)
+
+// channelArgs says which arguments of a command are Pub/Sub channels
+type channelArgs int
+
+const (
+	// noChannels is for commands without channel arguments
+	noChannels channelArgs = iota
+	// firstChannel is for commands publishing to their first argument
+	firstChannel
+	// everyChannel is for commands whose arguments are all channels
+	everyChannel
+	// everyPattern is for commands whose arguments are all channel patterns
+	everyPattern
+)
+
+// commandInfo is the metadata of a command the handler dispatches: its ACL
+// categories and which of its arguments are keys and channels
+type commandInfo struct {
+	categories acl.Category
+	// firstKey and lastKey are the positions of the first and last key among
+	// the arguments, a negative lastKey counting back from the end. keyStep
+	// is the distance between keys, zero for keyless commands.
+	firstKey, lastKey, keyStep int
+	// keysOf finds the keys of commands whose keys depend on a subcommand,
+	// in place of the positions
+	keysOf   func(args [][]byte) [][]byte
+	channels channelArgs
+}
+
+// keys returns the keys among args, or nil for keyless or malformed
+// commands. Keys at consecutive positions are a subslice of args.
+func (c *commandInfo) keys(args [][]byte) [][]byte {
+	if c.keysOf != nil {
+		return c.keysOf(args)
+	}
+	if c.keyStep == 0 {
+		return nil
+	}
+
+	last := c.lastKey
+	if last < 0 {
+		last += len(args)
+		// A variadic command's arguments come in whole groups, such as
+		// MSET's key and value pairs
+		if (len(args)-c.firstKey)%c.keyStep != 0 {
+			return nil
+		}
+	}
+	if c.firstKey > last || last >= len(args) {
+		return nil
+	}
+	if c.keyStep == 1 {
+		return args[c.firstKey : last+1]
+	}
+
+	keys := make([][]byte, 0, (last-c.firstKey)/c.keyStep+1)
+	for i := c.firstKey; i <= last; i += c.keyStep {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+// keyless describes a command without keys
+func keyless(categories acl.Category) *commandInfo {
+	return &commandInfo{categories: categories}
+}
+
+// keyed describes a command with keys from position first to last, every
+// step arguments
+func keyed(categories acl.Category, first, last, step int) *commandInfo {
+	return &commandInfo{categories: categories, firstKey: first, lastKey: last, keyStep: step}
+}
+
+// commandTable holds the metadata of every command the handler dispatches
+var commandTable = map[string]*commandInfo{
+	// Connection
+	"PING":  keyless(acl.Fast | acl.Connection),
+	"ECHO":  keyless(acl.Fast | acl.Connection),
+	"HELLO": keyless(acl.Fast | acl.Connection),
+	"AUTH":  keyless(acl.Fast | acl.Connection),
+	"QUIT":  keyless(acl.Fast | acl.Connection),
+	// Server
+	"INFO": keyless(acl.Slow | acl.Dangerous),
+	"ACL":  keyless(acl.Admin | acl.Slow | acl.Dangerous),
+	// Permissions apply to whole commands, so CLIENT counts as dangerous
+	// for the sake of KILL and PAUSE
+	"CLIENT":  keyless(acl.Admin | acl.Slow | acl.Dangerous | acl.Connection),
+	"HOTKEYS": keyless(acl.Read | acl.Admin | acl.Slow),
+	"MEMORY":  {categories: acl.Read | acl.Slow, keysOf: memoryKeys},
+	// Strings
+	"GET":         keyed(acl.Read|acl.String|acl.Fast, 0, 0, 1),
+	"SET":         keyed(acl.Write|acl.String|acl.Slow, 0, 0, 1),
+	"SETNX":       keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"SETEX":       keyed(acl.Write|acl.String|acl.Slow, 0, 0, 1),
+	"PSETEX":      keyed(acl.Write|acl.String|acl.Slow, 0, 0, 1),
+	"GETSET":      keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"GETDEL":      keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"GETEX":       keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"GETRANGE":    keyed(acl.Read|acl.String|acl.Slow, 0, 0, 1),
+	"SETRANGE":    keyed(acl.Write|acl.String|acl.Slow, 0, 0, 1),
+	"APPEND":      keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"STRLEN":      keyed(acl.Read|acl.String|acl.Fast, 0, 0, 1),
+	"INCR":        keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"DECR":        keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"INCRBY":      keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"DECRBY":      keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"INCRBYFLOAT": keyed(acl.Write|acl.String|acl.Fast, 0, 0, 1),
+	"MGET":        keyed(acl.Read|acl.String|acl.Fast, 0, -1, 1),
+	"MSET":        keyed(acl.Write|acl.String|acl.Slow, 0, -2, 2),
+	"MSETNX":      keyed(acl.Write|acl.String|acl.Slow, 0, -2, 2),
+	// Keyspace
+	"DEL":         keyed(acl.Keyspace|acl.Write|acl.Slow, 0, -1, 1),
+	"UNLINK":      keyed(acl.Keyspace|acl.Write|acl.Fast, 0, -1, 1),
+	"EXISTS":      keyed(acl.Keyspace|acl.Read|acl.Fast, 0, -1, 1),
+	"TOUCH":       keyed(acl.Keyspace|acl.Read|acl.Fast, 0, -1, 1),
+	"TYPE":        keyed(acl.Keyspace|acl.Read|acl.Fast, 0, 0, 1),
+	"OBJECT":      keyed(acl.Keyspace|acl.Read|acl.Slow, 1, 1, 1),
+	"EXPIRE":      keyed(acl.Keyspace|acl.Write|acl.Fast, 0, 0, 1),
+	"PEXPIRE":     keyed(acl.Keyspace|acl.Write|acl.Fast, 0, 0, 1),
+	"EXPIREAT":    keyed(acl.Keyspace|acl.Write|acl.Fast, 0, 0, 1),
+	"PEXPIREAT":   keyed(acl.Keyspace|acl.Write|acl.Fast, 0, 0, 1),
+	"PERSIST":     keyed(acl.Keyspace|acl.Write|acl.Fast, 0, 0, 1),
+	"TTL":         keyed(acl.Keyspace|acl.Read|acl.Fast, 0, 0, 1),
+	"PTTL":        keyed(acl.Keyspace|acl.Read|acl.Fast, 0, 0, 1),
+	"EXPIRETIME":  keyed(acl.Keyspace|acl.Read|acl.Fast, 0, 0, 1),
+	"PEXPIRETIME": keyed(acl.Keyspace|acl.Read|acl.Fast, 0, 0, 1),
+	"RENAME":      keyed(acl.Keyspace|acl.Write|acl.Slow, 0, 1, 1),
+	"RENAMENX":    keyed(acl.Keyspace|acl.Write|acl.Fast, 0, 1, 1),
+	"COPY":        keyed(acl.Keyspace|acl.Write|acl.Slow, 0, 1, 1),
+	"MOVE":        keyed(acl.Keyspace|acl.Write|acl.Fast, 0, 0, 1),
+	"DBSIZE":      keyless(acl.Keyspace | acl.Read | acl.Fast),
+	"SCAN":        keyless(acl.Keyspace | acl.Read | acl.Slow),
+	"KEYS":        keyless(acl.Keyspace | acl.Read | acl.Slow | acl.Dangerous),
+	"RANDOMKEY":   keyless(acl.Keyspace | acl.Read | acl.Slow),
+	"SELECT":      keyless(acl.Fast | acl.Connection),
+	"SWAPDB":      keyless(acl.Keyspace | acl.Write | acl.Fast | acl.Dangerous),
+	"FLUSHDB":     keyless(acl.Keyspace | acl.Write | acl.Slow | acl.Dangerous),
+	"FLUSHALL":    keyless(acl.Keyspace | acl.Write | acl.Slow | acl.Dangerous),
+	// Pub/Sub
+	"SUBSCRIBE":    {categories: acl.PubSub | acl.Slow, channels: everyChannel},
+	"PSUBSCRIBE":   {categories: acl.PubSub | acl.Slow, channels: everyPattern},
+	"UNSUBSCRIBE":  keyless(acl.PubSub | acl.Slow),
+	"PUNSUBSCRIBE": keyless(acl.PubSub | acl.Slow),
+	"PUBLISH":      {categories: acl.PubSub | acl.Fast, channels: firstChannel},
+	"PUBSUB":       keyless(acl.PubSub | acl.Slow),
+}
+
+// memoryKeys returns the key of MEMORY USAGE; other subcommands are keyless
+func memoryKeys(args [][]byte) [][]byte {
+	if len(args) < exactTwoArgs || !strings.EqualFold(string(args[0]), "USAGE") {
+		return nil
+	}
+	return args[1:2]
+}
+
+// commandCategories maps every command to its ACL categories
+func commandCategories() map[string]acl.Category {
+	categories := make(map[string]acl.Category, len(commandTable))
+	for name, info := range commandTable {
+		categories[name] = info.categories
+	}
+	return categories
+}
@@ -0,0 +1,64 @@
+package server_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+func TestCommandTable_Dispatched(t *testing.T) {
+	t.Parallel()
+
+	// ACL CAT lists every command with metadata; each must be one the
+	// handler runs
+	handler := createTestHandler(t)
+	commands := mustRun(t, handler, "ACL", "CAT", "all").Data.([]any)
+	if len(commands) == 0 {
+		t.Fatal("Expected commands in @all")
+	}
+	for _, command := range commands {
+		resp := handler.HandleCommand(request(strings.ToUpper(command.(string))))
+		if resp.Type == proto.Error && strings.HasPrefix(resp.Data.(string), "ERR unknown command") {
+			t.Errorf("Expected %s to be dispatched", command)
+		}
+	}
+}
+
+func TestCommandTable_Keys(t *testing.T) {
+	t.Parallel()
+
+	// A user allowed only k* keys is denied exactly when a key position
+	// holds another key
+	tests := []struct {
+		name   string
+		args   []string
+		denied bool
+	}{
+		{name: "single key", args: []string{"GET", "x"}, denied: true},
+		{name: "options are not keys", args: []string{"SET", "k", "x", "EX", "10"}},
+		{name: "every argument", args: []string{"EXISTS", "k1", "k2", "x"}, denied: true},
+		{name: "key and value pairs", args: []string{"MSETNX", "k1", "x", "k2", "x"}},
+		{name: "second key", args: []string{"COPY", "k1", "x"}, denied: true},
+		{name: "key after the subcommand", args: []string{"OBJECT", "FREQ", "x"}, denied: true},
+		{name: "subcommand with a key", args: []string{"MEMORY", "USAGE", "x"}, denied: true},
+		{name: "subcommand without keys", args: []string{"MEMORY", "HELP"}},
+		{name: "keyless command", args: []string{"DBSIZE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createTestHandler(t)
+			mustRun(t, handler, "ACL", "SETUSER", "limited", "on", "nopass", "~k*", "+@all")
+			mustRun(t, handler, "AUTH", "limited", "any")
+
+			resp := handler.HandleCommand(request(tt.args[0], tt.args[1:]...))
+			denied := resp.Type == proto.Error && resp.Data == "NOPERM No permissions to access a key"
+			if denied != tt.denied {
+				t.Errorf("Expected denied to be %v, got %v: %v", tt.denied, resp.Type, resp.Data)
+			}
+		})
+	}
+}
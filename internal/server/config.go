@@ -5,9 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/pubsub"
+	"github.com/Abhishek2095/kv-stash/internal/store"
 )
 
 const (
@@ -17,8 +23,44 @@ const (
 	defaultWriteTimeoutSeconds  = 30
 	defaultMaxClients           = 10000
 	defaultMaxPipeline          = 1024
+	defaultMaxKeysReply         = 100000
+	defaultDatabases            = 16
 	defaultActiveCycleMs        = 50
 	defaultSnapshotIntervalSecs = 300
+	defaultHotKeysSampleRate    = 0.01
+	defaultHotKeysWindowSecs    = 60
+	defaultHotKeysMetricTopN    = 10
+	defaultSubscriberBuffer     = 1024
+	defaultTLSReloadInterval    = 10 * time.Second
+	defaultShardMailbox         = 1024
+
+	// TTL strategies: lazy only removes expired keys when they are accessed,
+	// lazy+active also runs a background expiration cycle
+	ttlStrategyLazy       = "lazy"
+	ttlStrategyLazyActive = "lazy+active"
+
+	// Execution modes: mutex runs commands on connection goroutines, which
+	// take the shard locks, shard-loop on goroutines that each own a shard
+	executionModeMutex     = "mutex"
+	executionModeShardLoop = "shard-loop"
+
+	// TLS client authentication modes: none does not ask for a client
+	// certificate, optional verifies one if it is sent, required rejects
+	// clients without a valid one
+	tlsClientAuthNone     = "none"
+	tlsClientAuthOptional = "optional"
+	tlsClientAuthRequired = "required"
+
+	// TLS client user mappings: off leaves clients to authenticate with AUTH,
+	// CN authenticates them as the user named by their certificate's common
+	// name
+	tlsAuthClientsUserOff = "off"
+	tlsAuthClientsUserCN  = "CN"
+
+	// Listener networks
+	networkTCP  = "tcp"
+	networkTCP6 = "tcp6"
+	networkUnix = "unix"
 )
 
 // AppConfig represents the application configuration
@@ -29,26 +71,98 @@ type AppConfig struct {
 	TTL           TTLConfig           `yaml:"ttl"`
 	Persistence   PersistenceConfig   `yaml:"persistence"`
 	Replication   ReplicationConfig   `yaml:"replication"`
+	PubSub        PubSubConfig        `yaml:"pubsub"`
 	Observability ObservabilityConfig `yaml:"observability"`
 }
 
 // Config contains server-specific settings
 type Config struct {
-	ListenAddr   string        `yaml:"listen_addr"`
-	Shards       int           `yaml:"shards"`
-	AuthPassword string        `yaml:"auth_password"`
+	ListenAddr   string `yaml:"listen_addr"`
+	Shards       int    `yaml:"shards"`
+	AuthPassword string `yaml:"auth_password"`
+	// AuthPasswordFile names a file holding the password, so it need not be
+	// kept in the configuration file
+	AuthPasswordFile string `yaml:"auth_password_file"`
+	// AuthPasswordEnv names an environment variable holding the password
+	AuthPasswordEnv string `yaml:"auth_password_env"`
+	// ACLFile names the file ACL users are loaded from at startup and by ACL
+	// LOAD, and saved to by ACL SAVE
+	ACLFile      string        `yaml:"acl_file"`
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// ExecutionMode is "mutex" or "shard-loop"
+	ExecutionMode string `yaml:"execution_mode"`
+	// ShardMailbox is the number of commands that can wait for a shard loop
+	// before connections handing it more block
+	ShardMailbox int `yaml:"shard_mailbox"`
+	// Listeners are further listeners served alongside listen_addr
+	Listeners []ListenerConfig `yaml:"listeners"`
+	// TLS configures a TLS listener served alongside listen_addr
+	TLS TLSConfig `yaml:"tls"`
 }
 
-// LimitsConfig contains connection and pipeline limits
+// ListenerConfig contains the settings of a listener
+type ListenerConfig struct {
+	// Network is "tcp", "tcp6" or "unix"
+	Network string `yaml:"network"`
+	// Addr is the address to listen on, or the socket path for unix
+	Addr string `yaml:"addr"`
+	// SocketMode is the octal file mode of a unix socket, such as "0660";
+	// empty leaves it to the umask
+	SocketMode string `yaml:"socket_mode"`
+	// NoAuth logs clients in as the default user without AUTH, for sockets
+	// only trusted clients can reach
+	NoAuth bool `yaml:"no_auth"`
+}
+
+// TLSConfig contains the settings of the TLS listener
+type TLSConfig struct {
+	// ListenAddr is the address TLS clients connect to; empty disables TLS
+	ListenAddr string `yaml:"listen_addr"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	// CAFile holds the certificates client certificates are verified against
+	CAFile string `yaml:"ca_file"`
+	// ClientAuth is "none", "optional" or "required"
+	ClientAuth string `yaml:"client_auth"`
+	// MinVersion is the oldest TLS version accepted, "1.2" or "1.3"
+	MinVersion string `yaml:"min_version"`
+	// Ciphers restricts the TLS 1.2 cipher suites, by their Go names. TLS 1.3
+	// suites are not configurable.
+	Ciphers []string `yaml:"ciphers"`
+	// ReloadInterval is how often the certificate files are checked for
+	// changes; zero reloads them only on SIGHUP
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	// AuthClientsUser is "CN" to authenticate clients as the ACL user named
+	// by their certificate's common name, or "off"
+	AuthClientsUser string `yaml:"auth_clients_user"`
+}
+
+// LimitsConfig contains connection, pipeline and protocol limits
 type LimitsConfig struct {
-	MaxClients  int `yaml:"max_clients"`
-	MaxPipeline int `yaml:"max_pipeline"`
+	MaxClients   int `yaml:"max_clients"`
+	MaxPipeline  int `yaml:"max_pipeline"`
+	MaxKeysReply int `yaml:"max_keys_reply"`
+	// ProtoMaxBulkLen is the longest bulk string a request may carry
+	ProtoMaxBulkLen int `yaml:"proto_max_bulk_len"`
+	// MaxMultiBulkLen is the largest number of elements in a request
+	MaxMultiBulkLen int `yaml:"max_multibulk_len"`
+	// MaxInlineLen is the longest inline request or length header
+	MaxInlineLen int `yaml:"max_inline_len"`
+}
+
+// protoLimits returns the parser limits for client connections
+func (c LimitsConfig) protoLimits() proto.Limits {
+	return proto.Limits{
+		MaxBulkLen:      c.ProtoMaxBulkLen,
+		MaxMultiBulkLen: c.MaxMultiBulkLen,
+		MaxInlineLen:    c.MaxInlineLen,
+	}
 }
 
 // StorageConfig contains storage-related settings
 type StorageConfig struct {
+	Databases      int    `yaml:"databases"`
 	MaxMemoryBytes int64  `yaml:"maxmemory_bytes"`
 	EvictionPolicy string `yaml:"eviction_policy"`
 }
@@ -57,6 +171,7 @@ type StorageConfig struct {
 type TTLConfig struct {
 	Strategy    string        `yaml:"strategy"`
 	ActiveCycle time.Duration `yaml:"active_cycle_ms"`
+	Index       string        `yaml:"index"`
 }
 
 // PersistenceConfig contains persistence settings
@@ -85,34 +200,67 @@ type ReplicationConfig struct {
 	LeaderAddr string `yaml:"leader_addr"`
 }
 
+// PubSubConfig contains Pub/Sub and keyspace notification settings
+type PubSubConfig struct {
+	// NotifyKeyspaceEvents selects the published keyspace notifications
+	// using Redis' notify-keyspace-events flags; empty disables them
+	NotifyKeyspaceEvents string `yaml:"notify_keyspace_events"`
+	// SubscriberBuffer is the number of messages queued for a subscribed
+	// client before it is disconnected for falling behind
+	SubscriberBuffer int `yaml:"subscriber_buffer"`
+}
+
 // ObservabilityConfig contains observability settings
 type ObservabilityConfig struct {
-	LogLevel         string `yaml:"log_level"`
-	PrometheusListen string `yaml:"prometheus_listen"`
-	OTLPEndpoint     string `yaml:"otlp_endpoint"`
+	LogLevel         string        `yaml:"log_level"`
+	PrometheusListen string        `yaml:"prometheus_listen"`
+	OTLPEndpoint     string        `yaml:"otlp_endpoint"`
+	HotKeys          HotKeysConfig `yaml:"hotkeys"`
+}
+
+// HotKeysConfig contains hot key detection settings
+type HotKeysConfig struct {
+	SampleRate    float64 `yaml:"sample_rate"`
+	WindowSeconds int     `yaml:"window_seconds"`
+	MetricTopN    int     `yaml:"metric_top_n"`
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *AppConfig {
 	return &AppConfig{
 		Server: Config{
-			ListenAddr:   ":6380",
-			Shards:       defaultShardCount,
-			AuthPassword: "",
-			ReadTimeout:  defaultReadTimeoutSeconds * time.Second,
-			WriteTimeout: defaultWriteTimeoutSeconds * time.Second,
+			ListenAddr:    ":6380",
+			Shards:        defaultShardCount,
+			AuthPassword:  "",
+			ReadTimeout:   defaultReadTimeoutSeconds * time.Second,
+			WriteTimeout:  defaultWriteTimeoutSeconds * time.Second,
+			ExecutionMode: executionModeMutex,
+			ShardMailbox:  defaultShardMailbox,
+			TLS: TLSConfig{
+				ClientAuth:      tlsClientAuthNone,
+				MinVersion:      "1.2",
+				ReloadInterval:  defaultTLSReloadInterval,
+				AuthClientsUser: tlsAuthClientsUserOff,
+			},
 		},
 		Limits: LimitsConfig{
-			MaxClients:  defaultMaxClients,
-			MaxPipeline: defaultMaxPipeline,
+			MaxClients:   defaultMaxClients,
+			MaxPipeline:  defaultMaxPipeline,
+			MaxKeysReply: defaultMaxKeysReply,
+			// Same defaults as Redis
+			ProtoMaxBulkLen: proto.DefaultMaxBulkLen,
+			MaxMultiBulkLen: proto.DefaultMaxMultiBulkLen,
+			MaxInlineLen:    proto.DefaultMaxInlineLen,
 		},
 		Storage: StorageConfig{
+			Databases:      defaultDatabases,
 			MaxMemoryBytes: 0, // unlimited
 			EvictionPolicy: "noeviction",
 		},
 		TTL: TTLConfig{
-			Strategy:    "lazy+active",
+			Strategy:    ttlStrategyLazyActive,
 			ActiveCycle: defaultActiveCycleMs * time.Millisecond,
+			Index:       store.ExpiryIndexHeap,
 		},
 		Persistence: PersistenceConfig{
 			Snapshot: SnapshotConfig{
@@ -130,10 +278,19 @@ func DefaultConfig() *AppConfig {
 			Role:       "leader",
 			LeaderAddr: "",
 		},
+		PubSub: PubSubConfig{
+			NotifyKeyspaceEvents: "",
+			SubscriberBuffer:     defaultSubscriberBuffer,
+		},
 		Observability: ObservabilityConfig{
 			LogLevel:         "info",
 			PrometheusListen: ":9100",
 			OTLPEndpoint:     "",
+			HotKeys: HotKeysConfig{
+				SampleRate:    defaultHotKeysSampleRate,
+				WindowSeconds: defaultHotKeysWindowSecs,
+				MetricTopN:    defaultHotKeysMetricTopN,
+			},
 		},
 	}
 }
@@ -156,6 +313,10 @@ func LoadConfig(path string) (*AppConfig, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	if err := cfg.Server.resolveAuthPassword(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
@@ -163,12 +324,66 @@ func LoadConfig(path string) (*AppConfig, error) {
 	return cfg, nil
 }
 
+// resolveAuthPassword sets AuthPassword from AuthPasswordFile or
+// AuthPasswordEnv. At most one of the three may be given.
+func (c *Config) resolveAuthPassword() error {
+	sources := 0
+	for _, source := range []string{c.AuthPassword, c.AuthPasswordFile, c.AuthPasswordEnv} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of server.auth_password, server.auth_password_file and server.auth_password_env may be set")
+	}
+
+	switch {
+	case c.AuthPasswordFile != "":
+		data, err := os.ReadFile(c.AuthPasswordFile) // #nosec G304 -- Path comes from the operator's configuration
+		if err != nil {
+			return fmt.Errorf("failed to read server.auth_password_file: %w", err)
+		}
+		c.AuthPassword = strings.TrimRight(string(data), "\r\n")
+		if c.AuthPassword == "" {
+			return fmt.Errorf("server.auth_password_file %s is empty", c.AuthPasswordFile)
+		}
+	case c.AuthPasswordEnv != "":
+		c.AuthPassword = os.Getenv(c.AuthPasswordEnv)
+		if c.AuthPassword == "" {
+			return fmt.Errorf("environment variable %s named by server.auth_password_env is not set", c.AuthPasswordEnv)
+		}
+	}
+	return nil
+}
+
 // Validate validates the configuration
 func (c *AppConfig) Validate() error {
 	if c.Server.Shards <= 0 {
 		return errors.New("server.shards must be greater than 0")
 	}
 
+	if c.Server.ListenAddr == "" && len(c.Server.Listeners) == 0 && c.Server.TLS.ListenAddr == "" {
+		return errors.New("server.listen_addr, server.listeners or server.tls.listen_addr must be set")
+	}
+	for i := range c.Server.Listeners {
+		if err := c.Server.Listeners[i].validate(); err != nil {
+			return fmt.Errorf("server.listeners[%d]: %w", i, err)
+		}
+	}
+	if err := c.Server.TLS.validate(); err != nil {
+		return err
+	}
+
+	switch c.Server.ExecutionMode {
+	case executionModeMutex:
+	case executionModeShardLoop:
+		if c.Server.ShardMailbox <= 0 {
+			return errors.New("server.shard_mailbox must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("invalid execution mode: %s", c.Server.ExecutionMode)
+	}
+
 	if c.Limits.MaxClients <= 0 {
 		return errors.New("limits.max_clients must be greater than 0")
 	}
@@ -177,17 +392,72 @@ func (c *AppConfig) Validate() error {
 		return errors.New("limits.max_pipeline must be greater than 0")
 	}
 
+	if c.Limits.MaxKeysReply < 0 {
+		return errors.New("limits.max_keys_reply must not be negative")
+	}
+
+	if c.Limits.ProtoMaxBulkLen <= 0 {
+		return errors.New("limits.proto_max_bulk_len must be greater than 0")
+	}
+
+	if c.Limits.MaxMultiBulkLen <= 0 {
+		return errors.New("limits.max_multibulk_len must be greater than 0")
+	}
+
+	if c.Limits.MaxInlineLen <= 0 {
+		return errors.New("limits.max_inline_len must be greater than 0")
+	}
+
+	if c.Storage.Databases <= 0 {
+		return errors.New("storage.databases must be greater than 0")
+	}
+
 	validEvictionPolicies := map[string]bool{
-		"noeviction":   true,
-		"allkeys-lru":  true,
-		"volatile-lru": true,
-		"allkeys-lfu":  true,
-		"volatile-lfu": true,
+		"noeviction":      true,
+		"allkeys-lru":     true,
+		"volatile-lru":    true,
+		"allkeys-lfu":     true,
+		"volatile-lfu":    true,
+		"volatile-ttl":    true,
+		"allkeys-random":  true,
+		"volatile-random": true,
 	}
 	if !validEvictionPolicies[c.Storage.EvictionPolicy] {
 		return fmt.Errorf("invalid eviction policy: %s", c.Storage.EvictionPolicy)
 	}
 
+	switch c.TTL.Strategy {
+	case ttlStrategyLazy:
+	case ttlStrategyLazyActive:
+		if c.TTL.ActiveCycle <= 0 {
+			return errors.New("ttl.active_cycle_ms must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("invalid TTL strategy: %s", c.TTL.Strategy)
+	}
+
+	if c.TTL.Index != store.ExpiryIndexHeap && c.TTL.Index != store.ExpiryIndexWheel {
+		return fmt.Errorf("invalid TTL index: %s", c.TTL.Index)
+	}
+
+	if _, err := pubsub.ParseKeyspaceEvents(c.PubSub.NotifyKeyspaceEvents); err != nil {
+		return fmt.Errorf("invalid pubsub.notify_keyspace_events: %w", err)
+	}
+	if c.PubSub.SubscriberBuffer <= 0 {
+		return errors.New("pubsub.subscriber_buffer must be greater than 0")
+	}
+
+	hotKeys := c.Observability.HotKeys
+	if hotKeys.SampleRate < 0 || hotKeys.SampleRate > 1 {
+		return errors.New("observability.hotkeys.sample_rate must be between 0 and 1")
+	}
+	if hotKeys.SampleRate > 0 && hotKeys.WindowSeconds <= 0 {
+		return errors.New("observability.hotkeys.window_seconds must be greater than 0")
+	}
+	if hotKeys.MetricTopN < 0 {
+		return errors.New("observability.hotkeys.metric_top_n must not be negative")
+	}
+
 	validFsyncPolicies := map[string]bool{
 		"always":   true,
 		"everysec": true,
@@ -199,3 +469,74 @@ func (c *AppConfig) Validate() error {
 
 	return nil
 }
+
+// validate validates the listener settings
+func (c *ListenerConfig) validate() error {
+	switch c.Network {
+	case networkTCP, networkTCP6:
+		if c.SocketMode != "" {
+			return errors.New("socket_mode is only valid for unix listeners")
+		}
+	case networkUnix:
+		if _, err := c.socketMode(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid listener network: %s", c.Network)
+	}
+
+	if c.Addr == "" {
+		return errors.New("addr must be set")
+	}
+	return nil
+}
+
+// socketMode parses SocketMode, returning zero when it is empty
+func (c *ListenerConfig) socketMode() (os.FileMode, error) {
+	if c.SocketMode == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil || mode == 0 || mode > 0o777 {
+		return 0, fmt.Errorf("invalid socket mode: %s", c.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// validate validates the TLS settings when the TLS listener is enabled
+func (c *TLSConfig) validate() error {
+	if c.ListenAddr == "" {
+		return nil
+	}
+
+	if c.CertFile == "" || c.KeyFile == "" {
+		return errors.New("server.tls.cert_file and server.tls.key_file must be set")
+	}
+
+	switch c.ClientAuth {
+	case tlsClientAuthNone:
+	case tlsClientAuthOptional, tlsClientAuthRequired:
+		if c.CAFile == "" {
+			return fmt.Errorf("server.tls.ca_file must be set when server.tls.client_auth is %s", c.ClientAuth)
+		}
+	default:
+		return fmt.Errorf("invalid TLS client auth mode: %s", c.ClientAuth)
+	}
+
+	if _, err := parseTLSVersion(c.MinVersion); err != nil {
+		return err
+	}
+	if _, err := parseCipherSuites(c.Ciphers); err != nil {
+		return err
+	}
+
+	if c.ReloadInterval < 0 {
+		return errors.New("server.tls.reload_interval must not be negative")
+	}
+
+	if c.AuthClientsUser != tlsAuthClientsUserOff && c.AuthClientsUser != tlsAuthClientsUserCN {
+		return fmt.Errorf("invalid TLS auth clients user: %s", c.AuthClientsUser)
+	}
+
+	return nil
+}
@@ -39,6 +39,20 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Expected default write timeout 30s, got %v", config.Server.WriteTimeout)
 	}
 
+	if config.Server.ExecutionMode != "mutex" || config.Server.ShardMailbox != 1024 {
+		t.Errorf("Expected default execution mode mutex with a 1024 shard mailbox, got %q and %d",
+			config.Server.ExecutionMode, config.Server.ShardMailbox)
+	}
+
+	if config.Server.TLS.ListenAddr != "" {
+		t.Errorf("Expected TLS to be disabled by default, got %q", config.Server.TLS.ListenAddr)
+	}
+
+	if config.Server.TLS.ClientAuth != "none" || config.Server.TLS.MinVersion != "1.2" {
+		t.Errorf("Expected default TLS client auth none and min version 1.2, got %q and %q",
+			config.Server.TLS.ClientAuth, config.Server.TLS.MinVersion)
+	}
+
 	// Test limits defaults
 	if config.Limits.MaxClients != 10000 {
 		t.Errorf("Expected default max clients 10000, got %d", config.Limits.MaxClients)
@@ -48,7 +62,27 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Expected default max pipeline 1024, got %d", config.Limits.MaxPipeline)
 	}
 
+	if config.Limits.MaxKeysReply != 100000 {
+		t.Errorf("Expected default max keys reply 100000, got %d", config.Limits.MaxKeysReply)
+	}
+
+	if config.Limits.ProtoMaxBulkLen != 512*1024*1024 {
+		t.Errorf("Expected default proto max bulk len 512MB, got %d", config.Limits.ProtoMaxBulkLen)
+	}
+
+	if config.Limits.MaxMultiBulkLen != 1024*1024 {
+		t.Errorf("Expected default max multibulk len 1048576, got %d", config.Limits.MaxMultiBulkLen)
+	}
+
+	if config.Limits.MaxInlineLen != 64*1024 {
+		t.Errorf("Expected default max inline len 65536, got %d", config.Limits.MaxInlineLen)
+	}
+
 	// Test storage defaults
+	if config.Storage.Databases != 16 {
+		t.Errorf("Expected default databases 16, got %d", config.Storage.Databases)
+	}
+
 	if config.Storage.MaxMemoryBytes != 0 {
 		t.Errorf("Expected default max memory 0 (unlimited), got %d", config.Storage.MaxMemoryBytes)
 	}
@@ -66,6 +100,10 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Expected default active cycle 50ms, got %v", config.TTL.ActiveCycle)
 	}
 
+	if config.TTL.Index != "heap" {
+		t.Errorf("Expected default TTL index 'heap', got %q", config.TTL.Index)
+	}
+
 	// Test persistence defaults
 	if config.Persistence.Snapshot.Enabled {
 		t.Error("Expected snapshot to be disabled by default")
@@ -88,11 +126,19 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Expected default replication role 'leader', got %q", config.Replication.Role)
 	}
 
+	// Test Pub/Sub defaults
+	if config.PubSub.NotifyKeyspaceEvents != "" || config.PubSub.SubscriberBuffer != 1024 {
+		t.Errorf("Expected notifications disabled and a 1024 message buffer by default, got %+v", config.PubSub)
+	}
+
 	// Test observability defaults
 	if config.Observability.LogLevel != "info" {
 		t.Errorf("Expected default log level 'info', got %q", config.Observability.LogLevel)
 	}
 
+	if config.Observability.HotKeys.SampleRate != 0.01 || config.Observability.HotKeys.WindowSeconds != 60 {
+		t.Errorf("Expected hot keys sampled at 0.01 over 60s by default, got %+v", config.Observability.HotKeys)
+	}
 	if config.Observability.PrometheusListen != ":9100" {
 		t.Errorf("Expected default Prometheus listen ':9100', got %q", config.Observability.PrometheusListen)
 	}
@@ -135,6 +181,7 @@ server:
 limits:
   max_clients: 5000
   max_pipeline: 512
+  proto_max_bulk_len: 1048576
 
 storage:
   maxmemory_bytes: 1073741824
@@ -191,6 +238,14 @@ observability:
 		t.Errorf("Expected max clients 5000, got %d", config.Limits.MaxClients)
 	}
 
+	if config.Limits.ProtoMaxBulkLen != 1048576 {
+		t.Errorf("Expected proto max bulk len 1048576, got %d", config.Limits.ProtoMaxBulkLen)
+	}
+
+	if config.Limits.MaxInlineLen != 64*1024 {
+		t.Errorf("Expected unset max inline len to keep its default, got %d", config.Limits.MaxInlineLen)
+	}
+
 	if config.Storage.EvictionPolicy != "allkeys-lru" {
 		t.Errorf("Expected eviction policy 'allkeys-lru', got %q", config.Storage.EvictionPolicy)
 	}
@@ -263,6 +318,30 @@ func TestValidate(t *testing.T) {
 			wantErr:   true,
 			errString: "server.shards must be greater than 0",
 		},
+		{
+			name: "Shard loop execution mode",
+			modify: func(c *server.AppConfig) {
+				c.Server.ExecutionMode = "shard-loop"
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid execution mode",
+			modify: func(c *server.AppConfig) {
+				c.Server.ExecutionMode = "actor"
+			},
+			wantErr:   true,
+			errString: "invalid execution mode: actor",
+		},
+		{
+			name: "Shard loops without mailbox",
+			modify: func(c *server.AppConfig) {
+				c.Server.ExecutionMode = "shard-loop"
+				c.Server.ShardMailbox = 0
+			},
+			wantErr:   true,
+			errString: "server.shard_mailbox must be greater than 0",
+		},
 		{
 			name: "Zero max clients",
 			modify: func(c *server.AppConfig) {
@@ -287,6 +366,85 @@ func TestValidate(t *testing.T) {
 			wantErr:   true,
 			errString: "limits.max_pipeline must be greater than 0",
 		},
+		{
+			name: "Negative max keys reply",
+			modify: func(c *server.AppConfig) {
+				c.Limits.MaxKeysReply = -1
+			},
+			wantErr:   true,
+			errString: "limits.max_keys_reply must not be negative",
+		},
+		{
+			name: "Zero proto max bulk len",
+			modify: func(c *server.AppConfig) {
+				c.Limits.ProtoMaxBulkLen = 0
+			},
+			wantErr:   true,
+			errString: "limits.proto_max_bulk_len must be greater than 0",
+		},
+		{
+			name: "Zero max multibulk len",
+			modify: func(c *server.AppConfig) {
+				c.Limits.MaxMultiBulkLen = 0
+			},
+			wantErr:   true,
+			errString: "limits.max_multibulk_len must be greater than 0",
+		},
+		{
+			name: "Negative max inline len",
+			modify: func(c *server.AppConfig) {
+				c.Limits.MaxInlineLen = -1
+			},
+			wantErr:   true,
+			errString: "limits.max_inline_len must be greater than 0",
+		},
+		{
+			name: "Zero databases",
+			modify: func(c *server.AppConfig) {
+				c.Storage.Databases = 0
+			},
+			wantErr:   true,
+			errString: "storage.databases must be greater than 0",
+		},
+		{
+			name: "Invalid TTL strategy",
+			modify: func(c *server.AppConfig) {
+				c.TTL.Strategy = "eager"
+			},
+			wantErr:   true,
+			errString: "invalid TTL strategy",
+		},
+		{
+			name: "Invalid TTL index",
+			modify: func(c *server.AppConfig) {
+				c.TTL.Index = "tree"
+			},
+			wantErr:   true,
+			errString: "invalid TTL index",
+		},
+		{
+			name: "Timing wheel TTL index",
+			modify: func(c *server.AppConfig) {
+				c.TTL.Index = "wheel"
+			},
+			wantErr: false,
+		},
+		{
+			name: "Zero active cycle",
+			modify: func(c *server.AppConfig) {
+				c.TTL.ActiveCycle = 0
+			},
+			wantErr:   true,
+			errString: "ttl.active_cycle_ms must be greater than 0",
+		},
+		{
+			name: "Lazy TTL strategy without active cycle",
+			modify: func(c *server.AppConfig) {
+				c.TTL.Strategy = "lazy"
+				c.TTL.ActiveCycle = 0
+			},
+			wantErr: false,
+		},
 		{
 			name: "Invalid eviction policy",
 			modify: func(c *server.AppConfig) {
@@ -309,6 +467,82 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Valid volatile-ttl eviction policy",
+			modify: func(c *server.AppConfig) {
+				c.Storage.EvictionPolicy = "volatile-ttl"
+			},
+			wantErr: false,
+		},
+		{
+			name: "Valid allkeys-random eviction policy",
+			modify: func(c *server.AppConfig) {
+				c.Storage.EvictionPolicy = "allkeys-random"
+			},
+			wantErr: false,
+		},
+		{
+			name: "Valid volatile-random eviction policy",
+			modify: func(c *server.AppConfig) {
+				c.Storage.EvictionPolicy = "volatile-random"
+			},
+			wantErr: false,
+		},
+		{
+			name: "Hot key sample rate above 1",
+			modify: func(c *server.AppConfig) {
+				c.Observability.HotKeys.SampleRate = 1.5
+			},
+			wantErr:   true,
+			errString: "observability.hotkeys.sample_rate must be between 0 and 1",
+		},
+		{
+			name: "Hot key tracking without window",
+			modify: func(c *server.AppConfig) {
+				c.Observability.HotKeys.WindowSeconds = 0
+			},
+			wantErr:   true,
+			errString: "observability.hotkeys.window_seconds must be greater than 0",
+		},
+		{
+			name: "Hot key tracking disabled without window",
+			modify: func(c *server.AppConfig) {
+				c.Observability.HotKeys.SampleRate = 0
+				c.Observability.HotKeys.WindowSeconds = 0
+			},
+			wantErr: false,
+		},
+		{
+			name: "Negative hot key metric size",
+			modify: func(c *server.AppConfig) {
+				c.Observability.HotKeys.MetricTopN = -1
+			},
+			wantErr:   true,
+			errString: "observability.hotkeys.metric_top_n must not be negative",
+		},
+		{
+			name: "Valid keyspace events",
+			modify: func(c *server.AppConfig) {
+				c.PubSub.NotifyKeyspaceEvents = "KEA"
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid keyspace events",
+			modify: func(c *server.AppConfig) {
+				c.PubSub.NotifyKeyspaceEvents = "Kq"
+			},
+			wantErr:   true,
+			errString: "invalid pubsub.notify_keyspace_events",
+		},
+		{
+			name: "Non-positive subscriber buffer",
+			modify: func(c *server.AppConfig) {
+				c.PubSub.SubscriberBuffer = 0
+			},
+			wantErr:   true,
+			errString: "pubsub.subscriber_buffer must be greater than 0",
+		},
 		{
 			name: "Invalid AOF fsync policy",
 			modify: func(c *server.AppConfig) {
@@ -331,6 +565,140 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "No listen address",
+			modify: func(c *server.AppConfig) {
+				c.Server.ListenAddr = ""
+			},
+			wantErr:   true,
+			errString: "server.listen_addr, server.listeners or server.tls.listen_addr must be set",
+		},
+		{
+			name: "Valid listeners only",
+			modify: func(c *server.AppConfig) {
+				c.Server.ListenAddr = ""
+				c.Server.Listeners = []server.ListenerConfig{
+					{Network: "tcp6", Addr: "[::1]:6380"},
+					{Network: "unix", Addr: "/run/kvstash.sock", SocketMode: "0660", NoAuth: true},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid listener network",
+			modify: func(c *server.AppConfig) {
+				c.Server.Listeners = []server.ListenerConfig{{Network: "udp", Addr: ":6380"}}
+			},
+			wantErr:   true,
+			errString: "server.listeners[0]: invalid listener network: udp",
+		},
+		{
+			name: "Listener without address",
+			modify: func(c *server.AppConfig) {
+				c.Server.Listeners = []server.ListenerConfig{{Network: "unix"}}
+			},
+			wantErr:   true,
+			errString: "server.listeners[0]: addr must be set",
+		},
+		{
+			name: "Invalid socket mode",
+			modify: func(c *server.AppConfig) {
+				c.Server.Listeners = []server.ListenerConfig{{Network: "unix", Addr: "kv.sock", SocketMode: "0999"}}
+			},
+			wantErr:   true,
+			errString: "server.listeners[0]: invalid socket mode: 0999",
+		},
+		{
+			name: "Socket mode on TCP listener",
+			modify: func(c *server.AppConfig) {
+				c.Server.Listeners = []server.ListenerConfig{{Network: "tcp", Addr: ":6381", SocketMode: "0660"}}
+			},
+			wantErr:   true,
+			errString: "server.listeners[0]: socket_mode is only valid for unix listeners",
+		},
+		{
+			name: "Valid TLS only",
+			modify: func(c *server.AppConfig) {
+				c.Server.ListenAddr = ""
+				c.Server.TLS = validTLSConfig()
+			},
+			wantErr: false,
+		},
+		{
+			name: "Valid mutual TLS",
+			modify: func(c *server.AppConfig) {
+				c.Server.TLS = validTLSConfig()
+				c.Server.TLS.CAFile = "ca.pem"
+				c.Server.TLS.ClientAuth = "required"
+				c.Server.TLS.MinVersion = "1.3"
+				c.Server.TLS.Ciphers = []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}
+				c.Server.TLS.AuthClientsUser = "CN"
+			},
+			wantErr: false,
+		},
+		{
+			name: "TLS without certificate",
+			modify: func(c *server.AppConfig) {
+				c.Server.TLS = validTLSConfig()
+				c.Server.TLS.CertFile = ""
+			},
+			wantErr:   true,
+			errString: "server.tls.cert_file and server.tls.key_file must be set",
+		},
+		{
+			name: "Invalid TLS client auth",
+			modify: func(c *server.AppConfig) {
+				c.Server.TLS = validTLSConfig()
+				c.Server.TLS.ClientAuth = "sometimes"
+			},
+			wantErr:   true,
+			errString: "invalid TLS client auth mode: sometimes",
+		},
+		{
+			name: "TLS client auth without CA",
+			modify: func(c *server.AppConfig) {
+				c.Server.TLS = validTLSConfig()
+				c.Server.TLS.ClientAuth = "optional"
+			},
+			wantErr:   true,
+			errString: "server.tls.ca_file must be set when server.tls.client_auth is optional",
+		},
+		{
+			name: "Invalid TLS min version",
+			modify: func(c *server.AppConfig) {
+				c.Server.TLS = validTLSConfig()
+				c.Server.TLS.MinVersion = "1.0"
+			},
+			wantErr:   true,
+			errString: "invalid TLS min version: 1.0",
+		},
+		{
+			name: "Insecure TLS cipher",
+			modify: func(c *server.AppConfig) {
+				c.Server.TLS = validTLSConfig()
+				c.Server.TLS.Ciphers = []string{"TLS_RSA_WITH_RC4_128_SHA"}
+			},
+			wantErr:   true,
+			errString: "invalid TLS cipher suite: TLS_RSA_WITH_RC4_128_SHA",
+		},
+		{
+			name: "Negative TLS reload interval",
+			modify: func(c *server.AppConfig) {
+				c.Server.TLS = validTLSConfig()
+				c.Server.TLS.ReloadInterval = -time.Second
+			},
+			wantErr:   true,
+			errString: "server.tls.reload_interval must not be negative",
+		},
+		{
+			name: "Invalid TLS auth clients user",
+			modify: func(c *server.AppConfig) {
+				c.Server.TLS = validTLSConfig()
+				c.Server.TLS.AuthClientsUser = "OU"
+			},
+			wantErr:   true,
+			errString: "invalid TLS auth clients user: OU",
+		},
 	}
 
 	for _, tt := range tests {
@@ -357,6 +725,16 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+// validTLSConfig returns TLS settings that pass validation; the files are
+// not read
+func validTLSConfig() server.TLSConfig {
+	config := server.DefaultConfig().Server.TLS
+	config.ListenAddr = ":6381"
+	config.CertFile = "server.pem"
+	config.KeyFile = "server-key.pem"
+	return config
+}
+
 func TestLoadConfig_InvalidConfig(t *testing.T) {
 	t.Parallel()
 
@@ -387,6 +765,101 @@ limits:
 	}
 }
 
+func TestLoadConfig_AuthPasswordFile(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	passwordFile := filepath.Join(tmpDir, "password")
+	if err := os.WriteFile(passwordFile, []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+		errorMsg string
+	}{
+		{
+			name:     "password from file",
+			content:  "server:\n  auth_password_file: " + passwordFile + "\n",
+			expected: "file-secret",
+		},
+		{
+			name:     "missing file",
+			content:  "server:\n  auth_password_file: " + filepath.Join(tmpDir, "missing") + "\n",
+			errorMsg: "failed to read server.auth_password_file",
+		},
+		{
+			name:     "password and file",
+			content:  "server:\n  auth_password: secret\n  auth_password_file: " + passwordFile + "\n",
+			errorMsg: "only one of server.auth_password, server.auth_password_file and server.auth_password_env may be set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			configFile := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(configFile, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			config, err := server.LoadConfig(configFile)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if config.Server.AuthPassword != tt.expected {
+				t.Errorf("Expected auth password %q, got %q", tt.expected, config.Server.AuthPassword)
+			}
+		})
+	}
+}
+
+// TestLoadConfig_AuthPasswordEnv is not parallel as it sets the environment
+func TestLoadConfig_AuthPasswordEnv(t *testing.T) {
+	t.Setenv("KVSTASH_TEST_PASSWORD", "env-secret")
+
+	tests := []struct {
+		name     string
+		variable string
+		expected string
+		errorMsg string
+	}{
+		{name: "password from environment", variable: "KVSTASH_TEST_PASSWORD", expected: "env-secret"},
+		{name: "unset variable", variable: "KVSTASH_TEST_UNSET_PASSWORD", errorMsg: "environment variable KVSTASH_TEST_UNSET_PASSWORD named by server.auth_password_env is not set"},
+	}
+
+	for _, tt := range tests {
+		configFile := filepath.Join(t.TempDir(), "config.yml")
+		content := "server:\n  auth_password_env: " + tt.variable + "\n"
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		config, err := server.LoadConfig(configFile)
+		if tt.errorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.errorMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: LoadConfig failed: %v", tt.name, err)
+		}
+		if config.Server.AuthPassword != tt.expected {
+			t.Errorf("%s: expected auth password %q, got %q", tt.name, tt.expected, config.Server.AuthPassword)
+		}
+	}
+}
+
 func TestLoadConfig_FileReadError(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+// errShuttingDown is the reply to commands dispatched once the shard loops
+// have stopped
+var errShuttingDown = proto.NewError("ERR server is shutting down")
+
+// connectionCommands never touch the keyspace, so in the shard-loop
+// execution mode they run on the connection's goroutine
+var connectionCommands = map[string]bool{
+	"PING":         true,
+	"ECHO":         true,
+	"HELLO":        true,
+	"AUTH":         true,
+	"QUIT":         true,
+	"ACL":          true,
+	"CLIENT":       true,
+	"HOTKEYS":      true,
+	"SELECT":       true,
+	"SUBSCRIBE":    true,
+	"PSUBSCRIBE":   true,
+	"UNSUBSCRIBE":  true,
+	"PUNSUBSCRIBE": true,
+	"PUBLISH":      true,
+	"PUBSUB":       true,
+}
+
+// fanOutCommands are the multi-key commands whose keys are independent, as
+// they run one key at a time in the mutex mode too. When their keys span
+// shards, each shard's share runs on its own loop and the replies are merged.
+var fanOutCommands = map[string]bool{
+	"DEL":    true,
+	"UNLINK": true,
+	"EXISTS": true,
+	"TOUCH":  true,
+	"MGET":   true,
+	"MSET":   true,
+}
+
+// dispatch runs cmd as the execution mode wants. In the mutex mode it runs on
+// the connection's goroutine. In the shard-loop mode a command whose keys
+// share a shard runs on the shard's loop; one whose keys span shards is
+// fanned out to their loops or, when it must be atomic like RENAME or MSETNX,
+// runs once their loops have handed the shards over. Keyless commands reading
+// or writing the keyspace have every shard handed over.
+func (h *Handler) dispatch(cmd *proto.Request) *proto.Response {
+	if !h.store.ShardLoops() || connectionCommands[cmd.Name] {
+		return h.execute(cmd)
+	}
+	info := commandTable[cmd.Name]
+	if info == nil {
+		return h.execute(cmd)
+	}
+
+	keys := info.keys(cmd.Args)
+	if len(keys) == 0 {
+		// Also taken by malformed keyed commands, which the handler rejects
+		return h.executeExclusive(nil, cmd)
+	}
+
+	shard := h.store.ShardIndex(string(keys[0]))
+	for _, key := range keys[1:] {
+		if h.store.ShardIndex(string(key)) == shard {
+			continue
+		}
+		if fanOutCommands[cmd.Name] {
+			return h.fanOut(cmd, keys)
+		}
+		shards := make([]int, len(keys))
+		for i, key := range keys {
+			shards[i] = h.store.ShardIndex(string(key))
+		}
+		return h.executeExclusive(shards, cmd)
+	}
+
+	var resp *proto.Response
+	if err := h.store.Run(shard, func() { resp = h.execute(cmd) }); err != nil {
+		return errShuttingDown
+	}
+	return resp
+}
+
+// executeExclusive runs cmd once the loops of shards, or of every shard when
+// nil, have handed them over
+func (h *Handler) executeExclusive(shards []int, cmd *proto.Request) *proto.Response {
+	var resp *proto.Response
+	if err := h.store.Exclusive(shards, func() { resp = h.execute(cmd) }); err != nil {
+		return errShuttingDown
+	}
+	return resp
+}
+
+// fanOut splits a multi-key command into one command per shard, runs them on
+// their loops concurrently and merges the replies in key order
+func (h *Handler) fanOut(cmd *proto.Request, keys [][]byte) *proto.Response {
+	// Arguments per key: MSET carries a value with each key
+	perKey := len(cmd.Args) / len(keys)
+
+	partOf := make(map[int]int)
+	var shards []int
+	var parts []*proto.Request
+	var positions [][]int
+	for i, key := range keys {
+		shard := h.store.ShardIndex(string(key))
+		part, ok := partOf[shard]
+		if !ok {
+			part = len(parts)
+			partOf[shard] = part
+			shards = append(shards, shard)
+			parts = append(parts, &proto.Request{Name: cmd.Name})
+			positions = append(positions, nil)
+		}
+		parts[part].Args = append(parts[part].Args, cmd.Args[i*perKey:(i+1)*perKey]...)
+		positions[part] = append(positions[part], i)
+	}
+
+	replies := make([]*proto.Response, len(parts))
+	fns := make([]func(), len(parts))
+	for i, part := range parts {
+		fns[i] = func() { replies[i] = h.execute(part) }
+	}
+	if err := h.store.RunEach(shards, fns); err != nil {
+		return errShuttingDown
+	}
+
+	return mergeReplies(cmd.Name, len(keys), replies, positions)
+}
+
+// mergeReplies combines the per-shard replies of a fanned-out command: counts
+// are summed, MGET values are put back in key order and MSET succeeds once
+// every part has. The first error wins.
+func mergeReplies(name string, keys int, replies []*proto.Response, positions [][]int) *proto.Response {
+	for _, reply := range replies {
+		if reply.Type == proto.Error {
+			return reply
+		}
+	}
+
+	switch name {
+	case "MGET":
+		values := make([]any, keys)
+		for part, reply := range replies {
+			for i, value := range reply.Data.([]any) {
+				values[positions[part][i]] = value
+			}
+		}
+		return proto.NewArray(values)
+	case "MSET":
+		return proto.NewSimpleString("OK")
+	default:
+		var total int64
+		for _, reply := range replies {
+			total += reply.Data.(int64)
+		}
+		return proto.NewInteger(total)
+	}
+}
@@ -0,0 +1,243 @@
+package server_test
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func createShardLoopHandler(t *testing.T) *server.Handler {
+	t.Helper()
+
+	logger := obs.NewLogger(false)
+	s, err := store.New(&store.Config{
+		Shards:         4,
+		EvictionPolicy: "noeviction",
+		ShardLoops:     true,
+		ShardMailbox:   4,
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	config := server.DefaultConfig()
+	config.Server.ExecutionMode = "shard-loop"
+	return server.NewHandler(s, config, logger)
+}
+
+func TestHandler_ShardLoop(t *testing.T) {
+	t.Parallel()
+
+	handler := createShardLoopHandler(t)
+
+	// Ten keys span the four shards, so the multi-key commands are fanned
+	// out or run with several shards handed over
+	keys := make([]string, 10)
+	msetArgs := make([]string, 0, 2*len(keys))
+	mgetExpected := make([]any, 0, len(keys)+1)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		msetArgs = append(msetArgs, keys[i], fmt.Sprintf("v%d", i))
+		mgetExpected = append(mgetExpected, fmt.Sprintf("v%d", i))
+	}
+	mgetExpected = append(mgetExpected, nil)
+
+	tests := []struct {
+		name     string
+		command  []string
+		expected any
+	}{
+		{name: "Single key write", command: []string{"SET", "a", "1"}, expected: "OK"},
+		{name: "Single key read", command: []string{"GET", "a"}, expected: "1"},
+		{name: "Single key update", command: []string{"INCRBY", "a", "41"}, expected: int64(42)},
+		{name: "Fanned out MSET", command: append([]string{"MSET"}, msetArgs...), expected: "OK"},
+		{name: "Fanned out MGET keeps key order", command: append(append([]string{"MGET"}, keys...), "missing"), expected: mgetExpected},
+		{name: "Fanned out EXISTS", command: append(append([]string{"EXISTS"}, keys...), "missing", "a"), expected: int64(11)},
+		{name: "Cross-shard RENAME", command: []string{"RENAME", "key0", "renamed"}, expected: "OK"},
+		{name: "Renamed key is readable", command: []string{"GET", "renamed"}, expected: "v0"},
+		{name: "Cross-shard MSETNX", command: []string{"MSETNX", "key1", "x", "fresh", "y"}, expected: int64(0)},
+		{name: "MSETNX left every key alone", command: []string{"EXISTS", "fresh"}, expected: int64(0)},
+		{name: "Fanned out DEL", command: append([]string{"DEL", "renamed", "missing"}, keys[1:]...), expected: int64(10)},
+		{name: "Keyless read", command: []string{"DBSIZE"}, expected: int64(1)},
+		{name: "Keyless scan", command: []string{"KEYS", "*"}, expected: []any{"a"}},
+		{name: "Keyless write", command: []string{"FLUSHDB"}, expected: "OK"},
+		{name: "Flushed", command: []string{"DBSIZE"}, expected: int64(0)},
+		{name: "Connection command", command: []string{"ECHO", "hi"}, expected: "hi"},
+		{name: "Malformed MSET", command: []string{"MSET", "a"}, expected: "ERR wrong number of arguments for 'mset' command"},
+		{name: "Malformed GET", command: []string{"GET"}, expected: "ERR wrong number of arguments for 'get' command"},
+		{name: "Unknown command", command: []string{"NOSUCH", "a"}, expected: "ERR unknown command 'NOSUCH'"},
+	}
+
+	// The steps share one keyspace, so they run in order
+	for _, tt := range tests {
+		resp := handler.HandleCommand(request(tt.command[0], tt.command[1:]...))
+		if fmt.Sprint(resp.Data) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, resp.Data)
+		}
+	}
+}
+
+func TestHandler_ShardLoop_Closed(t *testing.T) {
+	t.Parallel()
+
+	logger := obs.NewLogger(false)
+	s, err := store.New(&store.Config{Shards: 4, ShardLoops: true, ShardMailbox: 4}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handler := server.NewHandler(s, server.DefaultConfig(), logger)
+	s.Close()
+
+	for _, command := range [][]string{{"GET", "a"}, {"DBSIZE"}, {"DEL", "key0", "key1", "key2"}} {
+		resp := handler.HandleCommand(request(command[0], command[1:]...))
+		if resp.Type != proto.Error || resp.Data != "ERR server is shutting down" {
+			t.Errorf("%v: expected a shutdown error, got %v: %v", command, resp.Type, resp.Data)
+		}
+	}
+}
+
+func startShardLoopServer(t *testing.T) string {
+	t.Helper()
+
+	config := server.DefaultConfig()
+	config.Server.ExecutionMode = "shard-loop"
+	config.Server.ShardMailbox = 4
+	return startServer(t, config)
+}
+
+func TestServer_ShardLoop_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	addr := startShardLoopServer(t)
+	const clients, increments = 8, 200
+
+	var wg sync.WaitGroup
+	for range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Errorf("Failed to connect: %v", err)
+				return
+			}
+			defer func() { _ = conn.Close() }()
+
+			// A deep pipeline fills the small mailboxes, so dispatch blocks,
+			// and the cross-shard commands interleave with the loops
+			var pipeline []byte
+			for range increments {
+				pipeline = append(pipeline, encodeCommand("INCR", "counter")...)
+				pipeline = append(pipeline, encodeCommand("MSET", "x", "1", "y", "2", "z", "3")...)
+				pipeline = append(pipeline, encodeCommand("RENAME", "x", "w")...)
+			}
+			go func() { _, _ = conn.Write(pipeline) }()
+
+			_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			buffer := make([]byte, 4096)
+			var received strings.Builder
+			for strings.Count(received.String(), "\r\n") < 3*increments {
+				n, err := conn.Read(buffer)
+				if err != nil {
+					t.Errorf("Failed to read: %v", err)
+					return
+				}
+				received.Write(buffer[:n])
+			}
+		}()
+	}
+	wg.Wait()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	expected := fmt.Sprintf(":%d\r\n", clients*increments)
+	if got := roundTrip(t, conn, expected, "INCRBY", "counter", "0"); got != expected {
+		t.Errorf("Expected every increment to be applied once, got %q", got)
+	}
+}
+
+// BenchmarkServer_ExecutionMode compares the latency of the mutex and
+// shard-loop execution modes under a mixed load of reads, writes and
+// multi-key commands from many concurrent clients
+func BenchmarkServer_ExecutionMode(b *testing.B) {
+	const keyspace = 1000
+
+	for _, mode := range []string{"mutex", "shard-loop"} {
+		b.Run(mode, func(b *testing.B) {
+			config := server.DefaultConfig()
+			config.Server.ExecutionMode = mode
+			addr := startServer(b, config)
+
+			var mu sync.Mutex
+			var latencies []time.Duration
+
+			b.SetParallelism(8)
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				conn, err := net.Dial("tcp", addr)
+				if err != nil {
+					b.Errorf("Failed to connect: %v", err)
+					return
+				}
+				defer func() { _ = conn.Close() }()
+
+				buffer := make([]byte, 4096)
+				var local []time.Duration
+				for i := 0; pb.Next(); i++ {
+					key := fmt.Sprintf("key%d", i%keyspace)
+					var command []byte
+					switch i % 20 {
+					case 0, 1, 2:
+						command = encodeCommand("SET", key, "value")
+					case 3:
+						command = encodeCommand("INCR", "counter")
+					case 4:
+						command = encodeCommand("MGET", key, "key1", "key2", "key3")
+					case 5:
+						command = encodeCommand("MSET", key, "value", "key1", "value")
+					default:
+						command = encodeCommand("GET", key)
+					}
+
+					start := time.Now()
+					if _, err := conn.Write(command); err != nil {
+						b.Errorf("Failed to write: %v", err)
+						return
+					}
+					// Every reply here fits in one read
+					if _, err := conn.Read(buffer); err != nil {
+						b.Errorf("Failed to read: %v", err)
+						return
+					}
+					local = append(local, time.Since(start))
+				}
+
+				mu.Lock()
+				latencies = append(latencies, local...)
+				mu.Unlock()
+			})
+
+			if len(latencies) == 0 {
+				return
+			}
+			slices.Sort(latencies)
+			b.ReportMetric(float64(latencies[len(latencies)/2].Nanoseconds()), "p50-ns")
+			b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+		})
+	}
+}
@@ -1,12 +1,18 @@
 package server
 
 import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Abhishek2095/kv-stash/internal/acl"
 	"github.com/Abhishek2095/kv-stash/internal/obs"
 	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/pubsub"
 	"github.com/Abhishek2095/kv-stash/internal/store"
 )
 
@@ -19,28 +25,216 @@ const (
 // Handler handles RESP commands
 type Handler struct {
 	store  *store.Store
-	config *Config
+	config *AppConfig
+	stats  *Stats
+	broker *pubsub.Broker
+	acl    *acl.ACL
 	logger *obs.Logger
+
+	// id identifies the connection, as reported by HELLO
+	id int64
+	// protocol is the RESP version negotiated with HELLO
+	protocol int
+	// name is the client name set with HELLO SETNAME
+	name string
+	// user is the name of the ACL user the client is authenticated as, set
+	// from the start when the default user needs no password. It is empty
+	// until the client authenticates.
+	user string
+
+	// subscriber holds the connection's subscriptions; nil until the first
+	// SUBSCRIBE or PSUBSCRIBE
+	subscriber *pubsub.Subscriber
+
+	// clients holds every connection's session, session this one's
+	clients *clientRegistry
+	session *clientSession
+	// replyMode is set by CLIENT REPLY. skipNext mutes the reply to the
+	// next command, and muted the reply to the current one.
+	replyMode string
+	skipNext  bool
+	muted     bool
+	// closing is set when the client killed itself, to disconnect it once
+	// the reply is sent
+	closing bool
+	// noEvict is set by CLIENT NO-EVICT
+	noEvict bool
+}
+
+// NewHandler creates a new command handler with its own server statistics,
+// Pub/Sub broker, ACL users and client registry
+func NewHandler(store *store.Store, config *AppConfig, logger *obs.Logger) *Handler {
+	return newHandler(store, config, NewStats(), pubsub.NewBroker(), newACL(config), newClientRegistry(), nil, logger)
+}
+
+// newHandler creates a command handler for conn reporting to the server's
+// shared statistics, publishing through its broker, authenticating its users
+// and registering with its clients
+func newHandler(store *store.Store, config *AppConfig, stats *Stats, broker *pubsub.Broker, users *acl.ACL,
+	clients *clientRegistry, conn net.Conn, logger *obs.Logger,
+) *Handler {
+	id := stats.nextClientID()
+	h := &Handler{
+		store:     store,
+		config:    config,
+		stats:     stats,
+		broker:    broker,
+		acl:       users,
+		logger:    logger,
+		id:        id,
+		protocol:  proto.RESP2,
+		clients:   clients,
+		session:   clients.register(id, conn),
+		replyMode: replyOn,
+	}
+	if user := users.User(acl.DefaultUser); user.Enabled() && user.NoPass() {
+		h.user = acl.DefaultUser
+	}
+	h.publishSession()
+	return h
+}
+
+// Stats returns the statistics the handler reports to
+func (h *Handler) Stats() *Stats {
+	return h.stats
+}
+
+// Protocol returns the RESP version replies to this client must be written in
+func (h *Handler) Protocol() int {
+	return h.protocol
+}
+
+// Name returns the client name set with HELLO SETNAME
+func (h *Handler) Name() string {
+	return h.name
+}
+
+// HandleCommand processes a single command. The client's session shows the
+// command while it runs and the connection's state once it is done.
+func (h *Handler) HandleCommand(cmd *proto.Request) *proto.Response {
+	h.session.begin(cmd.Name)
+	h.muted = h.replyMode == replyOff || h.skipNext
+	h.skipNext = false
+
+	resp := h.handleCommand(cmd)
+	h.publishSession()
+	return resp
+}
+
+// ReplyMuted reports whether the reply to the last command must not be sent,
+// as asked with CLIENT REPLY
+func (h *Handler) ReplyMuted() bool {
+	return h.muted
+}
+
+// Closing reports whether the connection must be closed once the reply to
+// the last command is sent
+func (h *Handler) Closing() bool {
+	return h.closing
+}
+
+// handleCommand checks and runs a command
+func (h *Handler) handleCommand(cmd *proto.Request) *proto.Response {
+	// Checked first as the attributes would be allocated on every command
+	if h.logger.Enabled(context.Background(), slog.LevelDebug) {
+		h.logger.Debug("Handling command", "name", cmd.Name, "args", len(cmd.Args))
+	}
+
+	if !noAuthCommands[cmd.Name] {
+		user := h.currentUser()
+		if user == nil {
+			return h.reject(cmd, proto.NewError("NOAUTH Authentication required."))
+		}
+		if resp := h.checkPermissions(user, cmd); resp != nil {
+			return h.reject(cmd, resp)
+		}
+	}
+
+	// RESP3 clients can tell pushed messages from replies, so only RESP2
+	// clients are restricted while subscribed
+	if h.protocol == proto.RESP2 && h.subscribed() && !subscribedCommands[cmd.Name] {
+		return h.reject(cmd, proto.NewError("ERR Can't execute '"+strings.ToLower(cmd.Name)+
+			"': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context"))
+	}
+
+	// CLIENT is exempt so a paused server can be unpaused
+	if cmd.Name != "CLIENT" {
+		info := commandTable[cmd.Name]
+		h.clients.waitPause(info != nil && info.categories&acl.Write != 0)
+	}
+
+	if denyOOMCommands[cmd.Name] {
+		if err := h.store.CheckMemory(commandSize(cmd)); err != nil {
+			return h.reject(cmd, proto.NewError("OOM "+err.Error()))
+		}
+	}
+
+	start := time.Now()
+	resp := h.dispatch(cmd)
+	if resp == nil {
+		// Unknown commands are kept out of the command statistics
+		return proto.NewError("ERR unknown command '" + cmd.Name + "'")
+	}
+
+	h.stats.recordCommand(cmd.Name, time.Since(start), resp.Type != proto.Error)
+	return resp
+}
+
+// denyOOMCommands are the commands that can grow memory usage. Before they run
+// the store evicts keys to make room, and they are rejected when it cannot;
+// reads and deletes always run.
+var denyOOMCommands = map[string]bool{
+	"SET":         true,
+	"SETNX":       true,
+	"SETEX":       true,
+	"PSETEX":      true,
+	"MSET":        true,
+	"MSETNX":      true,
+	"GETSET":      true,
+	"APPEND":      true,
+	"SETRANGE":    true,
+	"INCR":        true,
+	"DECR":        true,
+	"INCRBY":      true,
+	"DECRBY":      true,
+	"INCRBYFLOAT": true,
+	"COPY":        true,
 }
 
-// NewHandler creates a new command handler
-func NewHandler(store *store.Store, config *Config, logger *obs.Logger) *Handler {
-	return &Handler{
-		store:  store,
-		config: config,
-		logger: logger,
+// commandSize approximates the memory a write may add as the size of its
+// arguments plus the overhead of a new key
+func commandSize(cmd *proto.Request) int64 {
+	size := store.EntryOverhead
+	for _, arg := range cmd.Args {
+		size += int64(len(arg))
 	}
+	return size
 }
 
-// HandleCommand processes a single command
-func (h *Handler) HandleCommand(cmd *proto.Command) *proto.Response {
-	h.logger.Debug("Handling command", "name", cmd.Name, "args", len(cmd.Args))
+// reject records that cmd was refused before it ran and returns resp, the
+// refusal. Unknown commands are kept out of the command statistics.
+func (h *Handler) reject(cmd *proto.Request, resp *proto.Response) *proto.Response {
+	if commandTable[cmd.Name] != nil {
+		h.stats.recordRejected(cmd.Name)
+	}
+	return resp
+}
 
+// execute runs the handler for cmd, returning nil for unknown commands
+func (h *Handler) execute(cmd *proto.Request) *proto.Response {
 	switch cmd.Name {
 	case "PING":
 		return h.handlePing(cmd.Args)
 	case "ECHO":
 		return h.handleEcho(cmd.Args)
+	case "HELLO":
+		return h.handleHello(cmd.Args)
+	case "AUTH":
+		return h.handleAuth(cmd.Args)
+	case "ACL":
+		return h.handleACL(cmd.Args)
+	case "CLIENT":
+		return h.handleClient(cmd.Args)
 	case "INFO":
 		return h.handleInfo(cmd.Args)
 	case "GET":
@@ -52,9 +246,23 @@ func (h *Handler) HandleCommand(cmd *proto.Command) *proto.Response {
 	case "EXISTS":
 		return h.handleExists(cmd.Args)
 	case "EXPIRE":
-		return h.handleExpire(cmd.Args)
+		return h.handleExpire(cmd.Args, time.Second, false, "expire")
+	case "PEXPIRE":
+		return h.handleExpire(cmd.Args, time.Millisecond, false, "pexpire")
+	case "EXPIREAT":
+		return h.handleExpire(cmd.Args, time.Second, true, "expireat")
+	case "PEXPIREAT":
+		return h.handleExpire(cmd.Args, time.Millisecond, true, "pexpireat")
 	case "TTL":
 		return h.handleTTL(cmd.Args)
+	case "PTTL":
+		return h.handlePTTL(cmd.Args)
+	case "EXPIRETIME":
+		return h.handleExpireTime(cmd.Args, time.Second, "expiretime")
+	case "PEXPIRETIME":
+		return h.handleExpireTime(cmd.Args, time.Millisecond, "pexpiretime")
+	case "PERSIST":
+		return h.handlePersist(cmd.Args)
 	case "DBSIZE":
 		return h.handleDBSize(cmd.Args)
 	case "MGET":
@@ -69,59 +277,118 @@ func (h *Handler) HandleCommand(cmd *proto.Command) *proto.Response {
 		return h.handleIncrBy(cmd.Args)
 	case "DECRBY":
 		return h.handleDecrBy(cmd.Args)
+	case "APPEND":
+		return h.handleAppend(cmd.Args)
+	case "STRLEN":
+		return h.handleStrLen(cmd.Args)
+	case "GETRANGE":
+		return h.handleGetRange(cmd.Args)
+	case "SETRANGE":
+		return h.handleSetRange(cmd.Args)
+	case "GETDEL":
+		return h.handleGetDel(cmd.Args)
+	case "GETEX":
+		return h.handleGetEx(cmd.Args)
+	case "GETSET":
+		return h.handleGetSet(cmd.Args)
+	case "SETNX":
+		return h.handleSetNX(cmd.Args)
+	case "SETEX":
+		return h.handleSetEx(cmd.Args, time.Second, "setex")
+	case "PSETEX":
+		return h.handleSetEx(cmd.Args, time.Millisecond, "psetex")
+	case "MSETNX":
+		return h.handleMSetNX(cmd.Args)
+	case "INCRBYFLOAT":
+		return h.handleIncrByFloat(cmd.Args)
+	case "SCAN":
+		return h.handleScan(cmd.Args)
+	case "KEYS":
+		return h.handleKeys(cmd.Args)
+	case "RANDOMKEY":
+		return h.handleRandomKey(cmd.Args)
+	case "RENAME":
+		return h.handleRename(cmd.Args)
+	case "RENAMENX":
+		return h.handleRenameNX(cmd.Args)
+	case "COPY":
+		return h.handleCopy(cmd.Args)
+	case "TOUCH":
+		return h.handleTouch(cmd.Args)
+	case "UNLINK":
+		return h.handleUnlink(cmd.Args)
+	case "TYPE":
+		return h.handleType(cmd.Args)
+	case "OBJECT":
+		return h.handleObject(cmd.Args)
+	case "MEMORY":
+		return h.handleMemory(cmd.Args)
+	case "HOTKEYS":
+		return h.handleHotKeys(cmd.Args)
+	case "SELECT":
+		return h.handleSelect(cmd.Args)
+	case "MOVE":
+		return h.handleMove(cmd.Args)
+	case "SWAPDB":
+		return h.handleSwapDB(cmd.Args)
+	case "FLUSHDB":
+		return h.handleFlushDB(cmd.Args)
+	case "FLUSHALL":
+		return h.handleFlushAll(cmd.Args)
+	case "SUBSCRIBE":
+		return h.handleSubscribe(cmd.Args, false)
+	case "PSUBSCRIBE":
+		return h.handleSubscribe(cmd.Args, true)
+	case "UNSUBSCRIBE":
+		return h.handleUnsubscribe(cmd.Args, false)
+	case "PUNSUBSCRIBE":
+		return h.handleUnsubscribe(cmd.Args, true)
+	case "PUBLISH":
+		return h.handlePublish(cmd.Args)
+	case "PUBSUB":
+		return h.handlePubSub(cmd.Args)
 	case "QUIT":
 		return proto.NewSimpleString("OK")
 	default:
-		return proto.NewError("ERR unknown command '" + cmd.Name + "'")
+		return nil
 	}
 }
 
 // handlePing handles the PING command
-func (h *Handler) handlePing(args []string) *proto.Response {
+func (h *Handler) handlePing(args [][]byte) *proto.Response {
+	// Subscribed clients get PONG as a Pub/Sub message so it cannot be
+	// confused with a published payload
+	if h.protocol == proto.RESP2 && h.subscribed() && len(args) <= 1 {
+		message := ""
+		if len(args) == 1 {
+			message = string(args[0])
+		}
+		return proto.NewArray([]any{"pong", message})
+	}
 	if len(args) == 0 {
 		return proto.NewSimpleString("PONG")
 	}
 	if len(args) == 1 {
-		return proto.NewBulkString(args[0])
+		return proto.NewBulkString(string(args[0]))
 	}
 	return proto.NewError("ERR wrong number of arguments for 'ping' command")
 }
 
 // handleEcho handles the ECHO command
-func (h *Handler) handleEcho(args []string) *proto.Response {
+func (h *Handler) handleEcho(args [][]byte) *proto.Response {
 	if len(args) != 1 {
 		return proto.NewError("ERR wrong number of arguments for 'echo' command")
 	}
-	return proto.NewBulkString(args[0])
-}
-
-// handleInfo handles the INFO command
-func (h *Handler) handleInfo(_ []string) *proto.Response {
-	info := []string{
-		"# Server",
-		"kv_stash_version:dev",
-		"go_version:go1.25",
-		"uptime_in_seconds:0",
-		"",
-		"# Clients",
-		"connected_clients:1",
-		"",
-		"# Memory",
-		"used_memory:0",
-		"",
-		"# Keyspace",
-		"db0:keys=" + strconv.FormatInt(h.store.DBSize(), 10) + ",expires=0,avg_ttl=0",
-	}
-	return proto.NewBulkString(strings.Join(info, "\r\n"))
+	return proto.NewBulkString(string(args[0]))
 }
 
 // handleGet handles the GET command
-func (h *Handler) handleGet(args []string) *proto.Response {
+func (h *Handler) handleGet(args [][]byte) *proto.Response {
 	if len(args) != 1 {
 		return proto.NewError("ERR wrong number of arguments for 'get' command")
 	}
 
-	value, exists := h.store.Get(args[0])
+	value, exists := h.store.Get(string(args[0]))
 	if !exists {
 		return proto.NewNullBulkString()
 	}
@@ -130,24 +397,24 @@ func (h *Handler) handleGet(args []string) *proto.Response {
 }
 
 // handleSet handles the SET command
-func (h *Handler) handleSet(args []string) *proto.Response {
+func (h *Handler) handleSet(args [][]byte) *proto.Response {
 	if len(args) < minSetArgs {
 		return proto.NewError("ERR wrong number of arguments for 'set' command")
 	}
 
-	key := args[0]
-	value := args[1]
+	key := string(args[0])
+	value := string(args[1])
 	var expiration *time.Duration
 
 	// Parse options
 	for i := 2; i < len(args); i++ {
-		option := strings.ToUpper(args[i])
+		option := strings.ToUpper(string(args[i]))
 		switch option {
 		case "EX":
 			if i+1 >= len(args) {
 				return proto.NewError("ERR syntax error")
 			}
-			seconds, err := strconv.Atoi(args[i+1])
+			seconds, err := strconv.Atoi(string(args[i+1]))
 			if err != nil {
 				return proto.NewError("ERR value is not an integer or out of range")
 			}
@@ -158,7 +425,7 @@ func (h *Handler) handleSet(args []string) *proto.Response {
 			if i+1 >= len(args) {
 				return proto.NewError("ERR syntax error")
 			}
-			milliseconds, err := strconv.Atoi(args[i+1])
+			milliseconds, err := strconv.Atoi(string(args[i+1]))
 			if err != nil {
 				return proto.NewError("ERR value is not an integer or out of range")
 			}
@@ -175,14 +442,14 @@ func (h *Handler) handleSet(args []string) *proto.Response {
 }
 
 // handleDel handles the DEL command
-func (h *Handler) handleDel(args []string) *proto.Response {
+func (h *Handler) handleDel(args [][]byte) *proto.Response {
 	if len(args) == 0 {
 		return proto.NewError("ERR wrong number of arguments for 'del' command")
 	}
 
 	var deleted int64
 	for _, key := range args {
-		if h.store.Delete(key) {
+		if h.store.Delete(string(key)) {
 			deleted++
 		}
 	}
@@ -191,14 +458,14 @@ func (h *Handler) handleDel(args []string) *proto.Response {
 }
 
 // handleExists handles the EXISTS command
-func (h *Handler) handleExists(args []string) *proto.Response {
+func (h *Handler) handleExists(args [][]byte) *proto.Response {
 	if len(args) == 0 {
 		return proto.NewError("ERR wrong number of arguments for 'exists' command")
 	}
 
 	var count int64
 	for _, key := range args {
-		if h.store.Exists(key) {
+		if h.store.Exists(string(key)) {
 			count++
 		}
 	}
@@ -206,38 +473,18 @@ func (h *Handler) handleExists(args []string) *proto.Response {
 	return proto.NewInteger(count)
 }
 
-// handleExpire handles the EXPIRE command
-func (h *Handler) handleExpire(args []string) *proto.Response {
-	if len(args) != exactTwoArgs {
-		return proto.NewError("ERR wrong number of arguments for 'expire' command")
-	}
-
-	key := args[0]
-	seconds, err := strconv.Atoi(args[1])
-	if err != nil {
-		return proto.NewError("ERR value is not an integer or out of range")
-	}
-
-	duration := time.Duration(seconds) * time.Second
-	if h.store.Expire(key, duration) {
-		return proto.NewInteger(1)
-	}
-
-	return proto.NewInteger(0)
-}
-
 // handleTTL handles the TTL command
-func (h *Handler) handleTTL(args []string) *proto.Response {
+func (h *Handler) handleTTL(args [][]byte) *proto.Response {
 	if len(args) != 1 {
 		return proto.NewError("ERR wrong number of arguments for 'ttl' command")
 	}
 
-	ttl := h.store.TTL(args[0])
+	ttl := h.store.TTL(string(args[0]))
 	return proto.NewInteger(ttl)
 }
 
 // handleDBSize handles the DBSIZE command
-func (h *Handler) handleDBSize(args []string) *proto.Response {
+func (h *Handler) handleDBSize(args [][]byte) *proto.Response {
 	if len(args) != 0 {
 		return proto.NewError("ERR wrong number of arguments for 'dbsize' command")
 	}
@@ -247,14 +494,14 @@ func (h *Handler) handleDBSize(args []string) *proto.Response {
 }
 
 // handleMGet handles the MGET command
-func (h *Handler) handleMGet(args []string) *proto.Response {
+func (h *Handler) handleMGet(args [][]byte) *proto.Response {
 	if len(args) == 0 {
 		return proto.NewError("ERR wrong number of arguments for 'mget' command")
 	}
 
 	values := make([]any, len(args))
 	for i, key := range args {
-		if value, exists := h.store.Get(key); exists {
+		if value, exists := h.store.Get(string(key)); exists {
 			values[i] = value
 		} else {
 			values[i] = nil
@@ -265,80 +512,73 @@ func (h *Handler) handleMGet(args []string) *proto.Response {
 }
 
 // handleMSet handles the MSET command
-func (h *Handler) handleMSet(args []string) *proto.Response {
+func (h *Handler) handleMSet(args [][]byte) *proto.Response {
 	if len(args) == 0 || len(args)%2 != 0 {
 		return proto.NewError("ERR wrong number of arguments for 'mset' command")
 	}
 
 	for i := 0; i < len(args); i += 2 {
-		key := args[i]
-		value := args[i+1]
-		h.store.Set(key, value, nil)
+		h.store.Set(string(args[i]), string(args[i+1]), nil)
 	}
 
 	return proto.NewSimpleString("OK")
 }
 
 // handleIncr handles the INCR command
-func (h *Handler) handleIncr(args []string) *proto.Response {
+func (h *Handler) handleIncr(args [][]byte) *proto.Response {
 	if len(args) != 1 {
 		return proto.NewError("ERR wrong number of arguments for 'incr' command")
 	}
 
-	return h.incrementBy(args[0], 1)
+	return h.incrementBy(string(args[0]), 1)
 }
 
 // handleDecr handles the DECR command
-func (h *Handler) handleDecr(args []string) *proto.Response {
+func (h *Handler) handleDecr(args [][]byte) *proto.Response {
 	if len(args) != 1 {
 		return proto.NewError("ERR wrong number of arguments for 'decr' command")
 	}
 
-	return h.incrementBy(args[0], -1)
+	return h.incrementBy(string(args[0]), -1)
 }
 
 // handleIncrBy handles the INCRBY command
-func (h *Handler) handleIncrBy(args []string) *proto.Response {
+func (h *Handler) handleIncrBy(args [][]byte) *proto.Response {
 	if len(args) != exactTwoArgs {
 		return proto.NewError("ERR wrong number of arguments for 'incrby' command")
 	}
 
-	increment, err := strconv.ParseInt(args[1], 10, 64)
+	increment, err := strconv.ParseInt(string(args[1]), 10, 64)
 	if err != nil {
 		return proto.NewError("ERR value is not an integer or out of range")
 	}
 
-	return h.incrementBy(args[0], increment)
+	return h.incrementBy(string(args[0]), increment)
 }
 
 // handleDecrBy handles the DECRBY command
-func (h *Handler) handleDecrBy(args []string) *proto.Response {
+func (h *Handler) handleDecrBy(args [][]byte) *proto.Response {
 	if len(args) != exactTwoArgs {
 		return proto.NewError("ERR wrong number of arguments for 'decrby' command")
 	}
 
-	decrement, err := strconv.ParseInt(args[1], 10, 64)
+	decrement, err := strconv.ParseInt(string(args[1]), 10, 64)
 	if err != nil {
 		return proto.NewError("ERR value is not an integer or out of range")
 	}
+	if decrement == math.MinInt64 {
+		return proto.NewError("ERR decrement would overflow")
+	}
 
-	return h.incrementBy(args[0], -decrement)
+	return h.incrementBy(string(args[0]), -decrement)
 }
 
-// incrementBy increments a key by the given amount
+// incrementBy atomically increments a key by the given amount, keeping its TTL
 func (h *Handler) incrementBy(key string, increment int64) *proto.Response {
-	value, exists := h.store.Get(key)
-	var current int64
-
-	if exists {
-		parsed, err := strconv.ParseInt(value, 10, 64)
-		if err != nil {
-			return proto.NewError("ERR value is not an integer or out of range")
-		}
-		current = parsed
+	newValue, err := h.store.IncrBy(key, increment)
+	if err != nil {
+		return storeError(err)
 	}
 
-	newValue := current + increment
-	h.store.Set(key, strconv.FormatInt(newValue, 10), nil)
 	return proto.NewInteger(newValue)
 }
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/acl"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+// defaultACLLogCount is the number of entries ACL LOG returns without a count
+const defaultACLLogCount = 10
+
+// errNoACLFile is the reply to ACL LOAD and ACL SAVE without server.acl_file
+var errNoACLFile = proto.NewError("ERR This server is not configured to use an ACL file. " +
+	"Set server.acl_file to load and save users.")
+
+// checkPermissions returns a NOPERM error, and logs the denial, when user may
+// not run cmd or access one of its keys or channels
+func (h *Handler) checkPermissions(user *acl.User, cmd *proto.Request) *proto.Response {
+	info := commandTable[cmd.Name]
+	if info == nil {
+		// Unknown commands are reported as such once dispatched
+		return nil
+	}
+
+	if !user.CanRun(cmd.Name) {
+		name := strings.ToLower(cmd.Name)
+		h.acl.Log(acl.ReasonCommand, name, user.Name(), h.clientInfo())
+		return proto.NewError("NOPERM User " + user.Name() + " has no permissions to run the '" + name + "' command")
+	}
+
+	for _, key := range info.keys(cmd.Args) {
+		if !user.CanAccessKey(string(key)) {
+			h.acl.Log(acl.ReasonKey, string(key), user.Name(), h.clientInfo())
+			return proto.NewError("NOPERM No permissions to access a key")
+		}
+	}
+
+	var channels [][]byte
+	switch info.channels {
+	case firstChannel:
+		if len(cmd.Args) > 0 {
+			channels = cmd.Args[:1]
+		}
+	case everyChannel, everyPattern:
+		channels = cmd.Args
+	case noChannels:
+	}
+	for _, channel := range channels {
+		if !user.CanAccessChannel(string(channel), info.channels == everyPattern) {
+			h.acl.Log(acl.ReasonChannel, string(channel), user.Name(), h.clientInfo())
+			return proto.NewError("NOPERM No permissions to access a channel")
+		}
+	}
+
+	return nil
+}
+
+// handleACL handles the ACL command
+func (h *Handler) handleACL(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'acl' command")
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "SETUSER":
+		return h.handleACLSetUser(args[1:])
+	case "GETUSER":
+		return h.handleACLGetUser(args[1:])
+	case "DELUSER":
+		return h.handleACLDelUser(args[1:])
+	case "LIST":
+		return h.handleACLList(args[1:])
+	case "USERS":
+		return h.handleACLUsers(args[1:])
+	case "WHOAMI":
+		return h.handleACLWhoAmI(args[1:])
+	case "CAT":
+		return h.handleACLCat(args[1:])
+	case "LOG":
+		return h.handleACLLog(args[1:])
+	case "LOAD":
+		return h.handleACLLoad(args[1:])
+	case "SAVE":
+		return h.handleACLSave(args[1:])
+	case "HELP":
+		return proto.NewArray([]any{
+			"ACL <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"CAT [<category>]",
+			"    List all commands that belong to <category>, or all command categories when no category is specified.",
+			"DELUSER <username> [<username> ...]",
+			"    Delete a list of users.",
+			"GETUSER <username>",
+			"    Get the user's details.",
+			"LIST",
+			"    Show users details in config file format.",
+			"LOAD",
+			"    Reload users from the ACL file.",
+			"LOG [<count> | RESET]",
+			"    Show the ACL log entries.",
+			"SAVE",
+			"    Save the current config to the ACL file.",
+			"SETUSER <username> <attribute> [<attribute> ...]",
+			"    Create or modify a user with the specified attributes.",
+			"USERS",
+			"    List all the registered usernames.",
+			"WHOAMI",
+			"    Return the current connection username.",
+		})
+	default:
+		return proto.NewError("ERR unknown subcommand '" + string(args[0]) + "'. Try ACL HELP.")
+	}
+}
+
+// aclArityError is the reply to an ACL subcommand with the wrong number of
+// arguments
+func aclArityError(subcommand string) *proto.Response {
+	return proto.NewError("ERR wrong number of arguments for 'acl|" + subcommand + "' command")
+}
+
+// handleACLSetUser handles ACL SETUSER username [rule ...]
+func (h *Handler) handleACLSetUser(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return aclArityError("setuser")
+	}
+
+	if err := h.acl.SetUser(string(args[0]), argStrings(args[1:])...); err != nil {
+		return proto.NewError("ERR " + err.Error())
+	}
+	return proto.NewSimpleString("OK")
+}
+
+// handleACLGetUser handles ACL GETUSER username
+func (h *Handler) handleACLGetUser(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return aclArityError("getuser")
+	}
+
+	user := h.acl.User(string(args[0]))
+	if user == nil {
+		return proto.NewNull()
+	}
+
+	return proto.NewMap([]any{
+		"flags", stringsToAny(user.Flags()),
+		"passwords", stringsToAny(user.Passwords()),
+		"commands", user.CommandRules(),
+		"keys", user.KeyRules(),
+		"channels", user.ChannelRules(),
+		"selectors", []any{},
+	})
+}
+
+// handleACLDelUser handles ACL DELUSER username [username ...]
+func (h *Handler) handleACLDelUser(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return aclArityError("deluser")
+	}
+
+	deleted, err := h.acl.DeleteUser(argStrings(args)...)
+	if err != nil {
+		return proto.NewError("ERR " + err.Error())
+	}
+	return proto.NewInteger(int64(deleted))
+}
+
+// handleACLList handles ACL LIST
+func (h *Handler) handleACLList(args [][]byte) *proto.Response {
+	if len(args) != 0 {
+		return aclArityError("list")
+	}
+
+	users := h.acl.Users()
+	lines := make([]any, len(users))
+	for i, user := range users {
+		lines[i] = user.String()
+	}
+	return proto.NewArray(lines)
+}
+
+// handleACLUsers handles ACL USERS
+func (h *Handler) handleACLUsers(args [][]byte) *proto.Response {
+	if len(args) != 0 {
+		return aclArityError("users")
+	}
+
+	users := h.acl.Users()
+	names := make([]any, len(users))
+	for i, user := range users {
+		names[i] = user.Name()
+	}
+	return proto.NewArray(names)
+}
+
+// handleACLWhoAmI handles ACL WHOAMI
+func (h *Handler) handleACLWhoAmI(args [][]byte) *proto.Response {
+	if len(args) != 0 {
+		return aclArityError("whoami")
+	}
+	return proto.NewBulkString(h.user)
+}
+
+// handleACLCat handles ACL CAT [category]
+func (h *Handler) handleACLCat(args [][]byte) *proto.Response {
+	switch len(args) {
+	case 0:
+		return proto.NewArray(stringsToAny(acl.Categories()))
+	case 1:
+		category, ok := acl.ParseCategory(string(args[0]))
+		if !ok {
+			return proto.NewError("ERR Unknown category '" + string(args[0]) + "'")
+		}
+		commands := h.acl.Commands(category)
+		names := make([]any, len(commands))
+		for i, command := range commands {
+			names[i] = strings.ToLower(command)
+		}
+		return proto.NewArray(names)
+	default:
+		return aclArityError("cat")
+	}
+}
+
+// handleACLLog handles ACL LOG [count | RESET]
+func (h *Handler) handleACLLog(args [][]byte) *proto.Response {
+	count := defaultACLLogCount
+	switch len(args) {
+	case 0:
+	case 1:
+		if strings.ToUpper(string(args[0])) == "RESET" {
+			h.acl.ResetLog()
+			return proto.NewSimpleString("OK")
+		}
+		n, err := strconv.Atoi(string(args[0]))
+		if err != nil || n < 0 {
+			return proto.NewError("ERR value is out of range, must be positive")
+		}
+		count = n
+	default:
+		return aclArityError("log")
+	}
+
+	now := time.Now()
+	entries := h.acl.LogEntries(count)
+	replies := make([]any, len(entries))
+	for i, entry := range entries {
+		replies[i] = proto.NewMap([]any{
+			"count", entry.Count,
+			"reason", entry.Reason,
+			"context", "toplevel",
+			"object", entry.Object,
+			"username", entry.Username,
+			"age-seconds", proto.NewDouble(now.Sub(entry.Created).Seconds()),
+			"client-info", entry.ClientInfo,
+			"entry-id", entry.ID,
+			"timestamp-created", entry.Created.UnixMilli(),
+			"timestamp-last-updated", entry.Updated.UnixMilli(),
+		})
+	}
+	return proto.NewArray(replies)
+}
+
+// handleACLLoad handles ACL LOAD
+func (h *Handler) handleACLLoad(args [][]byte) *proto.Response {
+	if len(args) != 0 {
+		return aclArityError("load")
+	}
+	if h.config.Server.ACLFile == "" {
+		return errNoACLFile
+	}
+
+	// A file that fails to load leaves the users unchanged
+	if err := h.acl.LoadFile(h.config.Server.ACLFile); err != nil {
+		return proto.NewError("ERR " + err.Error())
+	}
+	return proto.NewSimpleString("OK")
+}
+
+// handleACLSave handles ACL SAVE
+func (h *Handler) handleACLSave(args [][]byte) *proto.Response {
+	if len(args) != 0 {
+		return aclArityError("save")
+	}
+	if h.config.Server.ACLFile == "" {
+		return errNoACLFile
+	}
+
+	if err := h.acl.SaveFile(h.config.Server.ACLFile); err != nil {
+		h.logger.Error("Failed to save ACL file", "error", err)
+		return proto.NewError("ERR There was an error trying to save the ACLs. Please check the server logs for more information")
+	}
+	return proto.NewSimpleString("OK")
+}
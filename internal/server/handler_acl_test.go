@@ -0,0 +1,298 @@
+package server_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/acl"
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// createACLHandler creates a handler whose server keeps its users in aclFile
+func createACLHandler(t *testing.T, aclFile string) *server.Handler {
+	t.Helper()
+
+	logger := obs.NewLogger(false)
+	s, err := store.New(&store.Config{Shards: 4}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	config := server.DefaultConfig()
+	config.Server.ACLFile = aclFile
+	return server.NewHandler(s, config, logger)
+}
+
+// mustRun runs a command that is expected to succeed
+func mustRun(t *testing.T, handler *server.Handler, name string, args ...string) *proto.Response {
+	t.Helper()
+
+	resp := handler.HandleCommand(request(name, args...))
+	if resp.Type == proto.Error {
+		t.Fatalf("%s %v failed: %v", name, args, resp.Data)
+	}
+	return resp
+}
+
+// authenticateAsJobs creates a user limited to job:* keys and jobs.*
+// channels without dangerous commands, and switches handler to it
+func authenticateAsJobs(t *testing.T, handler *server.Handler) {
+	t.Helper()
+
+	mustRun(t, handler, "ACL", "SETUSER", "jobs", "on", ">pw", "~job:*", "&jobs.*", "+@all", "-@dangerous")
+	mustRun(t, handler, "AUTH", "jobs", "pw")
+}
+
+func TestHandler_ACL_Permissions(t *testing.T) {
+	t.Parallel()
+
+	const (
+		noCommand = "NOPERM User jobs has no permissions to run the '%s' command"
+		noKey     = "NOPERM No permissions to access a key"
+		noChannel = "NOPERM No permissions to access a channel"
+	)
+
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{name: "allowed key", args: []string{"SET", "job:1", "v"}},
+		{name: "denied key", args: []string{"GET", "other"}, errorMsg: noKey},
+		{name: "every key of MSET", args: []string{"MSET", "job:1", "a", "job:2", "b"}},
+		{name: "one denied key of MSET", args: []string{"MSET", "job:1", "a", "other", "b"}, errorMsg: noKey},
+		{name: "values are not keys", args: []string{"MSET", "job:1", "other"}},
+		{name: "one denied key of DEL", args: []string{"DEL", "job:1", "other"}, errorMsg: noKey},
+		{name: "RENAME destination", args: []string{"RENAME", "job:1", "other"}, errorMsg: noKey},
+		{name: "OBJECT key", args: []string{"OBJECT", "ENCODING", "other"}, errorMsg: noKey},
+		{name: "OBJECT HELP is keyless", args: []string{"OBJECT", "HELP"}},
+		{name: "MEMORY USAGE key", args: []string{"MEMORY", "USAGE", "other"}, errorMsg: noKey},
+		{name: "MEMORY ANALYZE is keyless", args: []string{"MEMORY", "ANALYZE", "TOP", "1"}},
+		{name: "dangerous command", args: []string{"FLUSHALL"}, errorMsg: strings.Replace(noCommand, "%s", "flushall", 1)},
+		{name: "KEYS is dangerous", args: []string{"KEYS", "*"}, errorMsg: strings.Replace(noCommand, "%s", "keys", 1)},
+		{name: "allowed channel", args: []string{"PUBLISH", "jobs.done", "1"}},
+		{name: "denied channel", args: []string{"PUBLISH", "news", "1"}, errorMsg: noChannel},
+		{name: "SUBSCRIBE checks every channel", args: []string{"SUBSCRIBE", "jobs.a", "news"}, errorMsg: noChannel},
+		{name: "PSUBSCRIBE needs the same pattern", args: []string{"PSUBSCRIBE", "jobs.d*"}, errorMsg: noChannel},
+		{name: "malformed commands report their own error", args: []string{"GET"}, errorMsg: "ERR wrong number of arguments for 'get' command"},
+		{name: "unknown commands are unknown", args: []string{"NOSUCH"}, errorMsg: "ERR unknown command 'NOSUCH'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createTestHandler(t)
+			authenticateAsJobs(t, handler)
+
+			resp := handler.HandleCommand(request(tt.args[0], tt.args[1:]...))
+			if tt.errorMsg != "" {
+				if resp.Type != proto.Error || resp.Data != tt.errorMsg {
+					t.Errorf("Expected error %q, got %v: %v", tt.errorMsg, resp.Type, resp.Data)
+				}
+			} else if resp.Type == proto.Error {
+				t.Errorf("Unexpected error %v", resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_ACL_SetUser(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{name: "create user", args: []string{"SETUSER", "alice", "on", ">pw", "+@read", "~*"}},
+		{name: "invalid rule", args: []string{"SETUSER", "alice", "bogus"}, errorMsg: "ERR Error in ACL SETUSER modifier 'bogus': Syntax error"},
+		{name: "unknown command", args: []string{"SETUSER", "alice", "+nosuch"}, errorMsg: "ERR Error in ACL SETUSER modifier '+nosuch': Unknown command or category name in ACL"},
+		{name: "missing username", args: []string{"SETUSER"}, errorMsg: "ERR wrong number of arguments for 'acl|setuser' command"},
+		{name: "delete default user", args: []string{"DELUSER", "default"}, errorMsg: "ERR The 'default' user cannot be removed"},
+		{name: "unknown category", args: []string{"CAT", "nosuch"}, errorMsg: "ERR Unknown category 'nosuch'"},
+		{name: "invalid log count", args: []string{"LOG", "-1"}, errorMsg: "ERR value is out of range, must be positive"},
+		{name: "LOAD without a file", args: []string{"LOAD"}, errorMsg: "ERR This server is not configured to use an ACL file. Set server.acl_file to load and save users."},
+		{name: "unknown subcommand", args: []string{"NOSUCH"}, errorMsg: "ERR unknown subcommand 'NOSUCH'. Try ACL HELP."},
+	}
+
+	for _, tt := range tests {
+		resp := handler.HandleCommand(request("ACL", tt.args...))
+		if tt.errorMsg != "" {
+			if resp.Type != proto.Error || resp.Data != tt.errorMsg {
+				t.Errorf("%s: expected error %q, got %v: %v", tt.name, tt.errorMsg, resp.Type, resp.Data)
+			}
+		} else if resp.Type == proto.Error {
+			t.Errorf("%s: unexpected error %v", tt.name, resp.Data)
+		}
+	}
+
+	// The failed SETUSER calls left alice as created
+	resp := mustRun(t, handler, "ACL", "GETUSER", "alice")
+	expected := []any{
+		"flags", []any{"on"},
+		"passwords", []any{acl.HashPassword("pw")},
+		"commands", "-@all +@read",
+		"keys", "~*",
+		"channels", "",
+		"selectors", []any{},
+	}
+	if resp.Type != proto.Map || !reflect.DeepEqual(resp.Data, expected) {
+		t.Errorf("Expected GETUSER %v, got %v: %v", expected, resp.Type, resp.Data)
+	}
+	if resp := mustRun(t, handler, "ACL", "GETUSER", "nobody"); resp.Type != proto.Null {
+		t.Errorf("Expected a null reply for an unknown user, got %v", resp.Type)
+	}
+
+	resp = mustRun(t, handler, "ACL", "LIST")
+	expected = []any{
+		"user alice on #" + acl.HashPassword("pw") + " ~* resetchannels -@all +@read",
+		"user default on nopass ~* &* +@all",
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Errorf("Expected LIST %v, got %v", expected, resp.Data)
+	}
+	if resp := mustRun(t, handler, "ACL", "USERS"); !reflect.DeepEqual(resp.Data, []any{"alice", "default"}) {
+		t.Errorf("Expected USERS alice and default, got %v", resp.Data)
+	}
+
+	if resp := mustRun(t, handler, "ACL", "DELUSER", "alice", "nobody"); resp.Data != int64(1) {
+		t.Errorf("Expected 1 user deleted, got %v", resp.Data)
+	}
+}
+
+func TestHandler_ACL_WhoAmI(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	if resp := mustRun(t, handler, "ACL", "WHOAMI"); resp.Data != "default" {
+		t.Errorf("Expected default, got %v", resp.Data)
+	}
+
+	mustRun(t, handler, "ACL", "SETUSER", "alice", "on", ">pw", "+acl")
+	mustRun(t, handler, "AUTH", "alice", "pw")
+	if resp := mustRun(t, handler, "ACL", "WHOAMI"); resp.Data != "alice" {
+		t.Errorf("Expected alice, got %v", resp.Data)
+	}
+
+	// A user switched off keeps its connections; deleting it ends them
+	mustRun(t, handler, "ACL", "SETUSER", "alice", "off")
+	if resp := handler.HandleCommand(request("AUTH", "alice", "pw")); resp.Type != proto.Error {
+		t.Errorf("Expected a disabled user to be unable to authenticate, got %v", resp.Data)
+	}
+	mustRun(t, handler, "ACL", "DELUSER", "alice")
+	resp := handler.HandleCommand(request("ACL", "WHOAMI"))
+	if resp.Type != proto.Error || resp.Data != "NOAUTH Authentication required." {
+		t.Errorf("Expected NOAUTH once the user is deleted, got %v: %v", resp.Type, resp.Data)
+	}
+}
+
+func TestHandler_ACL_Cat(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	resp := mustRun(t, handler, "ACL", "CAT")
+	if categories := resp.Data.([]any); !reflect.DeepEqual(categories, []any{
+		"keyspace", "read", "write", "string", "pubsub", "admin", "fast", "slow", "dangerous", "connection",
+	}) {
+		t.Errorf("Unexpected categories %v", categories)
+	}
+
+	resp = mustRun(t, handler, "ACL", "CAT", "dangerous")
+	if expected := []any{"acl", "client", "flushall", "flushdb", "info", "keys", "swapdb"}; !reflect.DeepEqual(resp.Data, expected) {
+		t.Errorf("Expected dangerous commands %v, got %v", expected, resp.Data)
+	}
+}
+
+func TestHandler_ACL_Log(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	authenticateAsJobs(t, handler)
+	handler.HandleCommand(request("GET", "secret"))
+	handler.HandleCommand(request("FLUSHALL"))
+	handler.HandleCommand(request("FLUSHALL"))
+	handler.HandleCommand(request("AUTH", "jobs", "wrong"))
+	mustRun(t, handler, "AUTH", "default", "anything")
+
+	resp := mustRun(t, handler, "ACL", "LOG")
+	entries := resp.Data.([]any)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 log entries, got %v", entries)
+	}
+
+	expected := []struct {
+		count  int64
+		reason string
+		object string
+	}{
+		{count: 1, reason: "auth", object: "AUTH"},
+		{count: 2, reason: "command", object: "flushall"},
+		{count: 1, reason: "key", object: "secret"},
+	}
+	for i, want := range expected {
+		fields := map[string]any{}
+		pairs := entries[i].(*proto.Response).Data.([]any)
+		for j := 0; j+1 < len(pairs); j += 2 {
+			fields[pairs[j].(string)] = pairs[j+1]
+		}
+		if fields["count"] != want.count || fields["reason"] != want.reason || fields["object"] != want.object ||
+			fields["username"] != "jobs" || fields["context"] != "toplevel" {
+			t.Errorf("Entry %d: expected %+v, got %v", i, want, fields)
+		}
+		if info, _ := fields["client-info"].(string); !strings.Contains(info, "user=") {
+			t.Errorf("Entry %d: expected client info, got %q", i, info)
+		}
+	}
+
+	if resp := mustRun(t, handler, "ACL", "LOG", "1"); len(resp.Data.([]any)) != 1 {
+		t.Errorf("Expected 1 log entry, got %v", resp.Data)
+	}
+	mustRun(t, handler, "ACL", "LOG", "RESET")
+	if resp := mustRun(t, handler, "ACL", "LOG"); len(resp.Data.([]any)) != 0 {
+		t.Errorf("Expected an empty log after RESET, got %v", resp.Data)
+	}
+}
+
+func TestHandler_ACL_LoadSave(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "users.acl")
+	handler := createACLHandler(t, path)
+
+	mustRun(t, handler, "ACL", "SETUSER", "jobs", "on", ">pw", "~job:*", "+@all", "-@dangerous")
+	mustRun(t, handler, "ACL", "SAVE")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the ACL file: %v", err)
+	}
+	if !strings.Contains(string(data), "user jobs on #"+acl.HashPassword("pw")+" ~job:* resetchannels +@all -@dangerous\n") {
+		t.Errorf("Expected the saved file to hold jobs, got %q", data)
+	}
+
+	mustRun(t, handler, "ACL", "DELUSER", "jobs")
+	mustRun(t, handler, "ACL", "LOAD")
+	if resp := mustRun(t, handler, "ACL", "USERS"); !reflect.DeepEqual(resp.Data, []any{"default", "jobs"}) {
+		t.Errorf("Expected jobs to be loaded back, got %v", resp.Data)
+	}
+
+	if err := os.WriteFile(path, []byte("user jobs +nosuch\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write the ACL file: %v", err)
+	}
+	resp := handler.HandleCommand(request("ACL", "LOAD"))
+	if resp.Type != proto.Error || !strings.Contains(resp.Data.(string), "users.acl:1: Error in ACL SETUSER modifier '+nosuch'") {
+		t.Errorf("Expected the invalid file to be rejected, got %v: %v", resp.Type, resp.Data)
+	}
+	if resp := mustRun(t, handler, "ACL", "USERS"); !reflect.DeepEqual(resp.Data, []any{"default", "jobs"}) {
+		t.Errorf("Expected the users to be unchanged, got %v", resp.Data)
+	}
+}
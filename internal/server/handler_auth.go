@@ -0,0 +1,88 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/Abhishek2095/kv-stash/internal/acl"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+// errWrongPass is the reply to invalid credentials. It does not say whether
+// the user or the password was wrong.
+var errWrongPass = proto.NewError("WRONGPASS invalid username-password pair or user is disabled.")
+
+// noAuthCommands are the only commands a client may run before it
+// authenticates. They are allowed whatever the user's permissions, so a
+// client can always change user or disconnect.
+var noAuthCommands = map[string]bool{
+	"AUTH":  true,
+	"HELLO": true,
+	"QUIT":  true,
+}
+
+// handleAuth handles AUTH [username] password
+func (h *Handler) handleAuth(args [][]byte) *proto.Response {
+	username, password := acl.DefaultUser, ""
+	switch len(args) {
+	case 1:
+		if h.acl.User(acl.DefaultUser).NoPass() {
+			return proto.NewError("ERR AUTH <password> called without any password configured for the default user. " +
+				"Are you sure your configuration is correct?")
+		}
+		password = string(args[0])
+	case exactTwoArgs:
+		username, password = string(args[0]), string(args[1])
+	default:
+		return proto.NewError("ERR wrong number of arguments for 'auth' command")
+	}
+
+	if !h.checkCredentials(username, password) {
+		return errWrongPass
+	}
+
+	h.user = username
+	return proto.NewSimpleString("OK")
+}
+
+// checkCredentials reports whether username is an enabled user and password
+// one of its passwords. Failures are counted and logged.
+func (h *Handler) checkCredentials(username, password string) bool {
+	if _, ok := h.acl.Authenticate(username, password); !ok {
+		h.stats.authFailed()
+		h.acl.Log(acl.ReasonAuth, "AUTH", username, h.clientInfo())
+		return false
+	}
+	return true
+}
+
+// currentUser returns the user the client is authenticated as, or nil if it
+// is not authenticated or its user has since been deleted
+func (h *Handler) currentUser() *acl.User {
+	if h.user == "" {
+		return nil
+	}
+	return h.acl.User(h.user)
+}
+
+// clientInfo describes the connection in ACL log entries
+func (h *Handler) clientInfo() string {
+	return "id=" + strconv.FormatInt(h.id, 10) + " name=" + h.name + " user=" + h.user
+}
+
+// newACL creates the ACL users. The default user requires
+// server.auth_password when it is set.
+func newACL(config *AppConfig) *acl.ACL {
+	return acl.New(commandCategories(), config.Server.AuthPassword)
+}
+
+// authenticateAs authenticates the client as username without a password,
+// for clients vouched for by their TLS certificate or listener. It reports
+// whether the user exists and is enabled.
+func (h *Handler) authenticateAs(username string) bool {
+	user := h.acl.User(username)
+	if user == nil || !user.Enabled() {
+		return false
+	}
+	h.user = username
+	return true
+}
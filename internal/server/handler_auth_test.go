@@ -0,0 +1,113 @@
+package server_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+func TestHandler_AUTH(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		password string
+		args     []string
+		errorMsg string
+	}{
+		{name: "password", password: "secret", args: []string{"secret"}},
+		{name: "default user and password", password: "secret", args: []string{"default", "secret"}},
+		{name: "wrong password", password: "secret", args: []string{"nope"}, errorMsg: "WRONGPASS invalid username-password pair or user is disabled."},
+		{name: "unknown user", password: "secret", args: []string{"admin", "secret"}, errorMsg: "WRONGPASS invalid username-password pair or user is disabled."},
+		{name: "password prefix", password: "secret", args: []string{"secre"}, errorMsg: "WRONGPASS invalid username-password pair or user is disabled."},
+		{name: "no password configured", args: []string{"secret"}, errorMsg: "ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?"},
+		{name: "default user without password configured", args: []string{"default", "anything"}},
+		{name: "no arguments", password: "secret", args: []string{}, errorMsg: "ERR wrong number of arguments for 'auth' command"},
+		{name: "too many arguments", password: "secret", args: []string{"default", "secret", "extra"}, errorMsg: "ERR wrong number of arguments for 'auth' command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createPasswordHandler(t, tt.password)
+			resp := handler.HandleCommand(request("AUTH", tt.args...))
+
+			if tt.errorMsg != "" {
+				if resp.Type != proto.Error || resp.Data != tt.errorMsg {
+					t.Errorf("Expected error %q, got %v: %v", tt.errorMsg, resp.Type, resp.Data)
+				}
+			} else if resp.Type != proto.SimpleString || resp.Data != "OK" {
+				t.Errorf("Expected OK, got %v: %v", resp.Type, resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_NOAUTH(t *testing.T) {
+	t.Parallel()
+
+	handler := createPasswordHandler(t, "secret")
+
+	tests := []struct {
+		name     string
+		cmd      *proto.Request
+		errorMsg string
+	}{
+		{name: "commands are rejected", cmd: request("GET", "k"), errorMsg: "NOAUTH Authentication required."},
+		{name: "PING is rejected", cmd: request("PING"), errorMsg: "NOAUTH Authentication required."},
+		{name: "unknown commands are rejected", cmd: request("NOSUCH"), errorMsg: "NOAUTH Authentication required."},
+		{name: "failed AUTH", cmd: request("AUTH", "nope"), errorMsg: "WRONGPASS invalid username-password pair or user is disabled."},
+		{name: "still rejected after a failure", cmd: request("SET", "k", "v"), errorMsg: "NOAUTH Authentication required."},
+		{name: "AUTH", cmd: request("AUTH", "secret")},
+		{name: "commands run once authenticated", cmd: request("SET", "k", "v")},
+		{name: "a later failed AUTH keeps the connection authenticated", cmd: request("AUTH", "nope"), errorMsg: "WRONGPASS invalid username-password pair or user is disabled."},
+		{name: "commands still run", cmd: request("GET", "k")},
+	}
+
+	// The steps share one connection, so they run in order
+	for _, tt := range tests {
+		resp := handler.HandleCommand(tt.cmd)
+		if tt.errorMsg != "" {
+			if resp.Type != proto.Error || resp.Data != tt.errorMsg {
+				t.Errorf("%s: expected error %q, got %v: %v", tt.name, tt.errorMsg, resp.Type, resp.Data)
+			}
+		} else if resp.Type == proto.Error {
+			t.Errorf("%s: unexpected error %v", tt.name, resp.Data)
+		}
+	}
+
+	if failures := handler.Stats().AuthFailures(); failures != 2 {
+		t.Errorf("Expected 2 auth failures, got %d", failures)
+	}
+	info := handler.HandleCommand(request("INFO", "stats"))
+	if text, _ := info.Data.(string); !strings.Contains(text, "acl_access_denied_auth:2\r\n") {
+		t.Errorf("Expected INFO stats to report 2 auth failures, got %q", text)
+	}
+}
+
+func TestHandler_NOAUTH_HELLO(t *testing.T) {
+	t.Parallel()
+
+	handler := createPasswordHandler(t, "secret")
+
+	resp := handler.HandleCommand(request("HELLO", "3", "AUTH", "default", "nope"))
+	if resp.Type != proto.Error {
+		t.Fatalf("Expected HELLO with a wrong password to fail, got %v: %v", resp.Type, resp.Data)
+	}
+	if resp := handler.HandleCommand(request("GET", "k")); resp.Type != proto.Error {
+		t.Errorf("Expected GET to be rejected after a failed HELLO AUTH, got %v", resp.Type)
+	}
+
+	resp = handler.HandleCommand(request("HELLO", "3", "AUTH", "default", "secret"))
+	if resp.Type != proto.Map {
+		t.Fatalf("Expected HELLO AUTH to succeed, got %v: %v", resp.Type, resp.Data)
+	}
+	if resp := handler.HandleCommand(request("GET", "k")); resp.Type == proto.Error {
+		t.Errorf("Expected GET to run after HELLO AUTH, got %v", resp.Data)
+	}
+	if failures := handler.Stats().AuthFailures(); failures != 1 {
+		t.Errorf("Expected 1 auth failure, got %d", failures)
+	}
+}
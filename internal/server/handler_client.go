@@ -0,0 +1,349 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+// CLIENT REPLY modes
+const (
+	replyOn   = "ON"
+	replyOff  = "OFF"
+	replySkip = "SKIP"
+)
+
+// publishSession copies the connection's state to its session for other
+// connections to see
+func (h *Handler) publishSession() {
+	s := h.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.name = h.name
+	s.user = h.user
+	s.db = h.store.Index()
+	s.protocol = h.protocol
+	s.subscriber = h.subscriber
+	s.noEvict = h.noEvict
+	s.lastActive = time.Now()
+}
+
+// clientArityError is the reply to a CLIENT subcommand with the wrong number
+// of arguments
+func clientArityError(subcommand string) *proto.Response {
+	return proto.NewError("ERR wrong number of arguments for 'client|" + subcommand + "' command")
+}
+
+// handleClient handles the CLIENT command
+func (h *Handler) handleClient(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'client' command")
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "ID":
+		if len(args) != 1 {
+			return clientArityError("id")
+		}
+		return proto.NewInteger(h.id)
+	case "INFO":
+		if len(args) != 1 {
+			return clientArityError("info")
+		}
+		return proto.NewBulkString(h.session.info(time.Now()) + "\n")
+	case "LIST":
+		return h.handleClientList(args[1:])
+	case "SETNAME":
+		return h.handleClientSetName(args[1:])
+	case "GETNAME":
+		if len(args) != 1 {
+			return clientArityError("getname")
+		}
+		if h.name == "" {
+			return proto.NewNullBulkString()
+		}
+		return proto.NewBulkString(h.name)
+	case "KILL":
+		return h.handleClientKill(args[1:])
+	case "PAUSE":
+		return h.handleClientPause(args[1:])
+	case "UNPAUSE":
+		if len(args) != 1 {
+			return clientArityError("unpause")
+		}
+		h.clients.unpause()
+		return proto.NewSimpleString("OK")
+	case "NO-EVICT":
+		return h.handleClientNoEvict(args[1:])
+	case "REPLY":
+		return h.handleClientReply(args[1:])
+	case "HELP":
+		return proto.NewArray([]any{
+			"CLIENT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"GETNAME",
+			"    Return the name of the current connection.",
+			"ID",
+			"    Return the ID of the current connection.",
+			"INFO",
+			"    Return information about the current client connection.",
+			"KILL <ip:port>",
+			"    Kill connection made from <ip:port>.",
+			"KILL <option> <value> [<option> <value> [...]]",
+			"    Kill connections. Options are:",
+			"    * ADDR <ip:port>",
+			"      Kill connections made from the specified address",
+			"    * LADDR <ip:port>",
+			"      Kill connections made to specified local address",
+			"    * ID <client-id>",
+			"      Kill connections by client id.",
+			"    * USER <username>",
+			"      Kill connections authenticated by <username>.",
+			"    * SKIPME (YES|NO)",
+			"      Skip killing current connection (default: yes).",
+			"LIST [TYPE (NORMAL|PUBSUB)] [ID <client-id> [<client-id> ...]]",
+			"    Return information about client connections.",
+			"NO-EVICT (ON|OFF)",
+			"    Set the no-evict flag shown by CLIENT LIST. Clients are never evicted,",
+			"    so the flag protects nothing.",
+			"PAUSE <timeout> [WRITE|ALL]",
+			"    Suspend all, or just write, clients for <timeout> milliseconds.",
+			"REPLY (ON|OFF|SKIP)",
+			"    Control the replies sent to the current connection.",
+			"SETNAME <name>",
+			"    Assign the name <name> to the current connection.",
+			"UNPAUSE",
+			"    Stop the current client pause, resuming traffic.",
+		})
+	default:
+		return proto.NewError("ERR unknown subcommand '" + string(args[0]) + "'. Try CLIENT HELP.")
+	}
+}
+
+// handleClientList handles CLIENT LIST [TYPE NORMAL|PUBSUB] [ID id [id ...]]
+func (h *Handler) handleClientList(args [][]byte) *proto.Response {
+	sessions := h.clients.list()
+
+	if len(args) > 0 {
+		switch strings.ToUpper(string(args[0])) {
+		case "TYPE":
+			if len(args) != exactTwoArgs {
+				return proto.NewError("ERR syntax error")
+			}
+			var pubSub bool
+			switch strings.ToUpper(string(args[1])) {
+			case "NORMAL":
+			case "PUBSUB":
+				pubSub = true
+			default:
+				return proto.NewError("ERR Unknown client type '" + string(args[1]) + "'")
+			}
+			filtered := sessions[:0]
+			for _, session := range sessions {
+				if session.pubSub() == pubSub {
+					filtered = append(filtered, session)
+				}
+			}
+			sessions = filtered
+		case "ID":
+			if len(args) < exactTwoArgs {
+				return proto.NewError("ERR syntax error")
+			}
+			sessions = sessions[:0]
+			for _, arg := range args[1:] {
+				id, err := strconv.ParseInt(string(arg), 10, 64)
+				if err != nil || id <= 0 {
+					return proto.NewError("ERR Invalid client ID")
+				}
+				if session := h.clients.get(id); session != nil {
+					sessions = append(sessions, session)
+				}
+			}
+		default:
+			return proto.NewError("ERR syntax error")
+		}
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	for _, session := range sessions {
+		b.WriteString(session.info(now))
+		b.WriteByte('\n')
+	}
+	return proto.NewBulkString(b.String())
+}
+
+// handleClientSetName handles CLIENT SETNAME name. An empty name clears it.
+func (h *Handler) handleClientSetName(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return clientArityError("setname")
+	}
+	name := string(args[0])
+	if !validClientName(name) {
+		return proto.NewError("ERR Client names cannot contain spaces, newlines or special characters.")
+	}
+
+	h.name = name
+	return proto.NewSimpleString("OK")
+}
+
+// handleClientKill handles CLIENT KILL ip:port and CLIENT KILL with filters.
+// The first form replies OK, the second the number of clients killed.
+func (h *Handler) handleClientKill(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return clientArityError("kill")
+	}
+
+	if len(args) == 1 {
+		for _, session := range h.clients.list() {
+			if session.addr == string(args[0]) {
+				h.killClient(session)
+				return proto.NewSimpleString("OK")
+			}
+		}
+		return proto.NewError("ERR No such client")
+	}
+
+	if len(args)%2 != 0 {
+		return proto.NewError("ERR syntax error")
+	}
+
+	var (
+		id          int64
+		addr, laddr string
+		user        string
+		skipMe      = true
+	)
+	for i := 0; i < len(args); i += 2 {
+		value := string(args[i+1])
+		switch strings.ToUpper(string(args[i])) {
+		case "ID":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n <= 0 {
+				return proto.NewError("ERR client-id should be greater than 0")
+			}
+			id = n
+		case "ADDR":
+			addr = value
+		case "LADDR":
+			laddr = value
+		case "USER":
+			if h.acl.User(value) == nil {
+				return proto.NewError("ERR No such user '" + value + "'")
+			}
+			user = value
+		case "SKIPME":
+			switch strings.ToUpper(value) {
+			case "YES":
+				skipMe = true
+			case "NO":
+				skipMe = false
+			default:
+				return proto.NewError("ERR syntax error")
+			}
+		default:
+			return proto.NewError("ERR syntax error")
+		}
+	}
+
+	var killed int64
+	for _, session := range h.clients.list() {
+		if (id != 0 && session.id != id) ||
+			(addr != "" && session.addr != addr) ||
+			(laddr != "" && session.laddr != laddr) ||
+			(user != "" && session.userName() != user) ||
+			(skipMe && session == h.session) {
+			continue
+		}
+		h.killClient(session)
+		killed++
+	}
+	return proto.NewInteger(killed)
+}
+
+// killClient disconnects the client of session. A client killing itself is
+// disconnected once it has the reply.
+func (h *Handler) killClient(session *clientSession) {
+	if session == h.session {
+		h.closing = true
+		return
+	}
+	h.clients.kill(session)
+}
+
+// errPauseTimeout is the reply to CLIENT PAUSE with a timeout that is not a
+// number of milliseconds a duration can hold
+var errPauseTimeout = proto.NewError("ERR timeout is not an integer or out of range")
+
+// handleClientPause handles CLIENT PAUSE timeout [WRITE|ALL]
+func (h *Handler) handleClientPause(args [][]byte) *proto.Response {
+	if len(args) != 1 && len(args) != exactTwoArgs {
+		return clientArityError("pause")
+	}
+
+	timeout, err := strconv.ParseInt(string(args[0]), 10, 64)
+	if err != nil {
+		return errPauseTimeout
+	}
+	if timeout < 0 {
+		return proto.NewError("ERR timeout is negative")
+	}
+	duration, ok := expireDuration(timeout, time.Millisecond)
+	if !ok {
+		return errPauseTimeout
+	}
+
+	all := true
+	if len(args) == exactTwoArgs {
+		switch strings.ToUpper(string(args[1])) {
+		case "ALL":
+		case "WRITE":
+			all = false
+		default:
+			return proto.NewError("ERR syntax error")
+		}
+	}
+
+	h.clients.pause(duration, all)
+	return proto.NewSimpleString("OK")
+}
+
+// handleClientNoEvict handles CLIENT NO-EVICT ON|OFF. Clients are never
+// evicted, so the flag is only reported by CLIENT LIST, as its help says.
+func (h *Handler) handleClientNoEvict(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return clientArityError("no-evict")
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "ON":
+		h.noEvict = true
+	case "OFF":
+		h.noEvict = false
+	default:
+		return proto.NewError("ERR syntax error")
+	}
+	return proto.NewSimpleString("OK")
+}
+
+// handleClientReply handles CLIENT REPLY ON|OFF|SKIP. Only ON is answered:
+// OFF mutes every reply from then on, SKIP the reply to the next command.
+func (h *Handler) handleClientReply(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return clientArityError("reply")
+	}
+
+	switch mode := strings.ToUpper(string(args[0])); mode {
+	case replyOn:
+		h.replyMode, h.muted = replyOn, false
+	case replyOff:
+		h.replyMode, h.muted = replyOff, true
+	case replySkip:
+		h.skipNext, h.muted = true, true
+	default:
+		return proto.NewError("ERR syntax error")
+	}
+	return proto.NewSimpleString("OK")
+}
@@ -0,0 +1,141 @@
+package server_test
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+func TestHandler_CLIENT(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected *proto.Response
+	}{
+		{name: "GETNAME without name", args: []string{"GETNAME"}, expected: proto.NewNullBulkString()},
+		{name: "SETNAME", args: []string{"SETNAME", "worker"}, expected: proto.NewSimpleString("OK")},
+		{name: "SETNAME with a space", args: []string{"SETNAME", "my worker"},
+			expected: proto.NewError("ERR Client names cannot contain spaces, newlines or special characters.")},
+		{name: "NO-EVICT", args: []string{"NO-EVICT", "on"}, expected: proto.NewSimpleString("OK")},
+		{name: "NO-EVICT invalid", args: []string{"NO-EVICT", "maybe"}, expected: proto.NewError("ERR syntax error")},
+		{name: "PAUSE invalid timeout", args: []string{"PAUSE", "soon"},
+			expected: proto.NewError("ERR timeout is not an integer or out of range")},
+		{name: "PAUSE timeout overflowing a duration", args: []string{"PAUSE", "9223372036854775"},
+			expected: proto.NewError("ERR timeout is not an integer or out of range")},
+		{name: "PAUSE negative timeout", args: []string{"PAUSE", "-1"}, expected: proto.NewError("ERR timeout is negative")},
+		{name: "PAUSE invalid mode", args: []string{"PAUSE", "10", "READ"}, expected: proto.NewError("ERR syntax error")},
+		{name: "UNPAUSE", args: []string{"UNPAUSE"}, expected: proto.NewSimpleString("OK")},
+		{name: "REPLY invalid", args: []string{"REPLY", "LATER"}, expected: proto.NewError("ERR syntax error")},
+		{name: "KILL unknown address", args: []string{"KILL", "10.0.0.1:1234"}, expected: proto.NewError("ERR No such client")},
+		{name: "KILL invalid ID", args: []string{"KILL", "ID", "0"}, expected: proto.NewError("ERR client-id should be greater than 0")},
+		{name: "KILL unknown user", args: []string{"KILL", "USER", "nobody"}, expected: proto.NewError("ERR No such user 'nobody'")},
+		{name: "KILL unknown filter", args: []string{"KILL", "TYPE", "normal"}, expected: proto.NewError("ERR syntax error")},
+		{name: "KILL skips the caller", args: []string{"KILL", "USER", "default"}, expected: proto.NewInteger(0)},
+		{name: "LIST invalid type", args: []string{"LIST", "TYPE", "replica"}, expected: proto.NewError("ERR Unknown client type 'replica'")},
+		{name: "LIST invalid ID", args: []string{"LIST", "ID", "x"}, expected: proto.NewError("ERR Invalid client ID")},
+		{name: "LIST unknown ID", args: []string{"LIST", "ID", "999"}, expected: proto.NewBulkString("")},
+		{name: "ID with arguments", args: []string{"ID", "1"}, expected: proto.NewError("ERR wrong number of arguments for 'client|id' command")},
+		{name: "no subcommand", args: []string{}, expected: proto.NewError("ERR wrong number of arguments for 'client' command")},
+		{name: "unknown subcommand", args: []string{"NOPE"}, expected: proto.NewError("ERR unknown subcommand 'NOPE'. Try CLIENT HELP.")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createTestHandler(t)
+			resp := handler.HandleCommand(request("CLIENT", tt.args...))
+			if resp.Type != tt.expected.Type || resp.Data != tt.expected.Data {
+				t.Errorf("Expected %v, got %v", tt.expected, resp)
+			}
+		})
+	}
+}
+
+func TestHandler_CLIENT_Info(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	mustRun(t, handler, "CLIENT", "SETNAME", "worker")
+	mustRun(t, handler, "CLIENT", "NO-EVICT", "ON")
+	mustRun(t, handler, "SELECT", "3")
+
+	id := mustRun(t, handler, "CLIENT", "ID").Data.(int64)
+	if name := mustRun(t, handler, "CLIENT", "GETNAME").Data; name != "worker" {
+		t.Errorf("Expected name worker, got %v", name)
+	}
+
+	info := mustRun(t, handler, "CLIENT", "INFO").Data.(string)
+	pattern := regexp.MustCompile(`^id=` + strconv.FormatInt(id, 10) + ` addr= laddr= name=worker age=\d+ idle=\d+ ` +
+		`flags=e db=3 sub=0 psub=0 cmd=client user=default resp=2\n$`)
+	if !pattern.MatchString(info) {
+		t.Errorf("Unexpected CLIENT INFO %q", info)
+	}
+
+	// CLIENT LIST shows the same line for the only client
+	list := mustRun(t, handler, "CLIENT", "LIST").Data.(string)
+	if !pattern.MatchString(list) {
+		t.Errorf("Unexpected CLIENT LIST %q", list)
+	}
+	if list := mustRun(t, handler, "CLIENT", "LIST", "TYPE", "pubsub").Data; list != "" {
+		t.Errorf("Expected no Pub/Sub clients, got %q", list)
+	}
+
+	mustRun(t, handler, "SUBSCRIBE", "news")
+	mustRun(t, handler, "UNSUBSCRIBE")
+	mustRun(t, handler, "CLIENT", "SETNAME", "")
+	mustRun(t, handler, "CLIENT", "NO-EVICT", "OFF")
+	info = mustRun(t, handler, "CLIENT", "INFO").Data.(string)
+	if !strings.Contains(info, " name= ") || !strings.Contains(info, " flags=N ") {
+		t.Errorf("Expected the name and flags to be cleared, got %q", info)
+	}
+}
+
+func TestHandler_CLIENT_Reply(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	steps := []struct {
+		args  []string
+		muted bool
+	}{
+		{args: []string{"PING"}},
+		{args: []string{"CLIENT", "REPLY", "SKIP"}, muted: true},
+		{args: []string{"PING"}, muted: true},
+		{args: []string{"PING"}},
+		{args: []string{"CLIENT", "REPLY", "OFF"}, muted: true},
+		{args: []string{"PING"}, muted: true},
+		{args: []string{"CLIENT", "REPLY", "SKIP"}, muted: true},
+		{args: []string{"PING"}, muted: true},
+		{args: []string{"CLIENT", "REPLY", "ON"}},
+		{args: []string{"PING"}},
+	}
+	for _, step := range steps {
+		handler.HandleCommand(request(step.args[0], step.args[1:]...))
+		if handler.ReplyMuted() != step.muted {
+			t.Errorf("%v: expected muted %v, got %v", step.args, step.muted, handler.ReplyMuted())
+		}
+	}
+}
+
+func TestHandler_CLIENT_KillSelf(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	id := strconv.FormatInt(mustRun(t, handler, "CLIENT", "ID").Data.(int64), 10)
+
+	if resp := mustRun(t, handler, "CLIENT", "KILL", "ID", id); resp.Data != int64(0) || handler.Closing() {
+		t.Errorf("Expected the caller to be skipped, got %v", resp)
+	}
+	if resp := mustRun(t, handler, "CLIENT", "KILL", "ID", id, "SKIPME", "no"); resp.Data != int64(1) {
+		t.Errorf("Expected the caller to be killed, got %v", resp)
+	}
+	if !handler.Closing() {
+		t.Error("Expected the connection to close after the reply")
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+// handleSelect handles the SELECT command
+func (h *Handler) handleSelect(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return proto.NewError("ERR wrong number of arguments for 'select' command")
+	}
+
+	index, err := strconv.Atoi(string(args[0]))
+	if err != nil {
+		return proto.NewError("ERR value is not an integer or out of range")
+	}
+
+	db, err := h.store.DB(index)
+	if err != nil {
+		return storeError(err)
+	}
+
+	h.store = db
+	return proto.NewSimpleString("OK")
+}
+
+// handleMove handles the MOVE command
+func (h *Handler) handleMove(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'move' command")
+	}
+
+	index, err := strconv.Atoi(string(args[1]))
+	if err != nil {
+		return proto.NewError("ERR value is not an integer or out of range")
+	}
+
+	moved, err := h.store.Move(string(args[0]), index)
+	if err != nil {
+		return storeError(err)
+	}
+
+	return boolResponse(moved)
+}
+
+// handleSwapDB handles the SWAPDB command
+func (h *Handler) handleSwapDB(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'swapdb' command")
+	}
+
+	first, err := strconv.Atoi(string(args[0]))
+	if err != nil {
+		return proto.NewError("ERR invalid first DB index")
+	}
+	second, err := strconv.Atoi(string(args[1]))
+	if err != nil {
+		return proto.NewError("ERR invalid second DB index")
+	}
+
+	if err := h.store.SwapDB(first, second); err != nil {
+		return storeError(err)
+	}
+
+	return proto.NewSimpleString("OK")
+}
+
+// handleFlushDB handles the FLUSHDB command
+func (h *Handler) handleFlushDB(args [][]byte) *proto.Response {
+	async, errResp := parseFlushMode(args, "flushdb")
+	if errResp != nil {
+		return errResp
+	}
+
+	h.store.FlushDB(async)
+	return proto.NewSimpleString("OK")
+}
+
+// handleFlushAll handles the FLUSHALL command
+func (h *Handler) handleFlushAll(args [][]byte) *proto.Response {
+	async, errResp := parseFlushMode(args, "flushall")
+	if errResp != nil {
+		return errResp
+	}
+
+	h.store.FlushAll(async)
+	return proto.NewSimpleString("OK")
+}
+
+// parseFlushMode parses the optional ASYNC or SYNC argument of FLUSHDB and FLUSHALL
+func parseFlushMode(args [][]byte, command string) (bool, *proto.Response) {
+	switch len(args) {
+	case 0:
+		return false, nil
+	case 1:
+		switch strings.ToUpper(string(args[0])) {
+		case "ASYNC":
+			return true, nil
+		case "SYNC":
+			return false, nil
+		default:
+			return false, proto.NewError("ERR syntax error")
+		}
+	default:
+		return false, proto.NewError("ERR wrong number of arguments for '" + command + "' command")
+	}
+}
@@ -0,0 +1,107 @@
+package server_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+func TestHandler_SELECT(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("SET", "key", "zero"))
+
+	resp := handler.HandleCommand(request("SELECT", "1"))
+	if resp.Type != proto.SimpleString || resp.Data != "OK" {
+		t.Fatalf("Expected OK, got %v: %v", resp.Type, resp.Data)
+	}
+
+	resp = handler.HandleCommand(request("GET", "key"))
+	if resp.Type != proto.NullBulkString {
+		t.Errorf("Expected db1 not to see db0's key, got %v", resp.Data)
+	}
+
+	// Other connections keep their own selected database
+	other := createTestHandler(t)
+	other.HandleCommand(request("SELECT", "2"))
+	if resp := other.HandleCommand(request("DBSIZE")); resp.Data != int64(0) {
+		t.Errorf("Expected empty db2, got %v", resp.Data)
+	}
+
+	handler.HandleCommand(request("SELECT", "0"))
+	resp = handler.HandleCommand(request("GET", "key"))
+	if resp.Data != "zero" {
+		t.Errorf("Expected 'zero' back in db0, got %v", resp.Data)
+	}
+}
+
+func TestHandler_DatabaseCommands(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		setup    [][]string
+		command  []string
+		respType proto.ResponseType
+		expected any
+	}{
+		{name: "SELECT out of range", command: []string{"SELECT", "16"}, respType: proto.Error, expected: "ERR DB index is out of range"},
+		{name: "SELECT not a number", command: []string{"SELECT", "x"}, respType: proto.Error, expected: "ERR value is not an integer or out of range"},
+		{name: "MOVE", setup: [][]string{{"SET", "k", "v"}}, command: []string{"MOVE", "k", "1"}, respType: proto.Integer, expected: int64(1)},
+		{name: "MOVE missing key", command: []string{"MOVE", "k", "1"}, respType: proto.Integer, expected: int64(0)},
+		{name: "MOVE onto existing key", setup: [][]string{{"SELECT", "1"}, {"SET", "k", "v"}, {"SELECT", "0"}, {"SET", "k", "v"}}, command: []string{"MOVE", "k", "1"}, respType: proto.Integer, expected: int64(0)},
+		{name: "MOVE same database", setup: [][]string{{"SET", "k", "v"}}, command: []string{"MOVE", "k", "0"}, respType: proto.Error, expected: "ERR source and destination objects are the same"},
+		{name: "MOVE out of range", command: []string{"MOVE", "k", "-1"}, respType: proto.Error, expected: "ERR DB index is out of range"},
+		{name: "SWAPDB", setup: [][]string{{"SET", "k", "v"}, {"SWAPDB", "0", "1"}}, command: []string{"DBSIZE"}, respType: proto.Integer, expected: int64(0)},
+		{name: "SWAPDB invalid index", command: []string{"SWAPDB", "a", "1"}, respType: proto.Error, expected: "ERR invalid first DB index"},
+		{name: "SWAPDB out of range", command: []string{"SWAPDB", "0", "100"}, respType: proto.Error, expected: "ERR DB index is out of range"},
+		{name: "FLUSHDB", setup: [][]string{{"SET", "k", "v"}}, command: []string{"FLUSHDB"}, respType: proto.SimpleString, expected: "OK"},
+		{name: "FLUSHDB ASYNC", setup: [][]string{{"SET", "k", "v"}}, command: []string{"FLUSHDB", "async"}, respType: proto.SimpleString, expected: "OK"},
+		{name: "FLUSHALL SYNC", setup: [][]string{{"SET", "k", "v"}}, command: []string{"FLUSHALL", "SYNC"}, respType: proto.SimpleString, expected: "OK"},
+		{name: "FLUSHALL bad mode", command: []string{"FLUSHALL", "NOW"}, respType: proto.Error, expected: "ERR syntax error"},
+		{name: "FLUSHDB clears keys", setup: [][]string{{"SET", "k", "v"}, {"FLUSHDB"}}, command: []string{"DBSIZE"}, respType: proto.Integer, expected: int64(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createTestHandler(t)
+			for _, setup := range tt.setup {
+				handler.HandleCommand(request(setup[0], setup[1:]...))
+			}
+
+			resp := handler.HandleCommand(request(tt.command[0], tt.command[1:]...))
+			if resp.Type != tt.respType {
+				t.Fatalf("Expected response type %v, got %v (%v)", tt.respType, resp.Type, resp.Data)
+			}
+			if resp.Data != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_INFO_Keyspace(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("SET", "a", "v"))
+	handler.HandleCommand(request("SELECT", "5"))
+	handler.HandleCommand(request("SET", "b", "v", "EX", "100"))
+
+	resp := handler.HandleCommand(request("INFO"))
+	info := resp.Data.(string)
+
+	if !strings.Contains(info, "db0:keys=1,expires=0,avg_ttl=0") {
+		t.Errorf("Expected db0 keyspace line, got:\n%s", info)
+	}
+	if !strings.Contains(info, "db5:keys=1,expires=1,avg_ttl=") {
+		t.Errorf("Expected db5 keyspace line, got:\n%s", info)
+	}
+	if strings.Contains(info, "db1:") {
+		t.Errorf("Expected empty databases to be omitted, got:\n%s", info)
+	}
+}
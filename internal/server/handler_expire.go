@@ -0,0 +1,106 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// handleExpire handles the EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT commands.
+// unit is the unit of the time argument and absolute selects a unix timestamp
+// instead of a relative duration.
+func (h *Handler) handleExpire(args [][]byte, unit time.Duration, absolute bool, command string) *proto.Response {
+	if len(args) < exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for '" + command + "' command")
+	}
+
+	amount, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return proto.NewError("ERR value is not an integer or out of range")
+	}
+
+	condition, errResp := parseExpireCondition(args[2:])
+	if errResp != nil {
+		return errResp
+	}
+
+	// Work in unix milliseconds and reject anything that would overflow them
+	scale := int64(unit / time.Millisecond)
+	if amount > math.MaxInt64/scale || amount < math.MinInt64/scale {
+		return proto.NewError("ERR invalid expire time in '" + command + "' command")
+	}
+	millis := amount * scale
+	if !absolute {
+		now := time.Now().UnixMilli()
+		if millis > math.MaxInt64-now {
+			return proto.NewError("ERR invalid expire time in '" + command + "' command")
+		}
+		millis += now
+	}
+
+	return boolResponse(h.store.ExpireAt(string(args[0]), time.UnixMilli(millis), condition))
+}
+
+// parseExpireCondition parses the NX, XX, GT and LT flags of the EXPIRE family
+func parseExpireCondition(args [][]byte) (store.ExpireCondition, *proto.Response) {
+	condition := store.ExpireAlways
+	for _, arg := range args {
+		switch strings.ToUpper(string(arg)) {
+		case "NX":
+			condition |= store.ExpireNX
+		case "XX":
+			condition |= store.ExpireXX
+		case "GT":
+			condition |= store.ExpireGT
+		case "LT":
+			condition |= store.ExpireLT
+		default:
+			return condition, proto.NewError("ERR Unsupported option " + string(arg))
+		}
+	}
+
+	if condition&store.ExpireNX != 0 && condition != store.ExpireNX {
+		return condition, proto.NewError("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if condition&store.ExpireGT != 0 && condition&store.ExpireLT != 0 {
+		return condition, proto.NewError("ERR GT and LT options at the same time are not compatible")
+	}
+
+	return condition, nil
+}
+
+// handlePTTL handles the PTTL command
+func (h *Handler) handlePTTL(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return proto.NewError("ERR wrong number of arguments for 'pttl' command")
+	}
+
+	return proto.NewInteger(h.store.PTTL(string(args[0])))
+}
+
+// handleExpireTime handles the EXPIRETIME and PEXPIRETIME commands
+func (h *Handler) handleExpireTime(args [][]byte, unit time.Duration, command string) *proto.Response {
+	if len(args) != 1 {
+		return proto.NewError("ERR wrong number of arguments for '" + command + "' command")
+	}
+
+	at := h.store.ExpireTime(string(args[0]))
+	if at < 0 {
+		return proto.NewInteger(at)
+	}
+
+	return proto.NewInteger(at / int64(unit/time.Millisecond))
+}
+
+// handlePersist handles the PERSIST command
+func (h *Handler) handlePersist(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return proto.NewError("ERR wrong number of arguments for 'persist' command")
+	}
+
+	return boolResponse(h.store.Persist(string(args[0])))
+}
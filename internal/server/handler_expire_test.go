@@ -0,0 +1,89 @@
+package server_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+func TestHandler_ExpireCommands(t *testing.T) {
+	t.Parallel()
+
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name     string
+		setup    [][]string
+		command  []string
+		respType proto.ResponseType
+		expected any
+	}{
+		{name: "PEXPIRE", setup: [][]string{{"SET", "k", "v"}}, command: []string{"PEXPIRE", "k", "5000"}, respType: proto.Integer, expected: int64(1)},
+		{name: "EXPIREAT", setup: [][]string{{"SET", "k", "v"}}, command: []string{"EXPIREAT", "k", future}, respType: proto.Integer, expected: int64(1)},
+		{name: "PEXPIREAT missing key", command: []string{"PEXPIREAT", "k", future + "000"}, respType: proto.Integer, expected: int64(0)},
+		{name: "EXPIRE NX without TTL", setup: [][]string{{"SET", "k", "v"}}, command: []string{"EXPIRE", "k", "100", "NX"}, respType: proto.Integer, expected: int64(1)},
+		{name: "EXPIRE NX with TTL", setup: [][]string{{"SET", "k", "v", "EX", "10"}}, command: []string{"EXPIRE", "k", "100", "nx"}, respType: proto.Integer, expected: int64(0)},
+		{name: "EXPIRE XX without TTL", setup: [][]string{{"SET", "k", "v"}}, command: []string{"EXPIRE", "k", "100", "XX"}, respType: proto.Integer, expected: int64(0)},
+		{name: "EXPIRE GT later", setup: [][]string{{"SET", "k", "v", "EX", "10"}}, command: []string{"EXPIRE", "k", "100", "GT"}, respType: proto.Integer, expected: int64(1)},
+		{name: "EXPIRE GT without TTL", setup: [][]string{{"SET", "k", "v"}}, command: []string{"EXPIRE", "k", "100", "GT"}, respType: proto.Integer, expected: int64(0)},
+		{name: "EXPIRE LT without TTL", setup: [][]string{{"SET", "k", "v"}}, command: []string{"EXPIRE", "k", "100", "LT"}, respType: proto.Integer, expected: int64(1)},
+		{name: "EXPIRE LT XX without TTL", setup: [][]string{{"SET", "k", "v"}}, command: []string{"EXPIRE", "k", "100", "LT", "XX"}, respType: proto.Integer, expected: int64(0)},
+		{name: "EXPIRE NX and GT", command: []string{"EXPIRE", "k", "100", "NX", "GT"}, respType: proto.Error, expected: "ERR NX and XX, GT or LT options at the same time are not compatible"},
+		{name: "EXPIRE GT and LT", command: []string{"EXPIRE", "k", "100", "GT", "LT"}, respType: proto.Error, expected: "ERR GT and LT options at the same time are not compatible"},
+		{name: "EXPIRE unknown option", command: []string{"EXPIRE", "k", "100", "FOO"}, respType: proto.Error, expected: "ERR Unsupported option FOO"},
+		{name: "EXPIRE overflow", command: []string{"EXPIRE", "k", "9223372036854775807"}, respType: proto.Error, expected: "ERR invalid expire time in 'expire' command"},
+		{name: "EXPIRE negative deletes key", setup: [][]string{{"SET", "k", "v"}, {"EXPIRE", "k", "-1"}}, command: []string{"EXISTS", "k"}, respType: proto.Integer, expected: int64(0)},
+		{name: "PEXPIRE zero deletes key", setup: [][]string{{"SET", "k", "v"}, {"PEXPIRE", "k", "0"}}, command: []string{"DBSIZE"}, respType: proto.Integer, expected: int64(0)},
+		{name: "EXPIREAT in the past", setup: [][]string{{"SET", "k", "v"}}, command: []string{"EXPIREAT", "k", "1"}, respType: proto.Integer, expected: int64(1)},
+		{name: "PTTL missing key", command: []string{"PTTL", "k"}, respType: proto.Integer, expected: int64(-2)},
+		{name: "PTTL persistent key", setup: [][]string{{"SET", "k", "v"}}, command: []string{"PTTL", "k"}, respType: proto.Integer, expected: int64(-1)},
+		{name: "EXPIRETIME", setup: [][]string{{"SET", "k", "v"}, {"EXPIREAT", "k", future}}, command: []string{"EXPIRETIME", "k"}, respType: proto.Integer, expected: mustParseInt(future)},
+		{name: "PEXPIRETIME", setup: [][]string{{"SET", "k", "v"}, {"EXPIREAT", "k", future}}, command: []string{"PEXPIRETIME", "k"}, respType: proto.Integer, expected: mustParseInt(future) * 1000},
+		{name: "EXPIRETIME persistent key", setup: [][]string{{"SET", "k", "v"}}, command: []string{"EXPIRETIME", "k"}, respType: proto.Integer, expected: int64(-1)},
+		{name: "PERSIST", setup: [][]string{{"SET", "k", "v", "EX", "10"}}, command: []string{"PERSIST", "k"}, respType: proto.Integer, expected: int64(1)},
+		{name: "PERSIST persistent key", setup: [][]string{{"SET", "k", "v"}}, command: []string{"PERSIST", "k"}, respType: proto.Integer, expected: int64(0)},
+		{name: "PERSIST wrong args", command: []string{"PERSIST"}, respType: proto.Error, expected: "ERR wrong number of arguments for 'persist' command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createTestHandler(t)
+			for _, setup := range tt.setup {
+				handler.HandleCommand(request(setup[0], setup[1:]...))
+			}
+
+			resp := handler.HandleCommand(request(tt.command[0], tt.command[1:]...))
+			if resp.Type != tt.respType {
+				t.Fatalf("Expected response type %v, got %v (%v)", tt.respType, resp.Type, resp.Data)
+			}
+			if resp.Data != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_PTTL(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("SET", "k", "v"))
+	handler.HandleCommand(request("PEXPIRE", "k", "1500"))
+
+	resp := handler.HandleCommand(request("PTTL", "k"))
+	if ttl := resp.Data.(int64); ttl <= 1000 || ttl > 1500 {
+		t.Errorf("Expected PTTL between 1000 and 1500, got %d", ttl)
+	}
+}
+
+func mustParseInt(s string) int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+// handleHello handles HELLO [protover [AUTH username password] [SETNAME clientname]].
+// It switches the connection's protocol and replies with a map describing the
+// server, in the newly selected protocol. Nothing changes if any option fails.
+// Unauthenticated clients must authenticate with the AUTH option.
+func (h *Handler) handleHello(args [][]byte) *proto.Response {
+	protocol := h.protocol
+	if len(args) > 0 {
+		version, err := strconv.Atoi(string(args[0]))
+		if err != nil {
+			return proto.NewError("ERR Protocol version is not an integer or out of range")
+		}
+		if version != proto.RESP2 && version != proto.RESP3 {
+			return proto.NewError("NOPROTO unsupported protocol version")
+		}
+		protocol = version
+	}
+
+	name, setName, user := "", false, ""
+	if h.currentUser() != nil {
+		user = h.user
+	}
+	for i := 1; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		switch {
+		case option == "AUTH" && i+2 < len(args):
+			username := string(args[i+1])
+			if !h.checkCredentials(username, string(args[i+2])) {
+				return errWrongPass
+			}
+			user = username
+			i += 2
+		case option == "SETNAME" && i+1 < len(args):
+			name = string(args[i+1])
+			if !validClientName(name) {
+				return proto.NewError("ERR Client names cannot contain spaces, newlines or special characters.")
+			}
+			setName = true
+			i++
+		default:
+			return proto.NewError("ERR Syntax error in HELLO option '" + string(args[i]) + "'")
+		}
+	}
+
+	if user == "" {
+		return proto.NewError("NOAUTH HELLO must be called with the client already authenticated, " +
+			"otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate " +
+			"the client and select the RESP protocol version at the same time")
+	}
+
+	h.protocol = protocol
+	h.user = user
+	if setName {
+		h.name = name
+	}
+
+	return proto.NewMap([]any{
+		"server", "kv-stash",
+		"version", redisCompatVersion,
+		"proto", int64(h.protocol),
+		"id", h.id,
+		"mode", "standalone",
+		"role", "master",
+		"modules", []any{},
+	})
+}
+
+// validClientName reports whether name only holds printable, non-space ASCII
+func validClientName(name string) bool {
+	for i := range len(name) {
+		if name[i] < '!' || name[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package server_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// createPasswordHandler creates a handler whose server requires password
+func createPasswordHandler(t *testing.T, password string) *server.Handler {
+	t.Helper()
+
+	logger := obs.NewLogger(false)
+	s, err := store.New(&store.Config{Shards: 4}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	config := server.DefaultConfig()
+	config.Server.AuthPassword = password
+	return server.NewHandler(s, config, logger)
+}
+
+// noAuthHello is the error for HELLO without AUTH before authenticating
+const noAuthHello = "NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time"
+
+func TestHandler_HELLO(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []string
+		protocol int
+		errorMsg string
+	}{
+		{name: "switch to RESP3", args: []string{"3", "AUTH", "default", "secret"}, protocol: 3},
+		{name: "stay on RESP2", args: []string{"2", "AUTH", "default", "secret"}, protocol: 2},
+		{name: "unauthenticated without arguments", args: []string{}, protocol: 2, errorMsg: noAuthHello},
+		{name: "unauthenticated switch to RESP3", args: []string{"3"}, protocol: 2, errorMsg: noAuthHello},
+		{name: "AUTH and SETNAME", args: []string{"3", "AUTH", "default", "secret", "SETNAME", "app"}, protocol: 3},
+		{name: "unsupported version", args: []string{"4"}, protocol: 2, errorMsg: "NOPROTO unsupported protocol version"},
+		{name: "version not a number", args: []string{"three"}, protocol: 2, errorMsg: "ERR Protocol version is not an integer or out of range"},
+		{name: "wrong password", args: []string{"3", "AUTH", "default", "nope"}, protocol: 2, errorMsg: "WRONGPASS invalid username-password pair or user is disabled."},
+		{name: "unknown user", args: []string{"3", "AUTH", "admin", "secret"}, protocol: 2, errorMsg: "WRONGPASS invalid username-password pair or user is disabled."},
+		{name: "AUTH missing password", args: []string{"3", "AUTH", "default"}, protocol: 2, errorMsg: "ERR Syntax error in HELLO option 'AUTH'"},
+		{name: "invalid client name", args: []string{"3", "SETNAME", "my app"}, protocol: 2, errorMsg: "ERR Client names cannot contain spaces, newlines or special characters."},
+		{name: "unknown option", args: []string{"3", "FOO"}, protocol: 2, errorMsg: "ERR Syntax error in HELLO option 'FOO'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createPasswordHandler(t, "secret")
+			resp := handler.HandleCommand(request("HELLO", tt.args...))
+
+			if tt.errorMsg != "" {
+				if resp.Type != proto.Error || resp.Data != tt.errorMsg {
+					t.Errorf("Expected error %q, got %v: %v", tt.errorMsg, resp.Type, resp.Data)
+				}
+			} else if resp.Type != proto.Map {
+				t.Fatalf("Expected Map response, got %v: %v", resp.Type, resp.Data)
+			}
+			if got := handler.Protocol(); got != tt.protocol {
+				t.Errorf("Expected protocol %d, got %d", tt.protocol, got)
+			}
+		})
+	}
+}
+
+func TestHandler_HELLO_Reply(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	resp := handler.HandleCommand(request("HELLO", "3", "AUTH", "default", "anything", "SETNAME", "worker-1"))
+	if resp.Type != proto.Map {
+		t.Fatalf("Expected Map response, got %v: %v", resp.Type, resp.Data)
+	}
+
+	fields := map[string]any{}
+	pairs := resp.Data.([]any)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fields[pairs[i].(string)] = pairs[i+1]
+	}
+
+	expected := map[string]any{
+		"server":  "kv-stash",
+		"version": "7.2.0",
+		"proto":   int64(3),
+		"id":      fields["id"],
+		"mode":    "standalone",
+		"role":    "master",
+		"modules": []any{},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected HELLO fields %v, got %v", expected, fields)
+	}
+	if id, ok := fields["id"].(int64); !ok || id <= 0 {
+		t.Errorf("Expected a positive client id, got %v", fields["id"])
+	}
+	if name := handler.Name(); name != "worker-1" {
+		t.Errorf("Expected client name worker-1, got %q", name)
+	}
+}
+
+func TestHandler_RESP3_Replies(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	if resp := handler.HandleCommand(request("INFO", "server")); resp.Type != proto.BulkString {
+		t.Errorf("Expected INFO to be a bulk string under RESP2, got %v", resp.Type)
+	}
+
+	handler.HandleCommand(request("HELLO", "3"))
+	resp := handler.HandleCommand(request("INFO", "server"))
+	if resp.Type != proto.Map {
+		t.Fatalf("Expected INFO to be a map under RESP3, got %v", resp.Type)
+	}
+
+	// RESP3 clients may run any command while subscribed
+	handler.HandleCommand(request("SUBSCRIBE", "news"))
+	t.Cleanup(handler.Close)
+	if resp := handler.HandleCommand(request("SET", "k", "v")); resp.Type != proto.SimpleString {
+		t.Errorf("Expected SET to run while subscribed under RESP3, got %v: %v", resp.Type, resp.Data)
+	}
+	if resp := handler.HandleCommand(request("PING")); resp.Type != proto.SimpleString {
+		t.Errorf("Expected a plain PONG under RESP3, got %v: %v", resp.Type, resp.Data)
+	}
+}
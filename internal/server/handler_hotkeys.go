@@ -0,0 +1,74 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+const (
+	// defaultHotKeysCount is the number of keys HOTKEYS returns when COUNT
+	// is not given
+	defaultHotKeysCount = 10
+	// percent converts a traffic share to a percentage
+	percent = 100
+)
+
+// handleHotKeys handles the HOTKEYS [COUNT n] [WINDOW seconds] command. Each
+// reply entry is the key, its estimated access count over the window and its
+// share of the sampled traffic as a percentage.
+func (h *Handler) handleHotKeys(args [][]byte) *proto.Response {
+	maxWindow := h.store.HotKeyWindow()
+	if maxWindow == 0 {
+		return proto.NewError("ERR " + store.ErrHotKeysDisabled.Error())
+	}
+
+	count := defaultHotKeysCount
+	window := maxWindow
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return proto.NewError("ERR syntax error")
+		}
+
+		option := strings.ToUpper(string(args[i]))
+		if option != "COUNT" && option != "WINDOW" {
+			return proto.NewError("ERR syntax error")
+		}
+		n, err := strconv.Atoi(string(args[i+1]))
+		if err != nil {
+			return proto.NewError("ERR value is not an integer or out of range")
+		}
+
+		switch option {
+		case "COUNT":
+			if n < 1 {
+				return proto.NewError("ERR COUNT must be positive")
+			}
+			count = n
+		case "WINDOW":
+			maxSeconds := int(maxWindow / time.Second)
+			if n < 1 || n > maxSeconds {
+				return proto.NewError("ERR WINDOW must be between 1 and " + strconv.Itoa(maxSeconds) + " seconds")
+			}
+			window = time.Duration(n) * time.Second
+		}
+	}
+
+	hot, err := h.store.HotKeys(count, window)
+	if err != nil {
+		return storeError(err)
+	}
+
+	result := make([]any, len(hot))
+	for i, key := range hot {
+		result[i] = []any{
+			key.Key,
+			key.Count,
+			strconv.FormatFloat(key.Share*percent, 'f', 2, 64),
+		}
+	}
+	return proto.NewArray(result)
+}
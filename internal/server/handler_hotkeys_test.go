@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// createHotKeysHandler creates a handler whose store samples every access
+func createHotKeysHandler(t *testing.T) *server.Handler {
+	t.Helper()
+
+	logger := obs.NewLogger(false)
+	s, err := store.New(&store.Config{
+		Shards:           4,
+		HotKeySampleRate: 1,
+		HotKeyWindow:     10 * time.Second,
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	return server.NewHandler(s, server.DefaultConfig(), logger)
+}
+
+func TestHandler_HOTKEYS(t *testing.T) {
+	t.Parallel()
+
+	handler := createHotKeysHandler(t)
+	for range 8 {
+		handler.HandleCommand(request("GET", "hot"))
+	}
+	handler.HandleCommand(request("SET", "warm", "1"))
+	handler.HandleCommand(request("GET", "warm"))
+
+	resp := handler.HandleCommand(request("HOTKEYS"))
+	if resp.Type != proto.Array {
+		t.Fatalf("Expected Array response, got %v: %v", resp.Type, resp.Data)
+	}
+
+	entries := resp.Data.([]any)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 hot keys, got %v", entries)
+	}
+	expected := [][]any{
+		{"hot", int64(8), "80.00"},
+		{"warm", int64(2), "20.00"},
+	}
+	for i, want := range expected {
+		got := entries[i].([]any)
+		for j := range want {
+			if got[j] != want[j] {
+				t.Errorf("Entry %d: expected %v, got %v", i, want, got)
+				break
+			}
+		}
+	}
+
+	resp = handler.HandleCommand(request("HOTKEYS", "COUNT", "1", "WINDOW", "5"))
+	if entries := resp.Data.([]any); len(entries) != 1 || entries[0].([]any)[0] != "hot" {
+		t.Errorf("Expected only the hottest key with COUNT 1, got %v", resp.Data)
+	}
+}
+
+func TestHandler_HOTKEYS_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "missing value", args: []string{"COUNT"}, expected: "ERR syntax error"},
+		{name: "unknown option", args: []string{"LIMIT", "5"}, expected: "ERR syntax error"},
+		{name: "count not integer", args: []string{"COUNT", "many"}, expected: "ERR value is not an integer or out of range"},
+		{name: "count zero", args: []string{"COUNT", "0"}, expected: "ERR COUNT must be positive"},
+		{name: "window too long", args: []string{"WINDOW", "11"}, expected: "ERR WINDOW must be between 1 and 10 seconds"},
+		{name: "window zero", args: []string{"window", "0"}, expected: "ERR WINDOW must be between 1 and 10 seconds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp := createHotKeysHandler(t).HandleCommand(request("HOTKEYS", tt.args...))
+			if resp.Type != proto.Error || resp.Data != tt.expected {
+				t.Errorf("Expected error %q, got %v: %v", tt.expected, resp.Type, resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_HOTKEYS_Disabled(t *testing.T) {
+	t.Parallel()
+
+	resp := createTestHandler(t).HandleCommand(request("HOTKEYS"))
+	if resp.Type != proto.Error || resp.Data != "ERR hot key tracking is disabled" {
+		t.Errorf("Expected disabled error, got %v: %v", resp.Type, resp.Data)
+	}
+}
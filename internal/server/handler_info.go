@@ -0,0 +1,249 @@
+package server
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+const (
+	// serverVersion is the kv-stash version reported by INFO
+	serverVersion = "dev"
+	// redisCompatVersion is the Redis version kv-stash reports compatibility
+	// with; tools such as redis_exporter use it for feature detection
+	redisCompatVersion = "7.2.0"
+
+	secondsPerDay = 24 * 60 * 60
+)
+
+// infoSections lists the INFO sections in output order and whether the
+// default selection includes them
+var infoSections = []struct {
+	name      string
+	isDefault bool
+}{
+	{name: "server", isDefault: true},
+	{name: "clients", isDefault: true},
+	{name: "memory", isDefault: true},
+	{name: "persistence", isDefault: true},
+	{name: "stats", isDefault: true},
+	{name: "replication", isDefault: true},
+	{name: "commandstats", isDefault: false},
+	{name: "keyspace", isDefault: true},
+}
+
+// handleInfo handles the INFO command
+func (h *Handler) handleInfo(args [][]byte) *proto.Response {
+	selected := make(map[string]bool)
+	if len(args) == 0 {
+		args = [][]byte{[]byte("default")}
+	}
+	for _, arg := range args {
+		switch name := strings.ToLower(string(arg)); name {
+		case "default", "all", "everything":
+			for _, section := range infoSections {
+				if section.isDefault || name != "default" {
+					selected[section.name] = true
+				}
+			}
+		default:
+			selected[name] = true
+		}
+	}
+
+	// RESP3 clients get a map of section names to maps of fields, RESP2
+	// clients the text form
+	var sections []string
+	var pairs []any
+	for _, section := range infoSections {
+		if !selected[section.name] {
+			continue
+		}
+		lines := h.infoSection(section.name)
+		if h.protocol == proto.RESP3 {
+			pairs = append(pairs, section.name, infoFields(lines))
+		} else {
+			sections = append(sections, strings.Join(lines, "\r\n"))
+		}
+	}
+
+	if h.protocol == proto.RESP3 {
+		return proto.NewMap(pairs)
+	}
+	return proto.NewBulkString(strings.Join(sections, "\r\n\r\n"))
+}
+
+// infoFields converts the lines of an INFO section, a header followed by
+// name:value fields, into a map of field names to their text values
+func infoFields(lines []string) *proto.Response {
+	fields := make([]any, 0, 2*len(lines))
+	for _, line := range lines[1:] {
+		name, value, _ := strings.Cut(line, ":")
+		fields = append(fields, name, value)
+	}
+	return proto.NewMap(fields)
+}
+
+// infoSection returns the header and fields of one INFO section
+func (h *Handler) infoSection(name string) []string {
+	switch name {
+	case "server":
+		return h.infoServer()
+	case "clients":
+		return []string{
+			"# Clients",
+			"connected_clients:" + strconv.FormatInt(h.stats.ConnectedClients(), 10),
+			"maxclients:" + strconv.Itoa(h.config.Limits.MaxClients),
+			"blocked_clients:0",
+		}
+	case "memory":
+		return h.infoMemory()
+	case "persistence":
+		return h.infoPersistence()
+	case "stats":
+		return []string{
+			"# Stats",
+			"total_connections_received:" + strconv.FormatInt(h.stats.TotalConnections(), 10),
+			"total_commands_processed:" + strconv.FormatInt(h.stats.TotalCommands(), 10),
+			"rejected_connections:" + strconv.FormatInt(h.stats.RejectedConnections(), 10),
+			"acl_access_denied_auth:" + strconv.FormatInt(h.stats.AuthFailures(), 10),
+			"expired_keys:" + strconv.FormatInt(h.store.GetExpiredKeysCount(), 10),
+			"evicted_keys:" + strconv.FormatInt(h.store.GetEvictedKeysCount(), 10),
+			"expiry_index_keys:" + strconv.FormatInt(h.store.ExpiryIndexSize(), 10),
+		}
+	case "replication":
+		role := "master"
+		if h.config.Replication.Role == "follower" {
+			role = "slave"
+		}
+		return []string{
+			"# Replication",
+			"role:" + role,
+			"connected_slaves:0",
+		}
+	case "commandstats":
+		return h.infoCommandStats()
+	case "keyspace":
+		return h.infoKeyspace()
+	default:
+		return nil
+	}
+}
+
+// infoServer returns the server section
+func (h *Handler) infoServer() []string {
+	uptime := int64(h.stats.Uptime().Seconds())
+
+	port := ""
+	if _, p, err := net.SplitHostPort(h.config.Server.ListenAddr); err == nil {
+		port = p
+	}
+
+	return []string{
+		"# Server",
+		"kv_stash_version:" + serverVersion,
+		"redis_version:" + redisCompatVersion,
+		"redis_mode:standalone",
+		"os:" + runtime.GOOS,
+		"arch_bits:" + strconv.Itoa(strconv.IntSize),
+		"go_version:" + runtime.Version(),
+		"process_id:" + strconv.Itoa(os.Getpid()),
+		"run_id:" + h.stats.runID,
+		"tcp_port:" + port,
+		"server_time_usec:" + strconv.FormatInt(time.Now().UnixMicro(), 10),
+		"uptime_in_seconds:" + strconv.FormatInt(uptime, 10),
+		"uptime_in_days:" + strconv.FormatInt(uptime/secondsPerDay, 10),
+	}
+}
+
+// infoMemory returns the memory section
+func (h *Handler) infoMemory() []string {
+	heapAlloc, sys := h.stats.memory()
+	used := h.store.MemoryUsage()
+	maxMemory := h.config.Storage.MaxMemoryBytes
+
+	return []string{
+		"# Memory",
+		"used_memory:" + strconv.FormatInt(used, 10),
+		"used_memory_human:" + humanBytes(used),
+		"used_memory_heap:" + strconv.FormatUint(heapAlloc, 10),
+		"used_memory_sys:" + strconv.FormatUint(sys, 10),
+		"maxmemory:" + strconv.FormatInt(maxMemory, 10),
+		"maxmemory_human:" + humanBytes(maxMemory),
+		"maxmemory_policy:" + h.config.Storage.EvictionPolicy,
+		"lazyfree_pending_objects:" + strconv.FormatInt(h.store.LazyFreePending(), 10),
+	}
+}
+
+// infoPersistence returns the persistence section. Snapshots and the AOF are
+// not implemented yet, so only their configuration is reported.
+func (h *Handler) infoPersistence() []string {
+	return []string{
+		"# Persistence",
+		"loading:0",
+		"rdb_changes_since_last_save:0",
+		"rdb_bgsave_in_progress:0",
+		"rdb_last_save_time:" + strconv.FormatInt(h.stats.startTime.Unix(), 10),
+		"aof_enabled:" + boolInfo(h.config.Persistence.AOF.Enabled),
+		"aof_rewrite_in_progress:0",
+	}
+}
+
+// infoCommandStats returns the commandstats section
+func (h *Handler) infoCommandStats() []string {
+	lines := []string{"# Commandstats"}
+	for _, stat := range h.stats.CommandStats() {
+		perCall := 0.0
+		if stat.Calls > 0 {
+			perCall = float64(stat.Usec) / float64(stat.Calls)
+		}
+		lines = append(lines, "cmdstat_"+stat.Name+
+			":calls="+strconv.FormatInt(stat.Calls, 10)+
+			",usec="+strconv.FormatInt(stat.Usec, 10)+
+			",usec_per_call="+strconv.FormatFloat(perCall, 'f', 2, 64)+
+			",rejected_calls="+strconv.FormatInt(stat.RejectedCalls, 10)+
+			",failed_calls="+strconv.FormatInt(stat.FailedCalls, 10))
+	}
+	return lines
+}
+
+// infoKeyspace returns the keyspace section with a line per non-empty database
+func (h *Handler) infoKeyspace() []string {
+	lines := []string{"# Keyspace"}
+	for _, db := range h.store.Keyspace() {
+		lines = append(lines, "db"+strconv.Itoa(db.DB)+
+			":keys="+strconv.FormatInt(db.Keys, 10)+
+			",expires="+strconv.FormatInt(db.Expires, 10)+
+			",avg_ttl="+strconv.FormatInt(db.AvgTTL.Milliseconds(), 10))
+	}
+	return lines
+}
+
+// humanBytes formats a byte count the way Redis does, e.g. 1.50M
+func humanBytes(n int64) string {
+	const unit = 1024
+	value := float64(n)
+	for _, suffix := range []string{"B", "K", "M", "G", "T"} {
+		if value < unit || suffix == "T" {
+			if suffix == "B" {
+				return strconv.FormatInt(n, 10) + "B"
+			}
+			return strconv.FormatFloat(value, 'f', 2, 64) + suffix
+		}
+		value /= unit
+	}
+	return ""
+}
+
+// boolInfo formats a flag as INFO does
+func boolInfo(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
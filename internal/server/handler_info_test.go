@@ -0,0 +1,187 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+func info(t *testing.T, handler interface {
+	HandleCommand(*proto.Request) *proto.Response
+}, args ...string,
+) string {
+	t.Helper()
+
+	resp := handler.HandleCommand(request("INFO", args...))
+	if resp.Type != proto.BulkString {
+		t.Fatalf("Expected BulkString response, got %v: %v", resp.Type, resp.Data)
+	}
+	return resp.Data.(string)
+}
+
+func TestHandler_INFO_Sections(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []string
+		included []string
+		excluded []string
+	}{
+		{
+			name:     "default",
+			included: []string{"# Server", "# Clients", "# Memory", "# Persistence", "# Stats", "# Replication", "# Keyspace"},
+			excluded: []string{"# Commandstats"},
+		},
+		{name: "single section", args: []string{"memory"}, included: []string{"# Memory", "used_memory:"}, excluded: []string{"# Server", "# Keyspace"}},
+		{name: "case insensitive", args: []string{"STATS"}, included: []string{"# Stats", "expired_keys:0"}, excluded: []string{"# Memory"}},
+		{name: "several sections", args: []string{"clients", "keyspace"}, included: []string{"# Clients", "# Keyspace"}, excluded: []string{"# Memory"}},
+		{name: "all", args: []string{"all"}, included: []string{"# Server", "# Commandstats", "# Keyspace"}},
+		{name: "everything", args: []string{"everything"}, included: []string{"# Server", "# Commandstats", "# Keyspace"}},
+		{name: "commandstats", args: []string{"commandstats"}, included: []string{"# Commandstats"}, excluded: []string{"# Server"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			output := info(t, createTestHandler(t), tt.args...)
+			for _, section := range tt.included {
+				if !strings.Contains(output, section) {
+					t.Errorf("Expected %q in INFO %v, got:\n%s", section, tt.args, output)
+				}
+			}
+			for _, section := range tt.excluded {
+				if strings.Contains(output, section) {
+					t.Errorf("Did not expect %q in INFO %v, got:\n%s", section, tt.args, output)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_INFO_UnknownSection(t *testing.T) {
+	t.Parallel()
+
+	if output := info(t, createTestHandler(t), "nosuchsection"); output != "" {
+		t.Errorf("Expected empty INFO for unknown section, got %q", output)
+	}
+}
+
+func TestHandler_INFO_Stats(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("SET", "a", "1"))
+	handler.HandleCommand(request("SET", "b", "2", "EX", "100"))
+	handler.HandleCommand(request("GET", "a"))
+	handler.HandleCommand(request("GET"))
+	handler.HandleCommand(request("NOSUCHCOMMAND"))
+
+	output := info(t, handler, "all")
+
+	for _, field := range []string{
+		"total_commands_processed:4",
+		"cmdstat_set:calls=2,",
+		"cmdstat_get:calls=2,",
+		"failed_calls=1",
+		"db0:keys=2,expires=1,avg_ttl=",
+		"maxmemory_policy:noeviction",
+		"tcp_port:6380",
+	} {
+		if !strings.Contains(output, field) {
+			t.Errorf("Expected %q in INFO, got:\n%s", field, output)
+		}
+	}
+
+	if strings.Contains(output, "cmdstat_nosuchcommand") {
+		t.Errorf("Expected unknown commands to be left out of commandstats")
+	}
+	if strings.Contains(output, "used_memory:0\r\n") {
+		t.Errorf("Expected used_memory to account for stored keys")
+	}
+}
+
+// decodeRESP3 decodes one RESP3 reply made of maps, arrays, strings and
+// integers, as a client would
+func decodeRESP3(t *testing.T, r *bufio.Reader) any {
+	t.Helper()
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read a reply: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	n, _ := strconv.Atoi(line[1:])
+
+	switch line[0] {
+	case '%':
+		m := make(map[string]any, n)
+		for range n {
+			key, ok := decodeRESP3(t, r).(string)
+			if !ok {
+				t.Fatalf("Expected a string map key")
+			}
+			m[key] = decodeRESP3(t, r)
+		}
+		return m
+	case '*':
+		arr := make([]any, n)
+		for i := range arr {
+			arr[i] = decodeRESP3(t, r)
+		}
+		return arr
+	case '$':
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			t.Fatalf("Failed to read a bulk string: %v", err)
+		}
+		return string(data[:n])
+	case '+':
+		return line[1:]
+	case ':':
+		return int64(n)
+	default:
+		t.Fatalf("Unexpected reply %q", line)
+		return nil
+	}
+}
+
+func TestHandler_INFO_RESP3(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("HELLO", "3"))
+	handler.HandleCommand(request("SET", "k", "v"))
+
+	resp := handler.HandleCommand(request("INFO", "server", "keyspace", "commandstats"))
+	wire, err := proto.AppendResponse(nil, resp, proto.RESP3)
+	if err != nil {
+		t.Fatalf("Failed to encode INFO: %v", err)
+	}
+	if wire[0] != '%' {
+		t.Fatalf("Expected a RESP3 map, got %q", wire)
+	}
+
+	sections, ok := decodeRESP3(t, bufio.NewReader(bytes.NewReader(wire))).(map[string]any)
+	if !ok || len(sections) != 3 {
+		t.Fatalf("Expected a map of 3 sections, got %v", sections)
+	}
+	server, _ := sections["server"].(map[string]any)
+	if server["redis_version"] != "7.2.0" || server["redis_mode"] != "standalone" {
+		t.Errorf("Expected server fields, got %v", server)
+	}
+	keyspace, _ := sections["keyspace"].(map[string]any)
+	if keyspace["db0"] != "keys=1,expires=0,avg_ttl=0" {
+		t.Errorf("Expected the db0 field, got %v", keyspace)
+	}
+	commands, _ := sections["commandstats"].(map[string]any)
+	if set, _ := commands["cmdstat_set"].(string); !strings.HasPrefix(set, "calls=1,") {
+		t.Errorf("Expected the SET command stats, got %v", commands)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// defaultAnalyzeTop is the number of keys and prefixes MEMORY ANALYZE lists
+// when TOP is not given
+const defaultAnalyzeTop = 10
+
+// handleType handles the TYPE command
+func (h *Handler) handleType(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return proto.NewError("ERR wrong number of arguments for 'type' command")
+	}
+
+	info, exists := h.store.Inspect(string(args[0]))
+	if !exists {
+		return proto.NewSimpleString("none")
+	}
+
+	return proto.NewSimpleString(info.Type.String())
+}
+
+// handleObject handles the OBJECT command
+func (h *Handler) handleObject(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'object' command")
+	}
+
+	subcommand := strings.ToUpper(string(args[0]))
+	if subcommand == "HELP" {
+		return proto.NewArray([]any{
+			"OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"ENCODING <key>",
+			"    Return the kind of internal representation used in order to store the value associated with a <key>.",
+			"FREQ <key>",
+			"    Return the access frequency index of the <key>.",
+			"IDLETIME <key>",
+			"    Return the idle time of the <key>, that is the approximated number of seconds elapsed since the last access to the key.",
+			"REFCOUNT <key>",
+			"    Return the number of references of the value associated with the specified <key>.",
+		})
+	}
+
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'object|" + strings.ToLower(string(args[0])) + "' command")
+	}
+
+	switch subcommand {
+	case "ENCODING", "IDLETIME", "FREQ", "REFCOUNT":
+	default:
+		return proto.NewError("ERR unknown subcommand '" + string(args[0]) + "'. Try OBJECT HELP.")
+	}
+
+	info, exists := h.store.Inspect(string(args[1]))
+	if !exists {
+		return proto.NewNullBulkString()
+	}
+
+	switch subcommand {
+	case "ENCODING":
+		return proto.NewBulkString(info.Encoding)
+	case "IDLETIME":
+		return proto.NewInteger(int64(info.Idle.Seconds()))
+	case "FREQ":
+		return proto.NewInteger(int64(info.Freq))
+	default:
+		return proto.NewInteger(1)
+	}
+}
+
+// handleMemory handles the MEMORY command
+func (h *Handler) handleMemory(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'memory' command")
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "USAGE":
+		return h.handleMemoryUsage(args[1:])
+	case "ANALYZE":
+		return h.handleMemoryAnalyze(args[1:])
+	case "HELP":
+		return proto.NewArray([]any{
+			"MEMORY <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"USAGE <key> [SAMPLES <count>]",
+			"    Return memory in bytes used by <key> and its value.",
+			"ANALYZE [PREFIX-DELIMITER <delimiter>] [TOP <count>]",
+			"    Report the largest keys and key prefixes, and size and TTL histograms of the database.",
+		})
+	default:
+		return proto.NewError("ERR unknown subcommand '" + string(args[0]) + "'. Try MEMORY HELP.")
+	}
+}
+
+// handleMemoryUsage handles MEMORY USAGE key [SAMPLES count]
+func (h *Handler) handleMemoryUsage(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'memory|usage' command")
+	}
+
+	// SAMPLES only matters for aggregate types; strings are always measured exactly
+	for i := 1; i < len(args); i += 2 {
+		if strings.ToUpper(string(args[i])) != "SAMPLES" || i+1 >= len(args) {
+			return proto.NewError("ERR syntax error")
+		}
+		if samples, err := strconv.Atoi(string(args[i+1])); err != nil || samples < 0 {
+			return proto.NewError("ERR value is not an integer or out of range")
+		}
+	}
+
+	info, exists := h.store.Inspect(string(args[0]))
+	if !exists {
+		return proto.NewNullBulkString()
+	}
+
+	return proto.NewInteger(info.Size)
+}
+
+// handleMemoryAnalyze handles MEMORY ANALYZE [PREFIX-DELIMITER delimiter] [TOP count].
+// The reply is a map of field names to values like MEMORY STATS.
+func (h *Handler) handleMemoryAnalyze(args [][]byte) *proto.Response {
+	delimiter := store.DefaultAnalyzeDelimiter
+	top := defaultAnalyzeTop
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return proto.NewError("ERR syntax error")
+		}
+
+		switch strings.ToUpper(string(args[i])) {
+		case "PREFIX-DELIMITER":
+			delimiter = string(args[i+1])
+		case "TOP":
+			n, err := strconv.Atoi(string(args[i+1]))
+			if err != nil || n < 1 {
+				return proto.NewError("ERR TOP must be a positive integer")
+			}
+			top = n
+		default:
+			return proto.NewError("ERR syntax error")
+		}
+	}
+
+	analysis := h.store.Analyze(delimiter, top)
+
+	largest := make([]any, len(analysis.Largest))
+	for i, key := range analysis.Largest {
+		largest[i] = []any{key.Key, key.Bytes}
+	}
+
+	sizes := make([]any, len(analysis.SizeHistogram))
+	for i, bucket := range analysis.SizeHistogram {
+		sizes[i] = []any{analysis.SizeLabel(i), bucket.Keys, bucket.Bytes}
+	}
+
+	ttls := []any{[]any{"none", analysis.Persistent.Keys, analysis.Persistent.Bytes}}
+	for i, bucket := range analysis.TTLHistogram {
+		ttls = append(ttls, []any{analysis.TTLLabel(i), bucket.Keys, bucket.Bytes})
+	}
+
+	prefixes := make([]any, len(analysis.Prefixes))
+	for i, prefix := range analysis.Prefixes {
+		prefixes[i] = []any{prefix.Prefix, prefix.Keys, prefix.Bytes}
+	}
+
+	return proto.NewMap([]any{
+		"keys", analysis.Keys,
+		"bytes", analysis.Bytes,
+		"largest-keys", largest,
+		"size-histogram", sizes,
+		"ttl-histogram", ttls,
+		"prefixes", prefixes,
+	})
+}
@@ -0,0 +1,123 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+func TestHandler_Introspection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		setup    [][]string
+		command  []string
+		respType proto.ResponseType
+		expected any
+	}{
+		{name: "TYPE string", setup: [][]string{{"SET", "k", "v"}}, command: []string{"TYPE", "k"}, respType: proto.SimpleString, expected: "string"},
+		{name: "TYPE missing", command: []string{"TYPE", "k"}, respType: proto.SimpleString, expected: "none"},
+		{name: "OBJECT ENCODING int", setup: [][]string{{"SET", "k", "123"}}, command: []string{"OBJECT", "ENCODING", "k"}, respType: proto.BulkString, expected: "int"},
+		{name: "OBJECT ENCODING embstr", setup: [][]string{{"SET", "k", "hello"}}, command: []string{"OBJECT", "encoding", "k"}, respType: proto.BulkString, expected: "embstr"},
+		{name: "OBJECT REFCOUNT", setup: [][]string{{"SET", "k", "v"}}, command: []string{"OBJECT", "REFCOUNT", "k"}, respType: proto.Integer, expected: int64(1)},
+		{name: "OBJECT IDLETIME", setup: [][]string{{"SET", "k", "v"}}, command: []string{"OBJECT", "IDLETIME", "k"}, respType: proto.Integer, expected: int64(0)},
+		{name: "OBJECT FREQ", setup: [][]string{{"SET", "k", "v"}}, command: []string{"OBJECT", "FREQ", "k"}, respType: proto.Integer},
+		{name: "OBJECT missing key", command: []string{"OBJECT", "ENCODING", "k"}, respType: proto.NullBulkString},
+		{name: "OBJECT unknown subcommand", command: []string{"OBJECT", "FOO", "k"}, respType: proto.Error, expected: "ERR unknown subcommand 'FOO'. Try OBJECT HELP."},
+		{name: "OBJECT HELP", command: []string{"OBJECT", "HELP"}, respType: proto.Array},
+		{name: "MEMORY USAGE missing", command: []string{"MEMORY", "USAGE", "k"}, respType: proto.NullBulkString},
+		{name: "MEMORY USAGE SAMPLES", setup: [][]string{{"SET", "k", "v"}}, command: []string{"MEMORY", "USAGE", "k", "SAMPLES", "5"}, respType: proto.Integer},
+		{name: "MEMORY USAGE bad option", setup: [][]string{{"SET", "k", "v"}}, command: []string{"MEMORY", "USAGE", "k", "SAMPLE"}, respType: proto.Error, expected: "ERR syntax error"},
+		{name: "MEMORY ANALYZE empty", command: []string{"MEMORY", "ANALYZE"}, respType: proto.Map},
+		{name: "MEMORY ANALYZE bad TOP", command: []string{"MEMORY", "ANALYZE", "TOP", "0"}, respType: proto.Error, expected: "ERR TOP must be a positive integer"},
+		{name: "MEMORY ANALYZE missing value", command: []string{"MEMORY", "ANALYZE", "PREFIX-DELIMITER"}, respType: proto.Error, expected: "ERR syntax error"},
+		{name: "MEMORY ANALYZE unknown option", command: []string{"MEMORY", "ANALYZE", "MATCH", "*"}, respType: proto.Error, expected: "ERR syntax error"},
+		{name: "MEMORY unknown subcommand", command: []string{"MEMORY", "DOCTOR"}, respType: proto.Error, expected: "ERR unknown subcommand 'DOCTOR'. Try MEMORY HELP."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createTestHandler(t)
+			for _, setup := range tt.setup {
+				handler.HandleCommand(request(setup[0], setup[1:]...))
+			}
+
+			resp := handler.HandleCommand(request(tt.command[0], tt.command[1:]...))
+			if resp.Type != tt.respType {
+				t.Fatalf("Expected response type %v, got %v (%v)", tt.respType, resp.Type, resp.Data)
+			}
+			if tt.expected != nil && resp.Data != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_MEMORY_USAGE_GrowsWithValue(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("SET", "k", "v"))
+	small := handler.HandleCommand(request("MEMORY", "USAGE", "k")).Data.(int64)
+
+	handler.HandleCommand(request("APPEND", "k", "0123456789"))
+	large := handler.HandleCommand(request("MEMORY", "USAGE", "k")).Data.(int64)
+
+	if large != small+10 {
+		t.Errorf("Expected usage to grow by 10 bytes, got %d -> %d", small, large)
+	}
+}
+
+func TestHandler_MEMORY_ANALYZE(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	for _, cmd := range [][]string{
+		{"SET", "user/1", "alice"},
+		{"SET", "user/2", "bob", "EX", "30"},
+		{"SET", "cache/1", "0123456789012345678901234567890123456789"},
+	} {
+		handler.HandleCommand(request(cmd[0], cmd[1:]...))
+	}
+
+	resp := handler.HandleCommand(request("MEMORY", "ANALYZE", "PREFIX-DELIMITER", "/", "TOP", "1"))
+	if resp.Type != proto.Map {
+		t.Fatalf("Expected Map response, got %v: %v", resp.Type, resp.Data)
+	}
+
+	fields := map[string]any{}
+	reply := resp.Data.([]any)
+	for i := 0; i+1 < len(reply); i += 2 {
+		fields[reply[i].(string)] = reply[i+1]
+	}
+
+	if fields["keys"] != int64(3) {
+		t.Errorf("Expected 3 keys, got %v", fields["keys"])
+	}
+
+	largest := fields["largest-keys"].([]any)
+	if len(largest) != 1 || largest[0].([]any)[0] != "cache/1" {
+		t.Errorf("Expected cache/1 as the only largest key, got %v", largest)
+	}
+
+	prefixes := fields["prefixes"].([]any)
+	if len(prefixes) != 1 || prefixes[0].([]any)[0] != "user" || prefixes[0].([]any)[1] != int64(2) {
+		t.Errorf("Expected user with 2 keys as the top prefix, got %v", prefixes)
+	}
+
+	ttls := fields["ttl-histogram"].([]any)
+	if first := ttls[0].([]any); first[0] != "none" || first[1] != int64(2) {
+		t.Errorf("Expected 2 keys without TTL, got %v", first)
+	}
+	if second := ttls[1].([]any); second[0] != "<=60s" || second[1] != int64(1) {
+		t.Errorf("Expected 1 key expiring within a minute, got %v", second)
+	}
+
+	sizes := fields["size-histogram"].([]any)
+	if last := sizes[len(sizes)-1].([]any); last[0] != ">1048576" {
+		t.Errorf("Expected the last size bucket to be unbounded, got %v", last)
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Abhishek2095/kv-stash/internal/glob"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+const (
+	// defaultScanCount is the SCAN batch size when COUNT is not given
+	defaultScanCount = 10
+)
+
+// handleScan handles the SCAN command
+func (h *Handler) handleScan(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'scan' command")
+	}
+
+	cursor, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		return proto.NewError("ERR invalid cursor")
+	}
+
+	count := defaultScanCount
+	pattern := ""
+	typeName := ""
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return proto.NewError("ERR syntax error")
+		}
+
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			n, err := strconv.Atoi(string(args[i+1]))
+			if err != nil {
+				return proto.NewError("ERR value is not an integer or out of range")
+			}
+			if n < 1 {
+				return proto.NewError("ERR syntax error")
+			}
+			count = n
+		case "TYPE":
+			typeName = strings.ToLower(string(args[i+1]))
+		default:
+			return proto.NewError("ERR syntax error")
+		}
+	}
+
+	next, keys := h.store.Scan(cursor, count, keyFilter(pattern, typeName))
+
+	return proto.NewArray([]any{
+		strconv.FormatUint(next, 10),
+		stringsToAny(keys),
+	})
+}
+
+// handleKeys handles the KEYS command
+func (h *Handler) handleKeys(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return proto.NewError("ERR wrong number of arguments for 'keys' command")
+	}
+
+	limit := h.config.Limits.MaxKeysReply
+	keys, complete := h.store.Keys(keyFilter(string(args[0]), ""), limit)
+	if !complete {
+		return proto.NewError("ERR KEYS would return more than " + strconv.Itoa(limit) +
+			" keys (limits.max_keys_reply), use SCAN instead")
+	}
+
+	return proto.NewArray(stringsToAny(keys))
+}
+
+// handleRandomKey handles the RANDOMKEY command
+func (h *Handler) handleRandomKey(args [][]byte) *proto.Response {
+	if len(args) != 0 {
+		return proto.NewError("ERR wrong number of arguments for 'randomkey' command")
+	}
+
+	key, exists := h.store.RandomKey()
+	if !exists {
+		return proto.NewNullBulkString()
+	}
+
+	return proto.NewBulkString(key)
+}
+
+// handleRename handles the RENAME command
+func (h *Handler) handleRename(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'rename' command")
+	}
+
+	if err := h.store.Rename(string(args[0]), string(args[1])); err != nil {
+		return storeError(err)
+	}
+
+	return proto.NewSimpleString("OK")
+}
+
+// handleRenameNX handles the RENAMENX command
+func (h *Handler) handleRenameNX(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'renamenx' command")
+	}
+
+	renamed, err := h.store.RenameNX(string(args[0]), string(args[1]))
+	if err != nil {
+		return storeError(err)
+	}
+
+	return boolResponse(renamed)
+}
+
+// handleCopy handles the COPY command
+func (h *Handler) handleCopy(args [][]byte) *proto.Response {
+	if len(args) < exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'copy' command")
+	}
+
+	replace := false
+	for _, option := range args[2:] {
+		if !strings.EqualFold(string(option), "REPLACE") {
+			return proto.NewError("ERR syntax error")
+		}
+		replace = true
+	}
+
+	copied, err := h.store.Copy(string(args[0]), string(args[1]), replace)
+	if err != nil {
+		return storeError(err)
+	}
+
+	return boolResponse(copied)
+}
+
+// handleTouch handles the TOUCH command
+func (h *Handler) handleTouch(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'touch' command")
+	}
+
+	return proto.NewInteger(h.store.Touch(argStrings(args)...))
+}
+
+// handleUnlink handles the UNLINK command
+func (h *Handler) handleUnlink(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'unlink' command")
+	}
+
+	return proto.NewInteger(h.store.Unlink(argStrings(args)...))
+}
+
+// storeError converts a store error into a Redis error reply. Store errors
+// carry the Redis error text, so only the error code prefix is added.
+func storeError(err error) *proto.Response {
+	return proto.NewError("ERR " + err.Error())
+}
+
+// boolResponse converts a boolean outcome into the 1/0 integer reply
+func boolResponse(ok bool) *proto.Response {
+	if ok {
+		return proto.NewInteger(1)
+	}
+	return proto.NewInteger(0)
+}
+
+// keyFilter builds a store filter for an optional glob pattern and type name
+func keyFilter(pattern, typeName string) store.Filter {
+	if (pattern == "" || pattern == "*") && typeName == "" {
+		return nil
+	}
+
+	return func(key string, value *store.Value) bool {
+		if pattern != "" && !glob.Match(pattern, key) {
+			return false
+		}
+		return typeName == "" || value.Type.String() == typeName
+	}
+}
+
+// stringsToAny converts a string slice into array response elements
+func stringsToAny(values []string) []any {
+	result := make([]any, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// argStrings copies args into strings
+func argStrings(args [][]byte) []string {
+	result := make([]string, len(args))
+	for i, arg := range args {
+		result[i] = string(arg)
+	}
+	return result
+}
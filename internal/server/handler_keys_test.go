@@ -0,0 +1,190 @@
+package server_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func TestHandler_SCAN(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	for i := range 30 {
+		handler.HandleCommand(request("SET", fmt.Sprintf("user:%d", i), "v"))
+		handler.HandleCommand(request("SET", fmt.Sprintf("order:%d", i), "v"))
+	}
+
+	seen := make(map[string]bool)
+	cursor := "0"
+	for {
+		resp := handler.HandleCommand(request("SCAN", cursor, "MATCH", "user:*", "COUNT", "4", "TYPE", "string"))
+		if resp.Type != proto.Array {
+			t.Fatalf("Expected Array response, got %v: %v", resp.Type, resp.Data)
+		}
+
+		reply := resp.Data.([]any)
+		for _, key := range reply[1].([]any) {
+			seen[key.(string)] = true
+		}
+
+		cursor = reply[0].(string)
+		if cursor == "0" {
+			break
+		}
+	}
+
+	if len(seen) != 30 {
+		t.Errorf("Expected 30 matching keys, got %d", len(seen))
+	}
+}
+
+func TestHandler_SCAN_Errors(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "no cursor", args: []string{}, expected: "ERR wrong number of arguments for 'scan' command"},
+		{name: "invalid cursor", args: []string{"abc"}, expected: "ERR invalid cursor"},
+		{name: "zero count", args: []string{"0", "COUNT", "0"}, expected: "ERR syntax error"},
+		{name: "missing option value", args: []string{"0", "MATCH"}, expected: "ERR syntax error"},
+		{name: "unknown option", args: []string{"0", "LIMIT", "5"}, expected: "ERR syntax error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp := handler.HandleCommand(request("SCAN", tt.args...))
+			if resp.Type != proto.Error || resp.Data.(string) != tt.expected {
+				t.Errorf("Expected error %q, got %v: %v", tt.expected, resp.Type, resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_KEYS(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	for _, key := range []string{"hello", "hallo", "hxllo", "world"} {
+		handler.HandleCommand(request("SET", key, "v"))
+	}
+
+	resp := handler.HandleCommand(request("KEYS", "h[ae]llo"))
+	if resp.Type != proto.Array {
+		t.Fatalf("Expected Array response, got %v", resp.Type)
+	}
+
+	var keys []string
+	for _, key := range resp.Data.([]any) {
+		keys = append(keys, key.(string))
+	}
+	sort.Strings(keys)
+
+	if len(keys) != 2 || keys[0] != "hallo" || keys[1] != "hello" {
+		t.Errorf("Expected [hallo hello], got %v", keys)
+	}
+}
+
+func TestHandler_KEYS_Limit(t *testing.T) {
+	t.Parallel()
+
+	logger := obs.NewLogger(false)
+	s, err := store.New(&store.Config{Shards: 4, EvictionPolicy: "noeviction"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	config := server.DefaultConfig()
+	config.Limits.MaxKeysReply = 3
+	handler := server.NewHandler(s, config, logger)
+
+	for i := range 5 {
+		handler.HandleCommand(request("SET", fmt.Sprintf("key:%d", i), "v"))
+	}
+
+	resp := handler.HandleCommand(request("KEYS", "*"))
+	if resp.Type != proto.Error {
+		t.Errorf("Expected Error response over the KEYS limit, got %v", resp.Type)
+	}
+
+	resp = handler.HandleCommand(request("KEYS", "key:1"))
+	if resp.Type != proto.Array || len(resp.Data.([]any)) != 1 {
+		t.Errorf("Expected one key under the limit, got %v: %v", resp.Type, resp.Data)
+	}
+}
+
+func TestHandler_RANDOMKEY(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+
+	resp := handler.HandleCommand(request("RANDOMKEY"))
+	if resp.Type != proto.NullBulkString {
+		t.Errorf("Expected NullBulkString on empty database, got %v", resp.Type)
+	}
+
+	handler.HandleCommand(request("SET", "key", "v"))
+	resp = handler.HandleCommand(request("RANDOMKEY"))
+	if resp.Type != proto.BulkString || resp.Data.(string) != "key" {
+		t.Errorf("Expected 'key', got %v: %v", resp.Type, resp.Data)
+	}
+}
+
+func TestHandler_RENAME_COPY(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		setup    [][]string
+		command  []string
+		respType proto.ResponseType
+		expected any
+	}{
+		{name: "RENAME existing key", setup: [][]string{{"SET", "a", "1"}}, command: []string{"RENAME", "a", "b"}, respType: proto.SimpleString, expected: "OK"},
+		{name: "RENAME missing key", command: []string{"RENAME", "a", "b"}, respType: proto.Error, expected: "ERR no such key"},
+		{name: "RENAME wrong args", command: []string{"RENAME", "a"}, respType: proto.Error, expected: "ERR wrong number of arguments for 'rename' command"},
+		{name: "RENAMENX onto new key", setup: [][]string{{"SET", "a", "1"}}, command: []string{"RENAMENX", "a", "b"}, respType: proto.Integer, expected: int64(1)},
+		{name: "RENAMENX onto existing key", setup: [][]string{{"SET", "a", "1"}, {"SET", "b", "2"}}, command: []string{"RENAMENX", "a", "b"}, respType: proto.Integer, expected: int64(0)},
+		{name: "RENAMENX missing key", command: []string{"RENAMENX", "a", "b"}, respType: proto.Error, expected: "ERR no such key"},
+		{name: "COPY to new key", setup: [][]string{{"SET", "a", "1"}}, command: []string{"COPY", "a", "b"}, respType: proto.Integer, expected: int64(1)},
+		{name: "COPY onto existing key", setup: [][]string{{"SET", "a", "1"}, {"SET", "b", "2"}}, command: []string{"COPY", "a", "b"}, respType: proto.Integer, expected: int64(0)},
+		{name: "COPY with REPLACE", setup: [][]string{{"SET", "a", "1"}, {"SET", "b", "2"}}, command: []string{"COPY", "a", "b", "replace"}, respType: proto.Integer, expected: int64(1)},
+		{name: "COPY same key", setup: [][]string{{"SET", "a", "1"}}, command: []string{"COPY", "a", "a"}, respType: proto.Error, expected: "ERR source and destination objects are the same"},
+		{name: "COPY unknown option", setup: [][]string{{"SET", "a", "1"}}, command: []string{"COPY", "a", "b", "FORCE"}, respType: proto.Error, expected: "ERR syntax error"},
+		{name: "TOUCH", setup: [][]string{{"SET", "a", "1"}}, command: []string{"TOUCH", "a", "b"}, respType: proto.Integer, expected: int64(1)},
+		{name: "UNLINK", setup: [][]string{{"SET", "a", "1"}, {"SET", "b", "2"}}, command: []string{"UNLINK", "a", "b", "c"}, respType: proto.Integer, expected: int64(2)},
+		{name: "UNLINK without keys", command: []string{"UNLINK"}, respType: proto.Error, expected: "ERR wrong number of arguments for 'unlink' command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createTestHandler(t)
+			for _, setup := range tt.setup {
+				handler.HandleCommand(request(setup[0], setup[1:]...))
+			}
+
+			resp := handler.HandleCommand(request(tt.command[0], tt.command[1:]...))
+			if resp.Type != tt.respType {
+				t.Fatalf("Expected response type %v, got %v (%v)", tt.respType, resp.Type, resp.Data)
+			}
+			if resp.Data != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, resp.Data)
+			}
+		})
+	}
+}
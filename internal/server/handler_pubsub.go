@@ -0,0 +1,173 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/pubsub"
+)
+
+// subscribedCommands are the only commands a client may run while it has
+// subscriptions
+var subscribedCommands = map[string]bool{
+	"SUBSCRIBE":    true,
+	"UNSUBSCRIBE":  true,
+	"PSUBSCRIBE":   true,
+	"PUNSUBSCRIBE": true,
+	"PING":         true,
+	"QUIT":         true,
+}
+
+// Subscriber returns the connection's Pub/Sub subscriber, or nil until the
+// client first subscribes
+func (h *Handler) Subscriber() *pubsub.Subscriber {
+	return h.subscriber
+}
+
+// Close releases the connection's subscriptions and removes its session
+func (h *Handler) Close() {
+	if h.subscriber != nil {
+		h.subscriber.Close()
+	}
+	h.clients.remove(h.id)
+}
+
+// subscribed reports whether the client is in Pub/Sub mode
+func (h *Handler) subscribed() bool {
+	return h.subscriber != nil && h.subscriber.Count() > 0
+}
+
+// ensureSubscriber creates the connection's subscriber on first use
+func (h *Handler) ensureSubscriber() *pubsub.Subscriber {
+	if h.subscriber == nil {
+		h.subscriber = h.broker.NewSubscriber(h.config.PubSub.SubscriberBuffer)
+	}
+	return h.subscriber
+}
+
+// handleSubscribe handles SUBSCRIBE and PSUBSCRIBE. The reply is one
+// confirmation per channel or pattern with the resulting subscription count.
+func (h *Handler) handleSubscribe(args [][]byte, pattern bool) *proto.Response {
+	kind := "subscribe"
+	if pattern {
+		kind = "psubscribe"
+	}
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for '" + kind + "' command")
+	}
+
+	sub := h.ensureSubscriber()
+	replies := make([]*proto.Response, len(args))
+	for i, arg := range args {
+		name := string(arg)
+		var count int64
+		if pattern {
+			count = sub.PSubscribe(name)
+		} else {
+			count = sub.Subscribe(name)
+		}
+		replies[i] = proto.NewPush([]any{kind, name, count})
+	}
+	return proto.NewMulti(replies...)
+}
+
+// handleUnsubscribe handles UNSUBSCRIBE and PUNSUBSCRIBE. Without arguments
+// every channel or pattern is unsubscribed.
+func (h *Handler) handleUnsubscribe(args [][]byte, pattern bool) *proto.Response {
+	kind := "unsubscribe"
+	if pattern {
+		kind = "punsubscribe"
+	}
+
+	sub := h.ensureSubscriber()
+	names := argStrings(args)
+	if len(names) == 0 {
+		if pattern {
+			names = sub.Patterns()
+		} else {
+			names = sub.Channels()
+		}
+		if len(names) == 0 {
+			return proto.NewPush([]any{kind, nil, sub.Count()})
+		}
+	}
+
+	replies := make([]*proto.Response, len(names))
+	for i, name := range names {
+		var count int64
+		if pattern {
+			count = sub.PUnsubscribe(name)
+		} else {
+			count = sub.Unsubscribe(name)
+		}
+		replies[i] = proto.NewPush([]any{kind, name, count})
+	}
+	return proto.NewMulti(replies...)
+}
+
+// handlePublish handles PUBLISH channel message
+func (h *Handler) handlePublish(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'publish' command")
+	}
+
+	return proto.NewInteger(h.broker.Publish(string(args[0]), string(args[1])))
+}
+
+// handlePubSub handles PUBSUB CHANNELS, NUMSUB and NUMPAT
+func (h *Handler) handlePubSub(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'pubsub' command")
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "CHANNELS":
+		if len(args) > 2 {
+			return proto.NewError("ERR wrong number of arguments for 'pubsub|channels' command")
+		}
+		pattern := ""
+		if len(args) == 2 {
+			pattern = string(args[1])
+		}
+		channels := h.broker.Channels(pattern)
+		result := make([]any, len(channels))
+		for i, channel := range channels {
+			result[i] = channel
+		}
+		return proto.NewArray(result)
+	case "NUMSUB":
+		result := make([]any, 0, 2*len(args[1:]))
+		for _, channel := range argStrings(args[1:]) {
+			result = append(result, channel, h.broker.NumSub(channel))
+		}
+		return proto.NewMap(result)
+	case "NUMPAT":
+		if len(args) != 1 {
+			return proto.NewError("ERR wrong number of arguments for 'pubsub|numpat' command")
+		}
+		return proto.NewInteger(h.broker.NumPat())
+	case "HELP":
+		return proto.NewArray([]any{
+			"PUBSUB <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"CHANNELS [<pattern>]",
+			"    Return the currently active channels matching a <pattern> (default: '*').",
+			"NUMPAT",
+			"    Return number of subscriptions to patterns.",
+			"NUMSUB [<channel> ...]",
+			"    Return the number of subscribers for the specified channels, excluding",
+			"    pattern subscriptions(default: no channels).",
+		})
+	default:
+		return proto.NewError("ERR unknown subcommand '" + string(args[0]) + "'. Try PUBSUB HELP.")
+	}
+}
+
+// messageResponse renders a message delivered to a subscription. Like
+// subscription confirmations it is a push, so RESP3 clients can tell it from
+// command replies.
+func messageResponse(msg pubsub.Message) *proto.Response {
+	if msg.Pattern != "" {
+		return proto.NewPush([]any{"pmessage", msg.Pattern, msg.Channel, msg.Payload})
+	}
+	return proto.NewPush([]any{"message", msg.Channel, msg.Payload})
+}
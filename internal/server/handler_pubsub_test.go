@@ -0,0 +1,187 @@
+package server_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+)
+
+// multiParts returns the replies of a response, treating a single reply as a
+// one-part response
+func multiParts(resp *proto.Response) []*proto.Response {
+	if resp.Type == proto.Multi {
+		return resp.Data.([]*proto.Response)
+	}
+	return []*proto.Response{resp}
+}
+
+func TestHandler_PubSub(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+
+	steps := []struct {
+		name     string
+		command  []string
+		expected [][]any
+		errorMsg string
+		// reply marks a regular reply rather than Pub/Sub pushes
+		reply bool
+	}{
+		{
+			name:     "SUBSCRIBE without channels",
+			command:  []string{"SUBSCRIBE"},
+			errorMsg: "ERR wrong number of arguments for 'subscribe' command",
+		},
+		{
+			name:     "UNSUBSCRIBE without subscriptions",
+			command:  []string{"UNSUBSCRIBE"},
+			expected: [][]any{{"unsubscribe", nil, int64(0)}},
+		},
+		{
+			name:     "PUBLISH without subscribers",
+			command:  []string{"PUBLISH", "news", "hello"},
+			expected: nil,
+		},
+		{
+			name:    "SUBSCRIBE",
+			command: []string{"SUBSCRIBE", "news", "sports"},
+			expected: [][]any{
+				{"subscribe", "news", int64(1)},
+				{"subscribe", "sports", int64(2)},
+			},
+		},
+		{
+			name:     "PSUBSCRIBE",
+			command:  []string{"PSUBSCRIBE", "n*"},
+			expected: [][]any{{"psubscribe", "n*", int64(3)}},
+		},
+		{
+			name:     "PING in subscribed mode",
+			command:  []string{"PING"},
+			expected: [][]any{{"pong", ""}},
+			reply:    true,
+		},
+		{
+			name:     "PING with message in subscribed mode",
+			command:  []string{"PING", "hi"},
+			expected: [][]any{{"pong", "hi"}},
+			reply:    true,
+		},
+		{
+			name:     "regular command in subscribed mode",
+			command:  []string{"SET", "k", "v"},
+			errorMsg: "ERR Can't execute 'set': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context",
+		},
+		{
+			name:    "UNSUBSCRIBE all channels",
+			command: []string{"UNSUBSCRIBE"},
+			expected: [][]any{
+				{"unsubscribe", "news", int64(2)},
+				{"unsubscribe", "sports", int64(1)},
+			},
+		},
+		{
+			name:     "PUNSUBSCRIBE",
+			command:  []string{"PUNSUBSCRIBE", "n*"},
+			expected: [][]any{{"punsubscribe", "n*", int64(0)}},
+		},
+	}
+
+	for _, step := range steps {
+		cmd := request(step.command[0], step.command[1:]...)
+		resp := handler.HandleCommand(cmd)
+
+		if step.errorMsg != "" {
+			if resp.Type != proto.Error || resp.Data != step.errorMsg {
+				t.Errorf("%s: expected error %q, got %v: %v", step.name, step.errorMsg, resp.Type, resp.Data)
+			}
+			continue
+		}
+		if step.expected == nil {
+			if resp.Type != proto.Integer || resp.Data != int64(0) {
+				t.Errorf("%s: expected 0, got %v: %v", step.name, resp.Type, resp.Data)
+			}
+			continue
+		}
+
+		wantType := proto.Push
+		if step.reply {
+			wantType = proto.Array
+		}
+		parts := multiParts(resp)
+		if len(parts) != len(step.expected) {
+			t.Fatalf("%s: expected %d replies, got %d", step.name, len(step.expected), len(parts))
+		}
+		for i, part := range parts {
+			if part.Type != wantType || !reflect.DeepEqual(part.Data, step.expected[i]) {
+				t.Errorf("%s: reply %d = %v, want %v", step.name, i, part.Data, step.expected[i])
+			}
+		}
+	}
+
+	// Back out of subscribed mode, regular commands work again
+	if resp := handler.HandleCommand(request("SET", "k", "v")); resp.Type != proto.SimpleString {
+		t.Errorf("Expected SET to succeed after unsubscribing, got %v: %v", resp.Type, resp.Data)
+	}
+	handler.Close()
+}
+
+func TestHandler_PubSub_Close(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("SUBSCRIBE", "news"))
+	t.Cleanup(handler.Close)
+
+	sub := handler.Subscriber()
+	if sub == nil {
+		t.Fatal("Expected a subscriber after SUBSCRIBE")
+	}
+	if count := sub.Count(); count != 1 {
+		t.Errorf("Expected 1 subscription, got %d", count)
+	}
+
+	handler.Close()
+	if _, ok := <-sub.Messages(); ok {
+		t.Errorf("Expected Close to close the subscriber")
+	}
+	if count := sub.Count(); count != 0 {
+		t.Errorf("Expected no subscriptions after Close, got %d", count)
+	}
+}
+
+func TestHandler_PUBSUB(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+
+	tests := []struct {
+		name     string
+		args     []string
+		respType proto.ResponseType
+		expected any
+	}{
+		{name: "CHANNELS", args: []string{"CHANNELS"}, respType: proto.Array, expected: []any{}},
+		{name: "CHANNELS with pattern", args: []string{"CHANNELS", "n*"}, respType: proto.Array, expected: []any{}},
+		{name: "NUMSUB", args: []string{"NUMSUB", "a", "b"}, respType: proto.Map, expected: []any{"a", int64(0), "b", int64(0)}},
+		{name: "NUMPAT", args: []string{"NUMPAT"}, respType: proto.Integer, expected: int64(0)},
+		{name: "unknown subcommand", args: []string{"SHARDCHANNELS"}, respType: proto.Error, expected: "ERR unknown subcommand 'SHARDCHANNELS'. Try PUBSUB HELP."},
+		{name: "no subcommand", args: []string{}, respType: proto.Error, expected: "ERR wrong number of arguments for 'pubsub' command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp := handler.HandleCommand(request("PUBSUB", tt.args...))
+			if resp.Type != tt.respType {
+				t.Fatalf("Expected %v, got %v: %v", tt.respType, resp.Type, resp.Data)
+			}
+			if !reflect.DeepEqual(resp.Data, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, resp.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+const exactThreeArgs = 3
+
+// handleAppend handles the APPEND command
+func (h *Handler) handleAppend(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'append' command")
+	}
+
+	length, err := h.store.Append(string(args[0]), string(args[1]), h.maxStringLength())
+	if err != nil {
+		return stringError(err)
+	}
+	return proto.NewInteger(length)
+}
+
+// errStringTooLong is the reply to a command that would grow a string past
+// limits.proto_max_bulk_len
+var errStringTooLong = proto.NewError("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+
+// maxStringLength returns the longest a string may grow to:
+// limits.proto_max_bulk_len, the longest string a client could send or read
+func (h *Handler) maxStringLength() int64 {
+	return int64(h.config.Limits.ProtoMaxBulkLen)
+}
+
+// stringError converts an error from a string operation into a reply
+func stringError(err error) *proto.Response {
+	if errors.Is(err, store.ErrStringTooLong) {
+		return errStringTooLong
+	}
+	return storeError(err)
+}
+
+// handleStrLen handles the STRLEN command
+func (h *Handler) handleStrLen(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return proto.NewError("ERR wrong number of arguments for 'strlen' command")
+	}
+
+	value, _ := h.store.Get(string(args[0]))
+	return proto.NewInteger(int64(len(value)))
+}
+
+// handleGetRange handles the GETRANGE command
+func (h *Handler) handleGetRange(args [][]byte) *proto.Response {
+	if len(args) != exactThreeArgs {
+		return proto.NewError("ERR wrong number of arguments for 'getrange' command")
+	}
+
+	start, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return proto.NewError("ERR value is not an integer or out of range")
+	}
+	end, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return proto.NewError("ERR value is not an integer or out of range")
+	}
+
+	value, _ := h.store.Get(string(args[0]))
+	return proto.NewBulkString(substring(value, start, end))
+}
+
+// substring returns value[start:end] inclusive, where negative indices count
+// from the end of the string, following GETRANGE semantics
+func substring(value string, start, end int64) string {
+	length := int64(len(value))
+	if length == 0 || (start < 0 && end < 0 && start > end) {
+		return ""
+	}
+
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	start = max(start, 0)
+	end = max(end, 0)
+	end = min(end, length-1)
+
+	if start > end {
+		return ""
+	}
+
+	return value[start : end+1]
+}
+
+// handleSetRange handles the SETRANGE command
+func (h *Handler) handleSetRange(args [][]byte) *proto.Response {
+	if len(args) != exactThreeArgs {
+		return proto.NewError("ERR wrong number of arguments for 'setrange' command")
+	}
+
+	offset, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return proto.NewError("ERR value is not an integer or out of range")
+	}
+	if offset < 0 {
+		return proto.NewError("ERR offset is out of range")
+	}
+	if offset > h.maxStringLength() {
+		return errStringTooLong
+	}
+
+	length, err := h.store.SetRange(string(args[0]), int(offset), string(args[2]), h.maxStringLength())
+	if err != nil {
+		return stringError(err)
+	}
+	return proto.NewInteger(length)
+}
+
+// handleGetDel handles the GETDEL command
+func (h *Handler) handleGetDel(args [][]byte) *proto.Response {
+	if len(args) != 1 {
+		return proto.NewError("ERR wrong number of arguments for 'getdel' command")
+	}
+
+	value, exists := h.store.GetDel(string(args[0]))
+	if !exists {
+		return proto.NewNullBulkString()
+	}
+
+	return proto.NewBulkString(value)
+}
+
+// handleGetEx handles the GETEX command
+func (h *Handler) handleGetEx(args [][]byte) *proto.Response {
+	if len(args) == 0 {
+		return proto.NewError("ERR wrong number of arguments for 'getex' command")
+	}
+
+	var (
+		expiresAt time.Time
+		persist   bool
+	)
+
+	switch len(args) {
+	case 1:
+	case exactTwoArgs:
+		if strings.ToUpper(string(args[1])) != "PERSIST" {
+			return proto.NewError("ERR syntax error")
+		}
+		persist = true
+	case exactThreeArgs:
+		at, errResp := parseExpireOption(string(args[1]), string(args[2]), "getex")
+		if errResp != nil {
+			return errResp
+		}
+		expiresAt = at
+	default:
+		return proto.NewError("ERR syntax error")
+	}
+
+	value, exists := h.store.GetEx(string(args[0]), expiresAt, persist)
+	if !exists {
+		return proto.NewNullBulkString()
+	}
+
+	return proto.NewBulkString(value)
+}
+
+// parseExpireOption converts an EX/PX/EXAT/PXAT option and its argument into an
+// absolute expiration time
+func parseExpireOption(option, arg, command string) (time.Time, *proto.Response) {
+	amount, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return time.Time{}, proto.NewError("ERR value is not an integer or out of range")
+	}
+	if amount <= 0 {
+		return time.Time{}, proto.NewError("ERR invalid expire time in '" + command + "' command")
+	}
+
+	switch strings.ToUpper(option) {
+	case "EX":
+		return expireIn(amount, time.Second, command)
+	case "PX":
+		return expireIn(amount, time.Millisecond, command)
+	case "EXAT":
+		return time.Unix(amount, 0), nil
+	case "PXAT":
+		return time.UnixMilli(amount), nil
+	default:
+		return time.Time{}, proto.NewError("ERR syntax error")
+	}
+}
+
+// expireIn returns the time amount of unit from now
+func expireIn(amount int64, unit time.Duration, command string) (time.Time, *proto.Response) {
+	ttl, ok := expireDuration(amount, unit)
+	if !ok {
+		return time.Time{}, proto.NewError("ERR invalid expire time in '" + command + "' command")
+	}
+	return time.Now().Add(ttl), nil
+}
+
+// expireDuration converts a positive amount of unit into a duration,
+// reporting false when it does not fit in one
+func expireDuration(amount int64, unit time.Duration) (time.Duration, bool) {
+	if amount > math.MaxInt64/int64(unit) {
+		return 0, false
+	}
+	return time.Duration(amount) * unit, true
+}
+
+// handleGetSet handles the GETSET command
+func (h *Handler) handleGetSet(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'getset' command")
+	}
+
+	old, exists := h.store.GetSet(string(args[0]), string(args[1]))
+	if !exists {
+		return proto.NewNullBulkString()
+	}
+
+	return proto.NewBulkString(old)
+}
+
+// handleSetNX handles the SETNX command
+func (h *Handler) handleSetNX(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'setnx' command")
+	}
+
+	return boolResponse(h.store.SetNX(string(args[0]), string(args[1]), nil))
+}
+
+// handleSetEx handles the SETEX and PSETEX commands
+func (h *Handler) handleSetEx(args [][]byte, unit time.Duration, command string) *proto.Response {
+	if len(args) != exactThreeArgs {
+		return proto.NewError("ERR wrong number of arguments for '" + command + "' command")
+	}
+
+	amount, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return proto.NewError("ERR value is not an integer or out of range")
+	}
+	expiration, ok := expireDuration(amount, unit)
+	if amount <= 0 || !ok {
+		return proto.NewError("ERR invalid expire time in '" + command + "' command")
+	}
+
+	h.store.Set(string(args[0]), string(args[2]), &expiration)
+	return proto.NewSimpleString("OK")
+}
+
+// handleMSetNX handles the MSETNX command
+func (h *Handler) handleMSetNX(args [][]byte) *proto.Response {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return proto.NewError("ERR wrong number of arguments for 'msetnx' command")
+	}
+
+	keys := make([]string, 0, len(args)/2)
+	values := make([]string, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		keys = append(keys, string(args[i]))
+		values = append(values, string(args[i+1]))
+	}
+
+	return boolResponse(h.store.MSetNX(keys, values))
+}
+
+// handleIncrByFloat handles the INCRBYFLOAT command
+func (h *Handler) handleIncrByFloat(args [][]byte) *proto.Response {
+	if len(args) != exactTwoArgs {
+		return proto.NewError("ERR wrong number of arguments for 'incrbyfloat' command")
+	}
+
+	increment, err := strconv.ParseFloat(string(args[1]), 64)
+	if err != nil {
+		return proto.NewError("ERR value is not a valid float")
+	}
+
+	result, err := h.store.IncrByFloat(string(args[0]), increment)
+	if err != nil {
+		return storeError(err)
+	}
+
+	return proto.NewBulkString(store.FormatFloat(result))
+}
@@ -0,0 +1,150 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func TestHandler_StringCommands(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		setup    [][]string
+		command  []string
+		respType proto.ResponseType
+		expected any
+	}{
+		{name: "APPEND creates key", command: []string{"APPEND", "k", "abc"}, respType: proto.Integer, expected: int64(3)},
+		{name: "APPEND extends key", setup: [][]string{{"SET", "k", "abc"}}, command: []string{"APPEND", "k", "de"}, respType: proto.Integer, expected: int64(5)},
+		{name: "STRLEN missing key", command: []string{"STRLEN", "k"}, respType: proto.Integer, expected: int64(0)},
+		{name: "STRLEN existing key", setup: [][]string{{"SET", "k", "hello"}}, command: []string{"STRLEN", "k"}, respType: proto.Integer, expected: int64(5)},
+		{name: "GETRANGE positive", setup: [][]string{{"SET", "k", "This is a string"}}, command: []string{"GETRANGE", "k", "0", "3"}, respType: proto.BulkString, expected: "This"},
+		{name: "GETRANGE negative", setup: [][]string{{"SET", "k", "This is a string"}}, command: []string{"GETRANGE", "k", "-3", "-1"}, respType: proto.BulkString, expected: "ing"},
+		{name: "GETRANGE whole string", setup: [][]string{{"SET", "k", "This is a string"}}, command: []string{"GETRANGE", "k", "0", "-1"}, respType: proto.BulkString, expected: "This is a string"},
+		{name: "GETRANGE past end", setup: [][]string{{"SET", "k", "This is a string"}}, command: []string{"GETRANGE", "k", "10", "100"}, respType: proto.BulkString, expected: "string"},
+		{name: "GETRANGE inverted negatives", setup: [][]string{{"SET", "k", "abc"}}, command: []string{"GETRANGE", "k", "-1", "-5"}, respType: proto.BulkString, expected: ""},
+		{name: "GETRANGE missing key", command: []string{"GETRANGE", "k", "0", "-1"}, respType: proto.BulkString, expected: ""},
+		{name: "SETRANGE pads with zeros", command: []string{"SETRANGE", "k", "2", "x"}, respType: proto.Integer, expected: int64(3)},
+		{name: "SETRANGE negative offset", command: []string{"SETRANGE", "k", "-1", "x"}, respType: proto.Error, expected: "ERR offset is out of range"},
+		{name: "SETRANGE too large", command: []string{"SETRANGE", "k", "536870912", "x"}, respType: proto.Error, expected: "ERR string exceeds maximum allowed size (proto-max-bulk-len)"},
+		{name: "GETDEL existing key", setup: [][]string{{"SET", "k", "v"}}, command: []string{"GETDEL", "k"}, respType: proto.BulkString, expected: "v"},
+		{name: "GETDEL missing key", command: []string{"GETDEL", "k"}, respType: proto.NullBulkString},
+		{name: "GETEX with EX", setup: [][]string{{"SET", "k", "v"}}, command: []string{"GETEX", "k", "EX", "100"}, respType: proto.BulkString, expected: "v"},
+		{name: "GETEX with PERSIST", setup: [][]string{{"SET", "k", "v"}}, command: []string{"GETEX", "k", "persist"}, respType: proto.BulkString, expected: "v"},
+		{name: "GETEX with invalid expire", setup: [][]string{{"SET", "k", "v"}}, command: []string{"GETEX", "k", "PX", "0"}, respType: proto.Error, expected: "ERR invalid expire time in 'getex' command"},
+		{name: "GETEX with unknown option", setup: [][]string{{"SET", "k", "v"}}, command: []string{"GETEX", "k", "KEEPTTL"}, respType: proto.Error, expected: "ERR syntax error"},
+		{name: "GETSET returns old value", setup: [][]string{{"SET", "k", "old"}}, command: []string{"GETSET", "k", "new"}, respType: proto.BulkString, expected: "old"},
+		{name: "GETSET missing key", command: []string{"GETSET", "k", "new"}, respType: proto.NullBulkString},
+		{name: "SETNX missing key", command: []string{"SETNX", "k", "v"}, respType: proto.Integer, expected: int64(1)},
+		{name: "SETNX existing key", setup: [][]string{{"SET", "k", "v"}}, command: []string{"SETNX", "k", "v2"}, respType: proto.Integer, expected: int64(0)},
+		{name: "SETEX", command: []string{"SETEX", "k", "10", "v"}, respType: proto.SimpleString, expected: "OK"},
+		{name: "SETEX invalid expire", command: []string{"SETEX", "k", "0", "v"}, respType: proto.Error, expected: "ERR invalid expire time in 'setex' command"},
+		{name: "PSETEX", command: []string{"PSETEX", "k", "1000", "v"}, respType: proto.SimpleString, expected: "OK"},
+		{name: "PSETEX invalid expire", command: []string{"PSETEX", "k", "-5", "v"}, respType: proto.Error, expected: "ERR invalid expire time in 'psetex' command"},
+		{name: "SETEX overflowing expire", command: []string{"SETEX", "k", "9223372036854775807", "v"}, respType: proto.Error, expected: "ERR invalid expire time in 'setex' command"},
+		{name: "GETEX overflowing expire", setup: [][]string{{"SET", "k", "v"}}, command: []string{"GETEX", "k", "EX", "9223372036854775807"}, respType: proto.Error, expected: "ERR invalid expire time in 'getex' command"},
+		{name: "MSETNX all new", command: []string{"MSETNX", "a", "1", "b", "2"}, respType: proto.Integer, expected: int64(1)},
+		{name: "MSETNX one exists", setup: [][]string{{"SET", "b", "x"}}, command: []string{"MSETNX", "a", "1", "b", "2"}, respType: proto.Integer, expected: int64(0)},
+		{name: "MSETNX odd args", command: []string{"MSETNX", "a", "1", "b"}, respType: proto.Error, expected: "ERR wrong number of arguments for 'msetnx' command"},
+		{name: "INCRBYFLOAT", setup: [][]string{{"SET", "k", "10.50"}}, command: []string{"INCRBYFLOAT", "k", "0.1"}, respType: proto.BulkString, expected: "10.6"},
+		{name: "INCRBYFLOAT exponent", setup: [][]string{{"SET", "k", "5.0e3"}}, command: []string{"INCRBYFLOAT", "k", "2.0e2"}, respType: proto.BulkString, expected: "5200"},
+		{name: "INCRBYFLOAT missing key", command: []string{"INCRBYFLOAT", "k", "-5"}, respType: proto.BulkString, expected: "-5"},
+		{name: "INCRBYFLOAT invalid increment", command: []string{"INCRBYFLOAT", "k", "abc"}, respType: proto.Error, expected: "ERR value is not a valid float"},
+		{name: "INCRBYFLOAT non-float value", setup: [][]string{{"SET", "k", "abc"}}, command: []string{"INCRBYFLOAT", "k", "1"}, respType: proto.Error, expected: "ERR value is not a valid float"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := createTestHandler(t)
+			for _, setup := range tt.setup {
+				handler.HandleCommand(request(setup[0], setup[1:]...))
+			}
+
+			resp := handler.HandleCommand(request(tt.command[0], tt.command[1:]...))
+			if resp.Type != tt.respType {
+				t.Fatalf("Expected response type %v, got %v (%v)", tt.respType, resp.Type, resp.Data)
+			}
+
+			if tt.expected != nil && resp.Data != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_SETRANGE_ZeroPadding(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("SET", "key", "Hello"))
+	handler.HandleCommand(request("SETRANGE", "key", "7", "World"))
+
+	resp := handler.HandleCommand(request("GET", "key"))
+	if resp.Data.(string) != "Hello\x00\x00World" {
+		t.Errorf("Expected zero padded value, got %q", resp.Data.(string))
+	}
+}
+
+func TestHandler_GETEX_ExpireAndPersist(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	handler.HandleCommand(request("SET", "key", "value"))
+
+	handler.HandleCommand(request("GETEX", "key", "EX", "100"))
+	resp := handler.HandleCommand(request("TTL", "key"))
+	if ttl := resp.Data.(int64); ttl <= 0 || ttl > 100 {
+		t.Errorf("Expected TTL between 1 and 100, got %d", ttl)
+	}
+
+	handler.HandleCommand(request("GETEX", "key", "PERSIST"))
+	resp = handler.HandleCommand(request("TTL", "key"))
+	if ttl := resp.Data.(int64); ttl != -1 {
+		t.Errorf("Expected TTL -1 after PERSIST, got %d", ttl)
+	}
+
+	handler.HandleCommand(request("GETEX", "key", "EXAT", "1"))
+	resp = handler.HandleCommand(request("EXISTS", "key"))
+	if resp.Data.(int64) != 0 {
+		t.Errorf("Expected EXAT in the past to delete the key")
+	}
+}
+
+func TestHandler_StringLengthLimit(t *testing.T) {
+	t.Parallel()
+
+	s, err := store.New(&store.Config{Shards: 4, EvictionPolicy: "noeviction"}, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	config := server.DefaultConfig()
+	config.Limits.ProtoMaxBulkLen = 8
+	handler := server.NewHandler(s, config, obs.NewLogger(false))
+
+	tests := []struct {
+		args     []string
+		expected *proto.Response
+	}{
+		{args: []string{"APPEND", "k", "12345"}, expected: proto.NewInteger(5)},
+		{args: []string{"APPEND", "k", "678"}, expected: proto.NewInteger(8)},
+		{args: []string{"APPEND", "k", "9"}, expected: proto.NewError("ERR string exceeds maximum allowed size (proto-max-bulk-len)")},
+		{args: []string{"SETRANGE", "k", "7", "x"}, expected: proto.NewInteger(8)},
+		{args: []string{"SETRANGE", "k", "7", "xy"}, expected: proto.NewError("ERR string exceeds maximum allowed size (proto-max-bulk-len)")},
+		{args: []string{"SETRANGE", "k", "9223372036854775807", "x"},
+			expected: proto.NewError("ERR string exceeds maximum allowed size (proto-max-bulk-len)")},
+	}
+	for _, tt := range tests {
+		resp := handler.HandleCommand(request(tt.args[0], tt.args[1:]...))
+		if resp.Type != tt.expected.Type || resp.Data != tt.expected.Data {
+			t.Errorf("%v: expected %v, got %v", tt.args, tt.expected, resp)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package server_test
 
 import (
+	"strconv"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/Abhishek2095/kv-stash/internal/obs"
 	"github.com/Abhishek2095/kv-stash/internal/proto"
@@ -25,19 +25,24 @@ func createTestHandler(t *testing.T) *server.Handler {
 	if err != nil {
 		t.Fatalf("Failed to create store: %v", err)
 	}
+	t.Cleanup(s.Close)
 
-	serverConfig := &server.Config{
-		ListenAddr:   ":6380",
-		Shards:       4,
-		AuthPassword: "",
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-	}
+	serverConfig := server.DefaultConfig()
+	serverConfig.Server.Shards = 4
 
 	handler := server.NewHandler(s, serverConfig, logger)
 	return handler
 }
 
+// request builds the request a client sends to run command name with args
+func request(name string, args ...string) *proto.Request {
+	argv := make([][]byte, len(args))
+	for i, arg := range args {
+		argv[i] = []byte(arg)
+	}
+	return &proto.Request{Name: name, Args: argv}
+}
+
 func TestHandler_PING(t *testing.T) {
 	t.Parallel()
 
@@ -73,7 +78,7 @@ func TestHandler_PING(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			cmd := &proto.Command{Name: "PING", Args: tt.args}
+			cmd := request("PING", tt.args...)
 			resp := handler.HandleCommand(cmd)
 
 			if resp.Type != tt.respType {
@@ -128,7 +133,7 @@ func TestHandler_ECHO(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			cmd := &proto.Command{Name: "ECHO", Args: tt.args}
+			cmd := request("ECHO", tt.args...)
 			resp := handler.HandleCommand(cmd)
 
 			if resp.Type != tt.respType {
@@ -147,7 +152,7 @@ func TestHandler_INFO(t *testing.T) {
 
 	handler := createTestHandler(t)
 
-	cmd := &proto.Command{Name: "INFO", Args: []string{}}
+	cmd := request("INFO")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.BulkString {
@@ -163,8 +168,8 @@ func TestHandler_INFO(t *testing.T) {
 		}
 	}
 
-	// Test with args (should still work, args are ignored)
-	cmd = &proto.Command{Name: "INFO", Args: []string{"server"}}
+	// Test with a section argument
+	cmd = request("INFO", "server")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.BulkString {
@@ -178,7 +183,7 @@ func TestHandler_GET_SET(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Test GET non-existent key
-	cmd := &proto.Command{Name: "GET", Args: []string{"nonexistent"}}
+	cmd := request("GET", "nonexistent")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.NullBulkString {
@@ -186,7 +191,7 @@ func TestHandler_GET_SET(t *testing.T) {
 	}
 
 	// Test SET and then GET
-	cmd = &proto.Command{Name: "SET", Args: []string{"testkey", "testvalue"}}
+	cmd = request("SET", "testkey", "testvalue")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.SimpleString || resp.Data.(string) != "OK" {
@@ -194,7 +199,7 @@ func TestHandler_GET_SET(t *testing.T) {
 	}
 
 	// Test GET existing key
-	cmd = &proto.Command{Name: "GET", Args: []string{"testkey"}}
+	cmd = request("GET", "testkey")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.BulkString {
@@ -257,7 +262,7 @@ func TestHandler_SET_WithOptions(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			cmd := &proto.Command{Name: "SET", Args: tt.args}
+			cmd := request("SET", tt.args...)
 			resp := handler.HandleCommand(cmd)
 
 			if tt.wantErr {
@@ -280,7 +285,7 @@ func TestHandler_DEL(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Test DEL without args
-	cmd := &proto.Command{Name: "DEL", Args: []string{}}
+	cmd := request("DEL")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -288,11 +293,11 @@ func TestHandler_DEL(t *testing.T) {
 	}
 
 	// Set some keys
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"key1", "value1"}})
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"key2", "value2"}})
+	handler.HandleCommand(request("SET", "key1", "value1"))
+	handler.HandleCommand(request("SET", "key2", "value2"))
 
 	// Test DEL single key
-	cmd = &proto.Command{Name: "DEL", Args: []string{"key1"}}
+	cmd = request("DEL", "key1")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -304,7 +309,7 @@ func TestHandler_DEL(t *testing.T) {
 	}
 
 	// Test DEL multiple keys
-	cmd = &proto.Command{Name: "DEL", Args: []string{"key2", "nonexistent"}}
+	cmd = request("DEL", "key2", "nonexistent")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -322,7 +327,7 @@ func TestHandler_EXISTS(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Test EXISTS without args
-	cmd := &proto.Command{Name: "EXISTS", Args: []string{}}
+	cmd := request("EXISTS")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -330,7 +335,7 @@ func TestHandler_EXISTS(t *testing.T) {
 	}
 
 	// Test EXISTS for non-existent keys
-	cmd = &proto.Command{Name: "EXISTS", Args: []string{"nonexistent1", "nonexistent2"}}
+	cmd = request("EXISTS", "nonexistent1", "nonexistent2")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -342,9 +347,9 @@ func TestHandler_EXISTS(t *testing.T) {
 	}
 
 	// Set a key and test EXISTS
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"existingkey", "value"}})
+	handler.HandleCommand(request("SET", "existingkey", "value"))
 
-	cmd = &proto.Command{Name: "EXISTS", Args: []string{"existingkey", "nonexistent"}}
+	cmd = request("EXISTS", "existingkey", "nonexistent")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -362,7 +367,7 @@ func TestHandler_EXPIRE_TTL(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Test EXPIRE without proper args
-	cmd := &proto.Command{Name: "EXPIRE", Args: []string{"key"}}
+	cmd := request("EXPIRE", "key")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -370,7 +375,7 @@ func TestHandler_EXPIRE_TTL(t *testing.T) {
 	}
 
 	// Test EXPIRE with invalid timeout
-	cmd = &proto.Command{Name: "EXPIRE", Args: []string{"key", "invalid"}}
+	cmd = request("EXPIRE", "key", "invalid")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -378,9 +383,9 @@ func TestHandler_EXPIRE_TTL(t *testing.T) {
 	}
 
 	// Set a key and test EXPIRE
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"expirekey", "value"}})
+	handler.HandleCommand(request("SET", "expirekey", "value"))
 
-	cmd = &proto.Command{Name: "EXPIRE", Args: []string{"expirekey", "60"}}
+	cmd = request("EXPIRE", "expirekey", "60")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -392,7 +397,7 @@ func TestHandler_EXPIRE_TTL(t *testing.T) {
 	}
 
 	// Test TTL
-	cmd = &proto.Command{Name: "TTL", Args: []string{"expirekey"}}
+	cmd = request("TTL", "expirekey")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -405,7 +410,7 @@ func TestHandler_EXPIRE_TTL(t *testing.T) {
 	}
 
 	// Test EXPIRE on non-existent key
-	cmd = &proto.Command{Name: "EXPIRE", Args: []string{"nonexistent", "60"}}
+	cmd = request("EXPIRE", "nonexistent", "60")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -423,7 +428,7 @@ func TestHandler_DBSIZE(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Test DBSIZE with args (should error)
-	cmd := &proto.Command{Name: "DBSIZE", Args: []string{"invalid"}}
+	cmd := request("DBSIZE", "invalid")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -431,7 +436,7 @@ func TestHandler_DBSIZE(t *testing.T) {
 	}
 
 	// Test DBSIZE initially
-	cmd = &proto.Command{Name: "DBSIZE", Args: []string{}}
+	cmd = request("DBSIZE")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -441,8 +446,8 @@ func TestHandler_DBSIZE(t *testing.T) {
 	initialSize := resp.Data.(int64)
 
 	// Add some keys
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"key1", "value1"}})
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"key2", "value2"}})
+	handler.HandleCommand(request("SET", "key1", "value1"))
+	handler.HandleCommand(request("SET", "key2", "value2"))
 
 	// Test DBSIZE after adding keys
 	resp = handler.HandleCommand(cmd)
@@ -463,7 +468,7 @@ func TestHandler_MGET_MSET(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Test MGET without args
-	cmd := &proto.Command{Name: "MGET", Args: []string{}}
+	cmd := request("MGET")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -471,7 +476,7 @@ func TestHandler_MGET_MSET(t *testing.T) {
 	}
 
 	// Test MSET with odd number of args
-	cmd = &proto.Command{Name: "MSET", Args: []string{"key1", "value1", "key2"}}
+	cmd = request("MSET", "key1", "value1", "key2")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -479,7 +484,7 @@ func TestHandler_MGET_MSET(t *testing.T) {
 	}
 
 	// Test MSET with valid args
-	cmd = &proto.Command{Name: "MSET", Args: []string{"key1", "value1", "key2", "value2"}}
+	cmd = request("MSET", "key1", "value1", "key2", "value2")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.SimpleString || resp.Data.(string) != "OK" {
@@ -487,7 +492,7 @@ func TestHandler_MGET_MSET(t *testing.T) {
 	}
 
 	// Test MGET
-	cmd = &proto.Command{Name: "MGET", Args: []string{"key1", "nonexistent", "key2"}}
+	cmd = request("MGET", "key1", "nonexistent", "key2")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Array {
@@ -518,7 +523,7 @@ func TestHandler_INCR_DECR(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Test INCR on non-existent key
-	cmd := &proto.Command{Name: "INCR", Args: []string{"counter"}}
+	cmd := request("INCR", "counter")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -541,7 +546,7 @@ func TestHandler_INCR_DECR(t *testing.T) {
 	}
 
 	// Test DECR
-	cmd = &proto.Command{Name: "DECR", Args: []string{"counter"}}
+	cmd = request("DECR", "counter")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -553,7 +558,7 @@ func TestHandler_INCR_DECR(t *testing.T) {
 	}
 
 	// Test INCRBY
-	cmd = &proto.Command{Name: "INCRBY", Args: []string{"counter", "5"}}
+	cmd = request("INCRBY", "counter", "5")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -565,7 +570,7 @@ func TestHandler_INCR_DECR(t *testing.T) {
 	}
 
 	// Test DECRBY
-	cmd = &proto.Command{Name: "DECRBY", Args: []string{"counter", "3"}}
+	cmd = request("DECRBY", "counter", "3")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -577,9 +582,9 @@ func TestHandler_INCR_DECR(t *testing.T) {
 	}
 
 	// Test INCR on non-numeric value
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"nonnum", "notanumber"}})
+	handler.HandleCommand(request("SET", "nonnum", "notanumber"))
 
-	cmd = &proto.Command{Name: "INCR", Args: []string{"nonnum"}}
+	cmd = request("INCR", "nonnum")
 	resp = handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -615,7 +620,7 @@ func TestHandler_ArgsValidation(t *testing.T) {
 		t.Run(tt.command+"_validation", func(t *testing.T) {
 			t.Parallel()
 
-			cmd := &proto.Command{Name: tt.command, Args: tt.args}
+			cmd := request(tt.command, tt.args...)
 			resp := handler.HandleCommand(cmd)
 
 			if tt.wantErr && resp.Type != proto.Error {
@@ -630,7 +635,7 @@ func TestHandler_UnknownCommand(t *testing.T) {
 
 	handler := createTestHandler(t)
 
-	cmd := &proto.Command{Name: "UNKNOWN", Args: []string{}}
+	cmd := request("UNKNOWN")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -648,7 +653,7 @@ func TestHandler_QUIT(t *testing.T) {
 
 	handler := createTestHandler(t)
 
-	cmd := &proto.Command{Name: "QUIT", Args: []string{}}
+	cmd := request("QUIT")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.SimpleString {
@@ -695,7 +700,7 @@ func TestHandler_SET_PXOption(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			cmd := &proto.Command{Name: "SET", Args: tt.args}
+			cmd := request("SET", tt.args...)
 			resp := handler.HandleCommand(cmd)
 
 			if tt.wantErr {
@@ -757,7 +762,7 @@ func TestHandler_INCRBY_DECRBY_EdgeCases(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			cmd := &proto.Command{Name: tt.command, Args: tt.args}
+			cmd := request(tt.command, tt.args...)
 			resp := handler.HandleCommand(cmd)
 
 			if tt.wantErr {
@@ -780,10 +785,10 @@ func TestHandler_IncrementBy_ExistingNonNumericValue(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Set a non-numeric value first
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"nonnum", "hello"}})
+	handler.HandleCommand(request("SET", "nonnum", "hello"))
 
 	// Try to increment it
-	cmd := &proto.Command{Name: "INCRBY", Args: []string{"nonnum", "5"}}
+	cmd := request("INCRBY", "nonnum", "5")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Error {
@@ -802,22 +807,45 @@ func TestHandler_IncrementBy_IntegerOverflow(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Set a very large number
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"bignum", "9223372036854775807"}}) // max int64
+	handler.HandleCommand(request("SET", "bignum", "9223372036854775807")) // max int64
 
-	// Try to increment it (this will overflow to negative in Go)
-	cmd := &proto.Command{Name: "INCRBY", Args: []string{"bignum", "1"}}
+	// Try to increment it
+	cmd := request("INCRBY", "bignum", "1")
 	resp := handler.HandleCommand(cmd)
 
-	// The current implementation doesn't check for overflow, so it returns an integer
-	// In a production implementation, this should be an error, but for now we'll test what it actually does
-	if resp.Type != proto.Integer {
-		t.Errorf("Expected Integer response (current implementation), got %v", resp.Type)
+	if resp.Type != proto.Error {
+		t.Fatalf("Expected Error response on overflow, got %v", resp.Type)
+	}
+
+	expectedMsg := "ERR increment or decrement would overflow"
+	if resp.Data.(string) != expectedMsg {
+		t.Errorf("Expected error %q, got %q", expectedMsg, resp.Data.(string))
+	}
+
+	// The stored value must be left untouched
+	resp = handler.HandleCommand(request("GET", "bignum"))
+	if resp.Data.(string) != "9223372036854775807" {
+		t.Errorf("Expected value to be unchanged after overflow, got %q", resp.Data.(string))
 	}
 
-	// The result should be negative due to overflow (9223372036854775807 + 1 = -9223372036854775808)
-	result := resp.Data.(int64)
-	if result >= 0 {
-		t.Errorf("Expected negative result due to overflow, got %d", result)
+	// DECRBY with the minimum int64 cannot be negated
+	resp = handler.HandleCommand(request("DECRBY", "other", "-9223372036854775808"))
+	if resp.Type != proto.Error || resp.Data.(string) != "ERR decrement would overflow" {
+		t.Errorf("Expected decrement overflow error, got %v: %v", resp.Type, resp.Data)
+	}
+}
+
+func TestHandler_IncrementBy_PreservesTTL(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+
+	handler.HandleCommand(request("SET", "ratelimit", "1", "EX", "100"))
+	handler.HandleCommand(request("INCR", "ratelimit"))
+
+	resp := handler.HandleCommand(request("TTL", "ratelimit"))
+	if ttl := resp.Data.(int64); ttl <= 0 || ttl > 100 {
+		t.Errorf("Expected INCR to keep the TTL, got %d", ttl)
 	}
 }
 
@@ -827,10 +855,10 @@ func TestHandler_IncrementBy_ExistingNumericValue(t *testing.T) {
 	handler := createTestHandler(t)
 
 	// Set a numeric value first
-	handler.HandleCommand(&proto.Command{Name: "SET", Args: []string{"num", "100"}})
+	handler.HandleCommand(request("SET", "num", "100"))
 
 	// Increment it
-	cmd := &proto.Command{Name: "INCRBY", Args: []string{"num", "50"}}
+	cmd := request("INCRBY", "num", "50")
 	resp := handler.HandleCommand(cmd)
 
 	if resp.Type != proto.Integer {
@@ -841,3 +869,68 @@ func TestHandler_IncrementBy_ExistingNumericValue(t *testing.T) {
 		t.Errorf("Expected 150, got %d", resp.Data.(int64))
 	}
 }
+
+func TestHandler_MaxMemory(t *testing.T) {
+	t.Parallel()
+
+	logger := obs.NewLogger(false)
+	s, err := store.New(&store.Config{
+		Shards:         4,
+		MaxMemoryBytes: 1024,
+		EvictionPolicy: "noeviction",
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	config := server.DefaultConfig()
+	config.Storage.MaxMemoryBytes = 1024
+	handler := server.NewHandler(s, config, logger)
+
+	// Fill the store until writes are refused
+	var oom *proto.Response
+	for i := 0; i < 100 && oom == nil; i++ {
+		resp := handler.HandleCommand(request("SET", "key"+strconv.Itoa(i), "0123456789"))
+		if resp.Type == proto.Error {
+			oom = resp
+		}
+	}
+	if oom == nil {
+		t.Fatal("Expected SET to be refused once maxmemory is reached")
+	}
+	if want := "OOM command not allowed when used memory > 'maxmemory'"; oom.Data != want {
+		t.Errorf("Expected %q, got %v", want, oom.Data)
+	}
+	if used := s.MemoryUsage(); used > 1024 {
+		t.Errorf("Expected used memory to stay within the limit, got %d", used)
+	}
+
+	tests := []struct {
+		name     string
+		cmd      *proto.Request
+		wantType proto.ResponseType
+	}{
+		{name: "append refused", cmd: request("APPEND", "key0", strings.Repeat("x", 1024)), wantType: proto.Error},
+		{name: "get allowed", cmd: request("GET", "key0"), wantType: proto.BulkString},
+		{name: "expire allowed", cmd: request("EXPIRE", "key0", "100"), wantType: proto.Integer},
+		{name: "del allowed", cmd: request("DEL", "key0"), wantType: proto.Integer},
+	}
+
+	for _, tt := range tests {
+		if resp := handler.HandleCommand(tt.cmd); resp.Type != tt.wantType {
+			t.Errorf("%s: expected type %v, got %v: %v", tt.name, tt.wantType, resp.Type, resp.Data)
+		}
+	}
+
+	// Deleting frees memory so small writes are accepted again
+	handler.HandleCommand(request("FLUSHDB"))
+	if resp := handler.HandleCommand(request("SET", "key", "value")); resp.Type != proto.SimpleString {
+		t.Errorf("Expected SET to succeed after FLUSHDB, got %v", resp.Data)
+	}
+
+	info := handler.HandleCommand(request("INFO", "commandstats"))
+	if !strings.Contains(info.Data.(string), "rejected_calls=1,") {
+		t.Errorf("Expected rejected calls in commandstats, got:\n%s", info.Data)
+	}
+}
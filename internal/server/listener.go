@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+)
+
+// listener accepts clients on one of the configured addresses
+type listener struct {
+	net.Listener
+	// noAuth logs clients in as the default user without AUTH
+	noAuth bool
+}
+
+// openListeners opens server.listen_addr, server.listeners and the TLS
+// listener, in that order. On error the listeners already opened are closed.
+func (s *Server) openListeners() ([]*listener, error) {
+	var listeners []*listener
+	fail := func(err error) ([]*listener, error) {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+		return nil, err
+	}
+
+	if addr := s.config.Server.ListenAddr; addr != "" {
+		l, err := listen(networkTCP, addr)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, &listener{Listener: l})
+		s.logger.Info("Server listening", "addr", addr)
+	}
+
+	for _, config := range s.config.Server.Listeners {
+		var (
+			l   net.Listener
+			err error
+		)
+		if config.Network == networkUnix {
+			l, err = listenUnix(config)
+		} else {
+			l, err = listen(config.Network, config.Addr)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, &listener{Listener: l, noAuth: config.NoAuth})
+		s.logger.Info("Server listening", "network", config.Network, "addr", config.Addr, "no_auth", config.NoAuth)
+	}
+
+	if s.tls != nil {
+		addr := s.config.Server.TLS.ListenAddr
+		l, err := listen(networkTCP, addr)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, &listener{Listener: tls.NewListener(l, s.tls.serverConfig())})
+		s.logger.Info("Server listening for TLS", "addr", addr)
+	}
+
+	return listeners, nil
+}
+
+// listen opens a listener on addr over network
+func listen(network, addr string) (net.Listener, error) {
+	lc := net.ListenConfig{}
+	l, err := lc.Listen(context.Background(), network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return l, nil
+}
+
+// listenUnix opens a Unix socket listener. A socket left behind by a server
+// that did not shut down cleanly is replaced; the socket is removed again
+// when the listener is closed.
+func listenUnix(config ListenerConfig) (net.Listener, error) {
+	mode, err := config.socketMode()
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Lstat(config.Addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(config.Addr); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", config.Addr, err)
+		}
+	}
+
+	l, err := listen(networkUnix, config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if unixListener, ok := l.(*net.UnixListener); ok {
+		unixListener.SetUnlinkOnClose(true)
+	}
+
+	if mode != 0 {
+		if err := os.Chmod(config.Addr, mode); err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("failed to set the mode of socket %s: %w", config.Addr, err)
+		}
+	}
+	return l, nil
+}
+
+// remoteAddr names the client of conn in logs. Unix socket clients have no
+// address of their own, so they are named after the socket.
+func remoteAddr(conn net.Conn) string {
+	if addr := conn.RemoteAddr(); addr != nil && addr.String() != "" {
+		return addr.String()
+	}
+	return "unix:" + conn.LocalAddr().String()
+}
@@ -0,0 +1,164 @@
+package server_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+)
+
+func TestServer_UnixSocket(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(t.TempDir(), "kv.sock")
+	config := server.DefaultConfig()
+	config.Server.AuthPassword = "secret"
+	config.Server.Listeners = []server.ListenerConfig{
+		{Network: "unix", Addr: socket, SocketMode: "0600", NoAuth: true},
+	}
+	addr := startServer(t, config)
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("Failed to stat the socket: %v", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected a socket with mode 0600, got %v", info.Mode())
+	}
+
+	local, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to connect to the socket: %v", err)
+	}
+	defer func() { _ = local.Close() }()
+
+	remote, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = remote.Close() }()
+
+	// Only the socket's clients are let in without AUTH
+	steps := []struct {
+		conn     net.Conn
+		args     []string
+		expected string
+	}{
+		{conn: local, args: []string{"ACL", "WHOAMI"}, expected: "$7\r\ndefault\r\n"},
+		{conn: local, args: []string{"SET", "k", "v"}, expected: "+OK\r\n"},
+		{conn: remote, args: []string{"GET", "k"}, expected: "-NOAUTH Authentication required.\r\n"},
+		{conn: remote, args: []string{"AUTH", "secret"}, expected: "+OK\r\n"},
+		{conn: remote, args: []string{"GET", "k"}, expected: "$1\r\nv\r\n"},
+	}
+	for _, step := range steps {
+		if got := roundTrip(t, step.conn, step.expected, step.args...); got != step.expected {
+			t.Errorf("%v: expected %q, got %q", step.args, step.expected, got)
+		}
+	}
+}
+
+func TestServer_UnixSocket_Cleanup(t *testing.T) {
+	t.Parallel()
+
+	// A socket left behind by a server that did not shut down cleanly
+	socket := filepath.Join(t.TempDir(), "kv.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		t.Fatalf("Failed to create the stale socket: %v", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	config := server.DefaultConfig()
+	config.Server.ListenAddr = ""
+	config.Server.Listeners = []server.ListenerConfig{{Network: "unix", Addr: socket}}
+	config.Observability.PrometheusListen = ""
+	srv, err := server.New(config, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to connect to the socket: %v", err)
+	}
+	if got := roundTrip(t, conn, "+PONG\r\n", "PING"); got != "+PONG\r\n" {
+		t.Errorf("Expected PONG, got %q", got)
+	}
+	_ = conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("ListenAndServe failed: %v", err)
+	}
+
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("Expected the socket to be removed on shutdown, got %v", err)
+	}
+}
+
+func TestServer_Listeners_TCP6(t *testing.T) {
+	t.Parallel()
+
+	probe, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	addr := probe.Addr().String()
+	_ = probe.Close()
+
+	config := server.DefaultConfig()
+	config.Server.Listeners = []server.ListenerConfig{{Network: "tcp6", Addr: addr}}
+	startServer(t, config)
+
+	conn, err := net.Dial("tcp6", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if got := roundTrip(t, conn, "+PONG\r\n", "PING"); got != "+PONG\r\n" {
+		t.Errorf("Expected PONG, got %q", got)
+	}
+}
+
+func TestServer_ListenError(t *testing.T) {
+	t.Parallel()
+
+	// The second listener fails, and the first is closed again
+	addr := freeAddr(t)
+	config := server.DefaultConfig()
+	config.Server.ListenAddr = addr
+	config.Server.Listeners = []server.ListenerConfig{
+		{Network: "unix", Addr: filepath.Join(t.TempDir(), "missing", "kv.sock")},
+	}
+	config.Observability.PrometheusListen = ""
+	srv, err := server.New(config, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	if err := srv.ListenAndServe(); err == nil {
+		t.Fatal("Expected a listen error")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Expected %s to be released: %v", addr, err)
+	}
+	_ = listener.Close()
+}
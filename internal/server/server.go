@@ -2,51 +2,101 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
-	"sync/atomic"
 	"time"
 
+	"github.com/Abhishek2095/kv-stash/internal/acl"
 	"github.com/Abhishek2095/kv-stash/internal/obs"
 	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/pubsub"
 	"github.com/Abhishek2095/kv-stash/internal/store"
 )
 
+const (
+	// hotKeysMetricInterval is how often the hot key metric is refreshed
+	hotKeysMetricInterval = time.Second
+	// keyspaceMetricInterval is how often the key count and expiry index
+	// metrics are refreshed. Both lock every shard, so they are kept off the
+	// command path.
+	keyspaceMetricInterval = time.Second
+)
+
+// maxBatchBytes flushes a connection's buffered replies early once they reach
+// this size, however short the pipeline
+const maxBatchBytes = 64 * 1024
+
 // Server represents the main kv-stash server
 type Server struct {
-	config    *AppConfig
-	logger    *obs.Logger
-	listener  net.Listener
-	store     *store.Store
-	metrics   *obs.Metrics
-	startTime time.Time
-
-	// Connection management
-	connections sync.Map
-	connCount   int64
-
-	// Shutdown
+	config *AppConfig
+	logger *obs.Logger
+	// listeners are the plaintext, Unix socket and TLS listeners, set under
+	// acceptMu
+	listeners []*listener
+	// tls builds the TLS listener's configuration; nil without TLS
+	tls     *tlsReloader
+	store   *store.Store
+	metrics *obs.Metrics
+	stats   *Stats
+	broker  *pubsub.Broker
+	acl     *acl.ACL
+
+	// clients holds the session of every open connection
+	clients *clientRegistry
+
+	// Shutdown. acceptMu orders closing shutdown against wg.Add, so no
+	// goroutine is counted once Shutdown has started waiting.
 	shutdown chan struct{}
 	done     chan struct{}
 	wg       sync.WaitGroup
+	acceptMu sync.Mutex
 }
 
 // New creates a new server instance
 func New(config *AppConfig, logger *obs.Logger) (*Server, error) {
+	// Create metrics
+	metrics := obs.NewMetrics()
+
+	// Keyspace notifications are published through the same broker clients
+	// subscribe to
+	broker := pubsub.NewBroker()
+	events, err := pubsub.ParseKeyspaceEvents(config.PubSub.NotifyKeyspaceEvents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyspace events: %w", err)
+	}
+	var onKeyEvent func(db int, event, key string)
+	if notifier := pubsub.NewNotifier(broker, events); notifier.Enabled() {
+		onKeyEvent = notifier.Notify
+	}
+
 	// Create the store
+	var activeExpireCycle time.Duration
+	if config.TTL.Strategy == ttlStrategyLazyActive {
+		activeExpireCycle = config.TTL.ActiveCycle
+	}
 	storeInstance, err := store.New(&store.Config{
-		Shards:         config.Server.Shards,
-		MaxMemoryBytes: config.Storage.MaxMemoryBytes,
-		EvictionPolicy: config.Storage.EvictionPolicy,
+		Shards:            config.Server.Shards,
+		Databases:         config.Storage.Databases,
+		MaxMemoryBytes:    config.Storage.MaxMemoryBytes,
+		EvictionPolicy:    config.Storage.EvictionPolicy,
+		ExpiryIndex:       config.TTL.Index,
+		ActiveExpireCycle: activeExpireCycle,
+		OnExpired:         metrics.AddExpiredKeys,
+		OnEvicted:         metrics.AddEvictedKeys,
+		HotKeySampleRate:  config.Observability.HotKeys.SampleRate,
+		HotKeyWindow:      time.Duration(config.Observability.HotKeys.WindowSeconds) * time.Second,
+		OnKeyEvent:        onKeyEvent,
+		ShardLoops:        config.Server.ExecutionMode == executionModeShardLoop,
+		ShardMailbox:      config.Server.ShardMailbox,
 	}, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create store: %w", err)
 	}
 
-	// Create metrics
-	metrics := obs.NewMetrics()
-
 	// Start metrics server
 	if config.Observability.PrometheusListen != "" {
 		go func() {
@@ -56,41 +106,101 @@ func New(config *AppConfig, logger *obs.Logger) (*Server, error) {
 		}()
 	}
 
+	stats := NewStats()
+	stats.onAuthFailure = metrics.IncAuthFailures
+
+	users := newACL(config)
+	if config.Server.ACLFile != "" {
+		if err := users.LoadFile(config.Server.ACLFile); err != nil {
+			return nil, fmt.Errorf("failed to load ACL users: %w", err)
+		}
+	}
+
+	var reloader *tlsReloader
+	if config.Server.TLS.ListenAddr != "" {
+		reloader, err = newTLSReloader(config.Server.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure TLS: %w", err)
+		}
+	}
+
 	return &Server{
-		config:    config,
-		logger:    logger,
-		store:     storeInstance,
-		metrics:   metrics,
-		startTime: time.Now(),
-		shutdown:  make(chan struct{}),
-		done:      make(chan struct{}),
+		config:   config,
+		logger:   logger,
+		tls:      reloader,
+		clients:  newClientRegistry(),
+		store:    storeInstance,
+		metrics:  metrics,
+		stats:    stats,
+		broker:   broker,
+		acl:      users,
+		shutdown: make(chan struct{}),
+		done:     make(chan struct{}),
 	}, nil
 }
 
-// ListenAndServe starts the server and listens for connections
+// ListenAndServe starts the server and listens for connections on every
+// configured listener
 func (s *Server) ListenAndServe() error {
-	lc := net.ListenConfig{}
-	listener, err := lc.Listen(context.Background(), "tcp", s.config.Server.ListenAddr)
+	listeners, err := s.openListeners()
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", s.config.Server.ListenAddr, err)
+		return err
+	}
+
+	s.acceptMu.Lock()
+	select {
+	case <-s.shutdown:
+		s.acceptMu.Unlock()
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+		return nil
+	default:
+	}
+	s.listeners = listeners
+	if s.config.Observability.PrometheusListen != "" {
+		s.wg.Add(1)
+		go s.keyspaceMetricLoop()
 	}
-	s.listener = listener
+	if s.config.Observability.PrometheusListen != "" && s.config.Observability.HotKeys.MetricTopN > 0 &&
+		s.store.HotKeyWindow() > 0 {
+		s.wg.Add(1)
+		go s.hotKeysMetricLoop()
+	}
+	if s.tls != nil && s.config.Server.TLS.ReloadInterval > 0 {
+		s.wg.Add(1)
+		go s.tlsReloadLoop()
+	}
+	s.acceptMu.Unlock()
 
-	s.logger.Info("Server listening", "addr", s.config.Server.ListenAddr)
+	// Every listener but the last is served on its own goroutine
+	var serving sync.WaitGroup
+	for _, l := range listeners[:len(listeners)-1] {
+		serving.Add(1)
+		go func() {
+			defer serving.Done()
+			s.serve(l)
+		}()
+	}
+	s.serve(listeners[len(listeners)-1])
+	serving.Wait()
+	return nil
+}
 
-	// Accept connections
+// serve accepts connections from l until shutdown
+func (s *Server) serve(l *listener) {
 	for {
 		select {
 		case <-s.shutdown:
-			return nil
+			return
 		default:
 		}
 
-		conn, err := listener.Accept()
+		conn, err := l.Accept()
 		if err != nil {
 			select {
 			case <-s.shutdown:
-				return nil
+				return
 			default:
 				s.logger.Error("Failed to accept connection", "error", err)
 				continue
@@ -98,30 +208,41 @@ func (s *Server) ListenAndServe() error {
 		}
 
 		// Check connection limits
-		if atomic.LoadInt64(&s.connCount) >= int64(s.config.Limits.MaxClients) {
+		if s.stats.ConnectedClients() >= int64(s.config.Limits.MaxClients) {
 			s.logger.Warn("Connection limit reached, closing new connection")
+			s.stats.connectionRejected()
 			_ = conn.Close()
 			continue
 		}
 
-		// Handle connection
-		go s.handleConnection(conn)
+		// Handle connection. Count it before the goroutine starts so the limit
+		// check and Shutdown's wait both see it.
+		s.acceptMu.Lock()
+		select {
+		case <-s.shutdown:
+			s.acceptMu.Unlock()
+			_ = conn.Close()
+			return
+		default:
+		}
+		s.stats.clientConnected()
+		s.wg.Add(1)
+		s.acceptMu.Unlock()
+		go s.handleConnection(conn, l)
 	}
 }
 
-// handleConnection handles a single client connection
-func (s *Server) handleConnection(conn net.Conn) {
-	atomic.AddInt64(&s.connCount, 1)
+// handleConnection handles a single client connection accepted by l
+func (s *Server) handleConnection(conn net.Conn, l *listener) {
+	defer s.wg.Done()
+
 	s.metrics.IncConnections()
 	defer func() {
-		atomic.AddInt64(&s.connCount, -1)
+		s.stats.clientDisconnected()
 		s.metrics.DecConnections()
 		_ = conn.Close()
 	}()
 
-	s.wg.Add(1)
-	defer s.wg.Done()
-
 	// Set connection timeouts
 	if s.config.Server.ReadTimeout > 0 {
 		_ = conn.SetReadDeadline(time.Now().Add(s.config.Server.ReadTimeout))
@@ -130,16 +251,37 @@ func (s *Server) handleConnection(conn net.Conn) {
 		_ = conn.SetWriteDeadline(time.Now().Add(s.config.Server.WriteTimeout))
 	}
 
-	clientID := conn.RemoteAddr().String()
-	s.connections.Store(clientID, conn)
-	defer s.connections.Delete(clientID)
-
-	logger := s.logger.WithFields("client", clientID)
+	logger := s.logger.WithFields("client", remoteAddr(conn))
 	logger.Debug("Client connected")
 
-	// Create RESP parser and handler
-	parser := proto.NewParser(conn)
-	handler := NewHandler(s.store, &s.config.Server, logger)
+	// Create RESP parser, reply writer and handler
+	parser := proto.NewParserWithLimits(conn, s.config.Limits.protoLimits())
+	writer := proto.NewWriter(conn)
+	handler := newHandler(s.store, s.config, s.stats, s.broker, s.acl, s.clients, conn, logger)
+	defer handler.Close()
+	if l.noAuth {
+		handler.authenticateAs(acl.DefaultUser)
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if !s.handshake(tlsConn, handler, logger) {
+			return
+		}
+	}
+
+	// writeMu serializes command replies with published messages, which are
+	// written by a forwarder started once the client subscribes. It also
+	// guards the writer's buffer.
+	var writeMu sync.Mutex
+	forwarding := false
+	defer func() {
+		writeMu.Lock()
+		_ = writer.Flush()
+		writeMu.Unlock()
+	}()
+
+	// pending counts the replies buffered since the last flush
+	pending := 0
 
 	// Main request loop
 	for {
@@ -155,15 +297,18 @@ func (s *Server) handleConnection(conn net.Conn) {
 		}
 
 		// Parse command
-		cmd, err := parser.ParseCommand()
+		cmd, err := parser.ReadRequest()
 		if err != nil {
-			if err.Error() == "EOF" {
+			if errors.Is(err, io.EOF) {
 				logger.Debug("Client disconnected")
 				return
 			}
 			logger.Debug("Parse error", "error", err)
-			// Send error response for protocol errors
-			_ = proto.WriteResponse(conn, proto.NewError("ERR Protocol error: "+err.Error()))
+			// Send error response for protocol errors; it is flushed with
+			// any pending replies on return
+			writeMu.Lock()
+			_ = writer.WriteResponse(proto.NewError("ERR Protocol error: "+err.Error()), handler.Protocol())
+			writeMu.Unlock()
 			return
 		}
 
@@ -172,7 +317,10 @@ func (s *Server) handleConnection(conn net.Conn) {
 			_ = conn.SetWriteDeadline(time.Now().Add(s.config.Server.WriteTimeout))
 		}
 
-		// Handle command with metrics
+		// Handle command with metrics. The write lock is held until the
+		// reply is sent so a subscription's confirmation always precedes
+		// its messages.
+		writeMu.Lock()
 		s.metrics.IncCommandsInFlight()
 		start := time.Now()
 
@@ -184,17 +332,185 @@ func (s *Server) handleConnection(conn net.Conn) {
 		s.metrics.DecCommandsInFlight()
 
 		// Update metrics
-		s.metrics.SetKeys(s.store.DBSize())
-		s.metrics.SetUptime(time.Since(s.startTime))
+		s.metrics.SetMemoryUsage(s.store.MemoryUsage())
+		s.metrics.SetUptime(s.stats.Uptime())
+
+		// Buffer the reply. Replies are flushed once every pipelined command
+		// received so far is answered, or early when the batch reaches
+		// limits.max_pipeline. No further command is read until the flush
+		// completes, so a client that stops reading its replies stops being
+		// served.
+		if !handler.ReplyMuted() {
+			err = writer.WriteResponse(response, handler.Protocol())
+			pending++
+		}
+		if err == nil && (parser.Buffered() == 0 || pending >= s.config.Limits.MaxPipeline || writer.Buffered() >= maxBatchBytes) {
+			err = writer.Flush()
+			pending = 0
+		}
+		writeMu.Unlock()
+		if err != nil {
+			logger.Debug("Write error", "error", err)
+			return
+		}
+		if handler.Closing() {
+			logger.Debug("Client killed itself")
+			return
+		}
+
+		if sub := handler.Subscriber(); sub != nil && !forwarding {
+			forwarding = true
+			s.wg.Add(1)
+			go s.forwardMessages(conn, writer, handler, sub, &writeMu, logger)
+		}
+	}
+}
+
+// handshake completes the TLS handshake of conn and, with
+// server.tls.auth_clients_user set to CN, authenticates the client as the
+// user its certificate names. It reports whether the handshake succeeded.
+func (s *Server) handshake(conn *tls.Conn, handler *Handler, logger *obs.Logger) bool {
+	timeout := s.config.Server.ReadTimeout
+	if timeout <= 0 {
+		timeout = tlsHandshakeTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := conn.HandshakeContext(ctx); err != nil {
+		logger.Debug("TLS handshake failed", "error", err)
+		return false
+	}
+
+	if s.config.Server.TLS.AuthClientsUser == tlsAuthClientsUserCN {
+		if cn := certificateCommonName(conn.ConnectionState()); cn != "" && !handler.authenticateAs(cn) {
+			logger.Debug("No enabled user for the client certificate", "cn", cn)
+		}
+	}
+	return true
+}
+
+// ReloadTLS reloads the TLS certificate, key and CA files. New connections
+// use them while open ones keep their session; on error the previous files
+// stay in use.
+func (s *Server) ReloadTLS() error {
+	if s.tls == nil {
+		return errTLSDisabled
+	}
+	if err := s.tls.reload(); err != nil {
+		return fmt.Errorf("failed to reload TLS certificates: %w", err)
+	}
+	s.logger.Info("TLS certificates reloaded")
+	return nil
+}
+
+// tlsReloadLoop reloads the TLS files when they change until shutdown
+func (s *Server) tlsReloadLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Server.TLS.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+		}
 
-		// Note: Expired keys are tracked automatically in the store
+		if !s.tls.changed() {
+			continue
+		}
+		if err := s.ReloadTLS(); err != nil {
+			s.logger.Error("Failed to reload TLS certificates", "error", err)
+		}
+	}
+}
+
+// forwardMessages writes the messages published to the subscriber's channels
+// to conn until the subscriber is closed. A subscriber closed for falling
+// behind disconnects its client.
+func (s *Server) forwardMessages(conn net.Conn, writer *proto.Writer, handler *Handler, sub *pubsub.Subscriber, writeMu *sync.Mutex, logger *obs.Logger) {
+	defer s.wg.Done()
 
-		// Send response
-		if err := proto.WriteResponse(conn, response); err != nil {
+	for msg := range sub.Messages() {
+		writeMu.Lock()
+		if s.config.Server.WriteTimeout > 0 {
+			_ = conn.SetWriteDeadline(time.Now().Add(s.config.Server.WriteTimeout))
+		}
+		// The protocol is read under writeMu, which HELLO runs under
+		err := writer.WriteResponse(messageResponse(msg), handler.Protocol())
+		if err == nil {
+			err = writer.Flush()
+		}
+		writeMu.Unlock()
+		if err != nil {
 			logger.Debug("Write error", "error", err)
+			_ = conn.Close()
 			return
 		}
 	}
+
+	if sub.Overflowed() {
+		logger.Warn("Subscriber fell behind, closing connection")
+		_ = conn.Close()
+	}
+}
+
+// keyspaceMetricLoop periodically publishes the number of keys and of keys in
+// the expiry indexes until shutdown
+func (s *Server) keyspaceMetricLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(keyspaceMetricInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+		}
+
+		// Shard loops hand their shards over for the reads
+		var keys, indexed int64
+		err := s.store.Exclusive(nil, func() {
+			keys, indexed = s.store.DBSize(), s.store.ExpiryIndexSize()
+		})
+		if err != nil {
+			return
+		}
+		s.metrics.SetKeys(keys)
+		s.metrics.SetExpiryIndexSize(indexed)
+	}
+}
+
+// hotKeysMetricLoop periodically publishes the hottest keys over the whole
+// tracked window until shutdown
+func (s *Server) hotKeysMetricLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(hotKeysMetricInterval)
+	defer ticker.Stop()
+
+	window := s.store.HotKeyWindow()
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+		}
+
+		hot, err := s.store.HotKeys(s.config.Observability.HotKeys.MetricTopN, window)
+		if err != nil {
+			return
+		}
+		rates := make([]obs.HotKeyRate, len(hot))
+		for i, key := range hot {
+			rates[i] = obs.HotKeyRate{Key: key.Key, PerSecond: float64(key.Count) / window.Seconds()}
+		}
+		s.metrics.SetHotKeys(rates)
+	}
 }
 
 // Shutdown gracefully shuts down the server
@@ -202,12 +518,19 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Starting graceful shutdown")
 
 	// Signal shutdown
+	s.acceptMu.Lock()
 	close(s.shutdown)
+	s.acceptMu.Unlock()
 
-	// Close listener
-	if s.listener != nil {
-		_ = s.listener.Close()
+	// Paused clients are let through to finish
+	s.clients.unpause()
+
+	// Close listeners
+	s.acceptMu.Lock()
+	for _, l := range s.listeners {
+		_ = l.Close()
 	}
+	s.acceptMu.Unlock()
 
 	// Wait for connections to finish or timeout
 	done := make(chan struct{})
@@ -222,14 +545,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	case <-ctx.Done():
 		s.logger.Warn("Shutdown timeout reached, forcing close")
 		// Force close all connections
-		s.connections.Range(func(_, value any) bool {
-			if conn, ok := value.(net.Conn); ok {
-				_ = conn.Close()
-			}
-			return true
-		})
+		s.clients.killAll()
 	}
 
+	s.store.Close()
+
 	close(s.done)
 	return nil
 }
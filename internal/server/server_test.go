@@ -2,7 +2,11 @@ package server_test
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -386,3 +390,580 @@ func TestServer_ConnectionTimeouts(t *testing.T) {
 	defer cancel()
 	_ = srv.Shutdown(ctx)
 }
+
+func TestServer_INFO_ConnectionStats(t *testing.T) {
+	t.Parallel()
+
+	logger := obs.NewLogger(false)
+	config := server.DefaultConfig()
+
+	// Get available port
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	config.Server.ListenAddr = addr
+	config.Limits.MaxClients = 1
+	config.Observability.PrometheusListen = ""
+
+	srv, err := server.New(config, logger)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	time.Sleep(50 * time.Millisecond)
+
+	// Over the limit: accepted by the kernel, then closed by the server
+	rejected, err := net.Dial("tcp", addr)
+	if err == nil {
+		_ = rejected.SetReadDeadline(time.Now().Add(time.Second))
+		_, _ = rejected.Read(make([]byte, 1))
+		_ = rejected.Close()
+	}
+
+	_, err = conn.Write([]byte("*2\r\n$4\r\nINFO\r\n$5\r\nstats\r\n*2\r\n$4\r\nINFO\r\n$7\r\nclients\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var response strings.Builder
+	buffer := make([]byte, 4096)
+	for !strings.Contains(response.String(), "# Clients") || !strings.HasSuffix(response.String(), "\r\n") {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			t.Fatalf("Failed to read INFO: %v (got %q)", err, response.String())
+		}
+		response.Write(buffer[:n])
+	}
+
+	for _, field := range []string{"rejected_connections:1", "total_connections_received:1", "connected_clients:1"} {
+		if !strings.Contains(response.String(), field) {
+			t.Errorf("Expected %q in INFO, got:\n%s", field, response.String())
+		}
+	}
+
+	_ = conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+}
+
+func TestServer_PubSub_KeyspaceNotifications(t *testing.T) {
+	t.Parallel()
+
+	logger := obs.NewLogger(false)
+	config := server.DefaultConfig()
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	config.Server.ListenAddr = addr
+	config.Observability.PrometheusListen = ""
+	config.PubSub.NotifyKeyspaceEvents = "KEg$"
+
+	srv, err := server.New(config, logger)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	subscriber, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = subscriber.Close() }()
+	publisher, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = publisher.Close() }()
+
+	// readUntil reads from conn until the accumulated output ends with suffix
+	readUntil := func(conn net.Conn, suffix string) string {
+		t.Helper()
+
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		var response strings.Builder
+		buffer := make([]byte, 4096)
+		for !strings.HasSuffix(response.String(), suffix) {
+			n, err := conn.Read(buffer)
+			if err != nil {
+				t.Fatalf("Failed to read %q: %v (got %q)", suffix, err, response.String())
+			}
+			response.Write(buffer[:n])
+		}
+		return response.String()
+	}
+
+	if _, err := subscriber.Write([]byte("*3\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n$15\r\n__keyevent@0__*\r\n" +
+		"*2\r\n$10\r\nPSUBSCRIBE\r\n$15\r\n__keyevent@0__*\r\n")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	got := readUntil(subscriber, "$10\r\npsubscribe\r\n$15\r\n__keyevent@0__*\r\n:3\r\n")
+	expected := "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n" +
+		"*3\r\n$9\r\nsubscribe\r\n$15\r\n__keyevent@0__*\r\n:2\r\n" +
+		"*3\r\n$10\r\npsubscribe\r\n$15\r\n__keyevent@0__*\r\n:3\r\n"
+	if got != expected {
+		t.Errorf("Expected subscribe confirmations %q, got %q", expected, got)
+	}
+
+	// Subscribed clients may not run regular commands
+	if _, err := subscriber.Write([]byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if got := readUntil(subscriber, "\r\n"); !strings.HasPrefix(got, "-ERR Can't execute 'get'") {
+		t.Errorf("Expected GET to be refused, got %q", got)
+	}
+
+	if _, err := publisher.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n" +
+		"*2\r\n$3\r\nDEL\r\n$1\r\nk\r\n" +
+		"*3\r\n$7\r\nPUBLISH\r\n$4\r\nnews\r\n$2\r\nhi\r\n")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if got := readUntil(publisher, ":1\r\n:1\r\n"); got != "+OK\r\n:1\r\n:1\r\n" {
+		t.Errorf("Expected OK, 1 deleted and 1 receiver, got %q", got)
+	}
+
+	got = readUntil(subscriber, "$4\r\nnews\r\n$2\r\nhi\r\n")
+	expected = "*4\r\n$8\r\npmessage\r\n$15\r\n__keyevent@0__*\r\n$18\r\n__keyevent@0__:set\r\n$1\r\nk\r\n" +
+		"*4\r\n$8\r\npmessage\r\n$15\r\n__keyevent@0__*\r\n$18\r\n__keyevent@0__:del\r\n$1\r\nk\r\n" +
+		"*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n"
+	if got != expected {
+		t.Errorf("Expected messages %q, got %q", expected, got)
+	}
+
+	_ = subscriber.Close()
+	_ = publisher.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+}
+
+func TestServer_HELLO_RESP3(t *testing.T) {
+	t.Parallel()
+
+	logger := obs.NewLogger(false)
+	config := server.DefaultConfig()
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	config.Server.ListenAddr = addr
+	config.Observability.PrometheusListen = ""
+
+	srv, err := server.New(config, logger)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// The GET before HELLO is answered in RESP2, the one after in RESP3
+	_, err = conn.Write([]byte("*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n" +
+		"*2\r\n$5\r\nHELLO\r\n$1\r\n3\r\n" +
+		"*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var response strings.Builder
+	buffer := make([]byte, 4096)
+	for !strings.HasSuffix(response.String(), "*0\r\n_\r\n") {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			t.Fatalf("Failed to read: %v (got %q)", err, response.String())
+		}
+		response.Write(buffer[:n])
+	}
+
+	got := response.String()
+	if !strings.HasPrefix(got, "$-1\r\n%7\r\n$6\r\nserver\r\n$8\r\nkv-stash\r\n") {
+		t.Errorf("Expected a RESP2 null then a RESP3 map, got %q", got)
+	}
+	if !strings.Contains(got, "$5\r\nproto\r\n:3\r\n") {
+		t.Errorf("Expected proto 3 in HELLO reply, got %q", got)
+	}
+
+	_ = conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+}
+
+// encodeCommand encodes args as a RESP array of bulk strings
+func encodeCommand(args ...string) []byte {
+	command := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, arg := range args {
+		command = fmt.Appendf(command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return command
+}
+
+// roundTrip sends a command and reads a reply of the expected length
+func roundTrip(t *testing.T, conn net.Conn, expected string, args ...string) string {
+	t.Helper()
+
+	if _, err := conn.Write(encodeCommand(args...)); err != nil {
+		t.Fatalf("Failed to write %v: %v", args, err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	reply := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("Failed to read the reply to %v: %v (got %q)", args, err, reply)
+	}
+	return string(reply)
+}
+
+// startServer starts a server with config on a free port and returns its
+// address. The server is shut down when the test ends.
+func startServer(tb testing.TB, config *server.AppConfig) string {
+	tb.Helper()
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		tb.Fatalf("Failed to create listener: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	config.Server.ListenAddr = addr
+	config.Observability.PrometheusListen = ""
+
+	srv, err := server.New(config, obs.NewLogger(false))
+	if err != nil {
+		tb.Fatalf("Failed to create server: %v", err)
+	}
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	tb.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	return addr
+}
+
+// pipeline returns depth commands that alternately set and get a key
+func pipeline(depth int) []byte {
+	var commands []byte
+	for i := range depth {
+		if i%2 == 0 {
+			commands = append(commands, "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n"...)
+		} else {
+			commands = append(commands, "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"...)
+		}
+	}
+	return commands
+}
+
+// pipelineReplies returns the replies to pipeline(depth)
+func pipelineReplies(depth int) string {
+	var replies strings.Builder
+	for i := range depth {
+		if i%2 == 0 {
+			replies.WriteString("+OK\r\n")
+		} else {
+			replies.WriteString("$5\r\nvalue\r\n")
+		}
+	}
+	return replies.String()
+}
+
+func TestServer_Pipeline(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		maxPipeline int
+		depth       int
+	}{
+		{name: "Shorter than max pipeline", maxPipeline: 1024, depth: 16},
+		{name: "Longer than max pipeline", maxPipeline: 4, depth: 101},
+		{name: "Max pipeline of one", maxPipeline: 1, depth: 10},
+		{name: "Replies larger than a batch", maxPipeline: 1024, depth: 20000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := server.DefaultConfig()
+			config.Limits.MaxPipeline = tt.maxPipeline
+			conn, err := net.Dial("tcp", startServer(t, config))
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			// Write from another goroutine: a deep pipeline only makes
+			// progress while its replies are being read
+			go func() {
+				_, _ = conn.Write(pipeline(tt.depth))
+			}()
+
+			expected := pipelineReplies(tt.depth)
+			_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			got := make([]byte, len(expected))
+			if _, err := io.ReadFull(conn, got); err != nil {
+				t.Fatalf("Failed to read replies: %v", err)
+			}
+			if string(got) != expected {
+				t.Errorf("Expected %d replies in order, got %q", tt.depth, got)
+			}
+		})
+	}
+}
+
+func TestServer_ProtocolLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Bulk length over the limit", input: "*2\r\n$3\r\nGET\r\n$4000000000\r\n", expected: "-ERR Protocol error: invalid bulk length\r\n"},
+		{name: "Element count over the limit", input: "*2147483647\r\n", expected: "-ERR Protocol error: invalid multibulk length\r\n"},
+		{name: "Inline request over the limit", input: "GET " + strings.Repeat("x", 100) + "\r\n", expected: "-ERR Protocol error: too big inline request\r\n"},
+		{name: "Replies before the error are sent", input: "PING\r\n*-2\r\n", expected: "+PONG\r\n-ERR Protocol error: invalid multibulk length\r\n"},
+	}
+
+	config := server.DefaultConfig()
+	config.Limits.ProtoMaxBulkLen = 1024
+	config.Limits.MaxMultiBulkLen = 16
+	config.Limits.MaxInlineLen = 64
+	addr := startServer(t, config)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			if _, err := conn.Write([]byte(tt.input)); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+
+			// The server replies with the error and closes the connection
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			got, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// BenchmarkServer_Pipeline measures round trips over TCP, sending P commands
+// before reading their replies like redis-benchmark -P
+func BenchmarkServer_Pipeline(b *testing.B) {
+	for _, depth := range []int{1, 16} {
+		b.Run(fmt.Sprintf("P%d", depth), func(b *testing.B) {
+			conn, err := net.Dial("tcp", startServer(b, server.DefaultConfig()))
+			if err != nil {
+				b.Fatalf("Failed to connect: %v", err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			commands := pipeline(depth)
+			replies := make([]byte, len(pipelineReplies(depth)))
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := conn.Write(commands); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := io.ReadFull(conn, replies); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*depth)/b.Elapsed().Seconds(), "ops/s")
+		})
+	}
+}
+
+// BenchmarkServer_Commands measures single commands through the connection
+// loop, from parsing the request to writing the reply, so allocs/op is the
+// server's allocations per command
+func BenchmarkServer_Commands(b *testing.B) {
+	tests := []struct {
+		name  string
+		args  []string
+		reply string
+	}{
+		{name: "GET", args: []string{"GET", "key"}, reply: "$5\r\nvalue\r\n"},
+		{name: "SET", args: []string{"SET", "key", "value"}, reply: "+OK\r\n"},
+		{name: "MGET", args: []string{"MGET", "key", "key", "key"}, reply: "*3\r\n$5\r\nvalue\r\n$5\r\nvalue\r\n$5\r\nvalue\r\n"},
+		{name: "EXISTS", args: []string{"EXISTS", "key"}, reply: ":1\r\n"},
+	}
+
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			conn, err := net.Dial("tcp", startServer(b, server.DefaultConfig()))
+			if err != nil {
+				b.Fatalf("Failed to connect: %v", err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			set := encodeCommand("SET", "key", "value")
+			if _, err := conn.Write(set); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := io.ReadFull(conn, make([]byte, len("+OK\r\n"))); err != nil {
+				b.Fatal(err)
+			}
+
+			command := encodeCommand(tt.args...)
+			reply := make([]byte, len(tt.reply))
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := conn.Write(command); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := io.ReadFull(conn, reply); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestServer_RequestBuffer checks that what is kept from a request survives
+// the next request reusing the parser's buffer
+func TestServer_RequestBuffer(t *testing.T) {
+	t.Parallel()
+
+	config := server.DefaultConfig()
+	config.Observability.HotKeys.SampleRate = 1
+	conn, err := net.Dial("tcp", startServer(t, config))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"GET", "hot"}, expected: "$-1\r\n"},
+		{args: []string{"GET", "hot"}, expected: "$-1\r\n"},
+		// Overwrites the bytes the GETs were read from
+		{args: []string{"SET", "abc", "xyz"}, expected: "+OK\r\n"},
+		{args: []string{"HOTKEYS", "COUNT", "1"}, expected: "*1\r\n*3\r\n$3\r\nhot\r\n:2\r\n$5\r\n66.67\r\n"},
+	}
+	for _, step := range steps {
+		if got := roundTrip(t, conn, step.expected, step.args...); got != step.expected {
+			t.Errorf("%v: expected %q, got %q", step.args, step.expected, got)
+		}
+	}
+}
+
+func TestServer_ACLFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "users.acl")
+	if err := os.WriteFile(path, []byte("user jobs on >pw ~job:* +@all -@dangerous\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write the ACL file: %v", err)
+	}
+
+	config := server.DefaultConfig()
+	config.Server.AuthPassword = "admin"
+	config.Server.ACLFile = path
+	addr := startServer(t, config)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Users are shared by every connection
+	admin, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = admin.Close() }()
+
+	steps := []struct {
+		conn     net.Conn
+		args     []string
+		expected string
+	}{
+		{conn: conn, args: []string{"GET", "job:1"}, expected: "-NOAUTH Authentication required.\r\n"},
+		{conn: conn, args: []string{"AUTH", "jobs", "pw"}, expected: "+OK\r\n"},
+		{conn: conn, args: []string{"SET", "job:1", "v"}, expected: "+OK\r\n"},
+		{conn: conn, args: []string{"SET", "other", "v"}, expected: "-NOPERM No permissions to access a key\r\n"},
+		{conn: conn, args: []string{"FLUSHALL"}, expected: "-NOPERM User jobs has no permissions to run the 'flushall' command\r\n"},
+		// The default user kept the configured password as the file does not define it
+		{conn: admin, args: []string{"AUTH", "admin"}, expected: "+OK\r\n"},
+		{conn: admin, args: []string{"ACL", "SETUSER", "jobs", "~other"}, expected: "+OK\r\n"},
+		{conn: conn, args: []string{"SET", "other", "v"}, expected: "+OK\r\n"},
+	}
+	for _, step := range steps {
+		if got := roundTrip(t, step.conn, step.expected, step.args...); got != step.expected {
+			t.Errorf("%v: expected %q, got %q", step.args, step.expected, got)
+		}
+	}
+}
+
+func TestNew_InvalidACLFile(t *testing.T) {
+	t.Parallel()
+
+	config := server.DefaultConfig()
+	config.Server.ACLFile = filepath.Join(t.TempDir(), "missing.acl")
+
+	_, err := server.New(config, obs.NewLogger(false))
+	if err == nil || !strings.Contains(err.Error(), "failed to load ACL users") {
+		t.Errorf("Expected an ACL file error, got %v", err)
+	}
+}
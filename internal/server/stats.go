@@ -0,0 +1,196 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// runIDBytes is the size of the random server run ID (40 hex characters)
+	runIDBytes = 20
+	// memStatsMaxAge is how long a runtime memory reading is reused. Taking
+	// one stops the world, so INFO polled by monitoring must not take one
+	// every call.
+	memStatsMaxAge = time.Second
+)
+
+// Stats holds the server-wide counters reported by INFO. It is shared by
+// every connection's handler and safe for concurrent use.
+type Stats struct {
+	startTime time.Time
+	runID     string
+
+	connectedClients    int64
+	totalConnections    int64
+	rejectedConnections int64
+	totalCommands       int64
+	authFailures        int64
+
+	// onAuthFailure, when set, is called for every failed authentication
+	onAuthFailure func()
+
+	// lastClientID is the ID given to the most recent handler
+	lastClientID int64
+
+	commands sync.Map // command name -> *commandStats
+
+	// mem is the last runtime memory reading, taken at memRead; memMu
+	// guards both
+	memMu   sync.Mutex
+	mem     runtime.MemStats
+	memRead time.Time
+}
+
+// commandStats holds per-command counters for INFO commandstats
+type commandStats struct {
+	calls         int64
+	usec          int64
+	rejectedCalls int64
+	failedCalls   int64
+}
+
+// CommandStat is a snapshot of one command's counters
+type CommandStat struct {
+	Name          string
+	Calls         int64
+	Usec          int64
+	RejectedCalls int64
+	FailedCalls   int64
+}
+
+// NewStats creates a Stats instance whose uptime starts now
+func NewStats() *Stats {
+	id := make([]byte, runIDBytes)
+	_, _ = rand.Read(id)
+
+	return &Stats{
+		startTime: time.Now(),
+		runID:     hex.EncodeToString(id),
+	}
+}
+
+// Uptime returns how long the server has been running
+func (st *Stats) Uptime() time.Duration {
+	return time.Since(st.startTime)
+}
+
+// ConnectedClients returns the number of open client connections
+func (st *Stats) ConnectedClients() int64 {
+	return atomic.LoadInt64(&st.connectedClients)
+}
+
+// TotalConnections returns the number of connections accepted since startup
+func (st *Stats) TotalConnections() int64 {
+	return atomic.LoadInt64(&st.totalConnections)
+}
+
+// RejectedConnections returns the number of connections refused because the
+// client limit was reached
+func (st *Stats) RejectedConnections() int64 {
+	return atomic.LoadInt64(&st.rejectedConnections)
+}
+
+// TotalCommands returns the number of commands processed since startup
+func (st *Stats) TotalCommands() int64 {
+	return atomic.LoadInt64(&st.totalCommands)
+}
+
+// AuthFailures returns the number of failed AUTH and HELLO AUTH attempts
+func (st *Stats) AuthFailures() int64 {
+	return atomic.LoadInt64(&st.authFailures)
+}
+
+// memory returns the Go heap in use and the memory obtained from the OS, from
+// a runtime reading at most memStatsMaxAge old
+func (st *Stats) memory() (heapAlloc, sys uint64) {
+	st.memMu.Lock()
+	defer st.memMu.Unlock()
+
+	if now := time.Now(); now.Sub(st.memRead) >= memStatsMaxAge {
+		runtime.ReadMemStats(&st.mem)
+		st.memRead = now
+	}
+	return st.mem.HeapAlloc, st.mem.Sys
+}
+
+// nextClientID returns a new unique, increasing client ID
+func (st *Stats) nextClientID() int64 {
+	return atomic.AddInt64(&st.lastClientID, 1)
+}
+
+// clientConnected records an accepted connection
+func (st *Stats) clientConnected() {
+	atomic.AddInt64(&st.connectedClients, 1)
+	atomic.AddInt64(&st.totalConnections, 1)
+}
+
+// clientDisconnected records a closed connection
+func (st *Stats) clientDisconnected() {
+	atomic.AddInt64(&st.connectedClients, -1)
+}
+
+// connectionRejected records a connection refused because of maxclients
+func (st *Stats) connectionRejected() {
+	atomic.AddInt64(&st.rejectedConnections, 1)
+}
+
+// authFailed records a failed authentication attempt
+func (st *Stats) authFailed() {
+	atomic.AddInt64(&st.authFailures, 1)
+	if st.onAuthFailure != nil {
+		st.onAuthFailure()
+	}
+}
+
+// recordCommand records one command execution. Commands are counted under
+// their upper-case name, and reported in lower case.
+func (st *Stats) recordCommand(name string, duration time.Duration, success bool) {
+	atomic.AddInt64(&st.totalCommands, 1)
+
+	stats := st.command(name)
+	atomic.AddInt64(&stats.calls, 1)
+	atomic.AddInt64(&stats.usec, duration.Microseconds())
+	if !success {
+		atomic.AddInt64(&stats.failedCalls, 1)
+	}
+}
+
+// recordRejected records a command refused before it ran, e.g. because the
+// memory limit was reached
+func (st *Stats) recordRejected(name string) {
+	atomic.AddInt64(&st.command(name).rejectedCalls, 1)
+}
+
+// command returns the counters of the named command, creating them on first use
+func (st *Stats) command(name string) *commandStats {
+	entry, ok := st.commands.Load(name)
+	if !ok {
+		entry, _ = st.commands.LoadOrStore(name, &commandStats{})
+	}
+	return entry.(*commandStats)
+}
+
+// CommandStats returns the per-command counters sorted by command name
+func (st *Stats) CommandStats() []CommandStat {
+	var result []CommandStat
+	st.commands.Range(func(key, value any) bool {
+		stats := value.(*commandStats)
+		result = append(result, CommandStat{
+			Name:          strings.ToLower(key.(string)),
+			Calls:         atomic.LoadInt64(&stats.calls),
+			Usec:          atomic.LoadInt64(&stats.usec),
+			RejectedCalls: atomic.LoadInt64(&stats.rejectedCalls),
+			FailedCalls:   atomic.LoadInt64(&stats.failedCalls),
+		})
+		return true
+	})
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
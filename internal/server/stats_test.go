@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/proto"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func TestStats_New(t *testing.T) {
+	t.Parallel()
+
+	stats := server.NewStats()
+	if stats.Uptime() < 0 {
+		t.Errorf("Expected non-negative uptime, got %v", stats.Uptime())
+	}
+	if stats.ConnectedClients() != 0 || stats.TotalConnections() != 0 || stats.RejectedConnections() != 0 {
+		t.Errorf("Expected zero connection counters on a new Stats")
+	}
+	if stats.TotalCommands() != 0 || len(stats.CommandStats()) != 0 {
+		t.Errorf("Expected no recorded commands on a new Stats")
+	}
+}
+
+func TestStats_CommandStats(t *testing.T) {
+	t.Parallel()
+
+	handler := createTestHandler(t)
+	commands := []*proto.Request{
+		request("SET", "k", "v"),
+		request("GET", "k"),
+		request("INCR", "k"),
+		request("GET", "k"),
+	}
+	for _, cmd := range commands {
+		handler.HandleCommand(cmd)
+	}
+
+	expected := []server.CommandStat{
+		{Name: "get", Calls: 2},
+		{Name: "incr", Calls: 1, FailedCalls: 1},
+		{Name: "set", Calls: 1},
+	}
+
+	stats := handler.Stats().CommandStats()
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d command stats, got %+v", len(expected), stats)
+	}
+	for i, want := range expected {
+		got := stats[i]
+		if got.Name != want.Name || got.Calls != want.Calls || got.FailedCalls != want.FailedCalls {
+			t.Errorf("Expected %+v at %d, got %+v", want, i, got)
+		}
+	}
+	if total := handler.Stats().TotalCommands(); total != 4 {
+		t.Errorf("Expected 4 total commands, got %d", total)
+	}
+}
+
+func TestStats_RejectedCalls(t *testing.T) {
+	t.Parallel()
+
+	s, err := store.New(&store.Config{Shards: 4, EvictionPolicy: "noeviction"}, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	config := server.DefaultConfig()
+	config.Server.AuthPassword = "secret"
+	handler := server.NewHandler(s, config, obs.NewLogger(false))
+
+	// Commands refused before they run are rejected, not failed; unknown
+	// commands are not counted at all
+	commands := []*proto.Request{
+		request("GET", "k"),
+		request("NOSUCH"),
+		request("AUTH", "secret"),
+		request("SUBSCRIBE", "news"),
+		request("GET", "k"),
+	}
+	for _, cmd := range commands {
+		handler.HandleCommand(cmd)
+	}
+
+	stats := make(map[string]server.CommandStat)
+	for _, stat := range handler.Stats().CommandStats() {
+		stats[stat.Name] = stat
+	}
+	if get := stats["get"]; get.RejectedCalls != 2 || get.Calls != 0 {
+		t.Errorf("Expected 2 rejected GET calls, got %+v", get)
+	}
+	if stat, ok := stats["nosuch"]; ok {
+		t.Errorf("Expected unknown commands to be left out, got %+v", stat)
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tlsHandshakeTimeout bounds the handshake of a TLS client when no read
+// timeout is configured
+const tlsHandshakeTimeout = 10 * time.Second
+
+// errTLSDisabled is returned when reloading certificates without a TLS
+// listener
+var errTLSDisabled = errors.New("TLS is not enabled")
+
+// tlsReloader holds the TLS configuration built from the certificate files
+// and rebuilds it when they change. Connections pick up the current
+// configuration at the handshake, so a reload never affects open ones.
+type tlsReloader struct {
+	config  TLSConfig
+	current atomic.Pointer[tls.Config]
+
+	// mu serializes reloads and guards modTimes, the modification times of
+	// the files the current configuration was built from
+	mu       sync.Mutex
+	modTimes map[string]time.Time
+}
+
+// newTLSReloader builds the TLS configuration, failing if the certificate
+// files cannot be loaded
+func newTLSReloader(config TLSConfig) (*tlsReloader, error) {
+	r := &tlsReloader{config: config}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// serverConfig returns the configuration for a TLS listener, which hands
+// every handshake the current configuration
+func (r *tlsReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// reload rebuilds the configuration from the files. The current one is kept
+// when they fail to load.
+func (r *tlsReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The times are read first so a file changed during the load is
+	// reloaded again on the next check
+	modTimes := r.readModTimes()
+	config, err := buildTLSConfig(r.config)
+	if err != nil {
+		return err
+	}
+
+	r.current.Store(config)
+	r.modTimes = modTimes
+	return nil
+}
+
+// changed reports whether a file has changed since the configuration was
+// last built
+func (r *tlsReloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for path, modTime := range r.readModTimes() {
+		if !modTime.Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// readModTimes returns the modification times of the configured files, the
+// zero time for those that cannot be read
+func (r *tlsReloader) readModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time, 3)
+	for _, path := range []string{r.config.CertFile, r.config.KeyFile, r.config.CAFile} {
+		if path == "" {
+			continue
+		}
+		var modTime time.Time
+		if info, err := os.Stat(path); err == nil {
+			modTime = info.ModTime()
+		}
+		modTimes[path] = modTime
+	}
+	return modTimes
+}
+
+// buildTLSConfig loads the certificate files into a TLS configuration
+func buildTLSConfig(config TLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	minVersion, err := parseTLSVersion(config.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	ciphers, err := parseCipherSuites(config.Ciphers)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   minVersion,
+		CipherSuites: ciphers,
+	}
+
+	switch config.ClientAuth {
+	case tlsClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case tlsClientAuthRequired:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		tlsConfig.ClientAuth = tls.NoClientCert
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile) // #nosec G304 -- Path comes from the operator's configuration
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in TLS CA file %s", config.CAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// parseTLSVersion parses a server.tls.min_version
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS min version: %s", version)
+	}
+}
+
+// parseCipherSuites parses server.tls.ciphers. Only suites Go considers
+// secure are accepted, and none leaves the choice to Go.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	suites := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid TLS cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// certificateCommonName returns the common name of the verified client
+// certificate of a TLS connection, or "" if the client sent none
+func certificateCommonName(state tls.ConnectionState) string {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName
+}
@@ -0,0 +1,434 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/server"
+)
+
+// testCA issues certificates for the TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate the CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kv-stash test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create the CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse the CA certificate: %v", err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for commonName, valid for
+// localhost when usage is server authentication
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("Failed to generate a serial number: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if usage == x509.ExtKeyUsageServerAuth {
+		template.DNSNames = []string{"localhost"}
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create a certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal a key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// clientCertificate returns a client certificate for commonName
+func (ca *testCA) clientCertificate(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, commonName, x509.ExtKeyUsageClientAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load the client certificate: %v", err)
+	}
+	return cert
+}
+
+// writeFile writes data to name in dir and returns its path
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// tlsTestConfig returns a configuration whose TLS listener serves a
+// certificate for commonName issued by ca, with the CA file set for client
+// certificates
+func tlsTestConfig(t *testing.T, ca *testCA, commonName string) *server.AppConfig {
+	t.Helper()
+
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, commonName, x509.ExtKeyUsageServerAuth)
+
+	config := server.DefaultConfig()
+	config.Server.TLS.ListenAddr = freeAddr(t)
+	config.Server.TLS.CertFile = writeFile(t, dir, "server.pem", certPEM)
+	config.Server.TLS.KeyFile = writeFile(t, dir, "server-key.pem", keyPEM)
+	config.Server.TLS.CAFile = writeFile(t, dir, "ca.pem", ca.pem)
+	return config
+}
+
+// freeAddr returns an address with a free port
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	return addr
+}
+
+// dialTLS connects to addr trusting ca, presenting certs
+func dialTLS(addr string, ca *testCA, certs ...tls.Certificate) (*tls.Conn, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: time.Second},
+		Config: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: certs,
+		},
+	}
+	conn, err := dialer.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*tls.Conn), nil
+}
+
+// tlsCommand sends args over a new TLS connection and returns the reply,
+// which is expected to be as long as expected
+func tlsCommand(addr string, ca *testCA, expected string, certs []tls.Certificate, args ...string) (string, error) {
+	conn, err := dialTLS(addr, ca, certs...)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Write(encodeCommand(args...)); err != nil {
+		return "", err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	reply := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return "", err
+	}
+	return string(reply), nil
+}
+
+func TestServer_TLS(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	config := tlsTestConfig(t, ca, "localhost")
+	addr := startServer(t, config)
+
+	// The plaintext listener is served alongside the TLS one
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if got := roundTrip(t, conn, "+OK\r\n", "SET", "k", "v"); got != "+OK\r\n" {
+		t.Errorf("Expected OK over plaintext, got %q", got)
+	}
+
+	got, err := tlsCommand(config.Server.TLS.ListenAddr, ca, "$1\r\nv\r\n", nil, "GET", "k")
+	if err != nil {
+		t.Fatalf("TLS command failed: %v", err)
+	}
+	if got != "$1\r\nv\r\n" {
+		t.Errorf("Expected the key over TLS, got %q", got)
+	}
+
+	// A client that does not speak TLS is disconnected
+	plain, err := net.Dial("tcp", config.Server.TLS.ListenAddr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = plain.Close() }()
+	_, _ = plain.Write(encodeCommand("PING"))
+	_ = plain.SetReadDeadline(time.Now().Add(time.Second))
+	if reply, _ := io.ReadAll(plain); strings.Contains(string(reply), "PONG") {
+		t.Errorf("Expected a plaintext client to be disconnected, got %q", reply)
+	}
+}
+
+func TestServer_TLS_ClientAuth(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	other := newTestCA(t)
+
+	tests := []struct {
+		name       string
+		clientAuth string
+		certs      []tls.Certificate
+		wantErr    bool
+	}{
+		{name: "optional without certificate", clientAuth: "optional"},
+		{name: "optional with certificate", clientAuth: "optional", certs: []tls.Certificate{ca.clientCertificate(t, "app")}},
+		{name: "optional with untrusted certificate", clientAuth: "optional", certs: []tls.Certificate{other.clientCertificate(t, "app")}, wantErr: true},
+		{name: "required without certificate", clientAuth: "required", wantErr: true},
+		{name: "required with certificate", clientAuth: "required", certs: []tls.Certificate{ca.clientCertificate(t, "app")}},
+		{name: "required with untrusted certificate", clientAuth: "required", certs: []tls.Certificate{other.clientCertificate(t, "app")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := tlsTestConfig(t, ca, "localhost")
+			config.Server.ListenAddr = ""
+			config.Server.TLS.ClientAuth = tt.clientAuth
+			startTLSServer(t, config)
+
+			got, err := tlsCommand(config.Server.TLS.ListenAddr, ca, "+PONG\r\n", tt.certs, "PING")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected the connection to be rejected, got %q", got)
+				}
+				return
+			}
+			if err != nil || got != "+PONG\r\n" {
+				t.Errorf("Expected PONG, got %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestServer_TLS_CommonNameUser(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	config := tlsTestConfig(t, ca, "localhost")
+	config.Server.AuthPassword = "admin"
+	config.Server.ACLFile = writeFile(t, t.TempDir(), "users.acl",
+		[]byte("user jobs on >pw ~job:* +@all\nuser retired off >pw +@all\n"))
+	config.Server.TLS.ClientAuth = "optional"
+	config.Server.TLS.AuthClientsUser = "CN"
+	startServer(t, config)
+
+	tests := []struct {
+		name     string
+		certs    []tls.Certificate
+		expected string
+	}{
+		{name: "known user", certs: []tls.Certificate{ca.clientCertificate(t, "jobs")}, expected: "$4\r\njobs\r\n"},
+		{name: "unknown user", certs: []tls.Certificate{ca.clientCertificate(t, "nobody")}, expected: "-NOAUTH Authentication required.\r\n"},
+		{name: "disabled user", certs: []tls.Certificate{ca.clientCertificate(t, "retired")}, expected: "-NOAUTH Authentication required.\r\n"},
+		{name: "no certificate", expected: "-NOAUTH Authentication required.\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tlsCommand(config.Server.TLS.ListenAddr, ca, tt.expected, tt.certs, "ACL", "WHOAMI")
+			if err != nil {
+				t.Fatalf("TLS command failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestServer_TLS_ReloadOnChange(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	config := tlsTestConfig(t, ca, "first")
+	config.Server.TLS.ReloadInterval = 20 * time.Millisecond
+	startServer(t, config)
+
+	if cn := serverCommonName(t, config.Server.TLS.ListenAddr, ca); cn != "first" {
+		t.Fatalf("Expected the first certificate, got %q", cn)
+	}
+
+	// The modification times are moved on so the change is seen however
+	// coarse the file system's clock
+	certPEM, keyPEM := ca.issue(t, "second", x509.ExtKeyUsageServerAuth)
+	later := time.Now().Add(time.Minute)
+	for path, data := range map[string][]byte{config.Server.TLS.CertFile: certPEM, config.Server.TLS.KeyFile: keyPEM} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("Failed to rewrite %s: %v", path, err)
+		}
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatalf("Failed to touch %s: %v", path, err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for serverCommonName(t, config.Server.TLS.ListenAddr, ca) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the rewritten certificate to be served")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestServer_ReloadTLS(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	config := tlsTestConfig(t, ca, "localhost")
+	srv, err := server.New(config, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	if err := srv.ReloadTLS(); err != nil {
+		t.Errorf("ReloadTLS failed: %v", err)
+	}
+
+	writeFile(t, filepath.Dir(config.Server.TLS.CertFile), "server.pem", []byte("not a certificate"))
+	if err := srv.ReloadTLS(); err == nil || !strings.Contains(err.Error(), "failed to reload TLS certificates") {
+		t.Errorf("Expected a reload error, got %v", err)
+	}
+
+	plain, err := server.New(server.DefaultConfig(), obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	t.Cleanup(func() { _ = plain.Shutdown(context.Background()) })
+	if err := plain.ReloadTLS(); err == nil {
+		t.Error("Expected an error reloading TLS without a TLS listener")
+	}
+}
+
+func TestNew_InvalidTLS(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	tests := []struct {
+		name   string
+		modify func(*server.AppConfig)
+	}{
+		{name: "missing certificate", modify: func(c *server.AppConfig) {
+			c.Server.TLS.CertFile = filepath.Join(t.TempDir(), "missing.pem")
+		}},
+		{name: "invalid CA file", modify: func(c *server.AppConfig) {
+			c.Server.TLS.CAFile = writeFile(t, t.TempDir(), "ca.pem", []byte("not a certificate"))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := tlsTestConfig(t, ca, "localhost")
+			tt.modify(config)
+			_, err := server.New(config, obs.NewLogger(false))
+			if err == nil || !strings.Contains(err.Error(), "failed to configure TLS") {
+				t.Errorf("Expected a TLS error, got %v", err)
+			}
+		})
+	}
+}
+
+// startTLSServer starts a server with only a TLS listener, which is shut
+// down when the test ends
+func startTLSServer(t *testing.T, config *server.AppConfig) {
+	t.Helper()
+
+	config.Observability.PrometheusListen = ""
+	srv, err := server.New(config, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+
+	time.Sleep(100 * time.Millisecond)
+}
+
+// serverCommonName returns the common name of the certificate served at addr
+func serverCommonName(t *testing.T, addr string, ca *testCA) string {
+	t.Helper()
+
+	conn, err := dialTLS(addr, ca)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
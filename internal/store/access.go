@@ -0,0 +1,141 @@
+package store
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+const (
+	// lfuInitValue is the frequency counter given to new keys so they are not
+	// immediately the least frequently used
+	lfuInitValue = 5
+	// lfuLogFactor controls how quickly the logarithmic counter saturates;
+	// with 10 it reaches 255 after about a million accesses
+	lfuLogFactor = 10
+	// lfuMaxValue is the saturation point of the frequency counter
+	lfuMaxValue = 255
+	// lfuDecayTime is how long a key must go unaccessed for its frequency
+	// counter to drop by one
+	lfuDecayTime = time.Minute
+
+	// embstrMaxLength is the longest string Redis stores with the embstr encoding
+	embstrMaxLength = 44
+	// intEncodingMaxLength is the longest decimal representation of an int64
+	intEncodingMaxLength = 20
+
+	// mapEntryOverhead approximates the per-entry cost of the shard map beyond
+	// the key and value themselves (control bytes, slot padding, load factor)
+	mapEntryOverhead = 32
+	// stringHeaderSize is the size of a Go string header
+	stringHeaderSize = int64(unsafe.Sizeof(""))
+	// scanEntrySize is the size of a key's entry in the scan index
+	scanEntrySize = int64(unsafe.Sizeof(scanEntry{}))
+	// valueStructSize is the size of the Value metadata struct
+	valueStructSize = int64(unsafe.Sizeof(Value{}))
+	// timeStructSize is the size of the heap-allocated expiration time
+	timeStructSize = int64(unsafe.Sizeof(time.Time{}))
+)
+
+// KeyInfo describes a key for the TYPE, OBJECT and MEMORY commands
+type KeyInfo struct {
+	Type     ValueType
+	Encoding string
+	Idle     time.Duration
+	Freq     uint8
+	Size     int64
+}
+
+// Inspect returns introspection data for key without counting as an access
+func (s *Store) Inspect(key string) (KeyInfo, bool) {
+	shard := s.getShard(key)
+	shard.rlock()
+	defer shard.runlock()
+
+	now := time.Now()
+	value, exists := shard.dbs[s.db].data[key]
+	if !exists || value.isExpired(now) {
+		return KeyInfo{}, false
+	}
+
+	return KeyInfo{
+		Type:     value.Type,
+		Encoding: value.encoding(),
+		Idle:     now.Sub(time.Unix(0, atomic.LoadInt64(&value.lastAccess))),
+		Freq:     value.frequency(now),
+		Size:     EstimateSize(key, value),
+	}, true
+}
+
+// EstimateSize approximates the memory used by a key and its value, including
+// the value metadata, the shard map entry and the scan index entry
+func EstimateSize(key string, value *Value) int64 {
+	size := EntryOverhead + int64(len(key)) + int64(len(value.Data))
+	if value.ExpiresAt != nil {
+		size += timeStructSize
+	}
+	return size
+}
+
+// recordAccess updates the access time and frequency counter, first decaying
+// the counter for the time since the previous access. It only uses atomic
+// operations so it can run under the shard read lock.
+func (v *Value) recordAccess(now time.Time) {
+	last := atomic.SwapInt64(&v.lastAccess, now.UnixNano())
+
+	for {
+		stored := atomic.LoadUint32(&v.freq)
+		counter := decayFrequency(stored, last, now)
+
+		// Logarithmic increment: the higher the counter the less likely it grows
+		if counter < lfuMaxValue {
+			base := float64(0)
+			if counter > lfuInitValue {
+				base = float64(counter - lfuInitValue)
+			}
+			if rand.Float64() < 1/(base*lfuLogFactor+1) { // #nosec G404 -- probabilistic counter
+				counter++
+			}
+		}
+
+		if counter == stored || atomic.CompareAndSwapUint32(&v.freq, stored, counter) {
+			return
+		}
+	}
+}
+
+// frequency returns the logarithmic access frequency counter, decayed for the
+// time the value has been idle
+func (v *Value) frequency(now time.Time) uint8 {
+	counter := decayFrequency(atomic.LoadUint32(&v.freq), atomic.LoadInt64(&v.lastAccess), now)
+	return uint8(counter) // #nosec G115 -- counter saturates at 255
+}
+
+// decayFrequency lowers counter by one for every lfuDecayTime elapsed since
+// last, a unix nanosecond timestamp
+func decayFrequency(counter uint32, last int64, now time.Time) uint32 {
+	periods := now.Sub(time.Unix(0, last)) / lfuDecayTime
+	if periods <= 0 {
+		return counter
+	}
+	if periods >= time.Duration(counter) {
+		return 0
+	}
+	return counter - uint32(periods) // #nosec G115 -- periods is below counter
+}
+
+// encoding returns the Redis object encoding name for the value
+func (v *Value) encoding() string {
+	if len(v.Data) <= intEncodingMaxLength {
+		// Only canonical integers ("12", not "012" or "+12") are stored as ints
+		if n, err := strconv.ParseInt(v.Data, 10, 64); err == nil && strconv.FormatInt(n, 10) == v.Data {
+			return "int"
+		}
+	}
+	if len(v.Data) <= embstrMaxLength {
+		return "embstr"
+	}
+	return "raw"
+}
@@ -0,0 +1,104 @@
+package store_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func TestStore_Inspect(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+
+	if _, exists := s.Inspect("missing"); exists {
+		t.Errorf("Expected Inspect on missing key to report not found")
+	}
+
+	tests := []struct {
+		value    string
+		encoding string
+	}{
+		{value: "12345", encoding: "int"},
+		{value: "-42", encoding: "int"},
+		{value: "012", encoding: "embstr"},
+		{value: "hello", encoding: "embstr"},
+		{value: strings.Repeat("x", 44), encoding: "embstr"},
+		{value: strings.Repeat("x", 45), encoding: "raw"},
+	}
+
+	for _, tt := range tests {
+		s.Set("key", tt.value, nil)
+		info, exists := s.Inspect("key")
+		if !exists {
+			t.Fatalf("Expected key to exist")
+		}
+		if info.Encoding != tt.encoding {
+			t.Errorf("Expected encoding %q for %q, got %q", tt.encoding, tt.value, info.Encoding)
+		}
+		if info.Type != store.StringType {
+			t.Errorf("Expected string type, got %v", info.Type)
+		}
+	}
+}
+
+func TestStore_Inspect_IdleAndFreq(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	s.Set("key", "value", nil)
+
+	time.Sleep(20 * time.Millisecond)
+	info, _ := s.Inspect("key")
+	if info.Idle < 20*time.Millisecond {
+		t.Errorf("Expected idle time of at least 20ms, got %v", info.Idle)
+	}
+	initialFreq := info.Freq
+
+	// Inspect itself must not count as an access
+	info, _ = s.Inspect("key")
+	if info.Idle < 20*time.Millisecond {
+		t.Errorf("Expected Inspect not to reset the idle time, got %v", info.Idle)
+	}
+
+	for range 1000 {
+		s.Get("key")
+	}
+
+	info, _ = s.Inspect("key")
+	if info.Idle >= 20*time.Millisecond {
+		t.Errorf("Expected GET to reset the idle time, got %v", info.Idle)
+	}
+	if info.Freq <= initialFreq {
+		t.Errorf("Expected frequency to grow after many reads, got %d (initial %d)", info.Freq, initialFreq)
+	}
+
+	// Overwriting keeps the counter
+	s.Set("key", "other", nil)
+	if after, _ := s.Inspect("key"); after.Freq < info.Freq {
+		t.Errorf("Expected SET to keep the access frequency, got %d (before %d)", after.Freq, info.Freq)
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	s.Set("small", "v", nil)
+	s.Set("large", strings.Repeat("x", 1000), nil)
+	expiration := time.Minute
+	s.Set("volatile", "v", &expiration)
+
+	small, _ := s.Inspect("small")
+	large, _ := s.Inspect("large")
+	volatile, _ := s.Inspect("volatile")
+
+	if large.Size-small.Size != 999 {
+		t.Errorf("Expected size difference to equal the value length difference, got %d", large.Size-small.Size)
+	}
+	if volatile.Size <= small.Size+3 {
+		t.Errorf("Expected a TTL to add to the estimated size, got %d vs %d", volatile.Size, small.Size)
+	}
+}
@@ -0,0 +1,245 @@
+package store
+
+import (
+	"container/heap"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAnalyzeDelimiter separates a key's prefix from the rest of its name
+const DefaultAnalyzeDelimiter = ":"
+
+// analyzeBatchSize is the most keys Analyze copies out of a shard per lock hold
+const analyzeBatchSize = 1024
+
+// sizeBuckets are the upper bounds of the size histogram; larger keys fall
+// into a final unbounded bucket
+var sizeBuckets = []int64{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// ttlBuckets are the upper bounds of the TTL histogram of volatile keys;
+// longer TTLs fall into a final unbounded bucket
+var ttlBuckets = []time.Duration{time.Minute, time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+// Analysis describes the composition of a keyspace by memory
+type Analysis struct {
+	Keys  int64
+	Bytes int64
+	// Largest lists the biggest keys, largest first
+	Largest []KeySize
+	// SizeHistogram counts keys by size; bucket i holds sizes up to
+	// SizeBounds[i], the last bucket everything larger
+	SizeHistogram []HistogramBucket
+	SizeBounds    []int64
+	// Persistent counts keys without a TTL
+	Persistent HistogramBucket
+	// TTLHistogram counts volatile keys by remaining TTL; bucket i holds TTLs
+	// up to TTLBounds[i], the last bucket everything longer
+	TTLHistogram []HistogramBucket
+	TTLBounds    []time.Duration
+	// Prefixes lists the prefixes holding the most memory, largest first.
+	// Keys without the delimiter are grouped under the empty prefix.
+	Prefixes []PrefixSize
+}
+
+// SizeLabel names bucket i of the size histogram, e.g. "<=64" or ">1048576"
+func (a Analysis) SizeLabel(i int) string {
+	if i < len(a.SizeBounds) {
+		return "<=" + strconv.FormatInt(a.SizeBounds[i], 10)
+	}
+	return ">" + strconv.FormatInt(a.SizeBounds[len(a.SizeBounds)-1], 10)
+}
+
+// TTLLabel names bucket i of the TTL histogram in seconds, e.g. "<=60s"
+func (a Analysis) TTLLabel(i int) string {
+	if i < len(a.TTLBounds) {
+		return "<=" + ttlSeconds(a.TTLBounds[i])
+	}
+	return ">" + ttlSeconds(a.TTLBounds[len(a.TTLBounds)-1])
+}
+
+// ttlSeconds formats a TTL histogram bound in seconds
+func ttlSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d.Seconds()), 10) + "s"
+}
+
+// KeySize is a key and its estimated memory
+type KeySize struct {
+	Key   string
+	Bytes int64
+}
+
+// HistogramBucket counts keys and their memory
+type HistogramBucket struct {
+	Keys  int64
+	Bytes int64
+}
+
+// PrefixSize is the memory held by every key sharing a prefix
+type PrefixSize struct {
+	Prefix string
+	Keys   int64
+	Bytes  int64
+}
+
+// Analyzer accumulates an Analysis from keys fed one at a time, so the same
+// report can be built from a live keyspace or any other source of keys
+type Analyzer struct {
+	delimiter string
+	top       int
+	analysis  Analysis
+	largest   keySizeHeap
+	prefixes  map[string]*PrefixSize
+}
+
+// NewAnalyzer creates an analyzer grouping keys by the text before delimiter
+// and reporting the top largest keys and prefixes
+func NewAnalyzer(delimiter string, top int) *Analyzer {
+	return &Analyzer{
+		delimiter: delimiter,
+		top:       top,
+		analysis: Analysis{
+			SizeHistogram: make([]HistogramBucket, len(sizeBuckets)+1),
+			SizeBounds:    sizeBuckets,
+			TTLHistogram:  make([]HistogramBucket, len(ttlBuckets)+1),
+			TTLBounds:     ttlBuckets,
+		},
+		prefixes: make(map[string]*PrefixSize),
+	}
+}
+
+// Add records a key of the given size. ttl is its remaining time to live, or
+// negative for a key without expiration.
+func (a *Analyzer) Add(key string, size int64, ttl time.Duration) {
+	a.analysis.Keys++
+	a.analysis.Bytes += size
+
+	if a.top > 0 {
+		if len(a.largest) < a.top {
+			heap.Push(&a.largest, KeySize{Key: key, Bytes: size})
+		} else if size > a.largest[0].Bytes {
+			a.largest[0] = KeySize{Key: key, Bytes: size}
+			heap.Fix(&a.largest, 0)
+		}
+	}
+
+	bucket := sort.Search(len(sizeBuckets), func(i int) bool { return size <= sizeBuckets[i] })
+	a.analysis.SizeHistogram[bucket].Keys++
+	a.analysis.SizeHistogram[bucket].Bytes += size
+
+	if ttl < 0 {
+		a.analysis.Persistent.Keys++
+		a.analysis.Persistent.Bytes += size
+	} else {
+		bucket = sort.Search(len(ttlBuckets), func(i int) bool { return ttl <= ttlBuckets[i] })
+		a.analysis.TTLHistogram[bucket].Keys++
+		a.analysis.TTLHistogram[bucket].Bytes += size
+	}
+
+	prefix, _, found := strings.Cut(key, a.delimiter)
+	if !found {
+		prefix = ""
+	}
+	stats, exists := a.prefixes[prefix]
+	if !exists {
+		stats = &PrefixSize{Prefix: prefix}
+		a.prefixes[prefix] = stats
+	}
+	stats.Keys++
+	stats.Bytes += size
+}
+
+// Result returns the analysis of every key added so far
+func (a *Analyzer) Result() Analysis {
+	result := a.analysis
+
+	result.Largest = append([]KeySize(nil), a.largest...)
+	sort.Slice(result.Largest, func(i, j int) bool {
+		if result.Largest[i].Bytes != result.Largest[j].Bytes {
+			return result.Largest[i].Bytes > result.Largest[j].Bytes
+		}
+		return result.Largest[i].Key < result.Largest[j].Key
+	})
+
+	result.Prefixes = make([]PrefixSize, 0, len(a.prefixes))
+	for _, stats := range a.prefixes {
+		result.Prefixes = append(result.Prefixes, *stats)
+	}
+	sort.Slice(result.Prefixes, func(i, j int) bool {
+		if result.Prefixes[i].Bytes != result.Prefixes[j].Bytes {
+			return result.Prefixes[i].Bytes > result.Prefixes[j].Bytes
+		}
+		return result.Prefixes[i].Prefix < result.Prefixes[j].Prefix
+	})
+	if len(result.Prefixes) > a.top {
+		result.Prefixes = result.Prefixes[:a.top]
+	}
+
+	return result
+}
+
+// analyzedKey is the part of a key the analyzer needs, copied out of a shard
+type analyzedKey struct {
+	key       string
+	size      int64
+	expiresAt *time.Time
+}
+
+// Analyze reports which keys and key prefixes of the database dominate its
+// memory. Shards are walked one batch of analyzeBatchSize keys at a time: a
+// shard's read lock is held only while one batch's sizes are copied out, and
+// the aggregation runs with no lock held, so writers are never blocked for
+// long however large a shard grows. Keys written during the walk may or may
+// not be counted, like with SCAN.
+func (s *Store) Analyze(delimiter string, top int) Analysis {
+	analyzer := NewAnalyzer(delimiter, top)
+
+	batch := make([]analyzedKey, 0, analyzeBatchSize)
+	for _, shard := range s.shards {
+		for position := uint64(0); position != scanPositionEnd; {
+			batch = batch[:0]
+			now := time.Now()
+
+			shard.rlock()
+			_, position = shard.dbs[s.db].visit(position, analyzeBatchSize, func(key string, value *Value) {
+				if !value.isExpired(now) {
+					batch = append(batch, analyzedKey{key: key, size: value.size, expiresAt: value.ExpiresAt})
+				}
+			})
+			shard.runlock()
+
+			for _, entry := range batch {
+				ttl := time.Duration(-1)
+				if entry.expiresAt != nil {
+					ttl = entry.expiresAt.Sub(now)
+				}
+				analyzer.Add(entry.key, entry.size, ttl)
+			}
+
+			// Let writers waiting on the shard run between batches
+			runtime.Gosched()
+		}
+	}
+
+	return analyzer.Result()
+}
+
+// keySizeHeap is a min-heap of keys by size used to keep the largest keys
+type keySizeHeap []KeySize
+
+func (h keySizeHeap) Len() int           { return len(h) }
+func (h keySizeHeap) Less(i, j int) bool { return h[i].Bytes < h[j].Bytes }
+func (h keySizeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *keySizeHeap) Push(x any) {
+	*h = append(*h, x.(KeySize))
+}
+
+func (h *keySizeHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
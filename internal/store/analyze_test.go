@@ -0,0 +1,133 @@
+package store_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func TestAnalyzer(t *testing.T) {
+	t.Parallel()
+
+	analyzer := store.NewAnalyzer(":", 2)
+	analyzer.Add("user:1", 100, -1)
+	analyzer.Add("user:2", 300, 30*time.Second)
+	analyzer.Add("session:1", 5000, 2*time.Hour)
+	analyzer.Add("config", 50, -1)
+	analyzer.Add("session:2", 2000000, 30*24*time.Hour)
+
+	result := analyzer.Result()
+
+	if result.Keys != 5 || result.Bytes != 2005450 {
+		t.Errorf("Expected 5 keys and 2005450 bytes, got %d keys and %d bytes", result.Keys, result.Bytes)
+	}
+
+	expectedLargest := []store.KeySize{{Key: "session:2", Bytes: 2000000}, {Key: "session:1", Bytes: 5000}}
+	if len(result.Largest) != len(expectedLargest) {
+		t.Fatalf("Expected %v, got %v", expectedLargest, result.Largest)
+	}
+	for i := range expectedLargest {
+		if result.Largest[i] != expectedLargest[i] {
+			t.Errorf("Expected largest %v, got %v", expectedLargest, result.Largest)
+		}
+	}
+
+	expectedPrefixes := []store.PrefixSize{{Prefix: "session", Keys: 2, Bytes: 2005000}, {Prefix: "user", Keys: 2, Bytes: 400}}
+	if len(result.Prefixes) != len(expectedPrefixes) {
+		t.Fatalf("Expected %v, got %v", expectedPrefixes, result.Prefixes)
+	}
+	for i := range expectedPrefixes {
+		if result.Prefixes[i] != expectedPrefixes[i] {
+			t.Errorf("Expected prefixes %v, got %v", expectedPrefixes, result.Prefixes)
+		}
+	}
+
+	// Buckets: <=64, <=256, <=1K, <=4K, <=16K, ..., >1M
+	sizes := map[int]int64{0: 1, 1: 1, 2: 1, 4: 1, len(result.SizeBounds): 1}
+	for i, bucket := range result.SizeHistogram {
+		if bucket.Keys != sizes[i] {
+			t.Errorf("Size bucket %d: expected %d keys, got %d", i, sizes[i], bucket.Keys)
+		}
+	}
+
+	if result.Persistent.Keys != 2 || result.Persistent.Bytes != 150 {
+		t.Errorf("Expected 2 persistent keys of 150 bytes, got %+v", result.Persistent)
+	}
+	// Buckets: <=1m, <=1h, <=1d, <=7d, >7d
+	ttls := []int64{1, 0, 1, 0, 1}
+	for i, bucket := range result.TTLHistogram {
+		if bucket.Keys != ttls[i] {
+			t.Errorf("TTL bucket %d: expected %d keys, got %d", i, ttls[i], bucket.Keys)
+		}
+	}
+}
+
+func TestAnalyzer_NoDelimiter(t *testing.T) {
+	t.Parallel()
+
+	analyzer := store.NewAnalyzer("/", 10)
+	analyzer.Add("a/b", 10, -1)
+	analyzer.Add("a:b", 20, -1)
+
+	prefixes := analyzer.Result().Prefixes
+	if len(prefixes) != 2 || prefixes[0].Prefix != "" || prefixes[1].Prefix != "a" {
+		t.Errorf("Expected keys without the delimiter under the empty prefix, got %v", prefixes)
+	}
+}
+
+func TestStore_Analyze(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	ttl := time.Hour
+	short := time.Millisecond
+	s.Set("user:1", "alice", nil)
+	s.Set("user:2", "bob", &ttl)
+	s.Set("blob:1", strings.Repeat("x", 10000), nil)
+	s.Set("gone:1", "soon", &short)
+	time.Sleep(5 * time.Millisecond)
+
+	other, err := s.DB(1)
+	if err != nil {
+		t.Fatalf("DB(1) failed: %v", err)
+	}
+	other.Set("user:3", "carol", nil)
+
+	result := s.Analyze(":", 1)
+
+	if result.Keys != 3 {
+		t.Errorf("Expected the 3 live keys of the database, got %d", result.Keys)
+	}
+	if len(result.Largest) != 1 || result.Largest[0].Key != "blob:1" {
+		t.Errorf("Expected blob:1 as the largest key, got %v", result.Largest)
+	}
+	info, _ := s.Inspect("blob:1")
+	if result.Largest[0].Bytes != info.Size {
+		t.Errorf("Expected the largest key's size to match MEMORY USAGE (%d), got %d", info.Size, result.Largest[0].Bytes)
+	}
+	if len(result.Prefixes) != 1 || result.Prefixes[0].Prefix != "blob" {
+		t.Errorf("Expected blob as the top prefix, got %v", result.Prefixes)
+	}
+	if result.Persistent.Keys != 2 {
+		t.Errorf("Expected 2 persistent keys, got %d", result.Persistent.Keys)
+	}
+}
+
+func TestStore_Analyze_ManyBatches(t *testing.T) {
+	t.Parallel()
+
+	// Every shard holds several batches' worth of keys
+	s := createTestStore(t)
+	const numKeys = 10000
+	for i := range numKeys {
+		s.Set("key:"+strconv.Itoa(i), "value", nil)
+	}
+
+	result := s.Analyze(":", 1)
+	if result.Keys != numKeys || result.Bytes != s.MemoryUsage() {
+		t.Errorf("Expected %d keys and %d bytes, got %d keys and %d bytes", numKeys, s.MemoryUsage(), result.Keys, result.Bytes)
+	}
+}
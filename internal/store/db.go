@@ -0,0 +1,228 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultDatabases is the number of logical databases used when
+// Config.Databases is not set
+const DefaultDatabases = 16
+
+// ErrDBIndexOutOfRange is returned when a database index is not configured
+var ErrDBIndexOutOfRange = errors.New("DB index is out of range")
+
+// KeyspaceInfo summarizes one logical database for INFO keyspace
+type KeyspaceInfo struct {
+	DB      int
+	Keys    int64
+	Expires int64
+	AvgTTL  time.Duration
+}
+
+// DB returns a view of the logical database at index. The view shares the
+// store's shards and workers, so it is cheap to create.
+func (s *Store) DB(index int) (*Store, error) {
+	if index < 0 || index >= s.databases {
+		return nil, ErrDBIndexOutOfRange
+	}
+
+	return &Store{engine: s.engine, db: index}, nil
+}
+
+// Index returns the number of the logical database this view operates on
+func (s *Store) Index() int {
+	return s.db
+}
+
+// Databases returns the number of logical databases
+func (s *Store) Databases() int {
+	return s.databases
+}
+
+// Move moves key, with its TTL, to the logical database db. It reports false
+// when the key does not exist or already exists in the target database.
+func (s *Store) Move(key string, db int) (bool, error) {
+	if db < 0 || db >= s.databases {
+		return false, ErrDBIndexOutOfRange
+	}
+	if db == s.db {
+		return false, ErrSameObject
+	}
+
+	// A key hashes to the same shard in every database, so one lock suffices
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	now := time.Now()
+	value, exists := s.lookup(shard, key, now)
+	if !exists {
+		return false, nil
+	}
+
+	target := &Store{engine: s.engine, db: db}
+	if _, exists := target.lookup(shard, key, now); exists {
+		return false, nil
+	}
+
+	shard.dbs[s.db].remove(key)
+	shard.dbs[db].put(key, value)
+	s.notify(s.db, EventMoveFrom, key)
+	s.notify(db, EventMoveTo, key)
+	return true, nil
+}
+
+// SwapDB atomically exchanges the contents of two logical databases. Every
+// shard is locked for the swap, so no command observes a half-swapped state.
+func (s *Store) SwapDB(a, b int) error {
+	if a < 0 || a >= s.databases || b < 0 || b >= s.databases {
+		return ErrDBIndexOutOfRange
+	}
+
+	unlock := s.lockAllShards()
+	defer unlock()
+
+	for _, shard := range s.shards {
+		shard.dbs[a], shard.dbs[b] = shard.dbs[b], shard.dbs[a]
+	}
+	return nil
+}
+
+// FlushDB removes every key from the database. The old keys are swapped out
+// at once; with async their memory is freed by the background worker,
+// otherwise before FlushDB returns.
+func (s *Store) FlushDB(async bool) {
+	var released int64
+	for _, shard := range s.shards {
+		released += s.flushShard(shard, s.db, async)
+	}
+	s.free(released)
+}
+
+// FlushAll removes every key from every database, freeing their memory like
+// FlushDB
+func (s *Store) FlushAll(async bool) {
+	var released int64
+	for _, shard := range s.shards {
+		for db := range s.databases {
+			released += s.flushShard(shard, db, async)
+		}
+	}
+	s.free(released)
+}
+
+// flushShard replaces the shard's slice of database db with an empty one. It
+// returns the number of bytes it released inline, none with async.
+func (s *Store) flushShard(shard *Shard, db int, async bool) int64 {
+	// The index kind was validated when the store was created
+	fresh, _ := s.newKeyspace(shard)
+
+	shard.lock()
+	old := shard.dbs[db]
+	shard.dbs[db] = fresh
+	job := lazyFreeJob{keyspace: old, objects: int64(len(old.data)), bytes: old.used}
+	old.account(-old.used)
+	shard.unlock()
+
+	if job.objects == 0 {
+		return 0
+	}
+	if async {
+		s.freeLazily(job)
+		return 0
+	}
+	return s.release(job)
+}
+
+// keyspaceTTLSamples is the number of volatile keys per shard and database
+// whose TTL is read to estimate the average TTL reported by Keyspace
+const keyspaceTTLSamples = 16
+
+// Keyspace returns key counts for every non-empty database, ordered by index.
+// Counts are read from each keyspace and its expiry index, so they include
+// expired keys not yet removed, as in Redis. The average TTL is estimated from
+// a few sampled keys per shard, keeping the cost independent of the number of
+// keys.
+func (s *Store) Keyspace() []KeyspaceInfo {
+	infos := make([]KeyspaceInfo, s.databases)
+	// weightedTTL sums each shard's sampled average TTL weighted by its
+	// number of volatile keys
+	weightedTTL := make([]float64, s.databases)
+	now := time.Now()
+
+	for _, shard := range s.shards {
+		shard.rlock()
+		for db, ks := range shard.dbs {
+			expires := ks.expires.Len()
+			infos[db].Keys += int64(len(ks.data))
+			infos[db].Expires += int64(expires)
+			if avg, ok := ks.sampleTTL(now); ok {
+				weightedTTL[db] += float64(avg) * float64(expires)
+			}
+		}
+		shard.runlock()
+	}
+
+	result := make([]KeyspaceInfo, 0, len(infos))
+	for db, info := range infos {
+		if info.Keys == 0 {
+			continue
+		}
+		info.DB = db
+		if info.Expires > 0 {
+			info.AvgTTL = time.Duration(weightedTTL[db] / float64(info.Expires))
+		}
+		result = append(result, info)
+	}
+
+	return result
+}
+
+// sampleTTL returns the average remaining TTL of up to keyspaceTTLSamples
+// volatile keys, reporting false when there are none. The caller must hold
+// the shard lock.
+func (ks *keyspace) sampleTTL(now time.Time) (time.Duration, bool) {
+	var total time.Duration
+	var sampled int
+	for _, key := range ks.expires.Sample(keyspaceTTLSamples) {
+		value, exists := ks.data[key]
+		if !exists || value.ExpiresAt == nil {
+			continue
+		}
+		total += max(value.ExpiresAt.Sub(now), 0)
+		sampled++
+	}
+	if sampled == 0 {
+		return 0, false
+	}
+	return total / time.Duration(sampled), true
+}
+
+// ExpiryIndexSize returns the number of volatile keys tracked by the expiry
+// indexes of every database
+func (s *Store) ExpiryIndexSize() int64 {
+	var total int64
+	for _, shard := range s.shards {
+		shard.rlock()
+		for _, ks := range shard.dbs {
+			total += int64(ks.expires.Len())
+		}
+		shard.runlock()
+	}
+	return total
+}
+
+// lockAllShards write-locks every shard in ascending order. The returned
+// function releases the locks.
+func (s *Store) lockAllShards() func() {
+	for _, shard := range s.shards {
+		shard.lock()
+	}
+
+	return func() {
+		for i := len(s.shards) - 1; i >= 0; i-- {
+			s.shards[i].unlock()
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func selectDB(t *testing.T, s *store.Store, index int) *store.Store {
+	t.Helper()
+
+	db, err := s.DB(index)
+	if err != nil {
+		t.Fatalf("Failed to select database %d: %v", index, err)
+	}
+	return db
+}
+
+func TestStore_DB(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	if s.Databases() != store.DefaultDatabases {
+		t.Errorf("Expected %d databases by default, got %d", store.DefaultDatabases, s.Databases())
+	}
+
+	db1 := selectDB(t, s, 1)
+	if db1.Index() != 1 {
+		t.Errorf("Expected index 1, got %d", db1.Index())
+	}
+
+	s.Set("key", "zero", nil)
+	db1.Set("key", "one", nil)
+
+	if value, _ := s.Get("key"); value != "zero" {
+		t.Errorf("Expected 'zero' in db0, got %q", value)
+	}
+	if value, _ := db1.Get("key"); value != "one" {
+		t.Errorf("Expected 'one' in db1, got %q", value)
+	}
+	if _, exists := selectDB(t, s, 2).Get("key"); exists {
+		t.Errorf("Expected db2 to be empty")
+	}
+
+	for _, index := range []int{-1, store.DefaultDatabases} {
+		if _, err := s.DB(index); !errors.Is(err, store.ErrDBIndexOutOfRange) {
+			t.Errorf("Expected ErrDBIndexOutOfRange for %d, got %v", index, err)
+		}
+	}
+}
+
+func TestStore_New_Databases(t *testing.T) {
+	t.Parallel()
+
+	logger := obs.NewLogger(false)
+	s, err := store.New(&store.Config{Shards: 2, Databases: 2}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	if _, err := s.DB(2); !errors.Is(err, store.ErrDBIndexOutOfRange) {
+		t.Errorf("Expected db2 to be out of range with two databases, got %v", err)
+	}
+
+	if _, err := store.New(&store.Config{Shards: 2, Databases: -1}, logger); err == nil {
+		t.Errorf("Expected error for negative database count")
+	}
+}
+
+func TestStore_Move(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	db1 := selectDB(t, s, 1)
+	expiration := time.Minute
+	s.Set("key", "value", &expiration)
+
+	moved, err := s.Move("key", 1)
+	if err != nil || !moved {
+		t.Fatalf("Expected move to succeed, got %v, %v", moved, err)
+	}
+	if s.Exists("key") {
+		t.Errorf("Expected key to leave db0")
+	}
+	if ttl := db1.TTL("key"); ttl <= 0 {
+		t.Errorf("Expected moved key to keep its TTL, got %d", ttl)
+	}
+
+	// Missing source and existing destination are both no-ops
+	if moved, _ := s.Move("key", 1); moved {
+		t.Errorf("Expected move of missing key to fail")
+	}
+	s.Set("key", "other", nil)
+	if moved, _ := s.Move("key", 1); moved {
+		t.Errorf("Expected move onto existing key to fail")
+	}
+	if value, _ := db1.Get("key"); value != "value" {
+		t.Errorf("Expected destination to be untouched, got %q", value)
+	}
+
+	if _, err := s.Move("key", 0); !errors.Is(err, store.ErrSameObject) {
+		t.Errorf("Expected ErrSameObject, got %v", err)
+	}
+	if _, err := s.Move("key", 99); !errors.Is(err, store.ErrDBIndexOutOfRange) {
+		t.Errorf("Expected ErrDBIndexOutOfRange, got %v", err)
+	}
+}
+
+func TestStore_SwapDB(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	db1 := selectDB(t, s, 1)
+	for i := range 20 {
+		s.Set(fmt.Sprintf("zero:%d", i), "0", nil)
+	}
+	db1.Set("one", "1", nil)
+
+	if err := s.SwapDB(0, 1); err != nil {
+		t.Fatalf("SwapDB failed: %v", err)
+	}
+
+	if s.DBSize() != 1 || db1.DBSize() != 20 {
+		t.Errorf("Expected sizes 1 and 20 after swap, got %d and %d", s.DBSize(), db1.DBSize())
+	}
+	if value, _ := s.Get("one"); value != "1" {
+		t.Errorf("Expected db0 to hold db1's keys, got %q", value)
+	}
+
+	if err := s.SwapDB(0, 16); !errors.Is(err, store.ErrDBIndexOutOfRange) {
+		t.Errorf("Expected ErrDBIndexOutOfRange, got %v", err)
+	}
+}
+
+func TestStore_SwapDB_Atomic(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	db1 := selectDB(t, s, 1)
+	const keys = 50
+	for i := range keys {
+		s.Set(fmt.Sprintf("key:%d", i), "v", nil)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if err := s.SwapDB(0, 1); err != nil {
+					t.Errorf("SwapDB failed: %v", err)
+					return
+				}
+			}
+		}
+	}()
+
+	// Every snapshot of the keyspace sees all keys in exactly one database
+	for range 200 {
+		for _, info := range s.Keyspace() {
+			if info.Keys != keys {
+				t.Errorf("Observed partially swapped db%d with %d keys", info.DB, info.Keys)
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if s.DBSize()+db1.DBSize() != keys {
+		t.Errorf("Expected %d keys in total, got %d", keys, s.DBSize()+db1.DBSize())
+	}
+}
+
+func TestStore_Flush(t *testing.T) {
+	t.Parallel()
+
+	for _, async := range []bool{false, true} {
+		t.Run(fmt.Sprintf("async=%v", async), func(t *testing.T) {
+			t.Parallel()
+
+			s := createTestStore(t)
+			db1 := selectDB(t, s, 1)
+			for i := range 10 {
+				s.Set(fmt.Sprintf("key:%d", i), "v", nil)
+				db1.Set(fmt.Sprintf("key:%d", i), "v", nil)
+			}
+
+			s.FlushDB(async)
+			if s.DBSize() != 0 || db1.DBSize() != 10 {
+				t.Errorf("Expected FLUSHDB to clear only db0, got %d and %d", s.DBSize(), db1.DBSize())
+			}
+
+			s.FlushAll(async)
+			if db1.DBSize() != 0 {
+				t.Errorf("Expected FLUSHALL to clear db1, got %d", db1.DBSize())
+			}
+			if used := s.MemoryUsage(); used != 0 {
+				t.Errorf("Expected no memory in use after FLUSHALL, got %d", used)
+			}
+
+			// Closing waits for the background worker to free everything
+			s.Close()
+			if pending := s.LazyFreePending(); pending != 0 {
+				t.Errorf("Expected no keys pending after Close, got %d", pending)
+			}
+		})
+	}
+}
+
+func TestStore_Keyspace(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	db3 := selectDB(t, s, 3)
+	expiration := time.Minute
+	s.Set("a", "v", nil)
+	s.Set("b", "v", &expiration)
+	db3.Set("c", "v", nil)
+
+	infos := s.Keyspace()
+	if len(infos) != 2 {
+		t.Fatalf("Expected two non-empty databases, got %v", infos)
+	}
+	if infos[0].DB != 0 || infos[0].Keys != 2 || infos[0].Expires != 1 {
+		t.Errorf("Unexpected db0 info: %+v", infos[0])
+	}
+	if infos[0].AvgTTL <= 0 || infos[0].AvgTTL > time.Minute {
+		t.Errorf("Expected average TTL within a minute, got %v", infos[0].AvgTTL)
+	}
+	if infos[1].DB != 3 || infos[1].Keys != 1 || infos[1].Expires != 0 {
+		t.Errorf("Unexpected db3 info: %+v", infos[1])
+	}
+}
+
+func TestStore_Keyspace_SampledTTL(t *testing.T) {
+	t.Parallel()
+
+	// Far more volatile keys than are sampled, half of them with each TTL
+	s := createTestStore(t)
+	short, long := time.Hour, 3*time.Hour
+	for i := range 2000 {
+		ttl := short
+		if i%2 == 0 {
+			ttl = long
+		}
+		s.Set(fmt.Sprintf("key%d", i), "v", &ttl)
+	}
+
+	infos := s.Keyspace()
+	if len(infos) != 1 || infos[0].Keys != 2000 || infos[0].Expires != 2000 {
+		t.Fatalf("Expected 2000 volatile keys in db0, got %v", infos)
+	}
+	if infos[0].AvgTTL < short || infos[0].AvgTTL > long {
+		t.Errorf("Expected an average TTL between %v and %v, got %v", short, long, infos[0].AvgTTL)
+	}
+}
@@ -0,0 +1,245 @@
+package store
+
+import (
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Eviction policies, named as in Redis' maxmemory-policy
+const (
+	// EvictionNoEviction refuses writes once the memory limit is reached
+	EvictionNoEviction = "noeviction"
+	// EvictionAllKeysLRU evicts the least recently used keys
+	EvictionAllKeysLRU = "allkeys-lru"
+	// EvictionVolatileLRU evicts the least recently used keys with a TTL
+	EvictionVolatileLRU = "volatile-lru"
+	// EvictionAllKeysLFU evicts the least frequently used keys
+	EvictionAllKeysLFU = "allkeys-lfu"
+	// EvictionVolatileLFU evicts the least frequently used keys with a TTL
+	EvictionVolatileLFU = "volatile-lfu"
+	// EvictionVolatileTTL evicts the keys with the nearest expiration
+	EvictionVolatileTTL = "volatile-ttl"
+	// EvictionAllKeysRandom evicts random keys
+	EvictionAllKeysRandom = "allkeys-random"
+	// EvictionVolatileRandom evicts random keys with a TTL
+	EvictionVolatileRandom = "volatile-random"
+)
+
+const (
+	// evictionSamples is the number of keys sampled from each keyspace when
+	// refilling the eviction pool, like Redis' maxmemory-samples
+	evictionSamples = 5
+	// evictionPoolSize is the number of best candidates kept between evictions
+	evictionPoolSize = 16
+)
+
+// evictionCandidate is a sampled key in the eviction pool. A higher score
+// makes the key a better candidate.
+type evictionCandidate struct {
+	score int64
+	key   string
+	shard *Shard
+	db    int
+}
+
+// validEvictionPolicy reports whether policy names a known eviction policy.
+// The empty policy means noeviction.
+func validEvictionPolicy(policy string) bool {
+	switch policy {
+	case "", EvictionNoEviction,
+		EvictionAllKeysLRU, EvictionVolatileLRU,
+		EvictionAllKeysLFU, EvictionVolatileLFU,
+		EvictionVolatileTTL,
+		EvictionAllKeysRandom, EvictionVolatileRandom:
+		return true
+	default:
+		return false
+	}
+}
+
+// CheckMemory makes room for a write adding about needed bytes. When the
+// write would take the store past MaxMemoryBytes, keys are evicted according
+// to EvictionPolicy until it fits; ErrOutOfMemory is returned when the policy
+// is noeviction or nothing more can be evicted. A zero limit means unlimited.
+func (s *Store) CheckMemory(needed int64) error {
+	limit := s.config.MaxMemoryBytes
+	if limit <= 0 || s.MemoryUsage()+needed <= limit {
+		return nil
+	}
+
+	policy := s.config.EvictionPolicy
+	if policy == "" || policy == EvictionNoEviction || needed > limit {
+		return ErrOutOfMemory
+	}
+
+	// One eviction at a time keeps the pool consistent and stops concurrent
+	// writers from evicting more than needed
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+
+	var evicted int64
+	defer func() {
+		if evicted > 0 {
+			s.countEvicted(evicted)
+		}
+	}()
+
+	volatile := strings.HasPrefix(policy, "volatile-")
+	for s.MemoryUsage()+needed > limit {
+		var ok bool
+		if policy == EvictionAllKeysRandom || policy == EvictionVolatileRandom {
+			ok = s.evictRandom(volatile)
+		} else {
+			ok = s.evictFromPool(volatile, time.Now())
+		}
+		if !ok {
+			return ErrOutOfMemory
+		}
+		evicted++
+	}
+
+	return nil
+}
+
+// evictFromPool evicts the best candidate of the eviction pool, refilling the
+// pool by sampling every keyspace first. It reports false when there is no key
+// left to evict. The caller must hold evictMu.
+func (s *Store) evictFromPool(volatile bool, now time.Time) bool {
+	for {
+		s.fillEvictionPool(volatile, now)
+		if len(s.evictPool) == 0 {
+			return false
+		}
+
+		// The pool is sorted by ascending score; try the best first and drop
+		// candidates that were deleted or changed since they were sampled
+		for i := len(s.evictPool) - 1; i >= 0; i-- {
+			candidate := s.evictPool[i]
+			s.evictPool = s.evictPool[:i]
+			if s.evictKey(candidate, volatile) {
+				return true
+			}
+		}
+	}
+}
+
+// fillEvictionPool samples keys from every non-empty keyspace and merges them
+// into the pool, which keeps the evictionPoolSize best candidates
+func (s *Store) fillEvictionPool(volatile bool, now time.Time) {
+	for _, shard := range s.shards {
+		s.readShard(shard, func() {
+			for db, ks := range shard.dbs {
+				for _, key := range ks.sample(volatile, evictionSamples) {
+					value := ks.data[key]
+					s.addEvictionCandidate(evictionCandidate{
+						score: s.evictionScore(value, now),
+						key:   key,
+						shard: shard,
+						db:    db,
+					})
+				}
+			}
+		})
+	}
+}
+
+// addEvictionCandidate inserts candidate into the pool in score order,
+// dropping the worst candidate when the pool is full
+func (s *Store) addEvictionCandidate(candidate evictionCandidate) {
+	for _, pooled := range s.evictPool {
+		if pooled.key == candidate.key && pooled.shard == candidate.shard && pooled.db == candidate.db {
+			return
+		}
+	}
+
+	if len(s.evictPool) == evictionPoolSize {
+		if candidate.score <= s.evictPool[0].score {
+			return
+		}
+		s.evictPool = append(s.evictPool[:0], s.evictPool[1:]...)
+	}
+
+	i := len(s.evictPool)
+	s.evictPool = append(s.evictPool, candidate)
+	for ; i > 0 && s.evictPool[i-1].score > candidate.score; i-- {
+		s.evictPool[i] = s.evictPool[i-1]
+	}
+	s.evictPool[i] = candidate
+}
+
+// evictionScore rates value as an eviction candidate under the configured
+// policy; higher scores are evicted first
+func (s *Store) evictionScore(value *Value, now time.Time) int64 {
+	switch s.config.EvictionPolicy {
+	case EvictionAllKeysLFU, EvictionVolatileLFU:
+		return lfuMaxValue - int64(value.frequency(now))
+	case EvictionVolatileTTL:
+		return math.MaxInt64 - value.ExpiresAt.UnixNano()
+	default:
+		// LRU: the longer the key has been idle the better
+		return now.UnixNano() - atomic.LoadInt64(&value.lastAccess)
+	}
+}
+
+// evictKey deletes a pooled candidate if it still exists and, for volatile
+// policies, still has a TTL
+func (s *Store) evictKey(candidate evictionCandidate, volatile bool) bool {
+	evicted := false
+	s.withShard(candidate.shard, func() {
+		ks := candidate.shard.dbs[candidate.db]
+		value, exists := ks.data[candidate.key]
+		if !exists || (volatile && value.ExpiresAt == nil) {
+			return
+		}
+
+		ks.remove(candidate.key)
+		s.notify(candidate.db, EventEvicted, candidate.key)
+		evicted = true
+	})
+	return evicted
+}
+
+// evictRandom deletes a random key, starting from a random shard. It reports
+// false when there is no key left to evict.
+func (s *Store) evictRandom(volatile bool) bool {
+	start := rand.IntN(len(s.shards)) // #nosec G404 -- eviction does not need a secure source
+	for i := range s.shards {
+		shard := s.shards[(start+i)%len(s.shards)]
+		evicted := false
+		s.withShard(shard, func() {
+			for db, ks := range shard.dbs {
+				if keys := ks.sample(volatile, 1); len(keys) > 0 {
+					ks.remove(keys[0])
+					s.notify(db, EventEvicted, keys[0])
+					evicted = true
+					return
+				}
+			}
+		})
+		if evicted {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sample returns up to n keys of the keyspace, only volatile ones if volatile
+// is set. The caller must hold the shard lock.
+func (ks *keyspace) sample(volatile bool, n int) []string {
+	if volatile {
+		return ks.expires.Sample(n)
+	}
+	return sampleKeys(ks.data, n)
+}
+
+// countEvicted records keys removed to stay within the memory limit
+func (s *Store) countEvicted(count int64) {
+	atomic.AddInt64(&s.evictedCount, count)
+	if s.config.OnEvicted != nil {
+		s.config.OnEvicted(count)
+	}
+}
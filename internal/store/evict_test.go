@@ -0,0 +1,229 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+const (
+	// evictTestValue is the value stored by the eviction tests; with keys of
+	// a fixed length every key accounts for the same memory
+	evictTestValue = "0123456789"
+	evictTestKeys  = 12
+)
+
+// evictTestKeySize is the memory accounted to one key of the eviction tests
+var evictTestKeySize = store.EntryOverhead + int64(len("key:00")+len(evictTestValue))
+
+// createEvictionStore creates a single-shard store whose limit fits exactly
+// evictTestKeys keys and counts evictions reported through OnEvicted
+func createEvictionStore(t *testing.T, policy string) (*store.Store, *int64) {
+	t.Helper()
+
+	var evicted int64
+	s, err := store.New(&store.Config{
+		Shards:         1,
+		MaxMemoryBytes: evictTestKeys * evictTestKeySize,
+		EvictionPolicy: policy,
+		OnEvicted:      func(count int64) { atomic.AddInt64(&evicted, count) },
+	}, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	return s, &evicted
+}
+
+func evictTestKey(i int) string {
+	return fmt.Sprintf("key:%02d", i)
+}
+
+func TestStore_New_UnknownEvictionPolicy(t *testing.T) {
+	t.Parallel()
+
+	_, err := store.New(&store.Config{Shards: 1, EvictionPolicy: "allkeys-fifo"}, obs.NewLogger(false))
+	if err == nil {
+		t.Errorf("Expected error for unknown eviction policy")
+	}
+}
+
+func TestStore_CheckMemory_Evicts(t *testing.T) {
+	t.Parallel()
+
+	policies := []string{
+		store.EvictionAllKeysLRU,
+		store.EvictionAllKeysLFU,
+		store.EvictionAllKeysRandom,
+		store.EvictionVolatileLRU,
+		store.EvictionVolatileLFU,
+		store.EvictionVolatileTTL,
+		store.EvictionVolatileRandom,
+	}
+
+	for _, policy := range policies {
+		t.Run(policy, func(t *testing.T) {
+			t.Parallel()
+
+			s, evicted := createEvictionStore(t, policy)
+			for i := range evictTestKeys {
+				ttl := time.Duration(i+1) * time.Hour
+				s.Set(evictTestKey(i), evictTestValue, &ttl)
+			}
+
+			// Keys with a TTL account for a little more than evictTestKeySize,
+			// so the store starts over its limit
+			needed := 3 * evictTestKeySize
+			if err := s.CheckMemory(needed); err != nil {
+				t.Fatalf("CheckMemory failed: %v", err)
+			}
+
+			if used := s.MemoryUsage(); used+needed > evictTestKeys*evictTestKeySize {
+				t.Errorf("Expected room for %d bytes, used %d", needed, used)
+			}
+			removed := evictTestKeys - s.DBSize()
+			if removed < 3 {
+				t.Errorf("Expected at least 3 keys evicted, got %d", removed)
+			}
+			if got := s.GetEvictedKeysCount(); got != removed {
+				t.Errorf("Expected %d evicted keys, got %d", removed, got)
+			}
+			if got := atomic.LoadInt64(evicted); got != removed {
+				t.Errorf("Expected OnEvicted to report %d keys, got %d", removed, got)
+			}
+			if got, want := s.MemoryUsage(), recomputeMemory(t, s); got != want {
+				t.Errorf("MemoryUsage() = %d, recomputed %d", got, want)
+			}
+		})
+	}
+}
+
+func TestStore_CheckMemory_NoEviction(t *testing.T) {
+	t.Parallel()
+
+	s, evicted := createEvictionStore(t, store.EvictionNoEviction)
+	for i := range evictTestKeys {
+		s.Set(evictTestKey(i), evictTestValue, nil)
+	}
+
+	if err := s.CheckMemory(evictTestKeySize); !errors.Is(err, store.ErrOutOfMemory) {
+		t.Errorf("Expected ErrOutOfMemory, got %v", err)
+	}
+	if size := s.DBSize(); size != evictTestKeys {
+		t.Errorf("Expected no key evicted, got %d keys", size)
+	}
+	if atomic.LoadInt64(evicted) != 0 {
+		t.Errorf("Expected no evictions reported")
+	}
+}
+
+func TestStore_CheckMemory_TooLarge(t *testing.T) {
+	t.Parallel()
+
+	s, _ := createEvictionStore(t, store.EvictionAllKeysLRU)
+	s.Set(evictTestKey(0), evictTestValue, nil)
+
+	// No amount of eviction makes room for a write larger than the limit
+	if err := s.CheckMemory((evictTestKeys + 1) * evictTestKeySize); !errors.Is(err, store.ErrOutOfMemory) {
+		t.Errorf("Expected ErrOutOfMemory, got %v", err)
+	}
+	if s.DBSize() != 1 {
+		t.Errorf("Expected the key to be kept")
+	}
+}
+
+func TestStore_CheckMemory_VolatileOnly(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []string{store.EvictionVolatileLRU, store.EvictionVolatileLFU, store.EvictionVolatileTTL, store.EvictionVolatileRandom} {
+		t.Run(policy, func(t *testing.T) {
+			t.Parallel()
+
+			s, _ := createEvictionStore(t, policy)
+			for i := range evictTestKeys {
+				if i%2 == 0 {
+					s.Set(evictTestKey(i), evictTestValue, nil)
+					continue
+				}
+				ttl := time.Duration(i) * time.Hour
+				s.Set(evictTestKey(i), evictTestValue, &ttl)
+			}
+
+			// Six volatile keys can be evicted; the seventh eviction is impossible
+			if err := s.CheckMemory(6 * evictTestKeySize); err != nil {
+				t.Fatalf("CheckMemory failed: %v", err)
+			}
+			for i := 0; i < evictTestKeys; i += 2 {
+				if !s.Exists(evictTestKey(i)) {
+					t.Errorf("Expected persistent key %s to survive", evictTestKey(i))
+				}
+			}
+			if s.DBSize() != evictTestKeys/2 {
+				t.Errorf("Expected only the persistent keys to remain, got %d keys", s.DBSize())
+			}
+
+			if err := s.CheckMemory(7 * evictTestKeySize); !errors.Is(err, store.ErrOutOfMemory) {
+				t.Errorf("Expected ErrOutOfMemory without volatile keys, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_CheckMemory_KeepsHotKeys(t *testing.T) {
+	t.Parallel()
+
+	hot := []string{evictTestKey(3), evictTestKey(8)}
+
+	tests := []struct {
+		name   string
+		policy string
+		access func(s *store.Store, key string)
+	}{
+		{
+			name:   "lru keeps recently used keys",
+			policy: store.EvictionAllKeysLRU,
+			access: func(s *store.Store, key string) { s.Get(key) },
+		},
+		{
+			name:   "lfu keeps frequently used keys",
+			policy: store.EvictionAllKeysLFU,
+			access: func(s *store.Store, key string) {
+				for range 100 {
+					s.Get(key)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, _ := createEvictionStore(t, tt.policy)
+			for i := range evictTestKeys {
+				s.Set(evictTestKey(i), evictTestValue, nil)
+			}
+			time.Sleep(2 * time.Millisecond)
+			for _, key := range hot {
+				tt.access(s, key)
+			}
+
+			// Every refill samples at least three cold keys, so the pool always
+			// holds a better candidate than the hot keys
+			if err := s.CheckMemory(5 * evictTestKeySize); err != nil {
+				t.Fatalf("CheckMemory failed: %v", err)
+			}
+			for _, key := range hot {
+				if _, ok := s.Inspect(key); !ok {
+					t.Errorf("Expected hot key %s to survive eviction", key)
+				}
+			}
+		})
+	}
+}
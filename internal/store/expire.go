@@ -0,0 +1,226 @@
+package store
+
+import "time"
+
+// ExpireCondition restricts when ExpireAt may change a key's expiration. It
+// is a set of flags mirroring the NX, XX, GT and LT options of the EXPIRE
+// family; the zero value sets the expiration unconditionally.
+type ExpireCondition uint8
+
+const (
+	// ExpireNX only sets the expiration when the key has none
+	ExpireNX ExpireCondition = 1 << iota
+	// ExpireXX only sets the expiration when the key already has one
+	ExpireXX
+	// ExpireGT only sets the expiration when it is later than the current one.
+	// A key without expiration counts as never expiring, so GT never applies.
+	ExpireGT
+	// ExpireLT only sets the expiration when it is earlier than the current
+	// one. A key without expiration counts as never expiring, so LT applies.
+	ExpireLT
+
+	// ExpireAlways sets the expiration unconditionally
+	ExpireAlways ExpireCondition = 0
+)
+
+// ExpireAt sets the absolute expiration time of key if condition allows it
+// and reports whether it did. An expiration that is not in the future deletes
+// the key, which also counts as success.
+func (s *Store) ExpireAt(key string, at time.Time, condition ExpireCondition) bool {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	now := time.Now()
+	value, exists := s.lookup(shard, key, now)
+	if !exists {
+		return false
+	}
+
+	if !condition.allows(value.ExpiresAt, at) {
+		return false
+	}
+
+	if !at.After(now) {
+		shard.dbs[s.db].remove(key)
+		s.notify(s.db, EventDel, key)
+		return true
+	}
+
+	shard.dbs[s.db].setExpiry(key, value, &at)
+	s.notify(s.db, EventExpire, key)
+	return true
+}
+
+// allows reports whether the condition permits replacing current with next
+func (c ExpireCondition) allows(current *time.Time, next time.Time) bool {
+	if c&ExpireNX != 0 && current != nil {
+		return false
+	}
+	if c&ExpireXX != 0 && current == nil {
+		return false
+	}
+	if c&ExpireGT != 0 && (current == nil || !next.After(*current)) {
+		return false
+	}
+	if c&ExpireLT != 0 && current != nil && !next.Before(*current) {
+		return false
+	}
+	return true
+}
+
+// Persist removes the expiration of key and reports whether it had one
+func (s *Store) Persist(key string) bool {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	value, exists := s.lookup(shard, key, time.Now())
+	if !exists || value.ExpiresAt == nil {
+		return false
+	}
+
+	shard.dbs[s.db].setExpiry(key, value, nil)
+	s.notify(s.db, EventPersist, key)
+	return true
+}
+
+// PTTL returns the time to live for a key in milliseconds, -1 if the key has
+// no expiration or -2 if it does not exist
+func (s *Store) PTTL(key string) int64 {
+	ttl, code := s.remaining(key)
+	if code < 0 {
+		return code
+	}
+
+	return ttl.Milliseconds()
+}
+
+// ExpireTime returns the absolute expiration time of key as a unix timestamp
+// in milliseconds, -1 if the key has no expiration or -2 if it does not exist
+func (s *Store) ExpireTime(key string) int64 {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	value, exists := s.lookup(shard, key, time.Now())
+	if !exists {
+		return -2
+	}
+	if value.ExpiresAt == nil {
+		return -1
+	}
+
+	return value.ExpiresAt.UnixMilli()
+}
+
+// remaining returns the time left before key expires. The code is -2 if the
+// key does not exist, -1 if it has no expiration and 0 otherwise.
+func (s *Store) remaining(key string) (time.Duration, int64) {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	now := time.Now()
+	value, exists := s.lookup(shard, key, now)
+	if !exists {
+		return 0, -2 // key does not exist
+	}
+	if value.ExpiresAt == nil {
+		return 0, -1 // key exists but has no expiration
+	}
+
+	return value.ExpiresAt.Sub(now), 0
+}
+
+const (
+	// activeExpireBatchSize is the number of due keys removed per shard lock
+	// acquisition, so the cycle never blocks a shard for long
+	activeExpireBatchSize = 64
+	// activeExpireBudgetPercent is the share of the cycle interval a cycle
+	// may spend expiring keys
+	activeExpireBudgetPercent = 25
+)
+
+// activeExpireLoop runs the active expiration cycle every interval until the
+// store is closed
+func (s *Store) activeExpireLoop(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	budget := interval * activeExpireBudgetPercent / 100
+	for {
+		select {
+		case <-ticker.C:
+			s.activeExpireCycle(budget)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// activeExpireCycle pops due keys from the expiry index of every shard and
+// database and deletes them, in batches, until none are due or budget is
+// spent. It returns the number of keys removed.
+func (s *Store) activeExpireCycle(budget time.Duration) int64 {
+	deadline := time.Now().Add(budget)
+	var expired int64
+
+	// Rotate the starting shard so a cycle that runs out of budget does not
+	// keep starving the same shards
+	start := s.expireCursor
+	s.expireCursor = (s.expireCursor + 1) % len(s.shards)
+
+	for i := range s.shards {
+		shard := s.shards[(start+i)%len(s.shards)]
+		for db := range s.databases {
+			for {
+				popped, removed := s.expireDue(shard, db, time.Now())
+				expired += int64(removed)
+				if popped < activeExpireBatchSize {
+					break
+				}
+				if time.Now().After(deadline) {
+					return expired
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+
+	return expired
+}
+
+// expireDue pops up to activeExpireBatchSize due keys from the expiry index of
+// the shard's slice of database db and deletes them. It returns how many keys
+// it popped and how many it removed.
+func (s *Store) expireDue(shard *Shard, db int, now time.Time) (int, int) {
+	popped, removed := 0, 0
+	s.withShard(shard, func() {
+		ks := shard.dbs[db]
+		keys := ks.expires.PopDue(now, activeExpireBatchSize)
+		for _, key := range keys {
+			value, exists := ks.data[key]
+			switch {
+			case !exists:
+			case value.isExpired(now):
+				ks.drop(key)
+				removed++
+				s.notify(db, EventExpired, key)
+			case value.ExpiresAt != nil:
+				// Not due yet; keep it indexed
+				ks.expires.Set(key, *value.ExpiresAt)
+			}
+		}
+		popped = len(keys)
+	})
+
+	if removed > 0 {
+		s.countExpired(int64(removed))
+	}
+	return popped, removed
+}
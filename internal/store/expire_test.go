@@ -0,0 +1,250 @@
+package store_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func TestStore_ExpireAt_Conditions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	earlier := now.Add(time.Minute)
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		current   *time.Time
+		next      time.Time
+		condition store.ExpireCondition
+		expected  bool
+	}{
+		{name: "always on persistent key", next: later, condition: store.ExpireAlways, expected: true},
+		{name: "NX on persistent key", next: later, condition: store.ExpireNX, expected: true},
+		{name: "NX on volatile key", current: &earlier, next: later, condition: store.ExpireNX, expected: false},
+		{name: "XX on persistent key", next: later, condition: store.ExpireXX, expected: false},
+		{name: "XX on volatile key", current: &earlier, next: later, condition: store.ExpireXX, expected: true},
+		{name: "GT with later time", current: &earlier, next: later, condition: store.ExpireGT, expected: true},
+		{name: "GT with earlier time", current: &later, next: earlier, condition: store.ExpireGT, expected: false},
+		{name: "GT on persistent key", next: later, condition: store.ExpireGT, expected: false},
+		{name: "LT with earlier time", current: &later, next: earlier, condition: store.ExpireLT, expected: true},
+		{name: "LT with later time", current: &earlier, next: later, condition: store.ExpireLT, expected: false},
+		{name: "LT on persistent key", next: later, condition: store.ExpireLT, expected: true},
+		{name: "LT XX on persistent key", next: later, condition: store.ExpireLT | store.ExpireXX, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := createTestStore(t)
+			var expiration *time.Duration
+			if tt.current != nil {
+				d := time.Until(*tt.current)
+				expiration = &d
+			}
+			s.Set("key", "value", expiration)
+
+			if got := s.ExpireAt("key", tt.next, tt.condition); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStore_ExpireAt_PastDeletesKey(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	s.Set("key", "value", nil)
+
+	if !s.ExpireAt("key", time.Now().Add(-time.Second), store.ExpireAlways) {
+		t.Errorf("Expected expire in the past to succeed")
+	}
+	if s.DBSize() != 0 {
+		t.Errorf("Expected key to be deleted immediately, got %d keys", s.DBSize())
+	}
+
+	s.Set("key", "value", nil)
+	if !s.Expire("key", 0) || s.DBSize() != 0 {
+		t.Errorf("Expected zero expire to delete the key")
+	}
+}
+
+func TestStore_Expire_ExpiredKey(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	expiration := time.Millisecond
+	s.Set("key", "value", &expiration)
+	time.Sleep(5 * time.Millisecond)
+
+	if s.Expire("key", time.Minute) {
+		t.Errorf("Expected EXPIRE on an expired key to fail")
+	}
+	if _, exists := s.Get("key"); exists {
+		t.Errorf("Expected expired key not to be revived")
+	}
+}
+
+func TestStore_PersistAndPTTL(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+
+	if ttl := s.PTTL("missing"); ttl != -2 {
+		t.Errorf("Expected -2 for missing key, got %d", ttl)
+	}
+	if at := s.ExpireTime("missing"); at != -2 {
+		t.Errorf("Expected -2 for missing key, got %d", at)
+	}
+
+	s.Set("key", "value", nil)
+	if ttl := s.PTTL("key"); ttl != -1 {
+		t.Errorf("Expected -1 for persistent key, got %d", ttl)
+	}
+	if s.Persist("key") {
+		t.Errorf("Expected PERSIST on persistent key to fail")
+	}
+
+	at := time.Now().Add(10 * time.Second)
+	s.ExpireAt("key", at, store.ExpireAlways)
+	if ttl := s.PTTL("key"); ttl <= 9000 || ttl > 10000 {
+		t.Errorf("Expected PTTL close to 10000ms, got %d", ttl)
+	}
+	if got := s.ExpireTime("key"); got != at.UnixMilli() {
+		t.Errorf("Expected expire time %d, got %d", at.UnixMilli(), got)
+	}
+
+	if !s.Persist("key") {
+		t.Errorf("Expected PERSIST to remove the expiration")
+	}
+	if ttl := s.TTL("key"); ttl != -1 {
+		t.Errorf("Expected -1 after PERSIST, got %d", ttl)
+	}
+}
+
+func TestStore_ActiveExpireCycle(t *testing.T) {
+	t.Parallel()
+
+	for _, kind := range expiryIndexKinds {
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+
+			var reported atomic.Int64
+			s, err := store.New(&store.Config{
+				Shards:            4,
+				ExpiryIndex:       kind,
+				ActiveExpireCycle: 5 * time.Millisecond,
+				OnExpired:         func(count int64) { reported.Add(count) },
+			}, obs.NewLogger(false))
+			if err != nil {
+				t.Fatalf("Failed to create store: %v", err)
+			}
+			t.Cleanup(s.Close)
+
+			// A handful of volatile keys among many persistent ones, which
+			// random sampling would rarely find
+			for i := range 20000 {
+				s.Set(fmt.Sprintf("persistent:%d", i), "v", nil)
+			}
+			for i := range 200 {
+				expiration := time.Duration(10+i%50) * time.Millisecond
+				s.Set(fmt.Sprintf("volatile:%d", i), "v", &expiration)
+			}
+
+			// Nothing reads the volatile keys, so only the active cycle can remove them
+			deadline := time.Now().Add(2 * time.Second)
+			for s.DBSize() != 20000 && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			if size := s.DBSize(); size != 20000 {
+				t.Fatalf("Expected active expiration to leave 20000 keys, got %d", size)
+			}
+			if count := s.GetExpiredKeysCount(); count != 200 {
+				t.Errorf("Expected 200 expired keys, got %d", count)
+			}
+			if count := reported.Load(); count != 200 {
+				t.Errorf("Expected 200 expired keys reported, got %d", count)
+			}
+			if size := s.ExpiryIndexSize(); size != 0 {
+				t.Errorf("Expected empty expiry index, got %d", size)
+			}
+		})
+	}
+}
+
+func TestStore_ExpiryIndexSync(t *testing.T) {
+	t.Parallel()
+
+	for _, kind := range expiryIndexKinds {
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := store.New(&store.Config{Shards: 4, ExpiryIndex: kind}, obs.NewLogger(false))
+			if err != nil {
+				t.Fatalf("Failed to create store: %v", err)
+			}
+			t.Cleanup(s.Close)
+
+			expiration := time.Minute
+			steps := []struct {
+				name     string
+				op       func()
+				expected int64
+			}{
+				{name: "SET with TTL", op: func() { s.Set("a", "v", &expiration) }, expected: 1},
+				{name: "SET without TTL", op: func() { s.Set("a", "v", nil) }, expected: 0},
+				{name: "EXPIRE", op: func() { s.Expire("a", time.Minute) }, expected: 1},
+				{name: "PERSIST", op: func() { s.Persist("a") }, expected: 0},
+				{name: "GETEX EX", op: func() { s.GetEx("a", time.Now().Add(time.Minute), false) }, expected: 1},
+				{name: "RENAME", op: func() { _ = s.Rename("a", "b") }, expected: 1},
+				{name: "COPY", op: func() { _, _ = s.Copy("b", "c", false) }, expected: 2},
+				{name: "DEL", op: func() { s.Delete("c") }, expected: 1},
+				{name: "MOVE", op: func() { _, _ = s.Move("b", 1) }, expected: 1},
+				{name: "SWAPDB", op: func() { _ = s.SwapDB(0, 1) }, expected: 1},
+				{name: "GETSET", op: func() { s.GetSet("b", "v") }, expected: 0},
+				{name: "SETEX then UNLINK", op: func() { s.Set("d", "v", &expiration); s.Unlink("d") }, expected: 0},
+				{name: "EXPIRE in the past", op: func() { s.Set("e", "v", &expiration); s.Expire("e", -time.Second) }, expected: 0},
+				{name: "FLUSHALL", op: func() { s.Set("f", "v", &expiration); s.FlushAll(false) }, expected: 0},
+			}
+
+			for _, step := range steps {
+				step.op()
+				if size := s.ExpiryIndexSize(); size != step.expected {
+					t.Fatalf("After %s expected %d indexed keys, got %d", step.name, step.expected, size)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_LazyOnlyExpiration(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	expiration := time.Millisecond
+	s.Set("key", "value", &expiration)
+	time.Sleep(20 * time.Millisecond)
+
+	if size := s.DBSize(); size != 1 {
+		t.Errorf("Expected expired key to linger without an active cycle, got %d keys", size)
+	}
+
+	// EXISTS removes the expired key like any other lookup
+	if s.Exists("key") {
+		t.Errorf("Expected expired key not to exist")
+	}
+	if size := s.DBSize(); size != 0 {
+		t.Errorf("Expected EXISTS to evict the expired key, got %d keys", size)
+	}
+	if count := s.GetExpiredKeysCount(); count != 1 {
+		t.Errorf("Expected one expired key, got %d", count)
+	}
+}
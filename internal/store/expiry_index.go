@@ -0,0 +1,329 @@
+package store
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+const (
+	// ExpiryIndexHeap keeps volatile keys in a min-heap ordered by expiration
+	ExpiryIndexHeap = "heap"
+	// ExpiryIndexWheel keeps volatile keys in a hierarchical timing wheel
+	ExpiryIndexWheel = "wheel"
+)
+
+// ExpiryIndex tracks the expiration times of the volatile keys of one
+// keyspace so the active expiration cycle can find exactly the keys that are
+// due. Implementations are not safe for concurrent use; the store guards each
+// index with its shard's lock.
+type ExpiryIndex interface {
+	// Set records that key expires at, replacing any previous entry
+	Set(key string, at time.Time)
+	// Remove forgets key
+	Remove(key string)
+	// PopDue removes and returns up to limit keys expiring at or before now
+	PopDue(now time.Time, limit int) []string
+	// Len returns the number of indexed keys
+	Len() int
+	// Sample returns up to n indexed keys picked in no particular order
+	Sample(n int) []string
+}
+
+// NewExpiryIndex creates an empty index of the given kind. now is the current
+// time, from which a timing wheel starts ticking.
+func NewExpiryIndex(kind string, now time.Time) (ExpiryIndex, error) {
+	switch kind {
+	case "", ExpiryIndexHeap:
+		return newHeapIndex(), nil
+	case ExpiryIndexWheel:
+		return newTimingWheel(now), nil
+	default:
+		return nil, fmt.Errorf("unknown expiry index %q", kind)
+	}
+}
+
+// heapEntry is a key in the expiry heap
+type heapEntry struct {
+	key   string
+	at    time.Time
+	index int
+}
+
+// expiryHeap implements heap.Interface ordered by expiration time
+type expiryHeap []*heapEntry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	entry := x.(*heapEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// heapIndex is an ExpiryIndex backed by a min-heap with a position map, so
+// updates and removals are O(log n) and popping due keys is O(k log n)
+type heapIndex struct {
+	heap    expiryHeap
+	entries map[string]*heapEntry
+}
+
+func newHeapIndex() *heapIndex {
+	return &heapIndex{entries: make(map[string]*heapEntry)}
+}
+
+func (h *heapIndex) Set(key string, at time.Time) {
+	if entry, exists := h.entries[key]; exists {
+		entry.at = at
+		heap.Fix(&h.heap, entry.index)
+		return
+	}
+
+	entry := &heapEntry{key: key, at: at}
+	h.entries[key] = entry
+	heap.Push(&h.heap, entry)
+}
+
+func (h *heapIndex) Remove(key string) {
+	entry, exists := h.entries[key]
+	if !exists {
+		return
+	}
+
+	heap.Remove(&h.heap, entry.index)
+	delete(h.entries, key)
+}
+
+func (h *heapIndex) PopDue(now time.Time, limit int) []string {
+	var keys []string
+	for len(h.heap) > 0 && len(keys) < limit && !h.heap[0].at.After(now) {
+		entry := heap.Pop(&h.heap).(*heapEntry)
+		delete(h.entries, entry.key)
+		keys = append(keys, entry.key)
+	}
+	return keys
+}
+
+func (h *heapIndex) Len() int {
+	return len(h.entries)
+}
+
+func (h *heapIndex) Sample(n int) []string {
+	return sampleKeys(h.entries, n)
+}
+
+const (
+	// wheelLevelBits is log2 of the number of slots per wheel level
+	wheelLevelBits = 6
+	// wheelSlots is the number of slots per wheel level
+	wheelSlots = 1 << wheelLevelBits
+	// wheelLevels is the number of levels. With millisecond ticks the wheel
+	// spans 64^5 ms, about 12 days; later expirations park in the top level
+	// and are re-placed as it cascades.
+	wheelLevels = 5
+	// wheelReady marks entries that are due and waiting to be popped
+	wheelReady = -1
+)
+
+// wheelEntry is a key in the timing wheel
+type wheelEntry struct {
+	key   string
+	at    int64 // expiration in unix milliseconds, rounded up
+	level int
+	slot  int
+}
+
+// timingWheel is an ExpiryIndex backed by a hierarchical timing wheel with
+// millisecond ticks. Level l slots each span 64^l ticks; entries cascade to
+// lower levels as time reaches their slot. Insertions and removals are O(1)
+// and popping costs O(1) per elapsed tick plus O(1) per cascaded entry.
+type timingWheel struct {
+	current int64 // last processed tick in unix milliseconds
+	slots   [wheelLevels][wheelSlots]map[string]*wheelEntry
+	counts  [wheelLevels]int // entries per level, for skipping idle ticks
+	ready   map[string]*wheelEntry
+	entries map[string]*wheelEntry
+}
+
+func newTimingWheel(now time.Time) *timingWheel {
+	return &timingWheel{
+		current: now.UnixMilli(),
+		ready:   make(map[string]*wheelEntry),
+		entries: make(map[string]*wheelEntry),
+	}
+}
+
+func (w *timingWheel) Set(key string, at time.Time) {
+	// Round up so an entry never becomes due before its expiration
+	millis := at.UnixMilli()
+	if at.After(time.UnixMilli(millis)) {
+		millis++
+	}
+
+	entry, exists := w.entries[key]
+	if exists {
+		w.detach(entry)
+	} else {
+		entry = &wheelEntry{key: key}
+		w.entries[key] = entry
+	}
+
+	entry.at = millis
+	w.place(entry)
+}
+
+func (w *timingWheel) Remove(key string) {
+	entry, exists := w.entries[key]
+	if !exists {
+		return
+	}
+
+	w.detach(entry)
+	delete(w.entries, key)
+}
+
+// detach takes entry out of its slot or the ready set
+func (w *timingWheel) detach(entry *wheelEntry) {
+	if entry.level == wheelReady {
+		delete(w.ready, entry.key)
+		return
+	}
+
+	delete(w.slots[entry.level][entry.slot], entry.key)
+	w.counts[entry.level]--
+}
+
+// place files entry into the lowest level whose range covers it, or into the
+// ready set when it is already due
+func (w *timingWheel) place(entry *wheelEntry) {
+	if entry.at <= w.current {
+		entry.level = wheelReady
+		w.ready[entry.key] = entry
+		return
+	}
+
+	level, tick := wheelLevels-1, (w.current>>(wheelLevelBits*(wheelLevels-1)))+wheelSlots-1
+	for l := range wheelLevels {
+		shift := wheelLevelBits * l
+		if (entry.at>>shift)-(w.current>>shift) < wheelSlots {
+			level, tick = l, entry.at>>shift
+			break
+		}
+	}
+
+	entry.level = level
+	entry.slot = int(tick & (wheelSlots - 1))
+	if w.slots[level][entry.slot] == nil {
+		w.slots[level][entry.slot] = make(map[string]*wheelEntry)
+	}
+	w.slots[level][entry.slot][entry.key] = entry
+	w.counts[level]++
+}
+
+// advance processes one tick: slots of higher levels whose span starts now
+// cascade down, top level first, then the level 0 slot becomes ready
+func (w *timingWheel) advance() {
+	w.current++
+
+	for level := wheelLevels - 1; level > 0; level-- {
+		shift := wheelLevelBits * level
+		if w.current&(1<<shift-1) != 0 {
+			continue
+		}
+
+		slot := int((w.current >> shift) & (wheelSlots - 1))
+		entries := w.slots[level][slot]
+		w.slots[level][slot] = nil
+		w.counts[level] -= len(entries)
+		for _, entry := range entries {
+			w.place(entry)
+		}
+	}
+
+	slot := int(w.current & (wheelSlots - 1))
+	for key, entry := range w.slots[0][slot] {
+		entry.level = wheelReady
+		w.ready[key] = entry
+	}
+	w.counts[0] -= len(w.slots[0][slot])
+	w.slots[0][slot] = nil
+}
+
+// skipIdle moves the wheel forward, without passing target, to just before
+// the next tick that can make an entry ready or cascade one. Without it a
+// wheel holding only far-off entries would walk every millisecond.
+func (w *timingWheel) skipIdle(target int64) {
+	if w.counts[0] > 0 {
+		return
+	}
+
+	for level := 1; level < wheelLevels; level++ {
+		if w.counts[level] == 0 {
+			continue
+		}
+		// The next cascade of this level happens at its next span boundary
+		shift := wheelLevelBits * level
+		boundary := ((w.current >> shift) + 1) << shift
+		w.current = min(boundary-1, target)
+		return
+	}
+
+	// Nothing is scheduled
+	w.current = target
+}
+
+func (w *timingWheel) PopDue(now time.Time, limit int) []string {
+	target := now.UnixMilli()
+	for w.current < target && len(w.ready) < limit {
+		w.skipIdle(target)
+		if w.current < target {
+			w.advance()
+		}
+	}
+
+	var keys []string
+	for key := range w.ready {
+		if len(keys) == limit {
+			break
+		}
+		delete(w.ready, key)
+		delete(w.entries, key)
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (w *timingWheel) Len() int {
+	return len(w.entries)
+}
+
+func (w *timingWheel) Sample(n int) []string {
+	return sampleKeys(w.entries, n)
+}
+
+// sampleKeys returns up to n keys of entries, relying on the randomized
+// starting point of map iteration
+func sampleKeys[E any](entries map[string]E, n int) []string {
+	keys := make([]string, 0, min(n, len(entries)))
+	for key := range entries {
+		if len(keys) == n {
+			break
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,220 @@
+package store_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+var expiryIndexKinds = []string{store.ExpiryIndexHeap, store.ExpiryIndexWheel}
+
+func newExpiryIndex(tb testing.TB, kind string, now time.Time) store.ExpiryIndex {
+	tb.Helper()
+
+	index, err := store.NewExpiryIndex(kind, now)
+	if err != nil {
+		tb.Fatalf("Failed to create %s index: %v", kind, err)
+	}
+	return index
+}
+
+func TestExpiryIndex_PopDue(t *testing.T) {
+	t.Parallel()
+
+	for _, kind := range expiryIndexKinds {
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.UnixMilli(1_700_000_000_000)
+			index := newExpiryIndex(t, kind, start)
+
+			// Spread expirations over every wheel level, including beyond its span
+			offsets := []time.Duration{
+				time.Millisecond, 50 * time.Millisecond, 63 * time.Millisecond, 64 * time.Millisecond,
+				time.Second, 5 * time.Second, 10 * time.Minute, 5 * time.Hour, 20 * 24 * time.Hour,
+			}
+			for i, offset := range offsets {
+				index.Set(fmt.Sprintf("key:%d", i), start.Add(offset))
+			}
+			if index.Len() != len(offsets) {
+				t.Fatalf("Expected %d entries, got %d", len(offsets), index.Len())
+			}
+
+			for i, offset := range offsets {
+				due := start.Add(offset)
+				if keys := index.PopDue(due.Add(-time.Millisecond), 100); len(keys) != 0 {
+					t.Fatalf("Expected nothing due before %v, got %v", offset, keys)
+				}
+				keys := index.PopDue(due, 100)
+				if len(keys) != 1 || keys[0] != fmt.Sprintf("key:%d", i) {
+					t.Fatalf("Expected key:%d due at %v, got %v", i, offset, keys)
+				}
+			}
+
+			if index.Len() != 0 {
+				t.Errorf("Expected empty index, got %d entries", index.Len())
+			}
+		})
+	}
+}
+
+func TestExpiryIndex_SetAndRemove(t *testing.T) {
+	t.Parallel()
+
+	for _, kind := range expiryIndexKinds {
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.UnixMilli(1_700_000_000_000)
+			index := newExpiryIndex(t, kind, start)
+
+			index.Set("moved", start.Add(time.Second))
+			index.Set("moved", start.Add(time.Hour))
+			index.Set("removed", start.Add(time.Second))
+			index.Remove("removed")
+			index.Remove("missing")
+			index.Set("kept", start.Add(2*time.Second))
+
+			if index.Len() != 2 {
+				t.Fatalf("Expected 2 entries, got %d", index.Len())
+			}
+
+			keys := index.PopDue(start.Add(time.Minute), 100)
+			if len(keys) != 1 || keys[0] != "kept" {
+				t.Errorf("Expected only 'kept' due, got %v", keys)
+			}
+			keys = index.PopDue(start.Add(time.Hour), 100)
+			if len(keys) != 1 || keys[0] != "moved" {
+				t.Errorf("Expected 'moved' due at its new time, got %v", keys)
+			}
+		})
+	}
+}
+
+func TestExpiryIndex_Limit(t *testing.T) {
+	t.Parallel()
+
+	for _, kind := range expiryIndexKinds {
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.UnixMilli(1_700_000_000_000)
+			index := newExpiryIndex(t, kind, start)
+
+			var expected []string
+			for i := range 250 {
+				key := fmt.Sprintf("key:%03d", i)
+				expected = append(expected, key)
+				index.Set(key, start.Add(time.Duration(rand.IntN(5000))*time.Millisecond)) // #nosec G404 -- test data
+			}
+
+			var popped []string
+			now := start.Add(10 * time.Second)
+			for {
+				keys := index.PopDue(now, 64)
+				if len(keys) > 64 {
+					t.Fatalf("Expected at most 64 keys per pop, got %d", len(keys))
+				}
+				if len(keys) == 0 {
+					break
+				}
+				popped = append(popped, keys...)
+			}
+
+			slices.Sort(popped)
+			if !slices.Equal(popped, expected) {
+				t.Errorf("Expected every key popped exactly once, got %d keys", len(popped))
+			}
+		})
+	}
+}
+
+func TestExpiryIndex_Sample(t *testing.T) {
+	t.Parallel()
+
+	for _, kind := range expiryIndexKinds {
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.UnixMilli(1_700_000_000_000)
+			index := newExpiryIndex(t, kind, start)
+			if keys := index.Sample(5); len(keys) != 0 {
+				t.Errorf("Expected no samples from an empty index, got %v", keys)
+			}
+
+			indexed := map[string]bool{}
+			for i := range 20 {
+				key := fmt.Sprintf("key:%02d", i)
+				indexed[key] = true
+				index.Set(key, start.Add(time.Duration(i+1)*time.Hour))
+			}
+
+			keys := index.Sample(5)
+			if len(keys) != 5 {
+				t.Fatalf("Expected 5 samples, got %v", keys)
+			}
+			seen := map[string]bool{}
+			for _, key := range keys {
+				if !indexed[key] || seen[key] {
+					t.Errorf("Expected distinct indexed keys, got %v", keys)
+				}
+				seen[key] = true
+			}
+
+			if keys := index.Sample(100); len(keys) != 20 {
+				t.Errorf("Expected every key when sampling more than Len, got %d", len(keys))
+			}
+			if index.Len() != 20 {
+				t.Errorf("Expected Sample to leave the index unchanged, got Len %d", index.Len())
+			}
+		})
+	}
+}
+
+func TestNewExpiryIndex_Unknown(t *testing.T) {
+	t.Parallel()
+
+	if _, err := store.NewExpiryIndex("tree", time.Now()); err == nil {
+		t.Errorf("Expected error for unknown index kind")
+	}
+}
+
+// BenchmarkExpiryIndex_Churn compares the index structures under TTL churn:
+// keys are continually re-expired, persisted and popped as time advances
+func BenchmarkExpiryIndex_Churn(b *testing.B) {
+	const keys = 100_000
+
+	for _, kind := range expiryIndexKinds {
+		b.Run(kind, func(b *testing.B) {
+			now := time.UnixMilli(1_700_000_000_000)
+			index := newExpiryIndex(b, kind, now)
+			rng := rand.New(rand.NewPCG(1, 2)) // #nosec G404 -- benchmark data
+
+			names := make([]string, keys)
+			for i := range names {
+				names[i] = fmt.Sprintf("key:%d", i)
+				index.Set(names[i], now.Add(time.Duration(rng.IntN(60_000))*time.Millisecond))
+			}
+
+			b.ReportAllocs()
+			i := 0
+			for b.Loop() {
+				key := names[i%keys]
+				switch i % 10 {
+				case 0:
+					index.Remove(key)
+				case 1:
+					now = now.Add(time.Millisecond)
+					index.PopDue(now, 64)
+				default:
+					index.Set(key, now.Add(time.Duration(rng.IntN(60_000))*time.Millisecond))
+				}
+				i++
+			}
+		})
+	}
+}
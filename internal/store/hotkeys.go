@@ -0,0 +1,37 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/hotkeys"
+)
+
+// ErrHotKeysDisabled is returned when hot keys are queried but not tracked
+var ErrHotKeysDisabled = errors.New("hot key tracking is disabled")
+
+// recordHotKey samples an access to key for hot key detection
+func (s *Store) recordHotKey(key string) {
+	if s.hotKeys != nil {
+		s.hotKeys.Record(key)
+	}
+}
+
+// HotKeys returns up to count of the most accessed keys over the last window,
+// hottest first. A zero window means the whole tracked window. Keys are
+// tracked by name across every database.
+func (s *Store) HotKeys(count int, window time.Duration) ([]hotkeys.HotKey, error) {
+	if s.hotKeys == nil {
+		return nil, ErrHotKeysDisabled
+	}
+	return s.hotKeys.Top(count, window), nil
+}
+
+// HotKeyWindow returns the longest window HotKeys reports over, or zero when
+// hot keys are not tracked
+func (s *Store) HotKeyWindow() time.Duration {
+	if s.hotKeys == nil {
+		return 0
+	}
+	return s.hotKeys.Window()
+}
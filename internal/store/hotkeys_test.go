@@ -0,0 +1,66 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func TestStore_HotKeys_Disabled(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	if _, err := s.HotKeys(10, 0); !errors.Is(err, store.ErrHotKeysDisabled) {
+		t.Errorf("Expected ErrHotKeysDisabled, got %v", err)
+	}
+	if window := s.HotKeyWindow(); window != 0 {
+		t.Errorf("Expected no window when disabled, got %v", window)
+	}
+}
+
+func TestStore_HotKeys_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := store.New(&store.Config{Shards: 1, HotKeySampleRate: 2, HotKeyWindow: time.Minute}, obs.NewLogger(false))
+	if err == nil {
+		t.Errorf("Expected error for a sample rate above 1")
+	}
+}
+
+func TestStore_HotKeys_SamplesGetAndSet(t *testing.T) {
+	t.Parallel()
+
+	s, err := store.New(&store.Config{
+		Shards:           4,
+		HotKeySampleRate: 1,
+		HotKeyWindow:     10 * time.Second,
+	}, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	s.Set("hot", "value", nil)
+	for range 20 {
+		s.Get("hot")
+	}
+	s.Set("warm", "value", nil)
+	s.Get("warm")
+	s.Get("missing")
+	// Other commands are not sampled
+	s.Delete("warm")
+
+	hot, err := s.HotKeys(2, 0)
+	if err != nil {
+		t.Fatalf("HotKeys failed: %v", err)
+	}
+	if len(hot) != 2 || hot[0].Key != "hot" || hot[0].Count != 21 || hot[1].Key != "warm" || hot[1].Count != 2 {
+		t.Errorf("Expected hot (21) then warm (2), got %+v", hot)
+	}
+	if s.HotKeyWindow() != 10*time.Second {
+		t.Errorf("Expected a 10s window, got %v", s.HotKeyWindow())
+	}
+}
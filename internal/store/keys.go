@@ -0,0 +1,137 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNoSuchKey is returned when an operation requires an existing key
+	ErrNoSuchKey = errors.New("no such key")
+	// ErrSameObject is returned when source and destination keys are identical
+	ErrSameObject = errors.New("source and destination objects are the same")
+)
+
+// Rename moves the value stored at src, including its TTL and version, to dst,
+// overwriting dst if it exists. Both shards are locked in a fixed order so
+// concurrent renames in opposite directions cannot deadlock.
+func (s *Store) Rename(src, dst string) error {
+	_, err := s.rename(src, dst, false)
+	return err
+}
+
+// RenameNX renames src to dst only if dst does not exist. It reports whether
+// the rename happened.
+func (s *Store) RenameNX(src, dst string) (bool, error) {
+	return s.rename(src, dst, true)
+}
+
+func (s *Store) rename(src, dst string, nx bool) (bool, error) {
+	unlock := s.lockShards([]string{src, dst})
+	defer unlock()
+
+	now := time.Now()
+	srcShard, dstShard := s.getShard(src), s.getShard(dst)
+	value, exists := s.lookup(srcShard, src, now)
+	if !exists {
+		return false, ErrNoSuchKey
+	}
+
+	if src == dst {
+		return !nx, nil
+	}
+
+	if nx {
+		if _, exists := s.lookup(dstShard, dst, now); exists {
+			return false, nil
+		}
+	}
+
+	srcShard.dbs[s.db].remove(src)
+	dstShard.dbs[s.db].put(dst, value)
+	s.notify(s.db, EventRenameFrom, src)
+	s.notify(s.db, EventRenameTo, dst)
+	return true, nil
+}
+
+// Copy copies the value and TTL stored at src to dst. Unless replace is set
+// an existing dst is left untouched. It reports whether the copy happened.
+func (s *Store) Copy(src, dst string, replace bool) (bool, error) {
+	if src == dst {
+		return false, ErrSameObject
+	}
+
+	unlock := s.lockShards([]string{src, dst})
+	defer unlock()
+
+	now := time.Now()
+	srcShard, dstShard := s.getShard(src), s.getShard(dst)
+	value, exists := s.lookup(srcShard, src, now)
+	if !exists {
+		return false, nil
+	}
+
+	if _, exists := s.lookup(dstShard, dst, now); exists && !replace {
+		return false, nil
+	}
+
+	dup := newStringValue(value.Data)
+	dup.Type = value.Type
+	if value.ExpiresAt != nil {
+		expiresAt := *value.ExpiresAt
+		dup.ExpiresAt = &expiresAt
+	}
+
+	dstShard.dbs[s.db].put(dst, dup)
+	s.notify(s.db, EventCopyTo, dst)
+	return true, nil
+}
+
+// Touch updates the access time of every existing key and returns how many
+// of them exist
+func (s *Store) Touch(keys ...string) int64 {
+	var touched int64
+	now := time.Now()
+	for _, key := range keys {
+		shard := s.getShard(key)
+		shard.rlock()
+		if value, exists := shard.dbs[s.db].data[key]; exists && !value.isExpired(now) {
+			value.recordAccess(now)
+			touched++
+		}
+		shard.runlock()
+	}
+
+	return touched
+}
+
+// Unlink removes keys like Delete but hands large values to the background
+// worker to be freed, so the caller never pays for freeing them. It returns
+// the number of keys removed.
+func (s *Store) Unlink(keys ...string) int64 {
+	var removed int64
+	var job lazyFreeJob
+	now := time.Now()
+	for _, key := range keys {
+		shard := s.getShard(key)
+		shard.lock()
+		value, exists := s.lookup(shard, key, now)
+		if exists {
+			shard.dbs[s.db].remove(key)
+			removed++
+			s.notify(s.db, EventDel, key)
+		}
+		shard.unlock()
+
+		if exists && len(value.Data) >= lazyFreeThreshold {
+			job.values = append(job.values, value)
+			job.objects++
+			job.bytes += value.size
+		}
+	}
+
+	if job.objects > 0 {
+		s.freeLazily(job)
+	}
+	return removed
+}
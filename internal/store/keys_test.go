@@ -0,0 +1,179 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func TestStore_Rename(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	expiration := time.Minute
+	s.Set("src", "value", &expiration)
+	s.Set("dst", "old", nil)
+
+	if err := s.Rename("src", "dst"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if s.Exists("src") {
+		t.Errorf("Expected source key to be gone after RENAME")
+	}
+	if value, _ := s.Get("dst"); value != "value" {
+		t.Errorf("Expected destination to hold 'value', got %q", value)
+	}
+	if ttl := s.TTL("dst"); ttl <= 0 {
+		t.Errorf("Expected RENAME to move the TTL, got %d", ttl)
+	}
+
+	if err := s.Rename("missing", "dst"); !errors.Is(err, store.ErrNoSuchKey) {
+		t.Errorf("Expected ErrNoSuchKey, got %v", err)
+	}
+
+	// Renaming a key onto itself is a no-op
+	if err := s.Rename("dst", "dst"); err != nil {
+		t.Errorf("Expected self rename to succeed, got %v", err)
+	}
+}
+
+func TestStore_RenameNX(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	s.Set("a", "1", nil)
+	s.Set("b", "2", nil)
+
+	renamed, err := s.RenameNX("a", "b")
+	if err != nil || renamed {
+		t.Errorf("Expected RENAMENX onto existing key to fail, got %v (err: %v)", renamed, err)
+	}
+
+	renamed, err = s.RenameNX("a", "c")
+	if err != nil || !renamed {
+		t.Errorf("Expected RENAMENX onto new key to succeed, got %v (err: %v)", renamed, err)
+	}
+
+	if value, _ := s.Get("c"); value != "1" {
+		t.Errorf("Expected 'c' to hold '1', got %q", value)
+	}
+}
+
+func TestStore_Copy(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	expiration := time.Minute
+	s.Set("src", "value", &expiration)
+	s.Set("taken", "old", nil)
+
+	if copied, _ := s.Copy("src", "dst", false); !copied {
+		t.Errorf("Expected COPY to a new key to succeed")
+	}
+	if ttl := s.TTL("dst"); ttl <= 0 {
+		t.Errorf("Expected COPY to copy the TTL, got %d", ttl)
+	}
+
+	if copied, _ := s.Copy("src", "taken", false); copied {
+		t.Errorf("Expected COPY without REPLACE onto existing key to fail")
+	}
+	if copied, _ := s.Copy("src", "taken", true); !copied {
+		t.Errorf("Expected COPY with REPLACE to succeed")
+	}
+	if value, _ := s.Get("taken"); value != "value" {
+		t.Errorf("Expected replaced value, got %q", value)
+	}
+
+	if copied, _ := s.Copy("missing", "other", false); copied {
+		t.Errorf("Expected COPY of missing key to fail")
+	}
+	if _, err := s.Copy("src", "src", false); !errors.Is(err, store.ErrSameObject) {
+		t.Errorf("Expected ErrSameObject, got %v", err)
+	}
+
+	// The copy is independent of the source
+	_, _ = s.Append("dst", "!", noLimit)
+	if value, _ := s.Get("src"); value != "value" {
+		t.Errorf("Expected source to be unaffected by changes to the copy, got %q", value)
+	}
+}
+
+func TestStore_TouchAndUnlink(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	s.Set("small", "value", nil)
+	s.Set("large", strings.Repeat("x", 1<<20), nil)
+
+	if n := s.Touch("small", "large", "missing"); n != 2 {
+		t.Errorf("Expected TOUCH to count 2 keys, got %d", n)
+	}
+
+	if n := s.Unlink("small", "large", "missing"); n != 2 {
+		t.Errorf("Expected UNLINK to remove 2 keys, got %d", n)
+	}
+	if s.DBSize() != 0 {
+		t.Errorf("Expected empty store after UNLINK, got %d keys", s.DBSize())
+	}
+
+	// Closing waits for the background worker to free the large value
+	s.Close()
+	if pending := s.LazyFreePending(); pending != 0 {
+		t.Errorf("Expected no values pending after Close, got %d", pending)
+	}
+}
+
+func TestStore_Rename_OppositeDirectionsDoNotDeadlock(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+
+	// Pairs of keys spread over the shards, so most renames cross shards
+	var pairs [][2]string
+	for i := 0; len(pairs) < 8; i++ {
+		a, b := fmt.Sprintf("a:%d", i), fmt.Sprintf("b:%d", i)
+		s.Set(a, "x", nil)
+		s.Set(b, "y", nil)
+		pairs = append(pairs, [2]string{a, b})
+	}
+
+	const numGoroutines = 16
+	const numRenames = 2000
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := range numGoroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range numRenames {
+				pair := pairs[i%len(pairs)]
+				// Half the goroutines rename a->b, the other half b->a
+				if g%2 == 0 {
+					_ = s.Rename(pair[0], pair[1])
+					_, _ = s.RenameNX(pair[1], pair[0])
+				} else {
+					_ = s.Rename(pair[1], pair[0])
+					_, _ = s.Copy(pair[0], pair[1], true)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("Concurrent renames did not finish, possible deadlock")
+	}
+}
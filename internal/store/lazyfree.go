@@ -0,0 +1,101 @@
+package store
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+)
+
+const (
+	// lazyFreeThreshold is the value size from which UNLINK releases values in
+	// the background instead of inline
+	lazyFreeThreshold = 64 * 1024
+	// lazyFreeQueueSize bounds the number of jobs waiting for the worker
+	lazyFreeQueueSize = 1024
+	// returnMemoryBytes is the amount of released memory from which a full
+	// collection is run to hand the freed pages back to the OS
+	returnMemoryBytes = 64 * 1024 * 1024
+)
+
+// lazyFreeJob is memory detached from the keyspaces and waiting to be freed:
+// unlinked values or a whole flushed keyspace
+type lazyFreeJob struct {
+	values   []*Value
+	keyspace *keyspace
+	// objects is the number of keys the job holds and bytes their
+	// accounted size
+	objects, bytes int64
+}
+
+// LazyFreePending returns the number of keys whose memory is waiting to be
+// freed by the background worker
+func (s *Store) LazyFreePending() int64 {
+	return atomic.LoadInt64(&s.lazyFreePending)
+}
+
+// freeLazily hands job to the background worker, freeing it inline when the
+// queue is full
+func (s *Store) freeLazily(job lazyFreeJob) {
+	// Counted before the worker can pick the job up and uncount it
+	atomic.AddInt64(&s.lazyFreePending, job.objects)
+	select {
+	case s.lazyFree <- job:
+	default:
+		atomic.AddInt64(&s.lazyFreePending, -job.objects)
+		s.free(s.release(job))
+	}
+}
+
+// lazyFreeLoop frees detached memory until the store is closed. The garbage
+// collector owns the memory, so freeing means dropping the job's references
+// and, once enough has been released, running the collection that returns
+// it to the OS. That collection is the costly part, and it runs here instead
+// of on the connection that unlinked or flushed the keys.
+func (s *Store) lazyFreeLoop() {
+	defer s.wg.Done()
+
+	var released int64
+	for {
+		select {
+		case job := <-s.lazyFree:
+			released += s.release(job)
+			atomic.AddInt64(&s.lazyFreePending, -job.objects)
+			// Wait for the queue to drain so a burst of jobs costs one
+			// collection
+			if released >= returnMemoryBytes && len(s.lazyFree) == 0 {
+				debug.FreeOSMemory()
+				released = 0
+			}
+		case <-s.stop:
+			// Drain what is left so pending counts settle
+			for {
+				select {
+				case job := <-s.lazyFree:
+					s.release(job)
+					atomic.AddInt64(&s.lazyFreePending, -job.objects)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// release drops the references job holds, which nothing else shares once it
+// is detached, and returns the number of bytes released
+func (s *Store) release(job lazyFreeJob) int64 {
+	if ks := job.keyspace; ks != nil {
+		clear(ks.data)
+		ks.order = scanIndex{}
+	}
+	clear(job.values)
+	return job.bytes
+}
+
+// free runs the collection returning memory to the OS when the caller just
+// released bytes of it, so that FLUSHDB and FLUSHALL without ASYNC reply once
+// their memory is freed
+func (s *Store) free(bytes int64) {
+	if bytes >= returnMemoryBytes {
+		debug.FreeOSMemory()
+	}
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"slices"
+	"sync"
+)
+
+// shardTask is work for a shard loop. done, when set, receives a value once
+// fn has returned.
+type shardTask struct {
+	fn   func()
+	done chan<- struct{}
+}
+
+// ShardLoops reports whether each shard is owned by a loop. Every access to a
+// shard must then run on its loop, through Run or RunEach, or while its loop
+// has handed it over through Exclusive.
+func (s *Store) ShardLoops() bool {
+	return s.config.ShardLoops
+}
+
+// Run runs fn on the loop owning shard and returns once it has run. Without
+// shard loops fn runs on the caller.
+func (s *Store) Run(shard int, fn func()) error {
+	return s.RunEach([]int{shard}, []func(){fn})
+}
+
+// RunEach runs fns[i] on the loop owning shards[i] and returns once every
+// function has run. Functions for different loops run concurrently. Without
+// shard loops they run on the caller, one after the other. ErrClosed is
+// returned when the store was closed before they all ran.
+func (s *Store) RunEach(shards []int, fns []func()) error {
+	if !s.config.ShardLoops {
+		for _, fn := range fns {
+			fn()
+		}
+		return nil
+	}
+
+	done := make(chan struct{}, len(fns))
+	queued := 0
+	var err error
+	for i, shard := range shards {
+		if err = s.submit(shard, shardTask{fn: fns[i], done: done}); err != nil {
+			break
+		}
+		queued++
+	}
+
+	// Wait even on error, as the queued functions may still be running
+	for ; queued > 0; queued-- {
+		select {
+		case <-done:
+		case <-s.loopsDone:
+			// Tasks left once the loops are gone never run
+			if len(done) < queued {
+				return ErrClosed
+			}
+			return err
+		}
+	}
+	return err
+}
+
+// Exclusive runs fn on the caller once the loops owning shards have handed
+// them over, so fn can work on several shards atomically. The loops wait for
+// fn to return, then resume. A nil shards hands over every shard. Without
+// shard loops fn simply runs on the caller, and the store's methods take the
+// shard locks as usual.
+//
+// Loops are taken in ascending shard order, so concurrent calls cannot
+// deadlock. Functions run by Run, RunEach or Exclusive must not call them in
+// turn, as the loop they would wait for may be waiting for them.
+func (s *Store) Exclusive(shards []int, fn func()) error {
+	if !s.config.ShardLoops {
+		fn()
+		return nil
+	}
+
+	if shards == nil {
+		shards = make([]int, len(s.shards))
+		for i := range shards {
+			shards[i] = i
+		}
+	} else {
+		shards = slices.Compact(slices.Sorted(slices.Values(shards)))
+	}
+
+	// Each loop reports it has stopped, then waits until fn is done
+	handed := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	park := shardTask{fn: func() {
+		handed <- struct{}{}
+		<-release
+	}}
+
+	for _, shard := range shards {
+		if err := s.submit(shard, park); err != nil {
+			return err
+		}
+		select {
+		case <-handed:
+		case <-s.stop:
+			// The loops already handed over must be released for the store
+			// to close
+			return ErrClosed
+		}
+	}
+
+	fn()
+	return nil
+}
+
+// withShard runs fn with shard to itself: on the shard's loop, or under its
+// write lock without shard loops. It is how the store's own background work
+// reaches the shards. It reports false when the store closed before fn ran.
+func (s *Store) withShard(shard *Shard, fn func()) bool {
+	if shard.mailbox == nil {
+		shard.lock()
+		defer shard.unlock()
+		fn()
+		return true
+	}
+	return s.Run(shard.id, fn) == nil
+}
+
+// readShard is withShard for fn that only reads the shard, which takes the
+// read lock without shard loops
+func (s *Store) readShard(shard *Shard, fn func()) bool {
+	if shard.mailbox == nil {
+		shard.rlock()
+		defer shard.runlock()
+		fn()
+		return true
+	}
+	return s.Run(shard.id, fn) == nil
+}
+
+// submit queues task on the loop owning shard, waiting while its mailbox is
+// full
+func (s *Store) submit(shard int, task shardTask) error {
+	// Checked first as select picks at random among ready cases
+	select {
+	case <-s.stop:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case s.shards[shard].mailbox <- task:
+		return nil
+	case <-s.stop:
+		return ErrClosed
+	}
+}
+
+// startShardLoops starts the loop of every shard. loopsDone is closed once
+// they have all returned.
+func (s *Store) startShardLoops() {
+	var loops sync.WaitGroup
+	for _, shard := range s.shards {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			s.shardLoop(shard)
+		}()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		loops.Wait()
+		close(s.loopsDone)
+	}()
+}
+
+// shardLoop runs the tasks sent to shard, one at a time, until the store is
+// closed
+func (s *Store) shardLoop(shard *Shard) {
+	for {
+		select {
+		case task := <-shard.mailbox:
+			task.fn()
+			if task.done != nil {
+				task.done <- struct{}{}
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+func createLoopStore(t *testing.T, config *store.Config) *store.Store {
+	t.Helper()
+
+	config.ShardLoops = true
+	config.ShardMailbox = 4
+	if config.Shards == 0 {
+		config.Shards = 4
+	}
+	if config.EvictionPolicy == "" {
+		config.EvictionPolicy = "noeviction"
+	}
+	s, err := store.New(config, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// keysInShards returns a key of each of two different shards
+func keysInShards(t *testing.T, s *store.Store) (string, string) {
+	t.Helper()
+
+	first := "key0"
+	for i := 1; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		if s.ShardIndex(key) != s.ShardIndex(first) {
+			return first, key
+		}
+	}
+	t.Fatal("Expected 100 keys to span several shards")
+	return "", ""
+}
+
+func TestStore_ShardIndex(t *testing.T) {
+	t.Parallel()
+
+	s, err := store.New(&store.Config{Shards: 4, Databases: 2}, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	if s.ShardCount() != 4 {
+		t.Errorf("Expected 4 shards, got %d", s.ShardCount())
+	}
+
+	db := selectDB(t, s, 1)
+	used := make(map[int]bool)
+	for i := range 100 {
+		key := fmt.Sprintf("key%d", i)
+		shard := s.ShardIndex(key)
+		if shard < 0 || shard >= s.ShardCount() {
+			t.Fatalf("ShardIndex(%q) = %d, out of range", key, shard)
+		}
+		if db.ShardIndex(key) != shard {
+			t.Errorf("Expected %q in the same shard in every database", key)
+		}
+		used[shard] = true
+	}
+	if len(used) != s.ShardCount() {
+		t.Errorf("Expected 100 keys to spread over every shard, got %d shards", len(used))
+	}
+}
+
+func TestStore_ShardLoops_Config(t *testing.T) {
+	t.Parallel()
+
+	_, err := store.New(&store.Config{Shards: 4, ShardLoops: true}, obs.NewLogger(false))
+	if err == nil {
+		t.Error("Expected shard loops without a mailbox to be rejected")
+	}
+
+	s := createTestStore(t)
+	if s.ShardLoops() {
+		t.Error("Expected no shard loops by default")
+	}
+	ran := false
+	if err := s.Run(0, func() { ran = true }); err != nil || !ran {
+		t.Errorf("Expected Run to run on the caller without shard loops, got %v", err)
+	}
+}
+
+func TestStore_ShardLoops_Run(t *testing.T) {
+	t.Parallel()
+
+	s := createLoopStore(t, &store.Config{})
+	const writers, increments = 8, 100
+
+	shard := s.ShardIndex("counter")
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range increments {
+				err := s.Run(shard, func() {
+					if _, err := s.IncrBy("counter", 1); err != nil {
+						t.Errorf("IncrBy failed: %v", err)
+					}
+				})
+				if err != nil {
+					t.Errorf("Run failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var value string
+	if err := s.Run(shard, func() { value, _ = s.Get("counter") }); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := strconv.Itoa(writers * increments); value != want {
+		t.Errorf("Expected every increment to be applied once, got %s, want %s", value, want)
+	}
+}
+
+func TestStore_ShardLoops_RunEach(t *testing.T) {
+	t.Parallel()
+
+	s := createLoopStore(t, &store.Config{})
+	first, second := keysInShards(t, s)
+
+	shards := []int{s.ShardIndex(first), s.ShardIndex(second)}
+	err := s.RunEach(shards, []func(){
+		func() { s.Set(first, "1", nil) },
+		func() { s.Set(second, "2", nil) },
+	})
+	if err != nil {
+		t.Fatalf("RunEach failed: %v", err)
+	}
+
+	var values [2]string
+	err = s.RunEach(shards, []func(){
+		func() { values[0], _ = s.Get(first) },
+		func() { values[1], _ = s.Get(second) },
+	})
+	if err != nil || values != [2]string{"1", "2"} {
+		t.Errorf("Expected both keys to be set, got %v: %v", values, err)
+	}
+}
+
+func TestStore_ShardLoops_Exclusive(t *testing.T) {
+	t.Parallel()
+
+	s := createLoopStore(t, &store.Config{})
+	first, second := keysInShards(t, s)
+	if err := s.Exclusive(nil, func() { s.Set(first, "a", nil) }); err != nil {
+		t.Fatalf("Exclusive failed: %v", err)
+	}
+
+	// A key renamed back and forth across two shards is always in exactly
+	// one of them for readers holding both
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			src, dst := first, second
+			if i%2 == 1 {
+				src, dst = second, first
+			}
+			err := s.Exclusive([]int{s.ShardIndex(dst), s.ShardIndex(src)}, func() {
+				if err := s.Rename(src, dst); err != nil {
+					t.Errorf("Rename failed: %v", err)
+				}
+			})
+			if err != nil {
+				t.Errorf("Exclusive failed: %v", err)
+				return
+			}
+		}
+	}()
+
+	for range 200 {
+		var count int64
+		err := s.Exclusive([]int{s.ShardIndex(first), s.ShardIndex(second)}, func() {
+			count = s.DBSize()
+		})
+		if err != nil {
+			t.Fatalf("Exclusive failed: %v", err)
+		}
+		if count != 1 {
+			t.Fatalf("Expected the key in exactly one shard, found %d keys", count)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestStore_ShardLoops_BackgroundWork(t *testing.T) {
+	t.Parallel()
+
+	s := createLoopStore(t, &store.Config{
+		MaxMemoryBytes:    4096,
+		EvictionPolicy:    "allkeys-lru",
+		ActiveExpireCycle: 5 * time.Millisecond,
+	})
+
+	// Eviction and the active expiration cycle reach the shards through
+	// their loops
+	for i := range 100 {
+		if err := s.CheckMemory(64); err != nil {
+			t.Fatalf("CheckMemory failed: %v", err)
+		}
+		key := "key" + strconv.Itoa(i)
+		ttl := time.Millisecond
+		if err := s.Run(s.ShardIndex(key), func() { s.Set(key, "value", &ttl) }); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if used := s.MemoryUsage(); used > 4096 {
+		t.Errorf("Expected eviction to keep memory within the limit, got %d", used)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		var size int64
+		if err := s.Exclusive(nil, func() { size = s.DBSize() }); err != nil {
+			t.Fatalf("Exclusive failed: %v", err)
+		}
+		if size == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the active cycle to expire every key, %d left", size)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStore_ShardLoops_Closed(t *testing.T) {
+	t.Parallel()
+
+	s := createLoopStore(t, &store.Config{})
+	s.Close()
+
+	if err := s.Run(0, func() { t.Error("Expected no task to run once closed") }); !errors.Is(err, store.ErrClosed) {
+		t.Errorf("Expected ErrClosed from Run, got %v", err)
+	}
+	if err := s.Exclusive(nil, func() { t.Error("Expected no task to run once closed") }); !errors.Is(err, store.ErrClosed) {
+		t.Errorf("Expected ErrClosed from Exclusive, got %v", err)
+	}
+}
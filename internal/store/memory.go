@@ -0,0 +1,30 @@
+package store
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// EntryOverhead is the estimated memory of a key beyond the bytes of its name
+// and data: the value metadata, the shard map entry and the scan index entry
+const EntryOverhead = stringHeaderSize + valueStructSize + mapEntryOverhead + scanEntrySize
+
+// ErrOutOfMemory is returned when a write would take the store past its
+// memory limit and no memory can be freed
+var ErrOutOfMemory = errors.New("command not allowed when used memory > 'maxmemory'")
+
+// account adds delta bytes to the memory used by the keyspace and its shard.
+// The caller must hold the shard write lock.
+func (ks *keyspace) account(delta int64) {
+	ks.used += delta
+	atomic.AddInt64(&ks.shard.used, delta)
+}
+
+// MemoryUsage returns the estimated memory used by every key in every database
+func (s *Store) MemoryUsage() int64 {
+	var total int64
+	for _, shard := range s.shards {
+		total += atomic.LoadInt64(&shard.used)
+	}
+	return total
+}
@@ -0,0 +1,147 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// recomputeMemory sums the estimated size of every live key in every database
+func recomputeMemory(t *testing.T, s *store.Store) int64 {
+	t.Helper()
+
+	var total int64
+	for i := range s.Databases() {
+		db, err := s.DB(i)
+		if err != nil {
+			t.Fatalf("DB(%d) failed: %v", i, err)
+		}
+		keys, _ := db.Keys(nil, 0)
+		for _, key := range keys {
+			info, ok := db.Inspect(key)
+			if !ok {
+				t.Fatalf("Inspect(%q) found no key", key)
+			}
+			total += info.Size
+		}
+	}
+	return total
+}
+
+func TestStore_MemoryUsage_TracksMutations(t *testing.T) {
+	t.Parallel()
+
+	ttl := time.Hour
+	short := time.Millisecond
+
+	tests := []struct {
+		name string
+		op   func(s *store.Store)
+	}{
+		{name: "set", op: func(s *store.Store) { s.Set("a", "hello", nil) }},
+		{name: "overwrite larger", op: func(s *store.Store) { s.Set("a", "hello world, again", nil) }},
+		{name: "overwrite smaller", op: func(s *store.Store) { s.Set("a", "x", nil) }},
+		{name: "set with ttl", op: func(s *store.Store) { s.Set("b", "value", &ttl) }},
+		{name: "append", op: func(s *store.Store) { _, _ = s.Append("b", "-appended", noLimit) }},
+		{name: "setrange", op: func(s *store.Store) { _, _ = s.SetRange("b", 30, "far", noLimit) }},
+		{name: "persist", op: func(s *store.Store) { s.Persist("b") }},
+		{name: "expire", op: func(s *store.Store) { s.Expire("a", time.Hour) }},
+		{name: "incr", op: func(s *store.Store) { _, _ = s.IncrBy("n", 1000000) }},
+		{name: "getset", op: func(s *store.Store) { s.GetSet("n", "replaced") }},
+		{name: "msetnx", op: func(s *store.Store) { s.MSetNX([]string{"m1", "m2"}, []string{"1", "2"}) }},
+		{name: "rename", op: func(s *store.Store) { _ = s.Rename("m1", "a-much-longer-key-name") }},
+		{name: "copy", op: func(s *store.Store) { _, _ = s.Copy("b", "b-copy", false) }},
+		{name: "move", op: func(s *store.Store) { _, _ = s.Move("b-copy", 3) }},
+		{name: "swapdb", op: func(s *store.Store) { _ = s.SwapDB(0, 3) }},
+		{name: "swap back", op: func(s *store.Store) { _ = s.SwapDB(0, 3) }},
+		{name: "delete", op: func(s *store.Store) { s.Delete("m2") }},
+		{name: "unlink", op: func(s *store.Store) { s.Unlink("n") }},
+		{name: "getdel", op: func(s *store.Store) { s.GetDel("a-much-longer-key-name") }},
+		{name: "lazy expiration", op: func(s *store.Store) {
+			s.Set("gone", "soon", &short)
+			time.Sleep(5 * time.Millisecond)
+			s.Get("gone")
+		}},
+		{name: "flushdb", op: func(s *store.Store) { s.FlushDB(false) }},
+	}
+
+	s := createTestStore(t)
+	for _, tt := range tests {
+		tt.op(s)
+		if got, want := s.MemoryUsage(), recomputeMemory(t, s); got != want {
+			t.Fatalf("After %s: MemoryUsage() = %d, recomputed %d", tt.name, got, want)
+		}
+	}
+
+	s.FlushAll(true)
+	if got := s.MemoryUsage(); got != 0 {
+		t.Errorf("Expected no memory in use after FLUSHALL, got %d", got)
+	}
+}
+
+func TestStore_MemoryUsage_ActiveExpiration(t *testing.T) {
+	t.Parallel()
+
+	s, err := store.New(&store.Config{
+		Shards:            4,
+		ActiveExpireCycle: 5 * time.Millisecond,
+	}, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	short := time.Millisecond
+	s.Set("kept", "value", nil)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		s.Set(key, "expiring", &short)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for s.DBSize() > 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if got, want := s.MemoryUsage(), recomputeMemory(t, s); got != want {
+		t.Errorf("MemoryUsage() = %d, recomputed %d", got, want)
+	}
+}
+
+func TestStore_CheckMemory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		limit   int64
+		needed  int64
+		wantErr error
+	}{
+		{name: "unlimited", limit: 0, needed: 1 << 40, wantErr: nil},
+		{name: "fits", limit: 1 << 20, needed: 100, wantErr: nil},
+		{name: "exceeds", limit: 1 << 10, needed: 1 << 10, wantErr: store.ErrOutOfMemory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := store.New(&store.Config{
+				Shards:         4,
+				MaxMemoryBytes: tt.limit,
+				EvictionPolicy: "noeviction",
+			}, obs.NewLogger(false))
+			if err != nil {
+				t.Fatalf("Failed to create store: %v", err)
+			}
+			t.Cleanup(s.Close)
+
+			s.Set("key", "value", nil)
+			if err := s.CheckMemory(tt.needed); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckMemory(%d) = %v, want %v", tt.needed, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package store
+
+// Keyspace events reported through Config.OnKeyEvent, named as in Redis'
+// keyspace notifications
+const (
+	EventSet         = "set"
+	EventDel         = "del"
+	EventExpire      = "expire"
+	EventPersist     = "persist"
+	EventExpired     = "expired"
+	EventEvicted     = "evicted"
+	EventRenameFrom  = "rename_from"
+	EventRenameTo    = "rename_to"
+	EventCopyTo      = "copy_to"
+	EventMoveFrom    = "move_from"
+	EventMoveTo      = "move_to"
+	EventIncrBy      = "incrby"
+	EventIncrByFloat = "incrbyfloat"
+	EventAppend      = "append"
+	EventSetRange    = "setrange"
+)
+
+// notify reports that event happened to key in database db. It is called with
+// the key's shard still locked, so events for one key arrive in order.
+func (e *engine) notify(db int, event, key string) {
+	if e.config.OnKeyEvent != nil {
+		e.config.OnKeyEvent(db, event, key)
+	}
+}
@@ -0,0 +1,222 @@
+package store_test
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// eventRecorder collects the keyspace events reported by a store
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *eventRecorder) record(db int, event, key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("%d:%s:%s", db, event, key))
+}
+
+// take returns the events recorded so far and forgets them
+func (r *eventRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func createNotifyingStore(t *testing.T, config *store.Config) (*store.Store, *eventRecorder) {
+	t.Helper()
+
+	recorder := &eventRecorder{}
+	config.OnKeyEvent = recorder.record
+	s, err := store.New(config, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	return s, recorder
+}
+
+func TestStore_OnKeyEvent(t *testing.T) {
+	t.Parallel()
+
+	hour := time.Hour
+	tests := []struct {
+		name     string
+		setup    func(s *store.Store)
+		run      func(s *store.Store)
+		expected []string
+	}{
+		{
+			name:     "set",
+			run:      func(s *store.Store) { s.Set("k", "v", nil) },
+			expected: []string{"0:set:k"},
+		},
+		{
+			name:     "set with ttl",
+			run:      func(s *store.Store) { s.Set("k", "v", &hour) },
+			expected: []string{"0:set:k", "0:expire:k"},
+		},
+		{
+			name:     "setnx on existing key",
+			setup:    func(s *store.Store) { s.Set("k", "v", nil) },
+			run:      func(s *store.Store) { s.SetNX("k", "v", nil) },
+			expected: nil,
+		},
+		{
+			name:     "mset nx",
+			run:      func(s *store.Store) { s.MSetNX([]string{"a", "b"}, []string{"1", "2"}) },
+			expected: []string{"0:set:a", "0:set:b"},
+		},
+		{
+			name:     "delete",
+			setup:    func(s *store.Store) { s.Set("k", "v", nil) },
+			run:      func(s *store.Store) { s.Delete("k"); s.Delete("missing") },
+			expected: []string{"0:del:k"},
+		},
+		{
+			name:     "unlink",
+			setup:    func(s *store.Store) { s.Set("k", "v", nil) },
+			run:      func(s *store.Store) { s.Unlink("k", "missing") },
+			expected: []string{"0:del:k"},
+		},
+		{
+			name:     "expire",
+			setup:    func(s *store.Store) { s.Set("k", "v", nil) },
+			run:      func(s *store.Store) { s.Expire("k", time.Hour) },
+			expected: []string{"0:expire:k"},
+		},
+		{
+			name:     "expire in the past deletes",
+			setup:    func(s *store.Store) { s.Set("k", "v", nil) },
+			run:      func(s *store.Store) { s.Expire("k", -time.Second) },
+			expected: []string{"0:del:k"},
+		},
+		{
+			name:     "persist",
+			setup:    func(s *store.Store) { s.Set("k", "v", &hour) },
+			run:      func(s *store.Store) { s.Persist("k") },
+			expected: []string{"0:persist:k"},
+		},
+		{
+			name:     "rename",
+			setup:    func(s *store.Store) { s.Set("src", "v", nil) },
+			run:      func(s *store.Store) { _ = s.Rename("src", "dst") },
+			expected: []string{"0:rename_from:src", "0:rename_to:dst"},
+		},
+		{
+			name:     "incrby",
+			run:      func(s *store.Store) { _, _ = s.IncrBy("n", 2) },
+			expected: []string{"0:incrby:n"},
+		},
+		{
+			name:     "failed incrby",
+			setup:    func(s *store.Store) { s.Set("n", "abc", nil) },
+			run:      func(s *store.Store) { _, _ = s.IncrBy("n", 2) },
+			expected: nil,
+		},
+		{
+			name:     "incrbyfloat",
+			run:      func(s *store.Store) { _, _ = s.IncrByFloat("n", 1.5) },
+			expected: []string{"0:incrbyfloat:n"},
+		},
+		{
+			name:     "append and setrange",
+			run:      func(s *store.Store) { _, _ = s.Append("k", "ab", noLimit); _, _ = s.SetRange("k", 1, "c", noLimit) },
+			expected: []string{"0:append:k", "0:setrange:k"},
+		},
+		{
+			name:     "move",
+			setup:    func(s *store.Store) { s.Set("k", "v", nil) },
+			run:      func(s *store.Store) { _, _ = s.Move("k", 3) },
+			expected: []string{"0:move_from:k", "3:move_to:k"},
+		},
+		{
+			name: "lazy expiration",
+			setup: func(s *store.Store) {
+				ttl := time.Millisecond
+				s.Set("k", "v", &ttl)
+				time.Sleep(5 * time.Millisecond)
+			},
+			run:      func(s *store.Store) { s.Get("k") },
+			expected: []string{"0:expired:k"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, recorder := createNotifyingStore(t, &store.Config{Shards: 4})
+			if tt.setup != nil {
+				tt.setup(s)
+			}
+			recorder.take()
+
+			tt.run(s)
+			if got := recorder.take(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected events %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStore_OnKeyEvent_ActiveExpiration(t *testing.T) {
+	t.Parallel()
+
+	s, recorder := createNotifyingStore(t, &store.Config{Shards: 1, ActiveExpireCycle: 5 * time.Millisecond})
+	db, err := s.DB(2)
+	if err != nil {
+		t.Fatalf("DB failed: %v", err)
+	}
+	ttl := time.Millisecond
+	db.Set("k", "v", &ttl)
+	recorder.take()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if got := recorder.take(); len(got) > 0 {
+			if !reflect.DeepEqual(got, []string{"2:expired:k"}) {
+				t.Errorf("Expected an expired event, got %v", got)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("Expected the active cycle to report the expired key")
+}
+
+func TestStore_OnKeyEvent_Evicted(t *testing.T) {
+	t.Parallel()
+
+	s, recorder := createNotifyingStore(t, &store.Config{
+		Shards:         1,
+		MaxMemoryBytes: evictTestKeys * evictTestKeySize,
+		EvictionPolicy: store.EvictionAllKeysRandom,
+	})
+	for i := range evictTestKeys {
+		s.Set(evictTestKey(i), evictTestValue, nil)
+	}
+	recorder.take()
+
+	if err := s.CheckMemory(evictTestKeySize); err != nil {
+		t.Fatalf("CheckMemory failed: %v", err)
+	}
+	events := recorder.take()
+	if len(events) != 1 {
+		t.Fatalf("Expected one evicted event, got %v", events)
+	}
+	var key string
+	if _, err := fmt.Sscanf(events[0], "0:evicted:%s", &key); err != nil || s.Exists(key) {
+		t.Errorf("Expected an evicted event for a removed key, got %q", events[0])
+	}
+}
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	// scanPositionBits is the width of the in-shard position in a SCAN cursor.
+	// Positions range over [0, 2^32] so one extra bit is needed for the end marker.
+	scanPositionBits = 33
+	scanPositionMask = 1<<scanPositionBits - 1
+	scanPositionEnd  = 1 << 32
+)
+
+// Filter selects keys during SCAN and KEYS. It is called with the shard read
+// lock held and must not call back into the store.
+type Filter func(key string, value *Value) bool
+
+// Scan returns a batch of roughly count keys starting at cursor together with
+// the cursor for the next call; a returned cursor of 0 ends the iteration.
+//
+// A cursor encodes a shard index and a position within that shard. Keys inside
+// a shard are visited in order of a stable per-key hash rather than map order,
+// so the iteration is unaffected by map growth and every key that exists for
+// the whole scan is returned at least once. The filter is applied after keys
+// are picked, so a batch may contain fewer than count keys.
+func (s *Store) Scan(cursor uint64, count int, filter Filter) (uint64, []string) {
+	count = max(count, 1)
+	shardIndex := cursor >> scanPositionBits
+	position := cursor & scanPositionMask
+
+	var keys []string
+	visited := 0
+	for shardIndex < uint64(len(s.shards)) && visited < count {
+		var batch []string
+		var n int
+		batch, n, position = s.shards[shardIndex].scan(s.db, position, count-visited, filter)
+		keys = append(keys, batch...)
+		visited += n
+
+		if position == scanPositionEnd {
+			shardIndex++
+			position = 0
+		}
+	}
+
+	if shardIndex >= uint64(len(s.shards)) {
+		return 0, keys
+	}
+
+	return shardIndex<<scanPositionBits | position, keys
+}
+
+// scan visits count keys in hash order from position and returns those
+// accepted by filter, the number of keys visited and the next position
+func (sh *Shard) scan(db int, position uint64, count int, filter Filter) ([]string, int, uint64) {
+	sh.rlock()
+	defer sh.runlock()
+
+	now := time.Now()
+	var keys []string
+	visited, next := sh.dbs[db].visit(position, count, func(key string, value *Value) {
+		if !value.isExpired(now) && (filter == nil || filter(key, value)) {
+			keys = append(keys, key)
+		}
+	})
+
+	return keys, visited, next
+}
+
+// visit calls fn for count keys in hash order from position, together with
+// any further keys sharing the last hash so that none is skipped, and returns
+// the number of keys visited and the next position. The scan index makes this
+// O(log N + count) however large the keyspace is. The caller must hold the
+// shard lock.
+func (ks *keyspace) visit(position uint64, count int, fn func(key string, value *Value)) (int, uint64) {
+	visited := 0
+	next := uint64(scanPositionEnd)
+	var last uint32
+	ks.order.ascend(position, func(entry scanEntry) bool {
+		if visited >= count && entry.hash != last {
+			next = uint64(entry.hash)
+			return false
+		}
+		visited++
+		last = entry.hash
+		fn(entry.key, ks.data[entry.key])
+		return true
+	})
+
+	return visited, next
+}
+
+// Keys returns every live key accepted by filter. If more than limit keys
+// match (and limit is positive) it stops early and reports false.
+func (s *Store) Keys(filter Filter, limit int) ([]string, bool) {
+	now := time.Now()
+	var keys []string
+	for _, shard := range s.shards {
+		shard.rlock()
+		for key, value := range shard.dbs[s.db].data {
+			if value.isExpired(now) || (filter != nil && !filter(key, value)) {
+				continue
+			}
+			if limit > 0 && len(keys) >= limit {
+				shard.runlock()
+				return keys, false
+			}
+			keys = append(keys, key)
+		}
+		shard.runlock()
+	}
+
+	return keys, true
+}
+
+// RandomKey returns a random live key, or false when the store is empty
+func (s *Store) RandomKey() (string, bool) {
+	now := time.Now()
+	offset := rand.IntN(len(s.shards)) // #nosec G404 -- key sampling does not need a secure source
+	for i := range s.shards {
+		shard := s.shards[(offset+i)%len(s.shards)]
+		shard.rlock()
+		// Map iteration order is randomized, so the first live key is a random pick
+		for key, value := range shard.dbs[s.db].data {
+			if !value.isExpired(now) {
+				shard.runlock()
+				return key, true
+			}
+		}
+		shard.runlock()
+	}
+
+	return "", false
+}
+
+// scanHash orders keys within a shard for SCAN. It must differ from the
+// shard-selection hash, which is constant modulo the shard count inside a shard.
+func scanHash(key string) uint32 {
+	const (
+		fnvPrime64 = 1099511628211
+		fnvBasis64 = 14695981039346656037
+	)
+
+	hash := uint64(fnvBasis64)
+	for i := range len(key) {
+		hash ^= uint64(key[i])
+		hash *= fnvPrime64
+	}
+	return uint32(hash>>32) ^ uint32(hash) // #nosec G115 -- intentional truncation
+}
+
+// scanEntry is a key of the scan index, ordered by its scanHash and then by
+// name so that every entry is unique
+type scanEntry struct {
+	hash uint32
+	key  string
+}
+
+// less orders entries by hash, then key
+func (e scanEntry) less(other scanEntry) bool {
+	return e.hash < other.hash || (e.hash == other.hash && e.key < other.key)
+}
+
+// scanChunkSize is the most entries a scan index chunk holds before it is
+// split in two
+const scanChunkSize = 512
+
+// scanIndex holds a keyspace's keys in SCAN order. The keys are kept in sorted
+// chunks of at most scanChunkSize entries, so inserting or removing a key is a
+// binary search plus a bounded copy, and SCAN seeks to its cursor instead of
+// walking the whole shard. The zero value is an empty index.
+type scanIndex struct {
+	chunks [][]scanEntry
+}
+
+// chunkFor returns the index of the chunk that holds, or would hold, entry
+func (x *scanIndex) chunkFor(entry scanEntry) int {
+	i := sort.Search(len(x.chunks), func(i int) bool {
+		chunk := x.chunks[i]
+		return !chunk[len(chunk)-1].less(entry)
+	})
+	return min(i, len(x.chunks)-1)
+}
+
+// insert adds entry, which must not be in the index yet
+func (x *scanIndex) insert(entry scanEntry) {
+	if len(x.chunks) == 0 {
+		x.chunks = [][]scanEntry{{entry}}
+		return
+	}
+
+	i := x.chunkFor(entry)
+	chunk := x.chunks[i]
+	j := sort.Search(len(chunk), func(j int) bool { return !chunk[j].less(entry) })
+	chunk = slices.Insert(chunk, j, entry)
+	if len(chunk) <= scanChunkSize {
+		x.chunks[i] = chunk
+		return
+	}
+
+	half := len(chunk) / 2
+	x.chunks[i] = chunk[:half:half]
+	x.chunks = slices.Insert(x.chunks, i+1, slices.Clone(chunk[half:]))
+}
+
+// remove deletes entry if it is in the index. A chunk left small is merged
+// into the next one so that deletes do not leave the index fragmented.
+func (x *scanIndex) remove(entry scanEntry) {
+	if len(x.chunks) == 0 {
+		return
+	}
+
+	i := x.chunkFor(entry)
+	chunk := x.chunks[i]
+	j := sort.Search(len(chunk), func(j int) bool { return !chunk[j].less(entry) })
+	if j == len(chunk) || chunk[j] != entry {
+		return
+	}
+
+	chunk = slices.Delete(chunk, j, j+1)
+	switch {
+	case len(chunk) == 0:
+		x.chunks = slices.Delete(x.chunks, i, i+1)
+	case len(chunk) < scanChunkSize/4 && i+1 < len(x.chunks) && len(chunk)+len(x.chunks[i+1]) <= scanChunkSize:
+		x.chunks[i] = append(chunk, x.chunks[i+1]...)
+		x.chunks = slices.Delete(x.chunks, i+1, i+2)
+	default:
+		x.chunks[i] = chunk
+	}
+}
+
+// ascend calls fn for every entry with a hash at or after position, in order,
+// until fn returns false
+func (x *scanIndex) ascend(position uint64, fn func(scanEntry) bool) {
+	if position > math.MaxUint32 {
+		return
+	}
+
+	from := scanEntry{hash: uint32(position)} // #nosec G115 -- bounded above
+	i := sort.Search(len(x.chunks), func(i int) bool {
+		chunk := x.chunks[i]
+		return !chunk[len(chunk)-1].less(from)
+	})
+	if i == len(x.chunks) {
+		return
+	}
+
+	first := x.chunks[i]
+	j := sort.Search(len(first), func(j int) bool { return !first[j].less(from) })
+	for _, chunk := range x.chunks[i:] {
+		for _, entry := range chunk[j:] {
+			if !fn(entry) {
+				return
+			}
+		}
+		j = 0
+	}
+}
@@ -0,0 +1,223 @@
+package store_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// scanAll runs a full SCAN iteration and returns how often each key was seen
+func scanAll(s *store.Store, count int, filter store.Filter, during func()) map[string]int {
+	seen := make(map[string]int)
+	var cursor uint64
+	for {
+		next, keys := s.Scan(cursor, count, filter)
+		for _, key := range keys {
+			seen[key]++
+		}
+		if during != nil {
+			during()
+		}
+		if next == 0 {
+			return seen
+		}
+		cursor = next
+	}
+}
+
+func TestStore_Scan_ReturnsEveryKey(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	const numKeys = 1000
+	for i := range numKeys {
+		s.Set(fmt.Sprintf("key:%d", i), "value", nil)
+	}
+
+	seen := scanAll(s, 7, nil, nil)
+	if len(seen) != numKeys {
+		t.Fatalf("Expected %d distinct keys, got %d", numKeys, len(seen))
+	}
+
+	for key, n := range seen {
+		if n != 1 {
+			t.Errorf("Expected key %s to be returned once without concurrent writes, got %d", key, n)
+		}
+	}
+}
+
+func TestStore_Scan_StableAcrossMapGrowth(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	const numKeys = 200
+	for i := range numKeys {
+		s.Set(fmt.Sprintf("stable:%d", i), "value", nil)
+	}
+
+	// Grow every shard's map considerably during the first SCAN calls
+	added := 0
+	seen := scanAll(s, 5, nil, func() {
+		for added < 5000 {
+			s.Set(fmt.Sprintf("growth:%d", added), "value", nil)
+			added++
+			if added%500 == 0 {
+				return
+			}
+		}
+	})
+
+	for i := range numKeys {
+		key := fmt.Sprintf("stable:%d", i)
+		if seen[key] == 0 {
+			t.Errorf("Key %s present for the whole scan was not returned", key)
+		}
+	}
+}
+
+func TestStore_Scan_Filter(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	for i := range 50 {
+		s.Set(fmt.Sprintf("user:%d", i), "value", nil)
+		s.Set(fmt.Sprintf("order:%d", i), "value", nil)
+	}
+	expiration := time.Millisecond
+	s.Set("user:expired", "value", &expiration)
+	time.Sleep(5 * time.Millisecond)
+
+	seen := scanAll(s, 10, func(key string, _ *store.Value) bool {
+		return strings.HasPrefix(key, "user:")
+	}, nil)
+
+	if len(seen) != 50 {
+		t.Errorf("Expected 50 user keys, got %d", len(seen))
+	}
+	if seen["user:expired"] != 0 {
+		t.Errorf("Expected expired keys to be skipped")
+	}
+}
+
+func TestStore_Scan_AfterRemovals(t *testing.T) {
+	t.Parallel()
+
+	// Keys removed by DEL, MOVE and overwrites leave the scan order intact
+	s := createTestStore(t)
+	const numKeys = 3000
+	for i := range numKeys {
+		s.Set(fmt.Sprintf("key:%d", i), "value", nil)
+	}
+	for i := range numKeys {
+		switch i % 3 {
+		case 0:
+			s.Delete(fmt.Sprintf("key:%d", i))
+		case 1:
+			if _, err := s.Move(fmt.Sprintf("key:%d", i), 1); err != nil {
+				t.Fatalf("Failed to move: %v", err)
+			}
+		default:
+			s.Set(fmt.Sprintf("key:%d", i), "overwritten", nil)
+		}
+	}
+
+	seen := scanAll(s, 10, nil, nil)
+	if len(seen) != numKeys/3 {
+		t.Fatalf("Expected %d keys, got %d", numKeys/3, len(seen))
+	}
+	for key, n := range seen {
+		if n != 1 {
+			t.Errorf("Expected key %s to be returned once, got %d", key, n)
+		}
+	}
+
+	db1, err := s.DB(1)
+	if err != nil {
+		t.Fatalf("Failed to select DB 1: %v", err)
+	}
+	if seen := scanAll(db1, 10, nil, nil); len(seen) != numKeys/3 {
+		t.Errorf("Expected %d moved keys, got %d", numKeys/3, len(seen))
+	}
+}
+
+func TestStore_Scan_EmptyAndOutOfRange(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+
+	next, keys := s.Scan(0, 10, nil)
+	if next != 0 || len(keys) != 0 {
+		t.Errorf("Expected empty scan to finish immediately, got cursor %d and %d keys", next, len(keys))
+	}
+
+	next, keys = s.Scan(1<<62, 10, nil)
+	if next != 0 || len(keys) != 0 {
+		t.Errorf("Expected out of range cursor to finish, got cursor %d and %d keys", next, len(keys))
+	}
+}
+
+func TestStore_Keys(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	for i := range 20 {
+		s.Set(fmt.Sprintf("key:%d", i), "value", nil)
+	}
+
+	keys, complete := s.Keys(nil, 0)
+	if !complete || len(keys) != 20 {
+		t.Errorf("Expected 20 keys, got %d (complete: %v)", len(keys), complete)
+	}
+
+	keys, complete = s.Keys(nil, 20)
+	if !complete || len(keys) != 20 {
+		t.Errorf("Expected limit equal to key count to succeed, got %d (complete: %v)", len(keys), complete)
+	}
+
+	if _, complete = s.Keys(nil, 5); complete {
+		t.Errorf("Expected Keys to report an incomplete result over the limit")
+	}
+}
+
+func TestStore_RandomKey(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	if _, exists := s.RandomKey(); exists {
+		t.Errorf("Expected no random key in empty store")
+	}
+
+	s.Set("only", "value", nil)
+	key, exists := s.RandomKey()
+	if !exists || key != "only" {
+		t.Errorf("Expected 'only', got %q (exists: %v)", key, exists)
+	}
+}
+
+// BenchmarkStore_Scan measures one SCAN call in a large keyspace, which must
+// not depend on the number of keys in the shard
+func BenchmarkStore_Scan(b *testing.B) {
+	for _, numKeys := range []int{10_000, 1_000_000} {
+		b.Run(fmt.Sprintf("keys=%d", numKeys), func(b *testing.B) {
+			s, err := store.New(&store.Config{Shards: 4, EvictionPolicy: "noeviction"}, obs.NewLogger(false))
+			if err != nil {
+				b.Fatalf("Failed to create store: %v", err)
+			}
+			b.Cleanup(s.Close)
+			for i := range numKeys {
+				s.Set("key:"+strconv.Itoa(i), "value", nil)
+			}
+
+			b.ReportAllocs()
+			var cursor uint64
+			for b.Loop() {
+				cursor, _ = s.Scan(cursor, 10, nil)
+			}
+		})
+	}
+}
@@ -3,34 +3,144 @@ package store
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/Abhishek2095/kv-stash/internal/hotkeys"
 	"github.com/Abhishek2095/kv-stash/internal/obs"
 )
 
-// Store represents the main key-value store
+var (
+	// ErrNotFloat is returned when a value cannot be parsed as a float
+	ErrNotFloat = errors.New("value is not a valid float")
+	// ErrNaNOrInfinity is returned when a float operation would produce NaN or Infinity
+	ErrNaNOrInfinity = errors.New("increment would produce NaN or Infinity")
+	// ErrNotInteger is returned when a value cannot be parsed as a 64-bit integer
+	ErrNotInteger = errors.New("value is not an integer or out of range")
+	// ErrOverflow is returned when an integer operation would overflow int64
+	ErrOverflow = errors.New("increment or decrement would overflow")
+	// ErrStringTooLong is returned when a string would grow past the length
+	// limit of the operation
+	ErrStringTooLong = errors.New("string exceeds maximum allowed size")
+	// ErrClosed is returned when work is handed to the shard loops of a
+	// closed store
+	ErrClosed = errors.New("store is closed")
+)
+
+// Store represents the main key-value store. A Store operates on one logical
+// database; DB returns views of the other databases, which share the shards
+// and background workers.
 type Store struct {
+	*engine
+	db int
+}
+
+// engine holds the state shared by every database view of a store
+type engine struct {
 	config       *Config
 	logger       *obs.Logger
 	shards       []*Shard
+	databases    int
 	expiredCount int64
+	evictedCount int64
+
+	// expireCursor is the shard the next active expiration cycle starts at.
+	// Only the cycle goroutine touches it.
+	expireCursor int
+
+	// hotKeys samples Get and Set accesses; nil when tracking is disabled
+	hotKeys *hotkeys.Tracker
+
+	// evictMu serializes eviction and guards the eviction pool
+	evictMu   sync.Mutex
+	evictPool []evictionCandidate
+
+	// lazyFree queues memory for the background worker to free and
+	// lazyFreePending counts the keys it holds
+	lazyFree        chan lazyFreeJob
+	lazyFreePending int64
+
+	// loopsDone is closed once every shard loop has returned
+	loopsDone chan struct{}
+
+	// Background workers
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
 // Config represents store configuration
 type Config struct {
 	Shards         int
+	Databases      int
 	MaxMemoryBytes int64
 	EvictionPolicy string
+
+	// ExpiryIndex selects the structure indexing volatile keys for the
+	// active expiration cycle: ExpiryIndexHeap (the default) or
+	// ExpiryIndexWheel
+	ExpiryIndex string
+	// ActiveExpireCycle is the interval of the background expiration cycle.
+	// Zero leaves expiration entirely lazy.
+	ActiveExpireCycle time.Duration
+	// OnExpired, if set, is called with the number of keys removed because
+	// their TTL passed, whether lazily or by the active cycle
+	OnExpired func(count int64)
+	// OnEvicted, if set, is called with the number of keys removed to stay
+	// within MaxMemoryBytes
+	OnEvicted func(count int64)
+
+	// HotKeySampleRate is the fraction of Get and Set calls sampled for hot
+	// key detection. Zero disables it.
+	HotKeySampleRate float64
+	// HotKeyWindow is the longest window hot keys are reported over
+	HotKeyWindow time.Duration
+
+	// OnKeyEvent, if set, is called with every keyspace event: the database,
+	// one of the Event names and the key. It runs with the key's shard
+	// locked and must not call back into the store.
+	OnKeyEvent func(db int, event, key string)
+
+	// ShardLoops gives each shard to a goroutine of its own, which runs
+	// every access to the shard's data; the shard locks are then never
+	// taken. See Run and Exclusive.
+	ShardLoops bool
+	// ShardMailbox is the number of tasks that can wait for a shard loop
+	// before the goroutines handing it more block
+	ShardMailbox int
 }
 
-// Shard represents a single shard of the store
+// Shard represents a single shard of the store. It holds the slice of every
+// logical database that hashes to it, indexed by database number.
 type Shard struct {
 	id     int
 	mu     sync.RWMutex
-	data   map[string]*Value
+	dbs    []*keyspace
 	logger *obs.Logger
+
+	// mailbox queues the tasks of the loop owning the shard; nil without
+	// shard loops. A loop's shard is only accessed by the loop, or by a
+	// goroutine it has handed the shard over to, so mu is left alone.
+	mailbox chan shardTask
+
+	// used is the estimated memory of every key in the shard. It is written
+	// under mu but read atomically so the total can be summed without locking.
+	used int64
+}
+
+// keyspace is one shard's slice of a logical database. Writes go through put,
+// remove, setExpiry and resize so the expiry index and the memory accounting
+// always match the data.
+type keyspace struct {
+	data    map[string]*Value
+	expires ExpiryIndex
+	// order holds the keys in SCAN order
+	order scanIndex
+	shard *Shard
+	used  int64
 }
 
 // Value represents a stored value with metadata
@@ -39,6 +149,15 @@ type Value struct {
 	Type      ValueType
 	ExpiresAt *time.Time
 	Version   uint64
+
+	// lastAccess is the last access time in unix nanoseconds and freq the
+	// logarithmic access counter. Both are updated atomically so readers
+	// holding only the shard read lock can record accesses.
+	lastAccess int64
+	freq       uint32
+
+	// size is the memory accounted to the key in its keyspace
+	size int64
 }
 
 // ValueType represents the type of value
@@ -51,31 +170,102 @@ const (
 	IntegerType
 )
 
+// String returns the Redis type name reported by TYPE and used by SCAN's TYPE filter
+func (t ValueType) String() string {
+	switch t {
+	case StringType, IntegerType:
+		return "string"
+	default:
+		return "none"
+	}
+}
+
 // New creates a new store instance
 func New(config *Config, logger *obs.Logger) (*Store, error) {
 	if config.Shards <= 0 {
 		return nil, errors.New("shards must be greater than 0")
 	}
 
-	store := &Store{
-		config: config,
-		logger: logger,
-		shards: make([]*Shard, config.Shards),
+	databases := config.Databases
+	if databases == 0 {
+		databases = DefaultDatabases
+	}
+	if databases < 0 {
+		return nil, errors.New("databases must be greater than 0")
+	}
+
+	if !validEvictionPolicy(config.EvictionPolicy) {
+		return nil, fmt.Errorf("unknown eviction policy %q", config.EvictionPolicy)
+	}
+
+	var tracker *hotkeys.Tracker
+	if config.HotKeySampleRate > 0 {
+		var err error
+		tracker, err = hotkeys.New(config.HotKeySampleRate, config.HotKeyWindow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hot key tracking: %w", err)
+		}
+	}
+
+	if config.ShardLoops && config.ShardMailbox <= 0 {
+		return nil, errors.New("shard mailbox must be greater than 0")
 	}
 
+	store := &Store{engine: &engine{
+		config:    config,
+		logger:    logger,
+		shards:    make([]*Shard, config.Shards),
+		databases: databases,
+		hotKeys:   tracker,
+		lazyFree:  make(chan lazyFreeJob, lazyFreeQueueSize),
+		loopsDone: make(chan struct{}),
+		stop:      make(chan struct{}),
+	}}
+
 	// Initialize shards
 	for i := range config.Shards {
-		store.shards[i] = &Shard{
+		shard := &Shard{
 			id:     i,
-			data:   make(map[string]*Value),
+			dbs:    make([]*keyspace, databases),
 			logger: logger.WithFields("shard", i),
 		}
+		for db := range databases {
+			ks, err := store.newKeyspace(shard)
+			if err != nil {
+				return nil, err
+			}
+			shard.dbs[db] = ks
+		}
+		if config.ShardLoops {
+			shard.mailbox = make(chan shardTask, config.ShardMailbox)
+		}
+		store.shards[i] = shard
+	}
+
+	if config.ShardLoops {
+		store.startShardLoops()
 	}
 
-	logger.Info("Store initialized", "shards", config.Shards)
+	store.wg.Add(1)
+	go store.lazyFreeLoop()
+
+	if config.ActiveExpireCycle > 0 {
+		store.wg.Add(1)
+		go store.activeExpireLoop(config.ActiveExpireCycle)
+	}
+
+	logger.Info("Store initialized", "shards", config.Shards, "databases", databases, "shard_loops", config.ShardLoops)
 	return store, nil
 }
 
+// Close stops the store's background workers. It is safe to call more than once.
+func (s *Store) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+	})
+}
+
 // getShard returns the shard for a given key
 func (s *Store) getShard(key string) *Shard {
 	hash := fnv1aHash(key)
@@ -83,58 +273,147 @@ func (s *Store) getShard(key string) *Shard {
 	return s.shards[hash%shardCount]
 }
 
+// ShardCount returns the number of shards keys are partitioned into
+func (s *Store) ShardCount() int {
+	return len(s.shards)
+}
+
+// ShardIndex returns the index of the shard holding key, between 0 and
+// ShardCount()-1. It is the same in every database.
+func (s *Store) ShardIndex(key string) int {
+	return s.getShard(key).id
+}
+
+// lock write-locks the shard, unless a loop owns it
+func (sh *Shard) lock() {
+	if sh.mailbox == nil {
+		sh.mu.Lock()
+	}
+}
+
+// unlock releases the write lock taken by lock
+func (sh *Shard) unlock() {
+	if sh.mailbox == nil {
+		sh.mu.Unlock()
+	}
+}
+
+// rlock read-locks the shard, unless a loop owns it
+func (sh *Shard) rlock() {
+	if sh.mailbox == nil {
+		sh.mu.RLock()
+	}
+}
+
+// runlock releases the read lock taken by rlock
+func (sh *Shard) runlock() {
+	if sh.mailbox == nil {
+		sh.mu.RUnlock()
+	}
+}
+
 // Get retrieves a value by key
 func (s *Store) Get(key string) (string, bool) {
+	s.recordHotKey(key)
+
 	shard := s.getShard(key)
-	shard.mu.RLock()
-	defer shard.mu.RUnlock()
+	shard.rlock()
+	defer shard.runlock()
 
-	value, exists := shard.data[key]
+	value, exists := shard.dbs[s.db].data[key]
 	if !exists {
 		return "", false
 	}
 
 	// Check if value has expired
-	if value.ExpiresAt != nil && time.Now().After(*value.ExpiresAt) {
-		// Remove expired key (lazy expiration)
-		delete(shard.data, key)
-		atomic.AddInt64(&s.expiredCount, 1)
+	now := time.Now()
+	if value.isExpired(now) {
+		// Remove expired key (lazy expiration) under the write lock
+		shard.runlock()
+		shard.lock()
+		s.lookup(shard, key, time.Now())
+		shard.unlock()
+		shard.rlock()
 		return "", false
 	}
 
+	value.recordAccess(now)
 	return value.Data, true
 }
 
 // Set stores a value with optional expiration
 func (s *Store) Set(key, value string, expiration *time.Duration) {
+	s.recordHotKey(key)
+
 	shard := s.getShard(key)
-	shard.mu.Lock()
-	defer shard.mu.Unlock()
+	shard.lock()
+	defer shard.unlock()
 
-	timestamp := time.Now().UnixNano()
-	val := &Value{
-		Data:    value,
-		Type:    StringType,
-		Version: uint64(timestamp), // #nosec G115 -- timestamp is always non-negative
-	}
+	now := time.Now()
+	val := newStringValue(value)
 
 	if expiration != nil {
-		expiresAt := time.Now().Add(*expiration)
+		expiresAt := now.Add(*expiration)
 		val.ExpiresAt = &expiresAt
 	}
 
-	shard.data[key] = val
+	// Overwriting a key keeps its access frequency, like Redis
+	if old, exists := s.lookup(shard, key, now); exists {
+		val.freq = uint32(old.frequency(now))
+	}
+
+	shard.dbs[s.db].put(key, val)
+	s.notify(s.db, EventSet, key)
+	if expiration != nil {
+		s.notify(s.db, EventExpire, key)
+	}
+}
+
+// UpdateFunc computes the new value for a key from its current value. current
+// is a copy of the stored value, or nil when the key does not exist. Returning
+// a nil value deletes the key; returning an error leaves the key untouched.
+type UpdateFunc func(current *Value) (*Value, error)
+
+// Update atomically applies fn to the value stored at key while holding the
+// shard write lock, so concurrent read-modify-write operations never lose
+// updates. Callers that want to keep the key's TTL should return the value
+// they were given with its ExpiresAt unchanged.
+func (s *Store) Update(key string, fn UpdateFunc) error {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	var current *Value
+	if value, exists := s.lookup(shard, key, time.Now()); exists {
+		snapshot := *value
+		current = &snapshot
+	}
+
+	updated, err := fn(current)
+	if err != nil {
+		return err
+	}
+
+	if updated == nil {
+		shard.dbs[s.db].remove(key)
+		return nil
+	}
+
+	updated.Version = newVersion()
+	shard.dbs[s.db].put(key, updated)
+	return nil
 }
 
 // Delete removes a key
 func (s *Store) Delete(key string) bool {
 	shard := s.getShard(key)
-	shard.mu.Lock()
-	defer shard.mu.Unlock()
+	shard.lock()
+	defer shard.unlock()
 
-	_, exists := shard.data[key]
+	_, exists := shard.dbs[s.db].data[key]
 	if exists {
-		delete(shard.data, key)
+		shard.dbs[s.db].remove(key)
+		s.notify(s.db, EventDel, key)
 	}
 
 	return exists
@@ -143,77 +422,187 @@ func (s *Store) Delete(key string) bool {
 // Exists checks if a key exists
 func (s *Store) Exists(key string) bool {
 	shard := s.getShard(key)
-	shard.mu.RLock()
-	defer shard.mu.RUnlock()
+	shard.rlock()
+	defer shard.runlock()
 
-	value, exists := shard.data[key]
+	value, exists := shard.dbs[s.db].data[key]
 	if !exists {
 		return false
 	}
 
-	// Check if value has expired
-	if value.ExpiresAt != nil && time.Now().After(*value.ExpiresAt) {
+	if value.isExpired(time.Now()) {
+		// Remove expired key (lazy expiration) under the write lock
+		shard.runlock()
+		shard.lock()
+		s.lookup(shard, key, time.Now())
+		shard.unlock()
+		shard.rlock()
 		return false
 	}
 
 	return true
 }
 
-// Expire sets an expiration time for a key
+// Expire sets an expiration time for a key. A non-positive duration deletes
+// the key.
 func (s *Store) Expire(key string, duration time.Duration) bool {
-	shard := s.getShard(key)
-	shard.mu.Lock()
-	defer shard.mu.Unlock()
+	return s.ExpireAt(key, time.Now().Add(duration), ExpireAlways)
+}
+
+// TTL returns the time to live for a key in seconds, -1 if the key has no
+// expiration or -2 if it does not exist
+func (s *Store) TTL(key string) int64 {
+	ttl, code := s.remaining(key)
+	if code < 0 {
+		return code
+	}
+
+	// Return TTL in seconds, but ensure it's at least 1 if positive
+	ttlSeconds := int64(ttl.Seconds())
+	if ttlSeconds == 0 && ttl > 0 {
+		return 1 // Round up sub-second TTLs to 1 second
+	}
+
+	return ttlSeconds
+}
 
-	value, exists := shard.data[key]
+// lookup returns the live value for key, lazily removing it if it has expired.
+// The caller must hold the shard write lock.
+func (s *Store) lookup(shard *Shard, key string, now time.Time) (*Value, bool) {
+	value, exists := shard.dbs[s.db].data[key]
 	if !exists {
-		return false
+		return nil, false
 	}
 
-	expiresAt := time.Now().Add(duration)
-	value.ExpiresAt = &expiresAt
-	return true
+	if value.isExpired(now) {
+		shard.dbs[s.db].remove(key)
+		s.countExpired(1)
+		s.notify(s.db, EventExpired, key)
+		return nil, false
+	}
+
+	value.recordAccess(now)
+	return value, true
 }
 
-// TTL returns the time to live for a key
-func (s *Store) TTL(key string) int64 {
-	shard := s.getShard(key)
-	shard.mu.Lock()
-	defer shard.mu.Unlock()
+// lockShards write-locks every shard owning one of keys, in ascending shard
+// order so that concurrent multi-key operations cannot deadlock. The returned
+// function releases the locks.
+func (s *Store) lockShards(keys []string) func() {
+	seen := make(map[*Shard]bool, len(keys))
+	shards := make([]*Shard, 0, len(keys))
+	for _, key := range keys {
+		shard := s.getShard(key)
+		if !seen[shard] {
+			seen[shard] = true
+			shards = append(shards, shard)
+		}
+	}
 
-	value, exists := shard.data[key]
-	if !exists {
-		return -2 // key does not exist
+	sort.Slice(shards, func(i, j int) bool { return shards[i].id < shards[j].id })
+	for _, shard := range shards {
+		shard.lock()
+	}
+
+	return func() {
+		for i := len(shards) - 1; i >= 0; i-- {
+			shards[i].unlock()
+		}
 	}
+}
+
+// isExpired reports whether the value has an expiration at or before now
+func (v *Value) isExpired(now time.Time) bool {
+	return v.ExpiresAt != nil && !now.Before(*v.ExpiresAt)
+}
 
-	if value.ExpiresAt == nil {
-		return -1 // key exists but has no expiration
+// newKeyspace creates an empty keyspace of shard with the configured expiry index
+func (s *Store) newKeyspace(shard *Shard) (*keyspace, error) {
+	index, err := NewExpiryIndex(s.config.ExpiryIndex, time.Now())
+	if err != nil {
+		return nil, err
 	}
 
-	ttl := time.Until(*value.ExpiresAt)
-	if ttl <= 0 {
-		// Clean up expired key
-		delete(shard.data, key)
-		atomic.AddInt64(&s.expiredCount, 1)
-		return -2 // key has expired
+	return &keyspace{data: make(map[string]*Value), expires: index, shard: shard}, nil
+}
+
+// put stores value at key, indexing its expiration and accounting its memory
+func (ks *keyspace) put(key string, value *Value) {
+	if old, exists := ks.data[key]; exists {
+		ks.account(-old.size)
+	} else {
+		ks.order.insert(scanEntry{hash: scanHash(key), key: key})
 	}
 
-	// Return TTL in seconds, but ensure it's at least 1 if positive
-	ttlSeconds := int64(ttl.Seconds())
-	if ttlSeconds == 0 && ttl > 0 {
-		return 1 // Round up sub-second TTLs to 1 second
+	ks.data[key] = value
+	value.size = EstimateSize(key, value)
+	ks.account(value.size)
+
+	if value.ExpiresAt != nil {
+		ks.expires.Set(key, *value.ExpiresAt)
+	} else {
+		ks.expires.Remove(key)
 	}
+}
 
-	return ttlSeconds
+// remove deletes key and its expiry index entry
+func (ks *keyspace) remove(key string) {
+	ks.drop(key)
+	ks.expires.Remove(key)
+}
+
+// drop deletes key and releases its memory, leaving the expiry index alone
+func (ks *keyspace) drop(key string) {
+	if value, exists := ks.data[key]; exists {
+		ks.account(-value.size)
+		delete(ks.data, key)
+		ks.order.remove(scanEntry{hash: scanHash(key), key: key})
+	}
+}
+
+// setExpiry changes the expiration of the value stored at key; nil persists it
+func (ks *keyspace) setExpiry(key string, value *Value, at *time.Time) {
+	value.ExpiresAt = at
+	ks.resize(key, value)
+	if at != nil {
+		ks.expires.Set(key, *at)
+	} else {
+		ks.expires.Remove(key)
+	}
+}
+
+// resize re-accounts the memory of the value stored at key after it was
+// modified in place
+func (ks *keyspace) resize(key string, value *Value) {
+	size := EstimateSize(key, value)
+	ks.account(size - value.size)
+	value.size = size
+}
+
+// newStringValue creates a string value stamped with a fresh version and access time
+func newStringValue(data string) *Value {
+	now := time.Now().UnixNano()
+	return &Value{
+		Data:       data,
+		Type:       StringType,
+		Version:    uint64(now), // #nosec G115 -- timestamp is always non-negative
+		lastAccess: now,
+		freq:       lfuInitValue,
+	}
+}
+
+// newVersion returns a version number for a freshly written value
+func newVersion() uint64 {
+	return uint64(time.Now().UnixNano()) // #nosec G115 -- timestamp is always non-negative
 }
 
-// DBSize returns the total number of keys
+// DBSize returns the total number of keys in the database
 func (s *Store) DBSize() int64 {
 	var total int64
 	for _, shard := range s.shards {
-		shard.mu.RLock()
-		total += int64(len(shard.data))
-		shard.mu.RUnlock()
+		shard.rlock()
+		total += int64(len(shard.dbs[s.db].data))
+		shard.runlock()
 	}
 	return total
 }
@@ -233,6 +622,19 @@ func fnv1aHash(key string) uint32 {
 	return hash
 }
 
+// countExpired records keys removed because their TTL passed
+func (s *Store) countExpired(count int64) {
+	atomic.AddInt64(&s.expiredCount, count)
+	if s.config.OnExpired != nil {
+		s.config.OnExpired(count)
+	}
+}
+
+// GetEvictedKeysCount returns the total number of keys evicted to free memory
+func (s *Store) GetEvictedKeysCount() int64 {
+	return atomic.LoadInt64(&s.evictedCount)
+}
+
 // GetExpiredKeysCount returns the total number of expired keys
 func (s *Store) GetExpiredKeysCount() int64 {
 	return atomic.LoadInt64(&s.expiredCount)
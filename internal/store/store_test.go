@@ -1,6 +1,7 @@
 package store_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -475,3 +476,44 @@ func TestStore_ShardDistribution(t *testing.T) {
 		t.Errorf("Expected DBSize to be %d, got %d", len(keys), s.DBSize())
 	}
 }
+
+func TestStore_Update(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	s.Set("key", "value", nil)
+
+	errAbort := errors.New("abort")
+	err := s.Update("key", func(current *store.Value) (*store.Value, error) {
+		current.Data = "changed"
+		return nil, errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Errorf("Expected abort error, got %v", err)
+	}
+	if value, _ := s.Get("key"); value != "value" {
+		t.Errorf("Expected failed update to leave value untouched, got %q", value)
+	}
+
+	// Returning nil deletes the key
+	if err := s.Update("key", func(*store.Value) (*store.Value, error) { return nil, nil }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.Exists("key") {
+		t.Errorf("Expected key to be deleted by nil update")
+	}
+
+	// Missing keys are passed as nil
+	err = s.Update("missing", func(current *store.Value) (*store.Value, error) {
+		if current != nil {
+			t.Errorf("Expected nil current value for missing key")
+		}
+		return &store.Value{Data: "created", Type: store.StringType}, nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, _ := s.Get("missing"); value != "created" {
+		t.Errorf("Expected 'created', got %q", value)
+	}
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Append appends value to the string stored at key, creating the key if it
+// does not exist, and returns the length of the resulting string. It fails
+// with ErrStringTooLong, leaving the key alone, when the result would be
+// longer than maxLen bytes.
+func (s *Store) Append(key, value string, maxLen int64) (int64, error) {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	current, exists := s.lookup(shard, key, time.Now())
+	length := int64(len(value))
+	if exists {
+		length += int64(len(current.Data))
+	}
+	if length > maxLen {
+		return 0, ErrStringTooLong
+	}
+
+	defer s.notify(s.db, EventAppend, key)
+
+	if !exists {
+		shard.dbs[s.db].put(key, newStringValue(value))
+		return length, nil
+	}
+
+	current.Data += value
+	current.Type = StringType
+	current.Version = newVersion()
+	shard.dbs[s.db].resize(key, current)
+	return length, nil
+}
+
+// SetRange overwrites part of the string stored at key starting at offset,
+// padding with zero bytes when offset is past the end of the current value.
+// It returns the length of the resulting string. It fails with
+// ErrStringTooLong, leaving the key alone, when the write would end past
+// maxLen bytes.
+func (s *Store) SetRange(key string, offset int, value string, maxLen int64) (int64, error) {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	current, exists := s.lookup(shard, key, time.Now())
+	if value != "" && int64(offset) > maxLen-int64(len(value)) {
+		return 0, ErrStringTooLong
+	}
+	if !exists {
+		// SETRANGE with an empty value never creates the key
+		if value == "" {
+			return 0, nil
+		}
+		current = newStringValue("")
+		shard.dbs[s.db].put(key, current)
+	} else if value == "" {
+		return int64(len(current.Data)), nil
+	}
+
+	data := current.Data
+	if end := offset + len(value); end > len(data) {
+		data += strings.Repeat("\x00", end-len(data))
+	}
+	current.Data = data[:offset] + value + data[offset+len(value):]
+	current.Type = StringType
+	current.Version = newVersion()
+	shard.dbs[s.db].resize(key, current)
+	s.notify(s.db, EventSetRange, key)
+	return int64(len(current.Data)), nil
+}
+
+// GetDel returns the value stored at key and deletes the key
+func (s *Store) GetDel(key string) (string, bool) {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	value, exists := s.lookup(shard, key, time.Now())
+	if !exists {
+		return "", false
+	}
+
+	shard.dbs[s.db].remove(key)
+	s.notify(s.db, EventDel, key)
+	return value.Data, true
+}
+
+// GetEx returns the value stored at key and optionally changes its expiration.
+// When persist is true the expiration is removed; otherwise a non-zero
+// expiresAt replaces it. An expiresAt in the past deletes the key after it has
+// been read.
+func (s *Store) GetEx(key string, expiresAt time.Time, persist bool) (string, bool) {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	now := time.Now()
+	value, exists := s.lookup(shard, key, now)
+	if !exists {
+		return "", false
+	}
+
+	switch {
+	case persist:
+		if value.ExpiresAt != nil {
+			shard.dbs[s.db].setExpiry(key, value, nil)
+			s.notify(s.db, EventPersist, key)
+		}
+	case expiresAt.IsZero():
+		// Plain GET semantics
+	case !expiresAt.After(now):
+		shard.dbs[s.db].remove(key)
+		s.notify(s.db, EventDel, key)
+	default:
+		shard.dbs[s.db].setExpiry(key, value, &expiresAt)
+		s.notify(s.db, EventExpire, key)
+	}
+
+	return value.Data, true
+}
+
+// GetSet stores value at key, clearing any expiration, and returns the
+// previous value
+func (s *Store) GetSet(key, value string) (string, bool) {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	old, exists := s.lookup(shard, key, time.Now())
+	shard.dbs[s.db].put(key, newStringValue(value))
+	s.notify(s.db, EventSet, key)
+	if !exists {
+		return "", false
+	}
+
+	return old.Data, true
+}
+
+// SetNX stores value at key only if the key does not already exist
+func (s *Store) SetNX(key, value string, expiration *time.Duration) bool {
+	shard := s.getShard(key)
+	shard.lock()
+	defer shard.unlock()
+
+	now := time.Now()
+	if _, exists := s.lookup(shard, key, now); exists {
+		return false
+	}
+
+	val := newStringValue(value)
+	if expiration != nil {
+		expiresAt := now.Add(*expiration)
+		val.ExpiresAt = &expiresAt
+	}
+
+	shard.dbs[s.db].put(key, val)
+	s.notify(s.db, EventSet, key)
+	if expiration != nil {
+		s.notify(s.db, EventExpire, key)
+	}
+	return true
+}
+
+// MSetNX stores every key/value pair only if none of the keys exist. All
+// involved shards are locked for the duration so the check and the writes are
+// atomic. Later pairs win when a key is repeated.
+func (s *Store) MSetNX(keys, values []string) bool {
+	unlock := s.lockShards(keys)
+	defer unlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		if _, exists := s.lookup(s.getShard(key), key, now); exists {
+			return false
+		}
+	}
+
+	for i, key := range keys {
+		s.getShard(key).dbs[s.db].put(key, newStringValue(values[i]))
+		s.notify(s.db, EventSet, key)
+	}
+
+	return true
+}
+
+// IncrBy adds increment to the integer stored at key, keeping the key's
+// expiration, and returns the new value
+func (s *Store) IncrBy(key string, increment int64) (int64, error) {
+	var result int64
+	err := s.Update(key, func(current *Value) (*Value, error) {
+		var n int64
+		if current != nil {
+			parsed, err := strconv.ParseInt(current.Data, 10, 64)
+			if err != nil {
+				return nil, ErrNotInteger
+			}
+			n = parsed
+		}
+
+		if (increment > 0 && n > math.MaxInt64-increment) || (increment < 0 && n < math.MinInt64-increment) {
+			return nil, ErrOverflow
+		}
+		result = n + increment
+
+		s.notify(s.db, EventIncrBy, key)
+		return withData(current, strconv.FormatInt(result, 10)), nil
+	})
+
+	return result, err
+}
+
+// IncrByFloat adds increment to the floating point number stored at key,
+// keeping the key's expiration, and returns the new value
+func (s *Store) IncrByFloat(key string, increment float64) (float64, error) {
+	var result float64
+	err := s.Update(key, func(current *Value) (*Value, error) {
+		var f float64
+		if current != nil {
+			parsed, err := strconv.ParseFloat(current.Data, 64)
+			if err != nil || math.IsNaN(parsed) {
+				return nil, ErrNotFloat
+			}
+			f = parsed
+		}
+
+		result = f + increment
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return nil, ErrNaNOrInfinity
+		}
+
+		s.notify(s.db, EventIncrByFloat, key)
+		return withData(current, FormatFloat(result)), nil
+	})
+
+	return result, err
+}
+
+// withData returns current with its data replaced, or a fresh string value
+// when current is nil. The expiration of current is preserved.
+func withData(current *Value, data string) *Value {
+	if current == nil {
+		return newStringValue(data)
+	}
+
+	current.Data = data
+	current.Type = StringType
+	return current
+}
+
+// FormatFloat formats f the way Redis renders floating point replies: plain
+// decimal notation with no exponent and no trailing zeros
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
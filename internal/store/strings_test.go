@@ -0,0 +1,358 @@
+package store_test
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2095/kv-stash/internal/obs"
+	"github.com/Abhishek2095/kv-stash/internal/store"
+)
+
+// noLimit is a string length limit no test reaches
+const noLimit = math.MaxInt64
+
+func createTestStore(t *testing.T) *store.Store {
+	t.Helper()
+
+	s, err := store.New(&store.Config{
+		Shards:         4,
+		MaxMemoryBytes: 0,
+		EvictionPolicy: "noeviction",
+	}, obs.NewLogger(false))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func TestStore_Append(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+
+	if n, err := s.Append("key", "Hello", noLimit); err != nil || n != 5 {
+		t.Errorf("Expected length 5 after first APPEND, got %d, %v", n, err)
+	}
+	if n, err := s.Append("key", " World", 11); err != nil || n != 11 {
+		t.Errorf("Expected length 11 after second APPEND, got %d, %v", n, err)
+	}
+	if _, err := s.Append("key", "!", 11); !errors.Is(err, store.ErrStringTooLong) {
+		t.Errorf("Expected APPEND past the limit to fail, got %v", err)
+	}
+	if _, err := s.Append("other", "Hello", 4); !errors.Is(err, store.ErrStringTooLong) {
+		t.Errorf("Expected APPEND creating a key past the limit to fail, got %v", err)
+	}
+
+	value, _ := s.Get("key")
+	if value != "Hello World" {
+		t.Errorf("Expected 'Hello World', got %q", value)
+	}
+	if s.Exists("other") {
+		t.Errorf("Expected the failed APPEND not to create its key")
+	}
+}
+
+func TestStore_Append_ConcurrentLimit(t *testing.T) {
+	t.Parallel()
+
+	// Every APPEND is checked against the length under the shard lock, so
+	// concurrent appends stop exactly at the limit
+	const limit = 100
+	s := createTestStore(t)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				_, _ = s.Append("key", "x", limit)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := s.Get("key"); len(value) != limit {
+		t.Errorf("Expected the string to stop at %d bytes, got %d", limit, len(value))
+	}
+}
+
+func TestStore_SetRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		initial  *string
+		offset   int
+		value    string
+		limit    int64
+		expected string
+		length   int64
+		err      error
+	}{
+		{name: "overwrite middle", initial: ptr("Hello World"), offset: 6, value: "Redis", expected: "Hello Redis", length: 11},
+		{name: "extend past end", initial: ptr("Hello"), offset: 5, value: "!!", expected: "Hello!!", length: 7},
+		{name: "zero padding on missing key", offset: 3, value: "abc", expected: "\x00\x00\x00abc", length: 6},
+		{name: "zero padding on existing key", initial: ptr("ab"), offset: 4, value: "c", expected: "ab\x00\x00c", length: 5},
+		{name: "empty value on missing key", offset: 10, value: "", length: 0},
+		{name: "write ending at the limit", initial: ptr("ab"), offset: 2, value: "cd", limit: 4, expected: "abcd", length: 4},
+		{name: "write ending past the limit", initial: ptr("ab"), offset: 3, value: "cd", limit: 4, expected: "ab", err: store.ErrStringTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := createTestStore(t)
+			if tt.initial != nil {
+				s.Set("key", *tt.initial, nil)
+			}
+
+			limit := tt.limit
+			if limit == 0 {
+				limit = noLimit
+			}
+			if n, err := s.SetRange("key", tt.offset, tt.value, limit); n != tt.length || !errors.Is(err, tt.err) {
+				t.Errorf("Expected length %d and error %v, got %d and %v", tt.length, tt.err, n, err)
+			}
+
+			value, exists := s.Get("key")
+			if tt.initial == nil && tt.value == "" {
+				if exists {
+					t.Errorf("Expected empty SETRANGE not to create the key")
+				}
+				return
+			}
+			if value != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestStore_GetDel(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	s.Set("key", "value", nil)
+
+	value, exists := s.GetDel("key")
+	if !exists || value != "value" {
+		t.Errorf("Expected 'value', got %q (exists: %v)", value, exists)
+	}
+
+	if s.Exists("key") {
+		t.Errorf("Expected key to be deleted by GETDEL")
+	}
+
+	if _, exists := s.GetDel("key"); exists {
+		t.Errorf("Expected GETDEL on missing key to report not found")
+	}
+}
+
+func TestStore_GetEx(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	s.Set("key", "value", nil)
+
+	// Set an expiration
+	if _, exists := s.GetEx("key", time.Now().Add(time.Minute), false); !exists {
+		t.Fatalf("Expected key to exist")
+	}
+	if ttl := s.TTL("key"); ttl <= 0 || ttl > 60 {
+		t.Errorf("Expected TTL between 1 and 60, got %d", ttl)
+	}
+
+	// Remove it again
+	s.GetEx("key", time.Time{}, true)
+	if ttl := s.TTL("key"); ttl != -1 {
+		t.Errorf("Expected TTL -1 after PERSIST, got %d", ttl)
+	}
+
+	// Expiration in the past deletes the key but still returns the value
+	value, exists := s.GetEx("key", time.Now().Add(-time.Second), false)
+	if !exists || value != "value" {
+		t.Errorf("Expected 'value', got %q (exists: %v)", value, exists)
+	}
+	if s.Exists("key") {
+		t.Errorf("Expected key to be deleted by past expiration")
+	}
+}
+
+func TestStore_GetSetAndSetNX(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+
+	expiration := time.Minute
+	if !s.SetNX("key", "first", &expiration) {
+		t.Errorf("Expected SETNX to succeed on missing key")
+	}
+	if s.SetNX("key", "second", nil) {
+		t.Errorf("Expected SETNX to fail on existing key")
+	}
+
+	old, exists := s.GetSet("key", "third")
+	if !exists || old != "first" {
+		t.Errorf("Expected old value 'first', got %q (exists: %v)", old, exists)
+	}
+
+	// GETSET discards the TTL
+	if ttl := s.TTL("key"); ttl != -1 {
+		t.Errorf("Expected TTL -1 after GETSET, got %d", ttl)
+	}
+}
+
+func TestStore_MSetNX(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+
+	if !s.MSetNX([]string{"a", "b", "c"}, []string{"1", "2", "3"}) {
+		t.Errorf("Expected MSETNX to succeed when no key exists")
+	}
+
+	if s.MSetNX([]string{"d", "a"}, []string{"4", "5"}) {
+		t.Errorf("Expected MSETNX to fail when a key exists")
+	}
+
+	if s.Exists("d") {
+		t.Errorf("Expected MSETNX to write nothing when it fails")
+	}
+
+	if value, _ := s.Get("a"); value != "1" {
+		t.Errorf("Expected 'a' to keep its value, got %q", value)
+	}
+}
+
+func TestStore_IncrByFloat(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	expiration := time.Minute
+	s.Set("key", "10.50", &expiration)
+
+	result, err := s.IncrByFloat("key", 0.1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != 10.6 {
+		t.Errorf("Expected 10.6, got %v", result)
+	}
+
+	if value, _ := s.Get("key"); value != "10.6" {
+		t.Errorf("Expected stored value '10.6', got %q", value)
+	}
+
+	if ttl := s.TTL("key"); ttl <= 0 {
+		t.Errorf("Expected INCRBYFLOAT to keep the TTL, got %d", ttl)
+	}
+
+	s.Set("text", "abc", nil)
+	if _, err := s.IncrByFloat("text", 1); !errors.Is(err, store.ErrNotFloat) {
+		t.Errorf("Expected ErrNotFloat, got %v", err)
+	}
+
+	s.Set("big", "1.7976931348623157e308", nil)
+	if _, err := s.IncrByFloat("big", 1.7976931348623157e308); !errors.Is(err, store.ErrNaNOrInfinity) {
+		t.Errorf("Expected ErrNaNOrInfinity, got %v", err)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	t.Parallel()
+
+	tests := map[float64]string{
+		3.0e3:   "3000",
+		10.5:    "10.5",
+		-0.25:   "-0.25",
+		5.0e20:  "500000000000000000000",
+		1.0e-05: "0.00001",
+	}
+
+	for input, expected := range tests {
+		if got := store.FormatFloat(input); got != expected {
+			t.Errorf("FormatFloat(%v) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestStore_IncrBy(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+
+	if n, err := s.IncrBy("counter", 5); err != nil || n != 5 {
+		t.Errorf("Expected 5, got %d (err: %v)", n, err)
+	}
+
+	s.Set("max", "9223372036854775807", nil)
+	if _, err := s.IncrBy("max", 1); !errors.Is(err, store.ErrOverflow) {
+		t.Errorf("Expected ErrOverflow, got %v", err)
+	}
+
+	s.Set("min", "-9223372036854775808", nil)
+	if _, err := s.IncrBy("min", -1); !errors.Is(err, store.ErrOverflow) {
+		t.Errorf("Expected ErrOverflow, got %v", err)
+	}
+
+	s.Set("text", "abc", nil)
+	if _, err := s.IncrBy("text", 1); !errors.Is(err, store.ErrNotInteger) {
+		t.Errorf("Expected ErrNotInteger, got %v", err)
+	}
+
+	expiration := time.Minute
+	s.Set("ttl", "1", &expiration)
+	if _, err := s.IncrBy("ttl", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ttl := s.TTL("ttl"); ttl <= 0 {
+		t.Errorf("Expected INCRBY to keep the TTL, got %d", ttl)
+	}
+}
+
+func TestStore_IncrBy_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	s := createTestStore(t)
+	expiration := time.Hour
+	s.Set("counter", "0", &expiration)
+
+	const numGoroutines = 64
+	const numIncrements = 500
+
+	var wg sync.WaitGroup
+	for range numGoroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range numIncrements {
+				if _, err := s.IncrBy("counter", 1); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, _ := s.Get("counter")
+	expected := strconv.Itoa(numGoroutines * numIncrements)
+	if value != expected {
+		t.Errorf("Expected counter to be %s, got %s (lost updates)", expected, value)
+	}
+
+	if ttl := s.TTL("counter"); ttl <= 0 {
+		t.Errorf("Expected concurrent increments to keep the TTL, got %d", ttl)
+	}
+}